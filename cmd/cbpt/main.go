@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"github.com/anishpateluk/coinbasepro-trader/internal/app/cbpt"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"github.com/joho/godotenv"
+	"os"
+)
+
+func main() {
+	// a missing .env is fine, credentials can come from the environment alone
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, "cbpt:", err)
+		os.Exit(1)
+	}
+
+	connect := func() (cbpt.API, error) {
+		return coinbasepro.NewClient()
+	}
+
+	if err := cbpt.Run(os.Args[1:], os.Stdout, os.Stderr, connect); err != nil {
+		fmt.Fprintln(os.Stderr, "cbpt:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/anishpateluk/coinbasepro-trader/internal/app/dashboard"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"github.com/joho/godotenv"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sandboxEnvPrefix = "COINBASE_PRO_SANDBOX_"
+const sandboxWebsocketUrlKey = "COINBASE_PRO_SANDBOX_WEBSOCKET_URL"
+const defaultSandboxWebsocketUrl = "wss://ws-feed-public.sandbox.pro.coinbase.com"
+
+// paperFeed passes market data to the paper client before the dashboard sees it, so resting paper
+// orders fill against the live market.
+type paperFeed struct {
+	source coinbasepro.MessageSource
+	paper  *coinbasepro.PaperClient
+}
+
+func (f paperFeed) Read() (coinbasepro.Message, error) {
+	message, err := f.source.Read()
+	if err != nil {
+		return message, err
+	}
+
+	return message, f.paper.HandleMessage(message)
+}
+
+func parseBalances(value string) (map[string]float64, error) {
+	balances := make(map[string]float64)
+	for _, entry := range strings.Split(value, ",") {
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("balance %q is not CURRENCY=AMOUNT", entry)
+		}
+
+		amount, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		balances[strings.ToUpper(parts[0])] = amount
+	}
+
+	return balances, nil
+}
+
+func run() error {
+	products := flag.String("products", "BTC-USD", "comma separated products to show")
+	mode := flag.String("mode", dashboard.ModePaper, "live, sandbox, or paper to simulate orders against live market data")
+	balances := flag.String("balances", "USD=10000", "starting paper balances, CURRENCY=AMOUNT separated by commas")
+	refresh := flag.Duration("refresh", 5*time.Second, "how often balances, orders and fills are polled")
+	depth := flag.Int("depth", 8, "depth ladder levels per side")
+	flag.Parse()
+
+	// a missing .env is fine, credentials can come from the environment alone
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	config := dashboard.Config{ProductIds: strings.Split(*products, ","), Mode: *mode, Depth: *depth}
+
+	var api dashboard.API
+	var feed *coinbasepro.Feed
+	var source coinbasepro.MessageSource
+	switch *mode {
+	case dashboard.ModeLive:
+		client, err := coinbasepro.NewClient()
+		if err != nil {
+			return err
+		}
+		api, feed = client, client.NewFeed("")
+		source = feed
+	case dashboard.ModeSandbox:
+		client, err := coinbasepro.NewClientWithCredentialProvider(coinbasepro.NewEnvCredentialProvider(sandboxEnvPrefix))
+		if err != nil {
+			return err
+		}
+
+		websocketUrl := os.Getenv(sandboxWebsocketUrlKey)
+		if websocketUrl == "" {
+			websocketUrl = defaultSandboxWebsocketUrl
+		}
+		api, feed = client, client.NewFeed(websocketUrl)
+		source = feed
+	case dashboard.ModePaper:
+		startingBalances, err := parseBalances(*balances)
+		if err != nil {
+			return err
+		}
+
+		paper := coinbasepro.NewPaperClient(startingBalances, coinbasepro.DefaultFeeTier)
+		defer paper.Close()
+		api, feed = paper, coinbasepro.NewFeed("")
+		source = paperFeed{source: feed, paper: paper}
+	}
+
+	board, err := dashboard.New(api, config)
+	if err != nil {
+		return err
+	}
+
+	defer feed.Close()
+	subscription := coinbasepro.Subscription{
+		ProductIds: config.ProductIds,
+		Channels:   []string{coinbasepro.ChannelLevel2, coinbasepro.ChannelTicker, coinbasepro.ChannelMatches},
+	}
+	if err := feed.Subscribe(subscription); err != nil {
+		return err
+	}
+
+	restore, err := dashboard.MakeRaw(os.Stdin.Fd())
+	if err == nil {
+		defer restore()
+	}
+
+	terminal := dashboard.Terminal{
+		Input:  os.Stdin,
+		Output: os.Stdout,
+		Size: func() (int, int, error) {
+			return dashboard.Size(os.Stdout.Fd())
+		},
+	}
+
+	return dashboard.Run(board, terminal, source, *refresh)
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "dashboard:", err)
+		os.Exit(1)
+	}
+}
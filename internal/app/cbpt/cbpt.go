@@ -0,0 +1,550 @@
+// Package cbpt is the cbpt command line tool, which queries the exchange and manages orders from a
+// shell. Every command prints a table, JSON or CSV.
+package cbpt
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const UnknownCommandErrorMessage = "unknown command"
+const MissingProductErrorMessage = "a product id is required"
+const MissingOrderIdErrorMessage = "an order id or -client-oid is required"
+const MissingFillFilterErrorMessage = "fills needs -product or -order"
+
+// API is everything the commands use, a Client satisfies it.
+type API interface {
+	coinbasepro.MarketDataAPI
+	coinbasepro.TradingAPI
+	coinbasepro.AccountsAPI
+	coinbasepro.FundingAPI
+}
+
+var _ API = (*coinbasepro.Client)(nil)
+
+var openOrderStatuses = []string{"open", "pending", "active"}
+
+const usage = `usage: cbpt <command> [arguments] [-format table|json|csv]
+
+commands:
+  accounts                         list accounts
+  balances [-all]                  list non zero balances, or every one with -all
+  products                         list products
+  ticker <product>                 show the last trade and best prices
+  book <product> [-level] [-depth] show the order book
+  candles <product> [-granularity] [-start] [-end]
+                                   list candles, times are RFC 3339
+  orders list [-product] [-status] list orders, open ones by default
+  orders place -product -side [-type] [-price] [-size] [-funds] [-stop] [-stop-price]
+               [-time-in-force] [-post-only] [-client-oid]
+                                   place an order
+  orders cancel <order id>... | -client-oid <id>
+                                   cancel orders
+  orders cancel-all [-product]     cancel every open order
+  fills -product | -order          list fills
+  transfers [-type]                list deposits and withdrawals
+  time                             show the exchange time
+
+Credentials are read from the environment like coinbasepro.NewClient, after loading .env when
+there is one.
+`
+
+// Run runs the command in args, connecting to the exchange only once the command is known to be
+// valid.
+func Run(args []string, stdout, stderr io.Writer, connect func() (API, error)) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+
+	name, args := args[0], args[1:]
+	if name == "orders" {
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			name = "orders list"
+		} else {
+			name, args = "orders "+args[0], args[1:]
+		}
+	}
+
+	command, found := commands[name]
+	if !found {
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("%s: %q", UnknownCommandErrorMessage, name)
+	}
+
+	flags := flag.NewFlagSet("cbpt "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", FormatTable, "output format: table, json or csv")
+	options := command.flags(flags)
+
+	positional, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if *format != FormatTable && *format != FormatJson && *format != FormatCsv {
+		return fmt.Errorf("%s: %q", UnknownFormatErrorMessage, *format)
+	}
+
+	api, err := connect()
+	if err != nil {
+		return err
+	}
+
+	// a command can fail part way, like a cancel-all with some failed cancels, and still have
+	// results to print
+	result, err := options.run(api, positional)
+	if result.headers == nil {
+		return err
+	}
+
+	if writeErr := write(stdout, *format, result); err == nil {
+		err = writeErr
+	}
+
+	return err
+}
+
+// parse allows flags after positional arguments, as in cbpt ticker BTC-USD -format json.
+func parse(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// runner runs a command once its flags are parsed.
+type runner interface {
+	run(api API, args []string) (table, error)
+}
+
+type runnerFunc func(api API, args []string) (table, error)
+
+func (f runnerFunc) run(api API, args []string) (table, error) {
+	return f(api, args)
+}
+
+type command struct {
+	flags func(flags *flag.FlagSet) runner
+}
+
+func simple(run runnerFunc) command {
+	return command{flags: func(flags *flag.FlagSet) runner { return run }}
+}
+
+var commands = map[string]command{
+	"accounts":          simple(accounts),
+	"balances":          {flags: balancesFlags},
+	"products":          simple(products),
+	"ticker":            simple(ticker),
+	"book":              {flags: bookFlags},
+	"candles":           {flags: candlesFlags},
+	"orders list":       {flags: listOrdersFlags},
+	"orders place":      {flags: placeOrderFlags},
+	"orders cancel":     {flags: cancelOrdersFlags},
+	"orders cancel-all": {flags: cancelAllFlags},
+	"fills":             {flags: fillsFlags},
+	"transfers":         {flags: transfersFlags},
+	"time":              simple(serverTime),
+}
+
+func productArgument(args []string) (string, error) {
+	if len(args) == 0 || args[0] == "" {
+		return "", errors.New(MissingProductErrorMessage)
+	}
+
+	return args[0], nil
+}
+
+func accounts(api API, args []string) (table, error) {
+	accounts, err := api.GetAccounts()
+	if err != nil {
+		return table{}, err
+	}
+
+	result := table{headers: []string{"id", "currency", "balance", "available", "hold", "trading_enabled"}, value: accounts}
+	for _, account := range accounts {
+		result.add(account.Id, account.Currency, account.Balance, account.Available, account.Hold, strconv.FormatBool(account.TradingEnabled))
+	}
+
+	return result, nil
+}
+
+func balancesFlags(flags *flag.FlagSet) runner {
+	all := flags.Bool("all", false, "include currencies with a zero balance")
+
+	return runnerFunc(func(api API, args []string) (table, error) {
+		accounts, err := api.GetAccounts()
+		if err != nil {
+			return table{}, err
+		}
+
+		balances := make([]coinbasepro.Account, 0, len(accounts))
+		for _, account := range accounts {
+			if balance, _ := strconv.ParseFloat(account.Balance, 64); balance != 0 || *all {
+				balances = append(balances, account)
+			}
+		}
+
+		sort.SliceStable(balances, func(i, j int) bool {
+			return balances[i].Currency < balances[j].Currency
+		})
+
+		result := table{headers: []string{"currency", "balance", "available", "hold"}, value: balances}
+		for _, account := range balances {
+			result.add(account.Currency, account.Balance, account.Available, account.Hold)
+		}
+
+		return result, nil
+	})
+}
+
+func products(api API, args []string) (table, error) {
+	products, err := api.GetProducts()
+	if err != nil {
+		return table{}, err
+	}
+
+	sort.SliceStable(products, func(i, j int) bool {
+		return products[i].Id < products[j].Id
+	})
+
+	result := table{headers: []string{"id", "base", "quote", "base_min_size", "base_increment", "quote_increment", "status"}, value: products}
+	for _, product := range products {
+		result.add(product.Id, product.BaseCurrency, product.QuoteCurrency, product.BaseMinSize, product.BaseIncrement, product.QuoteIncrement, product.Status)
+	}
+
+	return result, nil
+}
+
+func ticker(api API, args []string) (table, error) {
+	productId, err := productArgument(args)
+	if err != nil {
+		return table{}, err
+	}
+
+	ticker, err := api.GetTicker(productId)
+	if err != nil {
+		return table{}, err
+	}
+
+	result := table{headers: []string{"product", "price", "size", "bid", "ask", "volume", "time"}, value: ticker}
+	result.add(productId, ticker.Price, ticker.Size, ticker.Bid, ticker.Ask, ticker.Volume, ticker.Time)
+
+	return result, nil
+}
+
+func bookFlags(flags *flag.FlagSet) runner {
+	level := flags.Int("level", 2, "book level, 1 for the best prices, 2 aggregated or 3 every order")
+	depth := flags.Int("depth", 10, "rows per side to print, 0 for all")
+
+	return runnerFunc(func(api API, args []string) (table, error) {
+		productId, err := productArgument(args)
+		if err != nil {
+			return table{}, err
+		}
+
+		book, err := api.GetProductBook(productId, *level)
+		if err != nil {
+			return table{}, err
+		}
+
+		if *depth > 0 {
+			if len(book.Bids) > *depth {
+				book.Bids = book.Bids[:*depth]
+			}
+			if len(book.Asks) > *depth {
+				book.Asks = book.Asks[:*depth]
+			}
+		}
+
+		result := table{headers: []string{"side", "price", "size", "orders"}, value: book}
+		// asks from the highest down to the spread, then bids away from it
+		for i := len(book.Asks) - 1; i >= 0; i-- {
+			result.add("ask", book.Asks[i].Price, book.Asks[i].Size, bookOrders(book.Asks[i]))
+		}
+		for _, entry := range book.Bids {
+			result.add("bid", entry.Price, entry.Size, bookOrders(entry))
+		}
+
+		return result, nil
+	})
+}
+
+func bookOrders(entry coinbasepro.BookEntry) string {
+	if entry.OrderId != "" {
+		return entry.OrderId
+	}
+
+	return strconv.Itoa(entry.NumOrders)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+func candlesFlags(flags *flag.FlagSet) runner {
+	granularity := flags.Int("granularity", 3600, "candle width in seconds: 60, 300, 900, 3600, 21600 or 86400")
+	start := flags.String("start", "", "first candle time")
+	end := flags.String("end", "", "last candle time")
+
+	return runnerFunc(func(api API, args []string) (table, error) {
+		productId, err := productArgument(args)
+		if err != nil {
+			return table{}, err
+		}
+
+		filter := coinbasepro.CandleFilter{Granularity: *granularity}
+		if filter.Start, err = parseTime(*start); err != nil {
+			return table{}, err
+		}
+		if filter.End, err = parseTime(*end); err != nil {
+			return table{}, err
+		}
+
+		candles, err := api.GetCandles(productId, filter)
+		if err != nil {
+			return table{}, err
+		}
+
+		sort.SliceStable(candles, func(i, j int) bool {
+			return candles[i].Time.Before(candles[j].Time)
+		})
+
+		result := table{headers: []string{"time", "open", "high", "low", "close", "volume"}, value: candles}
+		for _, candle := range candles {
+			result.add(candle.Time.UTC().Format(time.RFC3339), formatFloat(candle.Open), formatFloat(candle.High), formatFloat(candle.Low), formatFloat(candle.Close), formatFloat(candle.Volume))
+		}
+
+		return result, nil
+	})
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func orderTable(orders []coinbasepro.Order, value interface{}) table {
+	result := table{headers: []string{"id", "client_oid", "product", "side", "type", "price", "size", "funds", "filled_size", "status", "created_at"}, value: value}
+	for _, order := range orders {
+		result.add(order.Id, order.ClientOid, order.ProductId, order.Side, order.Type, order.Price, order.Size, order.Funds, order.FilledSize, order.Status, order.CreatedAt)
+	}
+
+	return result
+}
+
+func listOrdersFlags(flags *flag.FlagSet) runner {
+	productId := flags.String("product", "", "only orders for this product")
+	status := flags.String("status", strings.Join(openOrderStatuses, ","), "comma separated statuses, or all")
+
+	return runnerFunc(func(api API, args []string) (table, error) {
+		filter := coinbasepro.OrderFilter{ProductId: *productId}
+		if *status != "" {
+			filter.Statuses = strings.Split(*status, ",")
+		}
+
+		orders, err := api.ListOrders(filter)
+		if err != nil {
+			return table{}, err
+		}
+
+		return orderTable(orders, orders), nil
+	})
+}
+
+func placeOrderFlags(flags *flag.FlagSet) runner {
+	var order coinbasepro.Order
+	flags.StringVar(&order.ProductId, "product", "", "product id")
+	flags.StringVar(&order.Side, "side", "", "buy or sell")
+	flags.StringVar(&order.Type, "type", coinbasepro.OrderTypeLimit, "limit or market")
+	flags.StringVar(&order.Price, "price", "", "limit price")
+	flags.StringVar(&order.Size, "size", "", "size in the base currency")
+	flags.StringVar(&order.Funds, "funds", "", "funds in the quote currency, market orders only")
+	flags.StringVar(&order.Stop, "stop", "", "loss or entry")
+	flags.StringVar(&order.StopPrice, "stop-price", "", "price the stop triggers at")
+	flags.StringVar(&order.TimeInForce, "time-in-force", "", "GTC, GTT, IOC or FOK")
+	flags.StringVar(&order.CancelAfter, "cancel-after", "", "min, hour or day, GTT orders only")
+	flags.BoolVar(&order.PostOnly, "post-only", false, "reject the order rather than take liquidity")
+	flags.StringVar(&order.ClientOid, "client-oid", "", "client order id, a UUID")
+
+	return runnerFunc(func(api API, args []string) (table, error) {
+		if order.ProductId == "" {
+			return table{}, errors.New(MissingProductErrorMessage)
+		}
+
+		placed, err := api.PlaceOrder(order)
+		if err != nil {
+			return table{}, err
+		}
+
+		return orderTable([]coinbasepro.Order{placed}, placed), nil
+	})
+}
+
+// cancelled is one order a cancel command tried to cancel.
+type cancelled struct {
+	Id        string `json:"id,omitempty"`
+	ClientOid string `json:"client_oid,omitempty"`
+	ProductId string `json:"product_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func cancelTable(results []cancelled) (table, error) {
+	result := table{headers: []string{"id", "client_oid", "product", "result"}, value: results}
+	failed := 0
+	for _, cancel := range results {
+		outcome := "cancelled"
+		if cancel.Error != "" {
+			outcome = cancel.Error
+			failed++
+		}
+		result.add(cancel.Id, cancel.ClientOid, cancel.ProductId, outcome)
+	}
+
+	if failed > 0 {
+		return result, fmt.Errorf("%d of %d cancels failed", failed, len(results))
+	}
+
+	return result, nil
+}
+
+func cancelOrdersFlags(flags *flag.FlagSet) runner {
+	clientOid := flags.String("client-oid", "", "cancel the order with this client order id")
+
+	return runnerFunc(func(api API, args []string) (table, error) {
+		if len(args) == 0 && *clientOid == "" {
+			return table{}, errors.New(MissingOrderIdErrorMessage)
+		}
+
+		results := make([]cancelled, 0, len(args)+1)
+		if *clientOid != "" {
+			result := cancelled{ClientOid: *clientOid}
+			if err := api.CancelOrderByClientOid(*clientOid); err != nil {
+				result.Error = err.Error()
+			}
+			results = append(results, result)
+		}
+
+		for _, orderId := range args {
+			result := cancelled{Id: orderId}
+			if err := api.CancelOrder(orderId); err != nil {
+				result.Error = err.Error()
+			}
+			results = append(results, result)
+		}
+
+		return cancelTable(results)
+	})
+}
+
+func cancelAllFlags(flags *flag.FlagSet) runner {
+	productId := flags.String("product", "", "only cancel orders for this product")
+
+	// a listing holds at most a page of orders, so list again until it only holds orders that
+	// were already tried
+	return runnerFunc(func(api API, args []string) (table, error) {
+		results := make([]cancelled, 0)
+		tried := make(map[string]bool)
+		for {
+			orders, err := api.ListOrders(coinbasepro.OrderFilter{ProductId: *productId, Statuses: openOrderStatuses})
+			if err != nil {
+				return table{}, err
+			}
+
+			found := false
+			for _, order := range orders {
+				if tried[order.Id] {
+					continue
+				}
+				tried[order.Id] = true
+				found = true
+
+				result := cancelled{Id: order.Id, ClientOid: order.ClientOid, ProductId: order.ProductId}
+				if err := api.CancelOrder(order.Id); err != nil {
+					result.Error = err.Error()
+				}
+				results = append(results, result)
+			}
+
+			if !found {
+				return cancelTable(results)
+			}
+		}
+	})
+}
+
+func fillsFlags(flags *flag.FlagSet) runner {
+	var filter coinbasepro.FillFilter
+	flags.StringVar(&filter.ProductId, "product", "", "fills for this product")
+	flags.StringVar(&filter.OrderId, "order", "", "fills of this order")
+
+	return runnerFunc(func(api API, args []string) (table, error) {
+		if filter.ProductId == "" && filter.OrderId == "" {
+			return table{}, errors.New(MissingFillFilterErrorMessage)
+		}
+
+		fills, err := api.GetFills(filter)
+		if err != nil {
+			return table{}, err
+		}
+
+		result := table{headers: []string{"trade_id", "product", "order_id", "side", "price", "size", "fee", "liquidity", "created_at"}, value: fills}
+		for _, fill := range fills {
+			result.add(strconv.FormatInt(fill.TradeId, 10), fill.ProductId, fill.OrderId, fill.Side, fill.Price, fill.Size, fill.Fee, fill.Liquidity, fill.CreatedAt)
+		}
+
+		return result, nil
+	})
+}
+
+func transfersFlags(flags *flag.FlagSet) runner {
+	var filter coinbasepro.TransferFilter
+	flags.StringVar(&filter.Type, "type", "", "deposit or withdraw")
+
+	return runnerFunc(func(api API, args []string) (table, error) {
+		transfers, err := api.GetTransfers(filter)
+		if err != nil {
+			return table{}, err
+		}
+
+		result := table{headers: []string{"id", "type", "amount", "created_at", "completed_at", "canceled_at"}, value: transfers}
+		for _, transfer := range transfers {
+			result.add(transfer.Id, transfer.Type, transfer.Amount, transfer.CreatedAt, transfer.CompletedAt, transfer.CanceledAt)
+		}
+
+		return result, nil
+	})
+}
+
+func serverTime(api API, args []string) (table, error) {
+	serverTime, err := api.GetTime()
+	if err != nil {
+		return table{}, err
+	}
+
+	result := table{headers: []string{"iso", "epoch"}, value: serverTime}
+	result.add(serverTime.Iso, formatFloat(serverTime.Epoch))
+
+	return result, nil
+}
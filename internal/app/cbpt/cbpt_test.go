@@ -0,0 +1,194 @@
+package cbpt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro/mock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockAPI struct {
+	*mock.MarketDataAPI
+	*mock.TradingAPI
+	*mock.AccountsAPI
+	*mock.FundingAPI
+}
+
+func newMockAPI() mockAPI {
+	return mockAPI{&mock.MarketDataAPI{}, &mock.TradingAPI{}, &mock.AccountsAPI{}, &mock.FundingAPI{}}
+}
+
+func run(t *testing.T, api mockAPI, args ...string) (string, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err := Run(args, stdout, stderr, func() (API, error) { return api, nil })
+
+	return stdout.String(), err
+}
+
+func TestRun(t *testing.T) {
+	t.Run("should print balances as a table, json and csv", func(t *testing.T) {
+		api := newMockAPI()
+		api.GetAccountsFunc = func() ([]coinbasepro.Account, error) {
+			return []coinbasepro.Account{
+				{Id: "2", Currency: "USD", Balance: "100.50", Available: "90.50", Hold: "10.00"},
+				{Id: "3", Currency: "ETH", Balance: "0.0000000000000000"},
+				{Id: "1", Currency: "BTC", Balance: "1.5", Available: "1.5", Hold: "0"},
+			}, nil
+		}
+
+		out, err := run(t, api, "balances")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, out, "CURRENCY  BALANCE  AVAILABLE  HOLD\n"+
+			"BTC       1.5      1.5        0\n"+
+			"USD       100.50   90.50      10.00\n")
+
+		out, err = run(t, api, "balances", "-format", "csv", "-all")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, out, "currency,balance,available,hold\nBTC,1.5,1.5,0\nETH,0.0000000000000000,,\nUSD,100.50,90.50,10.00\n")
+
+		out, err = run(t, api, "balances", "-format", "json")
+		assert.Assert(t, is.Nil(err))
+		var balances []coinbasepro.Account
+		assert.Assert(t, is.Nil(json.Unmarshal([]byte(out), &balances)))
+		assert.Equal(t, len(balances), 2)
+		assert.Equal(t, balances[1].Id, "2")
+	})
+
+	t.Run("should accept flags after the product", func(t *testing.T) {
+		api := newMockAPI()
+		api.GetTickerFunc = func(productId string) (coinbasepro.Ticker, error) {
+			assert.Equal(t, productId, "BTC-USD")
+			return coinbasepro.Ticker{TradeId: 1, Price: "100.00", Size: "0.1", Bid: "99.99", Ask: "100.01", Volume: "5", Time: "2021-03-01T00:00:00Z"}, nil
+		}
+
+		out, err := run(t, api, "ticker", "BTC-USD", "-format", "csv")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, out, "product,price,size,bid,ask,volume,time\nBTC-USD,100.00,0.1,99.99,100.01,5,2021-03-01T00:00:00Z\n")
+
+		_, err = run(t, api, "ticker")
+		assert.Error(t, err, MissingProductErrorMessage)
+	})
+
+	t.Run("should print the book asks above bids up to the depth", func(t *testing.T) {
+		api := newMockAPI()
+		api.GetProductBookFunc = func(productId string, level int) (coinbasepro.ProductBook, error) {
+			assert.Equal(t, level, 2)
+			return coinbasepro.ProductBook{
+				Bids: []coinbasepro.BookEntry{{Price: "99", Size: "1", NumOrders: 1}, {Price: "98", Size: "2", NumOrders: 3}},
+				Asks: []coinbasepro.BookEntry{{Price: "101", Size: "1", NumOrders: 2}, {Price: "102", Size: "4", NumOrders: 1}},
+			}, nil
+		}
+
+		out, err := run(t, api, "book", "BTC-USD", "-depth", "1", "-format", "csv")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, out, "side,price,size,orders\nask,101,1,2\nbid,99,1,1\n")
+	})
+
+	t.Run("should pass the candle filter", func(t *testing.T) {
+		api := newMockAPI()
+		start := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+		api.GetCandlesFunc = func(productId string, filter coinbasepro.CandleFilter) ([]coinbasepro.Candle, error) {
+			assert.DeepEqual(t, filter, coinbasepro.CandleFilter{Start: start, Granularity: 60})
+			return []coinbasepro.Candle{
+				{Time: start.Add(time.Minute), Open: 2, High: 3, Low: 1, Close: 2.5, Volume: 10},
+				{Time: start, Open: 1, High: 2, Low: 1, Close: 2, Volume: 5},
+			}, nil
+		}
+
+		out, err := run(t, api, "candles", "BTC-USD", "-granularity", "60", "-start", "2021-03-01T00:00:00Z", "-format", "csv")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, out, "time,open,high,low,close,volume\n2021-03-01T00:00:00Z,1,2,1,2,5\n2021-03-01T00:01:00Z,2,3,1,2.5,10\n")
+	})
+
+	t.Run("should list open orders and place orders", func(t *testing.T) {
+		api := newMockAPI()
+		api.ListOrdersFunc = func(filter coinbasepro.OrderFilter) ([]coinbasepro.Order, error) {
+			assert.DeepEqual(t, filter, coinbasepro.OrderFilter{ProductId: "BTC-USD", Statuses: openOrderStatuses})
+			return []coinbasepro.Order{{Id: "o1", ProductId: "BTC-USD", Side: "buy", Type: "limit", Price: "100", Size: "1", Status: "open"}}, nil
+		}
+		api.PlaceOrderFunc = func(order coinbasepro.Order) (coinbasepro.Order, error) {
+			assert.DeepEqual(t, order, coinbasepro.Order{ProductId: "BTC-USD", Side: "sell", Type: "limit", Price: "110", Size: "0.5", PostOnly: true})
+			order.Id = "o2"
+			order.Status = "pending"
+			return order, nil
+		}
+
+		out, err := run(t, api, "orders", "-product", "BTC-USD", "-format", "csv")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, out, "id,client_oid,product,side,type,price,size,funds,filled_size,status,created_at\no1,,BTC-USD,buy,limit,100,1,,,open,\n")
+
+		out, err = run(t, api, "orders", "place", "-product", "BTC-USD", "-side", "sell", "-price", "110", "-size", "0.5", "-post-only", "-format", "json")
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, strings.Contains(out, `"id": "o2"`), out)
+	})
+
+	t.Run("should cancel every open order and report failures", func(t *testing.T) {
+		api := newMockAPI()
+		pages := [][]coinbasepro.Order{
+			{{Id: "o1", ProductId: "BTC-USD"}, {Id: "o2", ProductId: "ETH-USD"}},
+			{{Id: "o2", ProductId: "ETH-USD"}, {Id: "o3", ProductId: "BTC-USD"}},
+			{{Id: "o2", ProductId: "ETH-USD"}},
+		}
+		api.ListOrdersFunc = func(filter coinbasepro.OrderFilter) ([]coinbasepro.Order, error) {
+			page := pages[0]
+			if len(pages) > 1 {
+				pages = pages[1:]
+			}
+			return page, nil
+		}
+		api.CancelOrderFunc = func(orderId string) error {
+			if orderId == "o2" {
+				return errors.New("order not found")
+			}
+			return nil
+		}
+
+		out, err := run(t, api, "orders", "cancel-all", "-format", "csv")
+		assert.Error(t, err, "1 of 3 cancels failed")
+		assert.Equal(t, out, "id,client_oid,product,result\no1,,BTC-USD,cancelled\no2,,ETH-USD,order not found\no3,,BTC-USD,cancelled\n")
+		assert.Equal(t, api.TradingAPI.CallCount("CancelOrder"), 3)
+		assert.Equal(t, api.TradingAPI.CallCount("ListOrders"), 3)
+	})
+
+	t.Run("should cancel by order id and client oid", func(t *testing.T) {
+		api := newMockAPI()
+		api.CancelOrderFunc = func(orderId string) error { return nil }
+		api.CancelOrderByClientOidFunc = func(clientOid string) error { return nil }
+
+		_, err := run(t, api, "orders", "cancel", "o1", "o2", "-client-oid", "c1")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, api.TradingAPI.CallCount("CancelOrder"), 2)
+		assert.Equal(t, api.TradingAPI.CallCount("CancelOrderByClientOid"), 1)
+
+		_, err = run(t, api, "orders", "cancel")
+		assert.Error(t, err, MissingOrderIdErrorMessage)
+	})
+
+	t.Run("should require a fill filter", func(t *testing.T) {
+		_, err := run(t, newMockAPI(), "fills")
+		assert.Error(t, err, MissingFillFilterErrorMessage)
+	})
+
+	t.Run("should reject unknown commands and formats before connecting", func(t *testing.T) {
+		connect := func() (API, error) {
+			t.Fatal("should not connect")
+			return nil, nil
+		}
+
+		err := Run([]string{"wallets"}, &bytes.Buffer{}, &bytes.Buffer{}, connect)
+		assert.Error(t, err, UnknownCommandErrorMessage+`: "wallets"`)
+
+		err = Run([]string{"time", "-format", "xml"}, &bytes.Buffer{}, &bytes.Buffer{}, connect)
+		assert.Error(t, err, UnknownFormatErrorMessage+`: "xml"`)
+
+		out := &bytes.Buffer{}
+		assert.Assert(t, is.Nil(Run(nil, out, &bytes.Buffer{}, connect)))
+		assert.Assert(t, strings.HasPrefix(out.String(), "usage: cbpt"))
+	})
+}
@@ -0,0 +1,59 @@
+package cbpt
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const FormatTable = "table"
+const FormatJson = "json"
+const FormatCsv = "csv"
+
+const UnknownFormatErrorMessage = "unknown output format, use table, json or csv"
+
+// table is the rows a command prints for the table and csv formats. JSON prints value, the
+// response as the exchange returned it.
+type table struct {
+	headers []string
+	rows    [][]string
+	value   interface{}
+}
+
+func (t *table) add(row ...string) {
+	t.rows = append(t.rows, row)
+}
+
+func write(out io.Writer, format string, t table) error {
+	switch format {
+	case FormatTable:
+		writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.ToUpper(strings.Join(t.headers, "\t")))
+		for _, row := range t.rows {
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+
+		return writer.Flush()
+	case FormatJson:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(t.value)
+	case FormatCsv:
+		writer := csv.NewWriter(out)
+		if err := writer.Write(t.headers); err != nil {
+			return err
+		}
+
+		if err := writer.WriteAll(t.rows); err != nil {
+			return err
+		}
+
+		return writer.Error()
+	}
+
+	return fmt.Errorf("%s: %q", UnknownFormatErrorMessage, format)
+}
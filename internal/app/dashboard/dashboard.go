@@ -0,0 +1,425 @@
+// Package dashboard is a terminal dashboard showing balances, open orders, recent fills, a depth
+// ladder and a ticker for a set of products, with keyboard order entry and cancellation. It only
+// uses the standard library: frames are drawn with ANSI escape codes and keys read from a terminal
+// in raw mode.
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const ModeLive = "live"
+const ModeSandbox = "sandbox"
+const ModePaper = "paper"
+
+const UnknownModeErrorMessage = "unknown mode, use live, sandbox or paper"
+const MissingProductsErrorMessage = "the dashboard needs at least one product"
+
+const defaultDepth = 8
+const defaultFills = 8
+
+var openOrderStatuses = []string{"open", "pending", "active"}
+
+// API is what the dashboard trades and reads accounts through, a Client or a PaperClient.
+type API interface {
+	coinbasepro.TradingAPI
+	coinbasepro.AccountsAPI
+}
+
+var _ API = (*coinbasepro.Client)(nil)
+var _ API = (*coinbasepro.PaperClient)(nil)
+
+// Config chooses the products shown and how much of each panel. Mode is shown on every frame so
+// simulated and sandbox trading can not be mistaken for live trading.
+type Config struct {
+	ProductIds []string
+	Mode       string
+	Depth      int
+	Fills      int
+}
+
+type ticker struct {
+	price  string
+	bid    string
+	ask    string
+	volume string
+}
+
+// prompt is a question at the bottom of the screen. A confirmation prompt submits on y and is
+// dismissed by any other key, otherwise the typed input is submitted on enter. submit returns the
+// action to run once the dashboard is unlocked, which may be nil.
+type prompt struct {
+	label   string
+	input   string
+	confirm bool
+	submit  func(input string) func()
+}
+
+// Dashboard holds what is on screen. Market data arrives through HandleMessage, account data
+// through Refresh, keys through HandleKey, and Lines draws a frame. It is safe for concurrent use.
+type Dashboard struct {
+	api    API
+	config Config
+
+	mutex       sync.Mutex
+	product     int
+	books       map[string]*coinbasepro.OrderBook
+	tickers     map[string]ticker
+	balances    []coinbasepro.Account
+	orders      []coinbasepro.Order
+	fills       []coinbasepro.Fill
+	selected    int
+	prompt      *prompt
+	status      string
+	refreshedAt time.Time
+	now         func() time.Time
+}
+
+func New(api API, config Config) (*Dashboard, error) {
+	if len(config.ProductIds) == 0 {
+		return nil, errors.New(MissingProductsErrorMessage)
+	}
+
+	switch config.Mode {
+	case ModeLive, ModeSandbox, ModePaper:
+	default:
+		return nil, fmt.Errorf("%s: %q", UnknownModeErrorMessage, config.Mode)
+	}
+
+	if config.Depth <= 0 {
+		config.Depth = defaultDepth
+	}
+
+	if config.Fills <= 0 {
+		config.Fills = defaultFills
+	}
+
+	dashboard := &Dashboard{
+		api:     api,
+		config:  config,
+		books:   make(map[string]*coinbasepro.OrderBook),
+		tickers: make(map[string]ticker),
+		now:     time.Now,
+	}
+
+	for _, productId := range config.ProductIds {
+		dashboard.books[productId] = coinbasepro.NewOrderBook(productId)
+	}
+
+	return dashboard, nil
+}
+
+func (d *Dashboard) SetClock(now func() time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.now = now
+}
+
+// HandleMessage applies level2, ticker and match messages for the products on screen.
+func (d *Dashboard) HandleMessage(message coinbasepro.Message) error {
+	book, found := d.books[message.ProductId]
+	if !found {
+		return nil
+	}
+
+	if err := book.HandleMessage(message); err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	current := d.tickers[message.ProductId]
+	switch message.Type {
+	case coinbasepro.MessageTypeTicker:
+		current = ticker{price: message.Price, bid: message.BestBid, ask: message.BestAsk, volume: message.Volume24h}
+	case coinbasepro.MessageTypeMatch, coinbasepro.MessageTypeLastMatch:
+		current.price = message.Price
+	default:
+		return nil
+	}
+	d.tickers[message.ProductId] = current
+
+	return nil
+}
+
+// Refresh polls balances, open orders and fills. A failed call keeps what was shown before and
+// reports the error on the status line.
+func (d *Dashboard) Refresh() {
+	accounts, accountsErr := d.api.GetAccounts()
+	orders, ordersErr := d.api.ListOrders(coinbasepro.OrderFilter{Statuses: openOrderStatuses})
+
+	fills := make([]coinbasepro.Fill, 0)
+	var fillsErr error
+	for _, productId := range d.config.ProductIds {
+		productFills, err := d.api.GetFills(coinbasepro.FillFilter{ProductId: productId})
+		if err != nil {
+			fillsErr = err
+			continue
+		}
+		fills = append(fills, productFills...)
+	}
+
+	sort.SliceStable(fills, func(i, j int) bool {
+		return fills[i].CreatedAt > fills[j].CreatedAt
+	})
+	if len(fills) > d.config.Fills {
+		fills = fills[:d.config.Fills]
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, err := range []error{accountsErr, ordersErr, fillsErr} {
+		if err != nil {
+			d.status = "refresh failed: " + err.Error()
+		}
+	}
+
+	if accountsErr == nil {
+		d.balances = make([]coinbasepro.Account, 0, len(accounts))
+		for _, account := range accounts {
+			if balance, _ := strconv.ParseFloat(account.Balance, 64); balance != 0 {
+				d.balances = append(d.balances, account)
+			}
+		}
+
+		sort.SliceStable(d.balances, func(i, j int) bool {
+			return d.balances[i].Currency < d.balances[j].Currency
+		})
+	}
+
+	if ordersErr == nil {
+		sort.SliceStable(orders, func(i, j int) bool {
+			return orders[i].CreatedAt < orders[j].CreatedAt
+		})
+		d.orders = orders
+		if d.selected >= len(d.orders) {
+			d.selected = len(d.orders) - 1
+		}
+		if d.selected < 0 {
+			d.selected = 0
+		}
+	}
+
+	if fillsErr == nil || len(fills) > 0 {
+		d.fills = fills
+	}
+
+	d.refreshedAt = d.now()
+}
+
+func (d *Dashboard) productId() string {
+	return d.config.ProductIds[d.product]
+}
+
+// HandleKey applies one key, named as decodeKeys names them, and reports whether to quit. Actions
+// that call the exchange run before it returns.
+func (d *Dashboard) HandleKey(key string) bool {
+	d.mutex.Lock()
+	quit, action := d.handleKey(key)
+	d.mutex.Unlock()
+
+	if action != nil {
+		action()
+	}
+
+	return quit
+}
+
+func (d *Dashboard) handleKey(key string) (bool, func()) {
+	if key == "ctrl+c" {
+		return true, nil
+	}
+
+	if d.prompt != nil {
+		return false, d.handlePromptKey(key)
+	}
+
+	switch key {
+	case "q":
+		return true, nil
+	case "tab":
+		d.product = (d.product + 1) % len(d.config.ProductIds)
+	case "up", "k":
+		if d.selected > 0 {
+			d.selected--
+		}
+	case "down", "j":
+		if d.selected < len(d.orders)-1 {
+			d.selected++
+		}
+	case "b":
+		d.enterOrder(coinbasepro.OrderSideBuy)
+	case "s":
+		d.enterOrder(coinbasepro.OrderSideSell)
+	case "c":
+		d.confirmCancel()
+	case "C":
+		d.confirmCancelAll()
+	case "r":
+		return false, d.Refresh
+	}
+
+	return false, nil
+}
+
+func (d *Dashboard) handlePromptKey(key string) func() {
+	current := d.prompt
+	if current.confirm {
+		d.prompt = nil
+		if key == "y" || key == "Y" {
+			return current.submit("y")
+		}
+
+		d.status = "cancelled"
+		return nil
+	}
+
+	switch key {
+	case "esc":
+		d.prompt = nil
+		d.status = "cancelled"
+	case "enter":
+		d.prompt = nil
+		return current.submit(strings.TrimSpace(current.input))
+	case "backspace":
+		if current.input != "" {
+			_, size := utf8.DecodeLastRuneInString(current.input)
+			current.input = current.input[:len(current.input)-size]
+		}
+	default:
+		if utf8.RuneCountInString(key) == 1 {
+			current.input += key
+		}
+	}
+
+	return nil
+}
+
+func (d *Dashboard) tag() string {
+	return strings.ToUpper(d.config.Mode)
+}
+
+// enterOrder asks for a price, empty for a market order, then a size, then confirmation.
+func (d *Dashboard) enterOrder(side string) {
+	order := coinbasepro.Order{ProductId: d.productId(), Side: side, Type: coinbasepro.OrderTypeLimit}
+
+	askSize := func(input string) func() {
+		if size, err := strconv.ParseFloat(input, 64); err != nil || size <= 0 {
+			d.status = fmt.Sprintf("invalid size %q", input)
+			return nil
+		}
+		order.Size = input
+
+		description := fmt.Sprintf("%s %s %s %s", order.Type, order.Side, order.Size, order.ProductId)
+		if order.Type == coinbasepro.OrderTypeLimit {
+			description += " @ " + order.Price
+		}
+
+		d.prompt = &prompt{
+			label:   fmt.Sprintf("place %s on %s? [y/N]", description, d.tag()),
+			confirm: true,
+			submit: func(string) func() {
+				return func() { d.place(order) }
+			},
+		}
+
+		return nil
+	}
+
+	askPrice := func(input string) func() {
+		if input == "" {
+			order.Type = coinbasepro.OrderTypeMarket
+		} else if price, err := strconv.ParseFloat(input, 64); err != nil || price <= 0 {
+			d.status = fmt.Sprintf("invalid price %q", input)
+			return nil
+		} else {
+			order.Price = input
+		}
+
+		d.prompt = &prompt{label: fmt.Sprintf("%s %s size:", side, order.ProductId), submit: askSize}
+		return nil
+	}
+
+	d.prompt = &prompt{label: fmt.Sprintf("%s %s price (empty for market):", side, order.ProductId), submit: askPrice}
+}
+
+func (d *Dashboard) confirmCancel() {
+	if len(d.orders) == 0 {
+		d.status = "no open orders"
+		return
+	}
+
+	order := d.orders[d.selected]
+	d.prompt = &prompt{
+		label:   fmt.Sprintf("cancel %s %s %s @ %s (%s) on %s? [y/N]", order.Side, order.Size, order.ProductId, order.Price, order.Id, d.tag()),
+		confirm: true,
+		submit: func(string) func() {
+			return func() { d.cancel([]coinbasepro.Order{order}) }
+		},
+	}
+}
+
+func (d *Dashboard) confirmCancelAll() {
+	if len(d.orders) == 0 {
+		d.status = "no open orders"
+		return
+	}
+
+	orders := append([]coinbasepro.Order(nil), d.orders...)
+	d.prompt = &prompt{
+		label:   fmt.Sprintf("cancel all %d open orders on %s? [y/N]", len(orders), d.tag()),
+		confirm: true,
+		submit: func(string) func() {
+			return func() { d.cancel(orders) }
+		},
+	}
+}
+
+func (d *Dashboard) setStatus(status string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.status = status
+}
+
+func (d *Dashboard) place(order coinbasepro.Order) {
+	placed, err := d.api.PlaceOrder(order)
+	switch {
+	case err != nil:
+		d.setStatus("order failed: " + err.Error())
+	case placed.Status == "rejected":
+		d.setStatus(fmt.Sprintf("order %s rejected: %s", placed.Id, placed.RejectReason))
+	default:
+		d.setStatus(fmt.Sprintf("placed %s %s %s", placed.Side, placed.ProductId, placed.Id))
+	}
+
+	d.Refresh()
+}
+
+func (d *Dashboard) cancel(orders []coinbasepro.Order) {
+	failed := make([]string, 0)
+	for _, order := range orders {
+		if err := d.api.CancelOrder(order.Id); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", order.Id, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		d.setStatus(fmt.Sprintf("%d of %d cancels failed, %s", len(failed), len(orders), strings.Join(failed, ", ")))
+	} else {
+		d.setStatus(fmt.Sprintf("cancelled %d orders", len(orders)))
+	}
+
+	d.Refresh()
+}
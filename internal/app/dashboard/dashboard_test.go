@@ -0,0 +1,178 @@
+package dashboard
+
+import (
+	"bytes"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPaperDashboard(t *testing.T, mode string) (*Dashboard, *coinbasepro.PaperClient) {
+	paper := coinbasepro.NewPaperClient(map[string]float64{"USD": 1000}, coinbasepro.FeeTier{})
+	t.Cleanup(func() { paper.Close() })
+
+	board, err := New(paper, Config{ProductIds: []string{"BTC-USD", "ETH-USD"}, Mode: mode, Depth: 2})
+	assert.Assert(t, is.Nil(err))
+
+	book := coinbasepro.Message{
+		Type:      coinbasepro.MessageTypeSnapshot,
+		ProductId: "BTC-USD",
+		Bids:      [][]string{{"99", "1"}, {"98", "2"}, {"97", "3"}},
+		Asks:      [][]string{{"101", "1.5"}, {"102", "2"}},
+	}
+	assert.Assert(t, is.Nil(paper.HandleMessage(book)))
+	assert.Assert(t, is.Nil(board.HandleMessage(book)))
+
+	return board, paper
+}
+
+func typeKeys(board *Dashboard, keys ...string) {
+	for _, key := range keys {
+		board.HandleKey(key)
+	}
+}
+
+func screen(board *Dashboard) string {
+	return strings.Join(board.Lines(120, 30), "\n")
+}
+
+func TestDashboard(t *testing.T) {
+	t.Run("should mark the mode on every frame", func(t *testing.T) {
+		for mode, banner := range map[string]string{
+			ModePaper:   "PAPER TRADING",
+			ModeSandbox: "SANDBOX",
+			ModeLive:    "LIVE TRADING",
+		} {
+			board, _ := newPaperDashboard(t, mode)
+
+			lines := board.Lines(80, 12)
+			assert.Equal(t, len(lines), 12)
+			assert.Assert(t, strings.HasPrefix(lines[0], " "+banner), lines[0])
+			assert.Equal(t, strings.TrimSpace(lines[11]), help)
+
+			typeKeys(board, "b", "1", "0", "0", "enter", "1", "enter")
+			assert.Assert(t, is.Contains(board.Lines(120, 12)[10], "on "+strings.ToUpper(mode)+"?"))
+		}
+
+		_, err := New(nil, Config{ProductIds: []string{"BTC-USD"}, Mode: "demo"})
+		assert.Error(t, err, UnknownModeErrorMessage+`: "demo"`)
+	})
+
+	t.Run("should show the ticker and depth ladder of the selected product", func(t *testing.T) {
+		board, _ := newPaperDashboard(t, ModePaper)
+		assert.Assert(t, is.Nil(board.HandleMessage(coinbasepro.Message{Type: coinbasepro.MessageTypeTicker, ProductId: "BTC-USD", Price: "100", BestBid: "99", BestAsk: "101", Volume24h: "42"})))
+		assert.Assert(t, is.Nil(board.HandleMessage(coinbasepro.Message{Type: coinbasepro.MessageTypeMatch, ProductId: "BTC-USD", Price: "100.5", Size: "1"})))
+
+		lines := board.Lines(120, 30)
+		assert.Equal(t, strings.TrimSpace(lines[1]), "BTC-USD  last 100.5  bid 99  ask 101  24h volume 42  [1/2]")
+		assert.Assert(t, strings.HasPrefix(lines[4], ladderRow("102", "2")))
+		assert.Assert(t, strings.HasPrefix(lines[5], ladderRow("101", "1.5")))
+		assert.Assert(t, is.Contains(lines[6], "spread 2"))
+		assert.Assert(t, is.Contains(lines[8], "98"))
+		assert.Assert(t, !strings.Contains(screen(board), "97"))
+
+		board.HandleKey("tab")
+		assert.Assert(t, strings.HasPrefix(board.Lines(120, 30)[1], "ETH-USD  last -"))
+	})
+
+	t.Run("should place an order only after confirmation", func(t *testing.T) {
+		board, paper := newPaperDashboard(t, ModePaper)
+		board.Refresh()
+		assert.Assert(t, is.Contains(screen(board), "USD           1000.00000000"))
+
+		typeKeys(board, "b", "9", "x", "backspace", "9", "enter", "2", "enter")
+		assert.Assert(t, is.Contains(screen(board), "place limit buy 2 BTC-USD @ 99 on PAPER? [y/N]"))
+
+		board.HandleKey("n")
+		assert.Assert(t, is.Contains(screen(board), "cancelled"))
+		orders, _ := paper.ListOrders(coinbasepro.OrderFilter{Statuses: openOrderStatuses})
+		assert.Equal(t, len(orders), 0)
+
+		typeKeys(board, "b", "9", "9", "enter", "2", "enter", "y")
+		orders, _ = paper.ListOrders(coinbasepro.OrderFilter{Statuses: openOrderStatuses})
+		assert.Equal(t, len(orders), 1)
+		assert.Equal(t, orders[0].Price, "99")
+
+		output := screen(board)
+		assert.Assert(t, is.Contains(output, "placed buy BTC-USD "+orders[0].Id))
+		assert.Assert(t, is.Contains(output, "> BTC-USD"))
+	})
+
+	t.Run("should place market orders and show fills", func(t *testing.T) {
+		board, _ := newPaperDashboard(t, ModePaper)
+
+		typeKeys(board, "b", "enter", "1", "enter", "y")
+		output := screen(board)
+		assert.Assert(t, is.Contains(output, "placed buy BTC-USD"))
+		assert.Assert(t, is.Contains(output, "BTC-USD    buy      101.00000000     1.00000000"), output)
+	})
+
+	t.Run("should reject invalid input", func(t *testing.T) {
+		board, _ := newPaperDashboard(t, ModePaper)
+
+		typeKeys(board, "s", "a", "b", "c", "enter")
+		assert.Assert(t, is.Contains(screen(board), `invalid price "abc"`))
+
+		typeKeys(board, "s", "1", "0", "0", "enter", "esc")
+		assert.Assert(t, is.Contains(screen(board), "cancelled"))
+	})
+
+	t.Run("should cancel the selected order and all orders after confirmation", func(t *testing.T) {
+		board, paper := newPaperDashboard(t, ModePaper)
+		for _, price := range []string{"90", "91", "92"} {
+			_, err := paper.PlaceOrder(coinbasepro.Order{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Price: price, Size: "1"})
+			assert.Assert(t, is.Nil(err))
+		}
+		board.Refresh()
+
+		typeKeys(board, "j", "down", "k", "c")
+		assert.Assert(t, is.Contains(screen(board), "cancel buy 1 BTC-USD @ 91"))
+		board.HandleKey("y")
+
+		orders, _ := paper.ListOrders(coinbasepro.OrderFilter{Statuses: openOrderStatuses})
+		assert.Equal(t, len(orders), 2)
+		assert.Assert(t, is.Contains(screen(board), "cancelled 1 orders"))
+
+		typeKeys(board, "C", "y")
+		orders, _ = paper.ListOrders(coinbasepro.OrderFilter{Statuses: openOrderStatuses})
+		assert.Equal(t, len(orders), 0)
+		assert.Assert(t, is.Contains(screen(board), "no open orders"))
+	})
+
+	t.Run("should name keys from raw terminal input", func(t *testing.T) {
+		assert.DeepEqual(t, splitKeys([]byte("b1\r\x1b[A\x1b[B\x1b[C\x1b\t\x7f\x03é")),
+			[]string{"b", "1", "enter", "up", "down", "esc", "tab", "backspace", "ctrl+c", "é"})
+	})
+
+	t.Run("should draw frames until quit", func(t *testing.T) {
+		board, _ := newPaperDashboard(t, ModeSandbox)
+		reader, writer := io.Pipe()
+		output := &bytes.Buffer{}
+
+		done := make(chan error)
+		go func() {
+			done <- Run(board, Terminal{Input: reader, Output: output}, nil, time.Hour)
+		}()
+
+		writer.Write([]byte("\t"))
+		writer.Write([]byte("q"))
+
+		select {
+		case err := <-done:
+			assert.Assert(t, is.Nil(err))
+		case <-time.After(5 * time.Second):
+			t.Fatal("dashboard did not quit")
+		}
+
+		assert.Assert(t, is.Contains(output.String(), bannerSimulated+" SANDBOX"))
+		assert.Assert(t, strings.HasSuffix(output.String(), leaveAlternateScreen))
+	})
+}
+
+func ladderRow(price, size string) string {
+	return strings.Repeat(" ", 14-len(price)) + price + " " + strings.Repeat(" ", 16-len(size)) + size
+}
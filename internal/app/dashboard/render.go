@@ -0,0 +1,195 @@
+package dashboard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const help = "b/s buy/sell  j/k select  c cancel  C cancel all  tab product  r refresh  q quit"
+
+const bookWidth = 34
+
+const clearScreen = "\x1b[H\x1b[2J"
+const bannerLive = "\x1b[1;97;41m"
+const bannerSimulated = "\x1b[1;30;43m"
+const reverse = "\x1b[7m"
+const resetStyle = "\x1b[0m"
+
+// fit pads or cuts a line to exactly width characters.
+func fit(line string, width int) string {
+	count := utf8.RuneCountInString(line)
+	if count > width {
+		return string([]rune(line)[:width])
+	}
+
+	return line + strings.Repeat(" ", width-count)
+}
+
+func formatLevel(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func (d *Dashboard) banner() string {
+	switch d.config.Mode {
+	case ModePaper:
+		return "PAPER TRADING - orders are simulated, no funds are at risk"
+	case ModeSandbox:
+		return "SANDBOX - orders go to the exchange sandbox, not the live market"
+	}
+
+	return "LIVE TRADING - orders use real funds"
+}
+
+func (d *Dashboard) tickerLine() string {
+	productId := d.productId()
+	current := d.tickers[productId]
+	line := fmt.Sprintf("%s  last %s  bid %s  ask %s  24h volume %s", productId, orDash(current.price), orDash(current.bid), orDash(current.ask), orDash(current.volume))
+	if len(d.config.ProductIds) > 1 {
+		line += fmt.Sprintf("  [%d/%d]", d.product+1, len(d.config.ProductIds))
+	}
+
+	return line
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+// ladder is the depth ladder of the product on screen, asks above bids around the spread.
+func (d *Dashboard) ladder() []string {
+	book := d.books[d.productId()]
+	bids, asks := book.Bids(d.config.Depth), book.Asks(d.config.Depth)
+
+	lines := []string{fmt.Sprintf("%-14s %16s", "BOOK", "SIZE")}
+	for i := len(asks) - 1; i >= 0; i-- {
+		lines = append(lines, fmt.Sprintf("%14s %16s", formatLevel(asks[i].Price), formatLevel(asks[i].Size)))
+	}
+
+	spread := "-"
+	if len(bids) > 0 && len(asks) > 0 {
+		spread = strconv.FormatFloat(asks[0].Price-bids[0].Price, 'f', -1, 64)
+	}
+	lines = append(lines, fmt.Sprintf("%14s", "spread "+spread))
+
+	for _, bid := range bids {
+		lines = append(lines, fmt.Sprintf("%14s %16s", formatLevel(bid.Price), formatLevel(bid.Size)))
+	}
+
+	return lines
+}
+
+func (d *Dashboard) balanceLines() []string {
+	lines := []string{fmt.Sprintf("%-8s %18s %18s %18s", "BALANCE", "TOTAL", "AVAILABLE", "HOLD")}
+	for _, account := range d.balances {
+		lines = append(lines, fmt.Sprintf("%-8s %18s %18s %18s", account.Currency, account.Balance, account.Available, account.Hold))
+	}
+
+	if len(d.balances) == 0 {
+		lines = append(lines, "no balances")
+	}
+
+	return lines
+}
+
+func (d *Dashboard) orderLines() []string {
+	lines := []string{fmt.Sprintf("  %-10s %-6s %-6s %14s %14s %14s %-8s %s", "OPEN", "SIDE", "TYPE", "PRICE", "SIZE", "FILLED", "STATUS", "ID")}
+	for i, order := range d.orders {
+		marker := " "
+		if i == d.selected {
+			marker = ">"
+		}
+
+		lines = append(lines, fmt.Sprintf("%s %-10s %-6s %-6s %14s %14s %14s %-8s %s", marker, order.ProductId, order.Side, order.Type, orDash(order.Price), orDash(order.Size), orDash(order.FilledSize), order.Status, order.Id))
+	}
+
+	if len(d.orders) == 0 {
+		lines = append(lines, "  no open orders")
+	}
+
+	return lines
+}
+
+func (d *Dashboard) fillLines() []string {
+	lines := []string{fmt.Sprintf("  %-10s %-6s %14s %14s %12s %-24s", "FILLS", "SIDE", "PRICE", "SIZE", "FEE", "TIME")}
+	for _, fill := range d.fills {
+		lines = append(lines, fmt.Sprintf("  %-10s %-6s %14s %14s %12s %-24s", fill.ProductId, fill.Side, fill.Price, fill.Size, fill.Fee, fill.CreatedAt))
+	}
+
+	if len(d.fills) == 0 {
+		lines = append(lines, "  no fills")
+	}
+
+	return lines
+}
+
+// Lines draws a frame of at most height lines, each exactly width characters. The first line is
+// the mode banner and the last two the status or prompt and the key help, whatever the height.
+func (d *Dashboard) Lines(width, height int) []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	body := []string{d.tickerLine(), ""}
+
+	left, right := d.ladder(), d.balanceLines()
+	for i := 0; i < len(left) || i < len(right); i++ {
+		row := ""
+		if i < len(left) {
+			row = left[i]
+		}
+		row = fit(row, bookWidth)
+		if i < len(right) {
+			row += " | " + right[i]
+		}
+		body = append(body, row)
+	}
+
+	body = append(body, "")
+	body = append(body, d.orderLines()...)
+	body = append(body, "")
+	body = append(body, d.fillLines()...)
+
+	bottom := d.status
+	if d.prompt != nil {
+		bottom = d.prompt.label + " " + d.prompt.input
+	}
+
+	if room := height - 3; len(body) > room {
+		if room < 0 {
+			room = 0
+		}
+		body = body[:room]
+	}
+
+	lines := make([]string, 0, height)
+	lines = append(lines, fit(" "+d.banner(), width))
+	for _, line := range body {
+		lines = append(lines, fit(line, width))
+	}
+	for len(lines) < height-2 {
+		lines = append(lines, fit("", width))
+	}
+	lines = append(lines, fit(bottom, width), fit(help, width))
+
+	return lines
+}
+
+// Render draws a frame for a terminal, colouring the banner red when trading live and yellow when
+// simulated.
+func (d *Dashboard) Render(width, height int) string {
+	lines := d.Lines(width, height)
+
+	style := bannerSimulated
+	if d.config.Mode == ModeLive {
+		style = bannerLive
+	}
+	lines[0] = style + lines[0] + resetStyle
+	lines[len(lines)-2] = reverse + lines[len(lines)-2] + resetStyle
+
+	return clearScreen + strings.Join(lines, "\r\n")
+}
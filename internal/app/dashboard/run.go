@@ -0,0 +1,196 @@
+package dashboard
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+const enterAlternateScreen = "\x1b[?1049h\x1b[?25l"
+const leaveAlternateScreen = "\x1b[?25h\x1b[?1049l"
+
+const minimumWidth = 40
+const minimumHeight = 12
+const redrawInterval = 100 * time.Millisecond
+
+// Terminal is where the dashboard draws and reads keys. Size is asked before every frame and
+// falls back to 80 by 24 when it fails.
+type Terminal struct {
+	Input  io.Reader
+	Output io.Writer
+	Size   func() (int, int, error)
+}
+
+// splitKeys names the keys in one read from a raw terminal: arrows, enter, tab, esc, backspace and
+// ctrl+c by name, everything else as the character typed. Unknown escape sequences are dropped.
+func splitKeys(input []byte) []string {
+	keys := make([]string, 0, len(input))
+	for len(input) > 0 {
+		switch {
+		case input[0] == 0x1b && len(input) >= 3 && input[1] == '[':
+			switch input[2] {
+			case 'A':
+				keys = append(keys, "up")
+			case 'B':
+				keys = append(keys, "down")
+			}
+
+			// skip to the final byte of the sequence
+			end := 2
+			for end < len(input)-1 && (input[end] < 0x40 || input[end] > 0x7e) {
+				end++
+			}
+			input = input[end+1:]
+			continue
+		case input[0] == 0x1b:
+			keys = append(keys, "esc")
+		case input[0] == '\r' || input[0] == '\n':
+			keys = append(keys, "enter")
+		case input[0] == '\t':
+			keys = append(keys, "tab")
+		case input[0] == 0x7f || input[0] == 0x08:
+			keys = append(keys, "backspace")
+		case input[0] == 0x03:
+			keys = append(keys, "ctrl+c")
+		case input[0] < 0x20:
+		default:
+			r, size := utf8.DecodeRune(input)
+			keys = append(keys, string(r))
+			input = input[size:]
+			continue
+		}
+
+		input = input[1:]
+	}
+
+	return keys
+}
+
+func decodeKeys(input io.Reader, keys chan<- string, done <-chan struct{}) {
+	defer close(keys)
+
+	buffer := make([]byte, 64)
+	for {
+		n, err := input.Read(buffer)
+		for _, key := range splitKeys(buffer[:n]) {
+			select {
+			case keys <- key:
+			case <-done:
+				return
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Run draws the dashboard until q or ctrl+c is pressed or the input ends. Market data is read from
+// source and account data refreshed every refreshInterval. When market data stops the dashboard
+// keeps running and says so on the status line.
+func Run(d *Dashboard, terminal Terminal, source coinbasepro.MessageSource, refreshInterval time.Duration) error {
+	dirty := make(chan struct{}, 1)
+	markDirty := func() {
+		select {
+		case dirty <- struct{}{}:
+		default:
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	if source != nil {
+		go func() {
+			for {
+				message, err := source.Read()
+				if err != nil {
+					d.setStatus("market data stopped: " + err.Error())
+					markDirty()
+					return
+				}
+
+				if err := d.HandleMessage(message); err != nil {
+					d.setStatus("market data error: " + err.Error())
+				}
+				markDirty()
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			d.Refresh()
+			markDirty()
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	keys := make(chan string)
+	go decodeKeys(terminal.Input, keys, done)
+
+	if _, err := io.WriteString(terminal.Output, enterAlternateScreen); err != nil {
+		return err
+	}
+	defer io.WriteString(terminal.Output, leaveAlternateScreen)
+
+	draw := func() error {
+		width, height, err := 80, 24, error(nil)
+		if terminal.Size != nil {
+			if width, height, err = terminal.Size(); err != nil {
+				width, height = 80, 24
+			}
+		}
+
+		if width < minimumWidth {
+			width = minimumWidth
+		}
+		if height < minimumHeight {
+			height = minimumHeight
+		}
+
+		_, err = io.WriteString(terminal.Output, d.Render(width, height))
+		return err
+	}
+
+	if err := draw(); err != nil {
+		return err
+	}
+
+	redraw := time.NewTicker(redrawInterval)
+	defer redraw.Stop()
+
+	pending := false
+	for {
+		select {
+		case key, open := <-keys:
+			if !open || d.HandleKey(key) {
+				return nil
+			}
+			if err := draw(); err != nil {
+				return err
+			}
+			pending = false
+		case <-dirty:
+			pending = true
+		case <-redraw.C:
+			if !pending {
+				continue
+			}
+			if err := draw(); err != nil {
+				return err
+			}
+			pending = false
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build darwin || freebsd || netbsd || openbsd
+// +build darwin freebsd netbsd openbsd
+
+package dashboard
+
+import "syscall"
+
+const getTermios = syscall.TIOCGETA
+const setTermios = syscall.TIOCSETA
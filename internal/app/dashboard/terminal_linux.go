@@ -0,0 +1,9 @@
+//go:build linux
+// +build linux
+
+package dashboard
+
+import "syscall"
+
+const getTermios = syscall.TCGETS
+const setTermios = syscall.TCSETS
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package dashboard
+
+import "errors"
+
+const RawModeUnsupportedErrorMessage = "raw terminal mode is not supported on this platform, keys need enter"
+
+// MakeRaw is not supported here, the dashboard still runs but reads keys a line at a time.
+func MakeRaw(fd uintptr) (func() error, error) {
+	return nil, errors.New(RawModeUnsupportedErrorMessage)
+}
+
+func Size(fd uintptr) (int, int, error) {
+	return 0, 0, errors.New(RawModeUnsupportedErrorMessage)
+}
@@ -0,0 +1,54 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package dashboard
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func ioctl(fd, request uintptr, argument unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(argument)); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// MakeRaw switches the terminal to reading single key presses without echo, and returns a
+// function restoring how it was.
+func MakeRaw(fd uintptr) (func() error, error) {
+	var original syscall.Termios
+	if err := ioctl(fd, getTermios, unsafe.Pointer(&original)); err != nil {
+		return nil, err
+	}
+
+	raw := original
+	raw.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := ioctl(fd, setTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return ioctl(fd, setTermios, unsafe.Pointer(&original))
+	}, nil
+}
+
+// Size returns the columns and rows of the terminal.
+func Size(fd uintptr) (int, int, error) {
+	var size struct {
+		rows, columns, x, y uint16
+	}
+
+	if err := ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil {
+		return 0, 0, err
+	}
+
+	return int(size.columns), int(size.rows), nil
+}
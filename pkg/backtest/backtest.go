@@ -0,0 +1,357 @@
+// Package backtest replays stored candles or trades through the strategy engine used live, against
+// a simulated exchange, and reports how the strategy would have done.
+package backtest
+
+import (
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/strategy"
+	"io/ioutil"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const NoEventsErrorMessage = "a backtest needs at least one event"
+const MixedQuoteCurrenciesErrorMessage = "every product in a backtest must share one quote currency"
+
+const strategyName = "backtest"
+const clientOidPrefix = "b7"
+
+// Event is one bar or trade of historical market data.
+type Event struct {
+	Time  time.Time
+	Bar   *coinbasepro.Bar
+	Trade *coinbasepro.TradeTick
+}
+
+func (e Event) productId() string {
+	if e.Bar != nil {
+		return e.Bar.ProductId
+	}
+
+	return e.Trade.ProductId
+}
+
+// candle is the event as a bar, a trade is a bar whose prices are all the trade price.
+func (e Event) candle() coinbasepro.Candle {
+	if e.Bar != nil {
+		return e.Bar.Candle
+	}
+
+	price := e.Trade.Price
+	return coinbasepro.Candle{Time: e.Trade.Time, Low: price, High: price, Open: price, Close: price, Volume: e.Trade.Size}
+}
+
+// CandleEvents turns candles of one granularity into events at the time each candle closed.
+func CandleEvents(productId string, granularity time.Duration, candles []coinbasepro.Candle) []Event {
+	events := make([]Event, 0, len(candles))
+	for _, candle := range candles {
+		bar := &coinbasepro.Bar{Candle: candle, ProductId: productId, End: candle.Time.Add(granularity)}
+		events = append(events, Event{Time: bar.End, Bar: bar})
+	}
+
+	return Merge(events)
+}
+
+// ReadCandleEvents reads candles written by the candle downloader and turns them into events.
+func ReadCandleEvents(path, productId string, granularity time.Duration) ([]Event, error) {
+	format, err := coinbasepro.CandleFormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	candles, err := coinbasepro.ReadCandles(path, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return CandleEvents(productId, granularity, candles), nil
+}
+
+func TradeEvents(trades []coinbasepro.TradeTick) []Event {
+	events := make([]Event, 0, len(trades))
+	for i := range trades {
+		trade := trades[i]
+		events = append(events, Event{Time: trade.Time, Trade: &trade})
+	}
+
+	return Merge(events)
+}
+
+// Merge combines event streams into one ordered by time. Events at the same time keep the order of
+// their streams, so a merge is always the same for the same input.
+func Merge(streams ...[]Event) []Event {
+	merged := make([]Event, 0)
+	for _, stream := range streams {
+		merged = append(merged, stream...)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Time.Before(merged[j].Time)
+	})
+
+	return merged
+}
+
+// Config describes the account and market a strategy is backtested in. Equity is recorded after
+// every event, or at most once per EquityInterval when it is set. Strategy logs are discarded
+// unless Logger is set.
+type Config struct {
+	Balances       map[string]float64
+	FillModel      FillModel
+	Strategy       strategy.Config
+	EquityInterval time.Duration
+	Logger         *log.Logger
+}
+
+// EquityPoint values the account in the quote currency at one point in time.
+type EquityPoint struct {
+	Time          time.Time `json:"time"`
+	Equity        float64   `json:"equity"`
+	Cash          float64   `json:"cash"`
+	PositionValue float64   `json:"position_value"`
+}
+
+// Trade is a round trip, from a flat position in a product back to flat.
+type Trade struct {
+	ProductId  string    `json:"product_id"`
+	Side       string    `json:"side"`
+	EntryTime  time.Time `json:"entry_time"`
+	ExitTime   time.Time `json:"exit_time"`
+	Size       float64   `json:"size"`
+	EntryPrice float64   `json:"entry_price"`
+	ExitPrice  float64   `json:"exit_price"`
+	Fees       float64   `json:"fees"`
+	Pnl        float64   `json:"pnl"`
+}
+
+type Result struct {
+	Equity     []EquityPoint      `json:"equity"`
+	Fills      []coinbasepro.Fill `json:"fills"`
+	Trades     []Trade            `json:"trades"`
+	Statistics Statistics         `json:"statistics"`
+	Status     strategy.Status    `json:"status"`
+}
+
+// Run backtests one strategy over events, which must be ordered by time. The same strategy state,
+// config and events always give the same result.
+func Run(s strategy.Strategy, config Config, events []Event) (Result, error) {
+	if len(events) == 0 {
+		return Result{}, errors.New(NoEventsErrorMessage)
+	}
+
+	quote, err := quoteCurrency(config.Strategy.ProductIds)
+	if err != nil {
+		return Result{}, err
+	}
+
+	exchange := NewExchange(config.Balances, config.FillModel)
+	engine, err := strategy.NewEngine(exchange, clientOidPrefix)
+	if err != nil {
+		return Result{}, err
+	}
+
+	engine.Logger = config.Logger
+	if engine.Logger == nil {
+		engine.Logger = log.New(ioutil.Discard, "", 0)
+	}
+
+	if err := engine.Add(strategyName, s, config.Strategy); err != nil {
+		return Result{}, err
+	}
+
+	run := &backtest{exchange: exchange, engine: engine, config: config, quote: quote}
+	engine.Start()
+	run.sync()
+
+	for _, event := range events {
+		if err := run.handle(event); err != nil {
+			return Result{}, err
+		}
+	}
+
+	engine.Stop()
+	run.sync()
+	run.record(events[len(events)-1].Time, true)
+
+	result := Result{Equity: run.equity, Fills: exchange.Fills(), Trades: roundTrips(exchange.Fills())}
+	result.Statistics = ComputeStatistics(result.Equity, result.Trades)
+	for _, fill := range result.Fills {
+		fee, _ := strconv.ParseFloat(fill.Fee, 64)
+		result.Statistics.Fees += fee
+	}
+
+	result.Status, err = engine.Status(strategyName)
+	return result, err
+}
+
+func quoteCurrency(productIds []string) (string, error) {
+	quote := ""
+	for _, productId := range productIds {
+		_, productQuote, err := splitProductId(productId)
+		if err != nil {
+			return "", err
+		}
+
+		if quote != "" && productQuote != quote {
+			return "", errors.New(MixedQuoteCurrenciesErrorMessage)
+		}
+		quote = productQuote
+	}
+
+	return quote, nil
+}
+
+type backtest struct {
+	exchange *Exchange
+	engine   *strategy.Engine
+	config   Config
+	quote    string
+	equity   []EquityPoint
+}
+
+// handle executes open orders against the event before the strategy sees it, so orders placed on
+// one event can only fill on a later one.
+func (b *backtest) handle(event Event) error {
+	b.exchange.Advance(event.Time, event.productId(), event.candle())
+	b.sync()
+
+	if event.Bar != nil {
+		b.engine.HandleBar(*event.Bar)
+	} else {
+		trade := event.Trade
+		err := b.engine.HandleMessage(coinbasepro.Message{
+			Type:      coinbasepro.MessageTypeMatch,
+			ProductId: trade.ProductId,
+			TradeId:   trade.TradeId,
+			Side:      trade.Side,
+			Price:     strconv.FormatFloat(trade.Price, 'f', -1, 64),
+			Size:      strconv.FormatFloat(trade.Size, 'f', -1, 64),
+			Time:      trade.Time.Format(time.RFC3339Nano),
+		})
+		if err != nil {
+			return err
+		}
+
+		b.engine.Flush(event.Time)
+	}
+
+	b.sync()
+	b.record(event.Time, false)
+
+	return nil
+}
+
+// sync brings the engine's orders up to date with the simulated exchange, one order at a time in
+// the order they changed. Updates can lead strategies to place or cancel orders, which is repeated
+// until nothing changes.
+func (b *backtest) sync() {
+	orders := b.engine.OrderManager()
+	for {
+		changed := b.exchange.Changed()
+		if len(changed) == 0 {
+			return
+		}
+
+		for _, orderId := range changed {
+			orders.Refresh(orderId)
+		}
+	}
+}
+
+func (b *backtest) record(now time.Time, last bool) {
+	if !last && b.config.EquityInterval > 0 && len(b.equity) > 0 && now.Sub(b.equity[len(b.equity)-1].Time) < b.config.EquityInterval {
+		return
+	}
+
+	point := EquityPoint{Time: now, Cash: b.exchange.Balance(b.quote)}
+	for _, productId := range b.config.Strategy.ProductIds {
+		base, _, _ := splitProductId(productId)
+		point.PositionValue += b.exchange.Balance(base) * b.exchange.Mark(productId)
+	}
+	point.Equity = point.Cash + point.PositionValue
+
+	if len(b.equity) > 0 && b.equity[len(b.equity)-1].Time.Equal(now) {
+		b.equity[len(b.equity)-1] = point
+		return
+	}
+
+	b.equity = append(b.equity, point)
+}
+
+// roundTrips pairs fills into trades per product. A fill that takes a position through flat
+// closes one trade and opens the next with the rest.
+func roundTrips(fills []coinbasepro.Fill) []Trade {
+	type openTrade struct {
+		trade     Trade
+		position  float64
+		exitSize  float64
+		exitValue float64
+	}
+
+	open := make(map[string]*openTrade)
+	trades := make([]Trade, 0)
+
+	for _, fill := range fills {
+		price, _ := strconv.ParseFloat(fill.Price, 64)
+		size, _ := strconv.ParseFloat(fill.Size, 64)
+		fee, _ := strconv.ParseFloat(fill.Fee, 64)
+		at, _ := time.Parse(time.RFC3339Nano, fill.CreatedAt)
+
+		signed := size
+		if fill.Side == coinbasepro.OrderSideSell {
+			signed = -size
+		}
+
+		for math.Abs(signed) > sizeEpsilon {
+			current, found := open[fill.ProductId]
+			if !found {
+				current = &openTrade{trade: Trade{ProductId: fill.ProductId, Side: fill.Side, EntryTime: at}}
+				open[fill.ProductId] = current
+			}
+
+			if (current.position >= 0) == (signed > 0) || math.Abs(current.position) <= sizeEpsilon {
+				entered := math.Abs(signed)
+				current.trade.EntryPrice = (current.trade.EntryPrice*current.trade.Size + price*entered) / (current.trade.Size + entered)
+				current.trade.Size += entered
+				current.trade.Fees += fee
+				current.position += signed
+				break
+			}
+
+			closed := math.Min(math.Abs(signed), math.Abs(current.position))
+			share := closed / math.Abs(signed)
+			current.exitSize += closed
+			current.exitValue += closed * price
+			current.trade.Fees += fee * share
+			fee -= fee * share
+
+			if signed > 0 {
+				current.position += closed
+				signed -= closed
+			} else {
+				current.position -= closed
+				signed += closed
+			}
+
+			if math.Abs(current.position) <= sizeEpsilon {
+				trade := current.trade
+				trade.ExitTime = at
+				trade.ExitPrice = current.exitValue / current.exitSize
+				trade.Pnl = (trade.ExitPrice - trade.EntryPrice) * trade.Size
+				if trade.Side == coinbasepro.OrderSideSell {
+					trade.Pnl = -trade.Pnl
+				}
+				trade.Pnl -= trade.Fees
+
+				trades = append(trades, trade)
+				delete(open, fill.ProductId)
+			}
+		}
+	}
+
+	return trades
+}
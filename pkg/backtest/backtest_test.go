@@ -0,0 +1,179 @@
+package backtest
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/strategy"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// dipBuyer buys with a market order after a down bar and sells once the close is takeProfit above
+// the average cost.
+type dipBuyer struct {
+	strategy.BaseStrategy
+	size       float64
+	takeProfit float64
+	previous   float64
+}
+
+func (d *dipBuyer) OnCandle(ctx *strategy.Context, bar coinbasepro.Bar) {
+	position := ctx.Position(bar.ProductId)
+	idle := len(ctx.OpenOrders()) == 0
+
+	switch {
+	case idle && position.Size == 0 && d.previous > 0 && bar.Close < d.previous:
+		ctx.PlaceOrder(marketOrder(coinbasepro.OrderSideBuy, d.size))
+	case idle && position.Size > 0 && bar.Close >= position.AverageCost*(1+d.takeProfit):
+		ctx.PlaceOrder(marketOrder(coinbasepro.OrderSideSell, position.Size))
+	}
+
+	d.previous = bar.Close
+}
+
+func marketOrder(side string, size float64) coinbasepro.Order {
+	return coinbasepro.Order{Type: coinbasepro.OrderTypeMarket, Side: side, ProductId: "BTC-USD", Size: strconv.FormatFloat(size, 'f', -1, 64)}
+}
+
+func testEvents() []Event {
+	return CandleEvents("BTC-USD", time.Minute, []coinbasepro.Candle{
+		bar(0, 100, 100, 100, 100, 10),
+		bar(1, 100, 100, 98, 98, 10),
+		bar(2, 97, 99, 97, 99, 10),
+		bar(3, 100, 101, 100, 101, 10),
+		bar(4, 101, 101, 100, 100, 10),
+		bar(5, 99, 99, 95, 95, 10),
+		bar(6, 95, 96, 95, 96, 10),
+	})
+}
+
+func testConfig() Config {
+	return Config{
+		Balances: map[string]float64{"USD": 1000},
+		Strategy: strategy.Config{ProductIds: []string{"BTC-USD"}},
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Run("should replay candles through the strategy", func(t *testing.T) {
+		result, err := Run(&dipBuyer{size: 1, takeProfit: 0.02}, testConfig(), testEvents())
+		assert.Assert(t, is.Nil(err))
+
+		assert.Equal(t, len(result.Fills), 3)
+		assert.DeepEqual(t, []string{result.Fills[0].Price, result.Fills[1].Price, result.Fills[2].Price}, []string{"97.00000000", "100.00000000", "99.00000000"})
+
+		assert.Equal(t, len(result.Trades), 1)
+		trade := result.Trades[0]
+		assert.Equal(t, trade.Side, coinbasepro.OrderSideBuy)
+		assert.Assert(t, trade.EntryTime.Equal(start.Add(3*time.Minute)))
+		assert.Assert(t, trade.ExitTime.Equal(start.Add(4*time.Minute)))
+		assertClose(t, trade.Pnl, 3)
+
+		equity := make([]float64, 0, len(result.Equity))
+		for _, point := range result.Equity {
+			equity = append(equity, point.Equity)
+		}
+		assert.DeepEqual(t, equity, []float64{1000, 1000, 1002, 1003, 1003, 999, 1000})
+
+		statistics := result.Statistics
+		assert.Equal(t, statistics.Trades, 1)
+		assertClose(t, statistics.WinRate, 1)
+		assertClose(t, statistics.TotalReturn, 0)
+		assertClose(t, statistics.MaxDrawdown, 4.0/1003)
+		assertClose(t, statistics.Exposure, 2.0/6)
+
+		assert.Equal(t, result.Status.State, strategy.StateStopped)
+		assertClose(t, result.Status.Positions["BTC-USD"].Size, 1)
+		assertClose(t, result.Status.RealizedPnl, 3)
+	})
+
+	t.Run("should be deterministic", func(t *testing.T) {
+		config := testConfig()
+		config.FillModel = FillModel{Fees: coinbasepro.DefaultFeeTier, SlippageBps: 5, MaxParticipation: 0.05}
+
+		first, err := Run(&dipBuyer{size: 1, takeProfit: 0.01}, config, testEvents())
+		assert.Assert(t, is.Nil(err))
+		second, err := Run(&dipBuyer{size: 1, takeProfit: 0.01}, config, testEvents())
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, len(first.Fills) > 3, "expected partial fills, got %d fills", len(first.Fills))
+		assert.DeepEqual(t, first, second)
+		assert.Assert(t, first.Statistics.Fees > 0)
+	})
+
+	t.Run("should build bars from trades", func(t *testing.T) {
+		trades := make([]coinbasepro.TradeTick, 0)
+		for i, price := range []float64{100, 99, 98, 97, 99, 101, 102, 103} {
+			trades = append(trades, coinbasepro.TradeTick{ProductId: "BTC-USD", TradeId: int64(i + 1), Price: price, Size: 1, Time: start.Add(time.Duration(i*40) * time.Second)})
+		}
+
+		config := testConfig()
+		config.Strategy.BarSpec = coinbasepro.BarSpec{Type: coinbasepro.BarTypeTime, Interval: time.Minute}
+
+		result, err := Run(&dipBuyer{size: 1, takeProfit: 0.02}, config, TradeEvents(trades))
+		assert.Assert(t, is.Nil(err))
+
+		assert.Equal(t, len(result.Fills), 2)
+		assert.Equal(t, result.Fills[0].Price, "99.00000000")
+		assert.Equal(t, result.Fills[1].Price, "103.00000000")
+		assert.Equal(t, len(result.Equity), len(trades))
+	})
+
+	t.Run("should refuse what it cannot value", func(t *testing.T) {
+		_, err := Run(&dipBuyer{}, testConfig(), nil)
+		assert.Error(t, err, NoEventsErrorMessage)
+
+		config := testConfig()
+		config.Strategy.ProductIds = []string{"BTC-USD", "ETH-EUR"}
+		_, err = Run(&dipBuyer{}, config, testEvents())
+		assert.Error(t, err, MixedQuoteCurrenciesErrorMessage)
+	})
+}
+
+func TestComputeStatistics(t *testing.T) {
+	equity := make([]EquityPoint, 0)
+	for i, value := range []float64{100, 110, 99, 121} {
+		equity = append(equity, EquityPoint{Time: start.Add(time.Duration(i) * time.Hour), Equity: value, PositionValue: float64(i % 2)})
+	}
+
+	statistics := ComputeStatistics(equity, []Trade{{Pnl: 5}, {Pnl: -1}, {Pnl: 2}, {Pnl: 0}})
+	assertClose(t, statistics.TotalReturn, 0.21)
+	assertClose(t, statistics.MaxDrawdown, 0.1)
+	assertClose(t, statistics.WinRate, 0.5)
+	assertClose(t, statistics.Exposure, 1.0/3)
+	assert.Assert(t, math.Abs(statistics.Sharpe-42.63483779) < 1e-6, statistics.Sharpe)
+	assert.Assert(t, math.Abs(statistics.Sortino-120.12339335) < 1e-6, statistics.Sortino)
+}
+
+func TestSweep(t *testing.T) {
+	grid := Grid(map[string][]float64{"take_profit": {0.01, 0.05}, "size": {1, 2}})
+	assert.DeepEqual(t, grid, []Params{
+		{"size": 1, "take_profit": 0.01},
+		{"size": 1, "take_profit": 0.05},
+		{"size": 2, "take_profit": 0.01},
+		{"size": 2, "take_profit": 0.05},
+	})
+
+	factory := func(params Params) (strategy.Strategy, error) {
+		return &dipBuyer{size: params["size"], takeProfit: params["take_profit"]}, nil
+	}
+
+	parallel := Sweep(grid, factory, testConfig(), testEvents(), 3)
+	serial := Sweep(grid, factory, testConfig(), testEvents(), 1)
+	assert.DeepEqual(t, parallel, serial)
+
+	for i, params := range grid {
+		assert.Assert(t, is.Nil(parallel[i].Err))
+		assert.DeepEqual(t, parallel[i].Params, params)
+
+		expected, err := Run(&dipBuyer{size: params["size"], takeProfit: params["take_profit"]}, testConfig(), testEvents())
+		assert.Assert(t, is.Nil(err))
+		assert.DeepEqual(t, parallel[i].Result, expected)
+	}
+
+	assert.Equal(t, parallel[1].Result.Statistics.Trades, 0)
+	assertClose(t, parallel[2].Result.Trades[0].Pnl, 6)
+}
@@ -0,0 +1,525 @@
+package backtest
+
+import (
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sizeEpsilon = 1e-9
+
+// FillModel decides how simulated orders fill. Taker fills are SlippageBps worse than the price
+// they execute against. MaxParticipation caps the size all orders together may fill against one
+// bar or trade at that share of its volume, so large orders fill partially over several events,
+// zero leaves them uncapped.
+type FillModel struct {
+	Fees             coinbasepro.FeeTier
+	SlippageBps      float64
+	MaxParticipation float64
+}
+
+type simulatedOrder struct {
+	order    coinbasepro.Order
+	sequence int64
+	price    float64
+	size     float64
+	funds    float64
+	rested   bool
+
+	filledSize    float64
+	executedValue float64
+	fillFees      float64
+}
+
+func (o *simulatedOrder) done() bool {
+	return o.order.Status == "done" || o.order.Status == "rejected"
+}
+
+func (o *simulatedOrder) remainingSize(price float64) float64 {
+	if o.size > 0 {
+		return o.size - o.filledSize
+	}
+
+	return (o.funds - o.executedValue - o.fillFees) / price
+}
+
+func (o *simulatedOrder) snapshot() coinbasepro.Order {
+	order := o.order
+	order.FilledSize = formatDecimal(o.filledSize)
+	order.ExecutedValue = formatDecimal(o.executedValue)
+	order.FillFees = formatDecimal(o.fillFees)
+	order.Settled = order.Status == "done"
+
+	return order
+}
+
+// Exchange is a TradingAPI simulated from candles and trades rather than order books. Orders are
+// accepted at once but only execute against the next bar or trade, so a strategy can never trade
+// on the price it just saw: market orders and orders that cross on arrival take liquidity at the
+// open, resting limit orders fill as makers at their price once the market trades through it.
+type Exchange struct {
+	model FillModel
+
+	mutex       sync.Mutex
+	now         time.Time
+	balances    map[string]float64
+	marks       map[string]float64
+	orders      map[string]*simulatedOrder
+	clientOids  map[string]string
+	fills       []coinbasepro.Fill
+	changed     []string
+	nextOrderId int64
+	nextTradeId int64
+}
+
+func NewExchange(balances map[string]float64, model FillModel) *Exchange {
+	exchange := &Exchange{
+		model:      model,
+		balances:   make(map[string]float64),
+		marks:      make(map[string]float64),
+		orders:     make(map[string]*simulatedOrder),
+		clientOids: make(map[string]string),
+		fills:      make([]coinbasepro.Fill, 0),
+	}
+
+	for currency, amount := range balances {
+		exchange.balances[currency] = amount
+	}
+
+	return exchange
+}
+
+func splitProductId(productId string) (string, string, error) {
+	parts := strings.Split(productId, "-")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", coinbasepro.ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid product_id"}
+	}
+
+	return parts[0], parts[1], nil
+}
+
+func formatDecimal(value float64) string {
+	return strconv.FormatFloat(value, 'f', 8, 64)
+}
+
+func parseOptional(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
+
+// Balance returns the balance of one currency.
+func (e *Exchange) Balance(currency string) float64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.balances[currency]
+}
+
+// Mark returns the last price seen for a product.
+func (e *Exchange) Mark(productId string) float64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.marks[productId]
+}
+
+// available is the balance of a currency less what the open orders may still spend.
+func (e *Exchange) available(currency string) float64 {
+	available := e.balances[currency]
+	for _, order := range e.orders {
+		if order.done() {
+			continue
+		}
+
+		base, quote, _ := splitProductId(order.order.ProductId)
+		switch {
+		case order.order.Side == coinbasepro.OrderSideSell && base == currency:
+			available -= order.size - order.filledSize
+		case order.order.Side == coinbasepro.OrderSideBuy && quote == currency && order.funds > 0:
+			available -= order.funds - order.executedValue - order.fillFees
+		case order.order.Side == coinbasepro.OrderSideBuy && quote == currency:
+			available -= (order.size - order.filledSize) * e.costPrice(order) * (1 + e.feeRate())
+		}
+	}
+
+	return available
+}
+
+// costPrice is the most a buy order of the given size is expected to pay per unit.
+func (e *Exchange) costPrice(order *simulatedOrder) float64 {
+	if order.order.Type == coinbasepro.OrderTypeLimit {
+		return order.price
+	}
+
+	return e.marks[order.order.ProductId] * (1 + e.model.SlippageBps/10000)
+}
+
+func (e *Exchange) feeRate() float64 {
+	return math.Max(e.model.Fees.MakerFeeRate, e.model.Fees.TakerFeeRate)
+}
+
+func (e *Exchange) PlaceOrder(order coinbasepro.Order) (coinbasepro.Order, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	base, quote, err := splitProductId(order.ProductId)
+	if err != nil {
+		return coinbasepro.Order{}, err
+	}
+
+	if order.Side != coinbasepro.OrderSideBuy && order.Side != coinbasepro.OrderSideSell {
+		return coinbasepro.Order{}, coinbasepro.ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid side"}
+	}
+
+	placed := &simulatedOrder{order: order}
+	if placed.order.Type == "" {
+		placed.order.Type = coinbasepro.OrderTypeLimit
+	}
+
+	if placed.price, err = parseOptional(order.Price); err != nil {
+		return coinbasepro.Order{}, coinbasepro.ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid price"}
+	}
+
+	if placed.size, err = parseOptional(order.Size); err != nil {
+		return coinbasepro.Order{}, coinbasepro.ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid size"}
+	}
+
+	if placed.funds, err = parseOptional(order.Funds); err != nil {
+		return coinbasepro.Order{}, coinbasepro.ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid funds"}
+	}
+
+	switch placed.order.Type {
+	case coinbasepro.OrderTypeLimit:
+		if placed.price <= 0 || placed.size <= 0 {
+			return coinbasepro.Order{}, coinbasepro.ApiError{StatusCode: http.StatusBadRequest, Message: "Limit orders require price and size"}
+		}
+	case coinbasepro.OrderTypeMarket:
+		if placed.size <= 0 && (placed.funds <= 0 || order.Side == coinbasepro.OrderSideSell) {
+			return coinbasepro.Order{}, coinbasepro.ApiError{StatusCode: http.StatusBadRequest, Message: "Market orders require size or funds"}
+		}
+
+		if placed.size > 0 && e.marks[order.ProductId] <= 0 {
+			return coinbasepro.Order{}, coinbasepro.ApiError{StatusCode: http.StatusBadRequest, Message: "No price for " + order.ProductId}
+		}
+	default:
+		return coinbasepro.Order{}, coinbasepro.ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid order type"}
+	}
+
+	e.nextOrderId++
+	placed.sequence = e.nextOrderId
+	placed.order.Id = "backtest-" + strconv.FormatInt(placed.sequence, 10)
+	placed.order.CreatedAt = e.now.Format(time.RFC3339Nano)
+	placed.order.Status = "pending"
+	if order.ClientOid != "" {
+		e.clientOids[order.ClientOid] = placed.order.Id
+	}
+
+	mark := e.marks[order.ProductId]
+	crosses := mark > 0 && ((order.Side == coinbasepro.OrderSideBuy && placed.price >= mark) || (order.Side == coinbasepro.OrderSideSell && placed.price <= mark))
+	if placed.order.Type == coinbasepro.OrderTypeLimit && order.PostOnly && crosses {
+		placed.order.Status = "rejected"
+		placed.order.RejectReason = "post only"
+		e.orders[placed.order.Id] = placed
+
+		return placed.snapshot(), nil
+	}
+
+	required, currency := placed.size, base
+	if order.Side == coinbasepro.OrderSideBuy {
+		currency = quote
+		required = placed.funds
+		if placed.size > 0 {
+			required = placed.size * e.costPrice(placed) * (1 + e.feeRate())
+		}
+	}
+
+	if required > e.available(currency)+sizeEpsilon {
+		return coinbasepro.Order{}, coinbasepro.ApiError{StatusCode: http.StatusBadRequest, Message: coinbasepro.InsufficientFundsErrorMessage}
+	}
+
+	e.orders[placed.order.Id] = placed
+
+	return placed.snapshot(), nil
+}
+
+// Advance moves the simulated clock and executes the open orders for a product against a bar,
+// a trade is a bar whose prices are all the trade price. Orders fill in the order they were placed.
+func (e *Exchange) Advance(now time.Time, productId string, candle coinbasepro.Candle) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.now = now
+
+	liquidity := math.Inf(1)
+	if e.model.MaxParticipation > 0 {
+		liquidity = candle.Volume * e.model.MaxParticipation
+	}
+
+	for _, order := range e.openOrders(productId) {
+		liquidity -= e.execute(order, candle, math.Max(liquidity, 0))
+	}
+
+	e.marks[productId] = candle.Close
+}
+
+func (e *Exchange) openOrders(productId string) []*simulatedOrder {
+	open := make([]*simulatedOrder, 0)
+	for _, order := range e.orders {
+		if !order.done() && order.order.ProductId == productId {
+			open = append(open, order)
+		}
+	}
+
+	sort.Slice(open, func(i, j int) bool {
+		return open[i].sequence < open[j].sequence
+	})
+
+	return open
+}
+
+// execute fills as much of an order as the bar and the remaining liquidity allow and returns the
+// size filled.
+func (e *Exchange) execute(order *simulatedOrder, candle coinbasepro.Candle, liquidity float64) float64 {
+	buy := order.order.Side == coinbasepro.OrderSideBuy
+	slippage := 1 + e.model.SlippageBps/10000
+	if !buy {
+		slippage = 1 - e.model.SlippageBps/10000
+	}
+
+	first := !order.rested
+	order.rested = true
+
+	price, liquidityFlag := 0.0, "T"
+	switch {
+	case order.order.Type == coinbasepro.OrderTypeMarket:
+		price = candle.Open * slippage
+	case first && ((buy && candle.Open <= order.price) || (!buy && candle.Open >= order.price)):
+		price = candle.Open * slippage
+		if (buy && price > order.price) || (!buy && price < order.price) {
+			price = order.price
+		}
+	case (buy && candle.Low < order.price) || (!buy && candle.High > order.price):
+		price, liquidityFlag = order.price, "M"
+	}
+
+	size := 0.0
+	if price > 0 {
+		// orders for funds have to leave room for the fee
+		unitCost := price * (1 + e.model.Fees.TakerFeeRate)
+		size = math.Min(order.remainingSize(unitCost), liquidity)
+		if order.order.TimeInForce == "FOK" && size < order.remainingSize(unitCost)-sizeEpsilon {
+			size = 0
+		}
+	}
+
+	if size > sizeEpsilon {
+		e.fill(order, price, size, liquidityFlag)
+	}
+
+	if order.order.Status == "pending" {
+		order.order.Status = "open"
+		e.changed = append(e.changed, order.order.Id)
+	}
+
+	remaining := order.remainingSize(math.Max(price, candle.Close))
+	switch {
+	case remaining <= sizeEpsilon:
+		e.finish(order, "filled")
+	case order.order.TimeInForce == "IOC" || order.order.TimeInForce == "FOK":
+		e.finish(order, "canceled")
+	}
+
+	return size
+}
+
+func (e *Exchange) fill(order *simulatedOrder, price, size float64, liquidity string) {
+	base, quote, _ := splitProductId(order.order.ProductId)
+
+	feeRate := e.model.Fees.TakerFeeRate
+	if liquidity == "M" {
+		feeRate = e.model.Fees.MakerFeeRate
+	}
+
+	value := price * size
+	fee := value * feeRate
+	if order.order.Side == coinbasepro.OrderSideBuy {
+		e.balances[quote] -= value + fee
+		e.balances[base] += size
+	} else {
+		e.balances[base] -= size
+		e.balances[quote] += value - fee
+	}
+
+	order.filledSize += size
+	order.executedValue += value
+	order.fillFees += fee
+
+	e.nextTradeId++
+	e.fills = append(e.fills, coinbasepro.Fill{
+		TradeId:   e.nextTradeId,
+		ProductId: order.order.ProductId,
+		OrderId:   order.order.Id,
+		Price:     formatDecimal(price),
+		Size:      formatDecimal(size),
+		Fee:       formatDecimal(fee),
+		Side:      order.order.Side,
+		Liquidity: liquidity,
+		Settled:   true,
+		CreatedAt: e.now.Format(time.RFC3339Nano),
+	})
+	e.changed = append(e.changed, order.order.Id)
+}
+
+func (e *Exchange) finish(order *simulatedOrder, reason string) {
+	if reason == "filled" && order.filledSize <= sizeEpsilon {
+		reason = "canceled"
+	}
+
+	order.order.Status = "done"
+	order.order.DoneReason = reason
+	order.order.DoneAt = e.now.Format(time.RFC3339Nano)
+	e.changed = append(e.changed, order.order.Id)
+}
+
+// Changed returns the ids of the orders that changed since the last call, in the order they
+// changed and without repeats.
+func (e *Exchange) Changed() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	seen := make(map[string]bool, len(e.changed))
+	changed := make([]string, 0, len(e.changed))
+	for _, orderId := range e.changed {
+		if !seen[orderId] {
+			seen[orderId] = true
+			changed = append(changed, orderId)
+		}
+	}
+	e.changed = e.changed[:0]
+
+	return changed
+}
+
+func (e *Exchange) find(orderId string) (*simulatedOrder, error) {
+	order, found := e.orders[orderId]
+	if !found {
+		return nil, coinbasepro.ApiError{StatusCode: http.StatusNotFound, Message: "NotFound"}
+	}
+
+	return order, nil
+}
+
+func (e *Exchange) CancelOrder(orderId string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	order, found := e.orders[orderId]
+	if !found || order.done() {
+		return coinbasepro.ApiError{StatusCode: http.StatusNotFound, Message: "order not found"}
+	}
+
+	e.finish(order, "canceled")
+	return nil
+}
+
+func (e *Exchange) CancelOrderByClientOid(clientOid string) error {
+	e.mutex.Lock()
+	orderId := e.clientOids[clientOid]
+	e.mutex.Unlock()
+
+	return e.CancelOrder(orderId)
+}
+
+func (e *Exchange) GetOrder(orderId string) (coinbasepro.Order, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	order, err := e.find(orderId)
+	if err != nil {
+		return coinbasepro.Order{}, err
+	}
+
+	return order.snapshot(), nil
+}
+
+func (e *Exchange) GetOrderByClientOid(clientOid string) (coinbasepro.Order, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	order, err := e.find(e.clientOids[clientOid])
+	if err != nil {
+		return coinbasepro.Order{}, err
+	}
+
+	return order.snapshot(), nil
+}
+
+func (e *Exchange) ListOrders(filter coinbasepro.OrderFilter) ([]coinbasepro.Order, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{"open", "pending", "active"}
+	}
+
+	matched := make([]*simulatedOrder, 0)
+	for _, order := range e.orders {
+		if filter.ProductId != "" && order.order.ProductId != filter.ProductId {
+			continue
+		}
+
+		for _, status := range statuses {
+			if status == "all" || status == order.order.Status {
+				matched = append(matched, order)
+				break
+			}
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].sequence > matched[j].sequence
+	})
+
+	orders := make([]coinbasepro.Order, 0, len(matched))
+	for _, order := range matched {
+		orders = append(orders, order.snapshot())
+	}
+
+	return orders, nil
+}
+
+func (e *Exchange) GetFills(filter coinbasepro.FillFilter) ([]coinbasepro.Fill, error) {
+	if filter.OrderId == "" && filter.ProductId == "" {
+		return nil, errors.New(coinbasepro.MissingFillFilterErrorMessage)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	fills := make([]coinbasepro.Fill, 0)
+	for i := len(e.fills) - 1; i >= 0; i-- {
+		if filter.Includes(e.fills[i]) {
+			fills = append(fills, e.fills[i])
+		}
+	}
+
+	return fills, nil
+}
+
+// Fills returns every fill in the order they happened.
+func (e *Exchange) Fills() []coinbasepro.Fill {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return append([]coinbasepro.Fill(nil), e.fills...)
+}
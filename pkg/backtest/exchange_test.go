@@ -0,0 +1,128 @@
+package backtest
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"math"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func bar(minute int, open, high, low, close, volume float64) coinbasepro.Candle {
+	return coinbasepro.Candle{Time: start.Add(time.Duration(minute) * time.Minute), Open: open, High: high, Low: low, Close: close, Volume: volume}
+}
+
+func assertClose(t *testing.T, actual, expected float64) {
+	t.Helper()
+	assert.Assert(t, math.Abs(actual-expected) < 1e-9, "expected %v, got %v", expected, actual)
+}
+
+func newTestExchange(model FillModel) *Exchange {
+	exchange := NewExchange(map[string]float64{"USD": 10000, "BTC": 1}, model)
+	exchange.Advance(start, "BTC-USD", bar(0, 100, 100, 100, 100, 10))
+
+	return exchange
+}
+
+func TestExchange(t *testing.T) {
+	t.Run("should fill market orders at the next open with slippage and taker fees", func(t *testing.T) {
+		exchange := newTestExchange(FillModel{Fees: coinbasepro.FeeTier{MakerFeeRate: 0.001, TakerFeeRate: 0.002}, SlippageBps: 10})
+
+		placed, err := exchange.PlaceOrder(coinbasepro.Order{Type: coinbasepro.OrderTypeMarket, Side: coinbasepro.OrderSideBuy, ProductId: "BTC-USD", Size: "2"})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, placed.Status, "pending")
+		assert.Equal(t, len(exchange.Fills()), 0)
+
+		exchange.Advance(start.Add(time.Minute), "BTC-USD", bar(1, 110, 120, 105, 115, 10))
+		assert.DeepEqual(t, exchange.Changed(), []string{placed.Id})
+
+		order, err := exchange.GetOrder(placed.Id)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, order.Status, "done")
+		assert.Equal(t, order.DoneReason, "filled")
+
+		fills := exchange.Fills()
+		assert.Equal(t, len(fills), 1)
+		assert.Equal(t, fills[0].Price, "110.11000000")
+		assert.Equal(t, fills[0].Liquidity, "T")
+		assertClose(t, exchange.Balance("BTC"), 3)
+		assertClose(t, exchange.Balance("USD"), 10000-220.22*1.002)
+		assertClose(t, exchange.Mark("BTC-USD"), 115)
+	})
+
+	t.Run("should fill resting limit orders as makers once traded through", func(t *testing.T) {
+		exchange := newTestExchange(FillModel{Fees: coinbasepro.FeeTier{MakerFeeRate: 0.001, TakerFeeRate: 0.002}, MaxParticipation: 0.5})
+
+		placed, err := exchange.PlaceOrder(coinbasepro.Order{Side: coinbasepro.OrderSideBuy, ProductId: "BTC-USD", Price: "95", Size: "3"})
+		assert.Assert(t, is.Nil(err))
+
+		exchange.Advance(start.Add(time.Minute), "BTC-USD", bar(1, 100, 101, 95, 96, 10))
+		order, _ := exchange.GetOrder(placed.Id)
+		assert.Equal(t, order.Status, "open")
+		assert.Equal(t, order.FilledSize, "0.00000000")
+
+		exchange.Advance(start.Add(2*time.Minute), "BTC-USD", bar(2, 96, 97, 94, 94, 4))
+		order, _ = exchange.GetOrder(placed.Id)
+		assert.Equal(t, order.Status, "open")
+		assert.Equal(t, order.FilledSize, "2.00000000")
+
+		exchange.Advance(start.Add(3*time.Minute), "BTC-USD", bar(3, 94, 95, 90, 92, 4))
+		order, _ = exchange.GetOrder(placed.Id)
+		assert.Equal(t, order.Status, "done")
+		assert.Equal(t, order.FilledSize, "3.00000000")
+
+		fills := exchange.Fills()
+		assert.Equal(t, len(fills), 2)
+		assert.Equal(t, fills[1].Price, "95.00000000")
+		assert.Equal(t, fills[1].Liquidity, "M")
+		assertClose(t, exchange.Balance("USD"), 10000-285*1.001)
+	})
+
+	t.Run("should take liquidity when a limit order crosses on arrival", func(t *testing.T) {
+		exchange := newTestExchange(FillModel{SlippageBps: 100})
+
+		placed, err := exchange.PlaceOrder(coinbasepro.Order{Side: coinbasepro.OrderSideSell, ProductId: "BTC-USD", Price: "99.5", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		exchange.Advance(start.Add(time.Minute), "BTC-USD", bar(1, 100, 100, 99, 99, 10))
+		fills := exchange.Fills()
+		assert.Equal(t, len(fills), 1)
+		assert.Equal(t, fills[0].OrderId, placed.Id)
+		assert.Equal(t, fills[0].Price, "99.50000000")
+		assert.Equal(t, fills[0].Liquidity, "T")
+	})
+
+	t.Run("should reject what the exchange would reject", func(t *testing.T) {
+		exchange := newTestExchange(FillModel{})
+
+		placed, err := exchange.PlaceOrder(coinbasepro.Order{Side: coinbasepro.OrderSideBuy, ProductId: "BTC-USD", Price: "101", Size: "1", PostOnly: true})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, placed.Status, "rejected")
+
+		_, err = exchange.PlaceOrder(coinbasepro.Order{Side: coinbasepro.OrderSideBuy, ProductId: "BTC-USD", Price: "100", Size: "101"})
+		assert.Error(t, err, "400 - "+coinbasepro.InsufficientFundsErrorMessage)
+
+		_, err = exchange.PlaceOrder(coinbasepro.Order{Side: coinbasepro.OrderSideSell, ProductId: "BTC-USD", Price: "100", Size: "0.6"})
+		assert.Assert(t, is.Nil(err))
+		_, err = exchange.PlaceOrder(coinbasepro.Order{Side: coinbasepro.OrderSideSell, ProductId: "BTC-USD", Price: "100", Size: "0.6"})
+		assert.Error(t, err, "400 - "+coinbasepro.InsufficientFundsErrorMessage)
+	})
+
+	t.Run("should cancel the rest of immediate or cancel orders", func(t *testing.T) {
+		exchange := newTestExchange(FillModel{MaxParticipation: 0.1})
+
+		placed, err := exchange.PlaceOrder(coinbasepro.Order{Side: coinbasepro.OrderSideBuy, ProductId: "BTC-USD", Price: "101", Size: "5", TimeInForce: "IOC"})
+		assert.Assert(t, is.Nil(err))
+
+		exchange.Advance(start.Add(time.Minute), "BTC-USD", bar(1, 100, 100, 100, 100, 20))
+		order, _ := exchange.GetOrder(placed.Id)
+		assert.Equal(t, order.Status, "done")
+		assert.Equal(t, order.DoneReason, "canceled")
+		assert.Equal(t, order.FilledSize, "2.00000000")
+
+		assert.Error(t, exchange.CancelOrder(placed.Id), "404 - order not found")
+	})
+}
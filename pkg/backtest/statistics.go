@@ -0,0 +1,108 @@
+package backtest
+
+import (
+	"math"
+	"time"
+)
+
+const year = 365.25 * 24 * time.Hour
+
+// Statistics summarise a backtest. Returns and drawdown are fractions, Sharpe and Sortino are
+// annualised from the returns between equity points with a risk free rate of zero, and Exposure is
+// the fraction of time a position was held.
+type Statistics struct {
+	StartEquity float64 `json:"start_equity"`
+	EndEquity   float64 `json:"end_equity"`
+	TotalReturn float64 `json:"total_return"`
+	Sharpe      float64 `json:"sharpe"`
+	Sortino     float64 `json:"sortino"`
+	MaxDrawdown float64 `json:"max_drawdown"`
+	WinRate     float64 `json:"win_rate"`
+	Exposure    float64 `json:"exposure"`
+	Trades      int     `json:"trades"`
+	Fees        float64 `json:"fees"`
+}
+
+func ComputeStatistics(equity []EquityPoint, trades []Trade) Statistics {
+	statistics := Statistics{Trades: len(trades)}
+
+	wins := 0
+	for _, trade := range trades {
+		if trade.Pnl > 0 {
+			wins++
+		}
+	}
+	if len(trades) > 0 {
+		statistics.WinRate = float64(wins) / float64(len(trades))
+	}
+
+	if len(equity) == 0 {
+		return statistics
+	}
+
+	statistics.StartEquity = equity[0].Equity
+	statistics.EndEquity = equity[len(equity)-1].Equity
+	if statistics.StartEquity != 0 {
+		statistics.TotalReturn = statistics.EndEquity/statistics.StartEquity - 1
+	}
+
+	peak := equity[0].Equity
+	for _, point := range equity {
+		peak = math.Max(peak, point.Equity)
+		if peak > 0 {
+			statistics.MaxDrawdown = math.Max(statistics.MaxDrawdown, (peak-point.Equity)/peak)
+		}
+	}
+
+	if len(equity) < 2 {
+		return statistics
+	}
+
+	returns := make([]float64, 0, len(equity)-1)
+	var exposed time.Duration
+	for i := 1; i < len(equity); i++ {
+		if equity[i-1].Equity != 0 {
+			returns = append(returns, equity[i].Equity/equity[i-1].Equity-1)
+		}
+
+		if math.Abs(equity[i-1].PositionValue) > 1e-9 {
+			exposed += equity[i].Time.Sub(equity[i-1].Time)
+		}
+	}
+
+	duration := equity[len(equity)-1].Time.Sub(equity[0].Time)
+	if duration <= 0 || len(returns) == 0 {
+		return statistics
+	}
+	statistics.Exposure = float64(exposed) / float64(duration)
+
+	periodsPerYear := float64(year) / (float64(duration) / float64(len(equity)-1))
+
+	mean, variance, downside := 0.0, 0.0, 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+
+	if len(returns) > 1 {
+		variance /= float64(len(returns) - 1)
+	}
+	downside /= float64(len(returns))
+
+	if variance > 0 {
+		statistics.Sharpe = mean / math.Sqrt(variance) * math.Sqrt(periodsPerYear)
+	}
+
+	if downside > 0 {
+		statistics.Sortino = mean / math.Sqrt(downside) * math.Sqrt(periodsPerYear)
+	}
+
+	return statistics
+}
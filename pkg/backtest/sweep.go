@@ -0,0 +1,85 @@
+package backtest
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/strategy"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Params is one combination of strategy parameters in a sweep.
+type Params map[string]float64
+
+// Grid returns every combination of the given parameter values. Combinations are ordered by
+// parameter name, the last name varying fastest.
+func Grid(values map[string][]float64) []Params {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	grid := []Params{{}}
+	for _, name := range names {
+		next := make([]Params, 0, len(grid)*len(values[name]))
+		for _, params := range grid {
+			for _, value := range values[name] {
+				combination := make(Params, len(params)+1)
+				for key, existing := range params {
+					combination[key] = existing
+				}
+				combination[name] = value
+				next = append(next, combination)
+			}
+		}
+		grid = next
+	}
+
+	return grid
+}
+
+type SweepResult struct {
+	Params Params
+	Result Result
+	Err    error
+}
+
+// Sweep backtests a fresh strategy from factory for every combination in grid, on up to workers
+// goroutines or one per CPU when workers is not positive. Results are in the order of grid and do
+// not depend on the number of workers.
+func Sweep(grid []Params, factory func(params Params) (strategy.Strategy, error), config Config, events []Event, workers int) []SweepResult {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]SweepResult, len(grid))
+	jobs := make(chan int)
+
+	waitGroup := sync.WaitGroup{}
+	for worker := 0; worker < workers; worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+
+			for i := range jobs {
+				results[i].Params = grid[i]
+
+				s, err := factory(grid[i])
+				if err != nil {
+					results[i].Err = err
+					continue
+				}
+
+				results[i].Result, results[i].Err = Run(s, config, events)
+			}
+		}()
+	}
+
+	for i := range grid {
+		jobs <- i
+	}
+	close(jobs)
+	waitGroup.Wait()
+
+	return results
+}
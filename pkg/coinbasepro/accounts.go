@@ -0,0 +1,33 @@
+package coinbasepro
+
+import "fmt"
+
+type Account struct {
+	Id             string `json:"id"`
+	Currency       string `json:"currency"`
+	Balance        string `json:"balance"`
+	Available      string `json:"available"`
+	Hold           string `json:"hold"`
+	ProfileId      string `json:"profile_id"`
+	TradingEnabled bool   `json:"trading_enabled"`
+}
+
+func (t *Client) GetAccounts() ([]Account, error) {
+	var accounts []Account
+	_, err := t.executeRequest("GET", "/accounts", nil, &accounts, defaultMaxRetriesOn429)
+	if err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+func (t *Client) GetAccount(accountId string) (Account, error) {
+	var account Account
+	_, err := t.executeRequest("GET", fmt.Sprintf("/accounts/%s", accountId), nil, &account, defaultMaxRetriesOn429)
+	if err != nil {
+		return Account{}, err
+	}
+
+	return account, nil
+}
@@ -0,0 +1,43 @@
+package coinbasepro
+
+// MarketDataAPI covers the public product and market data endpoints.
+type MarketDataAPI interface {
+	GetProducts() ([]Product, error)
+	GetProduct(productId string) (Product, error)
+	GetTicker(productId string) (Ticker, error)
+	GetProductBook(productId string, level int) (ProductBook, error)
+	GetTrades(productId string) ([]Trade, error)
+	GetCandles(productId string, filter CandleFilter) ([]Candle, error)
+	GetTime() (ServerTime, error)
+}
+
+// TradingAPI places, cancels and queries orders and fills. Client trades on the exchange and
+// PaperClient against a simulated matching engine.
+type TradingAPI interface {
+	PlaceOrder(order Order) (Order, error)
+	CancelOrder(orderId string) error
+	CancelOrderByClientOid(clientOid string) error
+	GetOrder(orderId string) (Order, error)
+	GetOrderByClientOid(clientOid string) (Order, error)
+	ListOrders(filter OrderFilter) ([]Order, error)
+	GetFills(filter FillFilter) ([]Fill, error)
+}
+
+type AccountsAPI interface {
+	GetAccounts() ([]Account, error)
+	GetAccount(accountId string) (Account, error)
+}
+
+type FundingAPI interface {
+	GetTransfers(filter TransferFilter) ([]Transfer, error)
+	GetTransfer(transferId string) (Transfer, error)
+	GetPaymentMethods() ([]PaymentMethod, error)
+}
+
+var _ MarketDataAPI = (*Client)(nil)
+var _ TradingAPI = (*Client)(nil)
+var _ AccountsAPI = (*Client)(nil)
+var _ FundingAPI = (*Client)(nil)
+
+var _ TradingAPI = (*PaperClient)(nil)
+var _ AccountsAPI = (*PaperClient)(nil)
@@ -0,0 +1,22 @@
+package coinbasepro_test
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro/conformance"
+	"testing"
+)
+
+func TestClientConformance(t *testing.T) {
+	conformance.RunTradingAPI(t, conformance.TradingAPIConfig{
+		New: func(t *testing.T) coinbasepro.TradingAPI {
+			return coinbasepro.NewFakeExchangeClient(t)
+		},
+		ProductId:    "BTC-USD",
+		RestingPrice: "100",
+		RestingSize:  "1",
+	})
+
+	conformance.RunAccountsAPI(t, func(t *testing.T) coinbasepro.AccountsAPI {
+		return coinbasepro.NewFakeExchangeClient(t, coinbasepro.Account{Id: "71452118-efc7-4cc4-8780-a5e22d4baa53", Currency: "USD", Balance: "100"})
+	})
+}
@@ -0,0 +1,450 @@
+package coinbasepro
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+type BarType string
+
+const BarTypeTime BarType = "time"
+const BarTypeTick BarType = "tick"
+const BarTypeVolume BarType = "volume"
+const BarTypeDollar BarType = "dollar"
+
+const InvalidBarSpecErrorMessage = "time bars need a positive interval, tick, volume and dollar bars a positive threshold"
+
+// BarSpec describes how trades are grouped into bars. Time bars use Interval, tick bars close after
+// Threshold trades, volume bars after Threshold base currency and dollar bars after Threshold quote
+// currency has traded.
+type BarSpec struct {
+	Type      BarType
+	Interval  time.Duration
+	Threshold float64
+}
+
+func (s BarSpec) validate() error {
+	switch s.Type {
+	case BarTypeTime:
+		if s.Interval > 0 {
+			return nil
+		}
+	case BarTypeTick, BarTypeVolume, BarTypeDollar:
+		if s.Threshold > 0 {
+			return nil
+		}
+	}
+
+	return errors.New(InvalidBarSpecErrorMessage)
+}
+
+// TradeTick is a single trade from either the matches channel or the REST trades endpoint.
+type TradeTick struct {
+	ProductId string
+	TradeId   int64
+	Price     float64
+	Size      float64
+	Side      string
+	Time      time.Time
+}
+
+func TradeTickFromMessage(message Message) (TradeTick, error) {
+	price, err := parseDecimal(message.Price)
+	if err != nil {
+		return TradeTick{}, err
+	}
+
+	size, err := parseDecimal(message.Size)
+	if err != nil {
+		return TradeTick{}, err
+	}
+
+	tradeTime, err := time.Parse(time.RFC3339Nano, message.Time)
+	if err != nil {
+		return TradeTick{}, err
+	}
+
+	return TradeTick{ProductId: message.ProductId, TradeId: message.TradeId, Price: price, Size: size, Side: message.Side, Time: tradeTime}, nil
+}
+
+func TradeTickFromTrade(productId string, trade Trade) (TradeTick, error) {
+	return TradeTickFromMessage(Message{ProductId: productId, TradeId: trade.TradeId, Price: trade.Price, Size: trade.Size, Side: trade.Side, Time: trade.Time})
+}
+
+// Bar is a Candle built locally from trades. End is the interval end for time bars and the time of
+// the last trade for the other bar types.
+type Bar struct {
+	Candle
+	ProductId    string
+	End          time.Time
+	Trades       int
+	Value        float64
+	FirstTradeId int64
+	LastTradeId  int64
+}
+
+func newBar(productId string, start time.Time, trade TradeTick) *Bar {
+	return &Bar{
+		Candle:       Candle{Time: start, Open: trade.Price, High: trade.Price, Low: trade.Price, Close: trade.Price},
+		ProductId:    productId,
+		End:          trade.Time,
+		FirstTradeId: trade.TradeId,
+	}
+}
+
+func (b *Bar) add(trade TradeTick, size float64, isLate bool) {
+	if trade.Price > b.High {
+		b.High = trade.Price
+	}
+
+	if trade.Price < b.Low {
+		b.Low = trade.Price
+	}
+
+	// a late trade changes the range of a bar but not how it opened or closed, and a trade older
+	// than the ones already in the bar only changes how it opened
+	if !isLate {
+		switch {
+		case trade.TradeId == 0 || trade.TradeId > b.LastTradeId:
+			b.Close = trade.Price
+			b.LastTradeId = trade.TradeId
+		case trade.TradeId < b.FirstTradeId:
+			b.Open = trade.Price
+			b.FirstTradeId = trade.TradeId
+		}
+	}
+
+	b.Volume += size
+	b.Value += size * trade.Price
+	b.Trades++
+}
+
+type BarUpdate struct {
+	Bar    Bar
+	Closed bool
+	// Revised is set when a late trade changed a bar that was already published as closed.
+	Revised bool
+}
+
+type aggregatorProduct struct {
+	current     *Bar
+	closed      []*Bar
+	seen        map[int64]time.Time
+	lastTradeId int64
+	watermark   time.Time
+}
+
+// isLate reports whether a bar starting at start was already closed.
+func (p *aggregatorProduct) isLate(start time.Time) bool {
+	if p.current != nil {
+		return start.Before(p.current.Time)
+	}
+
+	return len(p.closed) > 0 && !start.After(p.closed[len(p.closed)-1].Time)
+}
+
+// CandleAggregator builds bars from trades and publishes partial bars as trades arrive and closed
+// bars once they are complete. Time bars accept trades up to LateTradeWindow after they closed and
+// republish the corrected bar, later trades are dropped and counted. It is safe for concurrent use.
+type CandleAggregator struct {
+	spec            BarSpec
+	lateTradeWindow time.Duration
+
+	mutex             sync.Mutex
+	products          map[string]*aggregatorProduct
+	lateTradesDropped int
+
+	subscribersMutex sync.RWMutex
+	subscribers      map[int]func(BarUpdate)
+	nextSubscriberId int
+}
+
+func NewCandleAggregator(spec BarSpec, lateTradeWindow time.Duration) (*CandleAggregator, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+
+	return &CandleAggregator{
+		spec:            spec,
+		lateTradeWindow: lateTradeWindow,
+		products:        make(map[string]*aggregatorProduct),
+		subscribers:     make(map[int]func(BarUpdate)),
+	}, nil
+}
+
+func (a *CandleAggregator) Spec() BarSpec {
+	return a.spec
+}
+
+// Subscribe registers fn for every bar update and returns a function that removes it.
+func (a *CandleAggregator) Subscribe(fn func(BarUpdate)) func() {
+	a.subscribersMutex.Lock()
+	defer a.subscribersMutex.Unlock()
+
+	id := a.nextSubscriberId
+	a.nextSubscriberId++
+	a.subscribers[id] = fn
+
+	return func() {
+		a.subscribersMutex.Lock()
+		defer a.subscribersMutex.Unlock()
+
+		delete(a.subscribers, id)
+	}
+}
+
+func (a *CandleAggregator) notify(updates []BarUpdate) {
+	a.subscribersMutex.RLock()
+	defer a.subscribersMutex.RUnlock()
+
+	for _, update := range updates {
+		for _, fn := range a.subscribers {
+			fn(update)
+		}
+	}
+}
+
+func (a *CandleAggregator) LateTradesDropped() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.lateTradesDropped
+}
+
+// Current returns the bar being built for a product.
+func (a *CandleAggregator) Current(productId string) (Bar, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	product, found := a.products[productId]
+	if !found || product.current == nil {
+		return Bar{}, false
+	}
+
+	return *product.current, true
+}
+
+// HandleMessage aggregates match and last_match messages and ignores everything else.
+func (a *CandleAggregator) HandleMessage(message Message) error {
+	if message.Type != MessageTypeMatch && message.Type != MessageTypeLastMatch {
+		return nil
+	}
+
+	trade, err := TradeTickFromMessage(message)
+	if err != nil {
+		return err
+	}
+
+	a.AddTrade(trade)
+	return nil
+}
+
+// Consume aggregates every message from source until source fails.
+func (a *CandleAggregator) Consume(source MessageSource) error {
+	for {
+		message, err := source.Read()
+		if err != nil {
+			return err
+		}
+
+		if err := a.HandleMessage(message); err != nil {
+			return err
+		}
+	}
+}
+
+func (a *CandleAggregator) product(productId string) *aggregatorProduct {
+	product, found := a.products[productId]
+	if !found {
+		product = &aggregatorProduct{seen: make(map[int64]time.Time)}
+		a.products[productId] = product
+	}
+
+	return product
+}
+
+// AddTrades adds trades from the REST trades endpoint, which lists the newest first, in trade id
+// order.
+func (a *CandleAggregator) AddTrades(productId string, trades []Trade) error {
+	ticks := make([]TradeTick, 0, len(trades))
+	for _, trade := range trades {
+		tick, err := TradeTickFromTrade(productId, trade)
+		if err != nil {
+			return err
+		}
+		ticks = append(ticks, tick)
+	}
+
+	sort.SliceStable(ticks, func(i, j int) bool {
+		return ticks[i].TradeId < ticks[j].TradeId
+	})
+
+	for _, tick := range ticks {
+		a.AddTrade(tick)
+	}
+
+	return nil
+}
+
+func (a *CandleAggregator) AddTrade(trade TradeTick) {
+	a.mutex.Lock()
+	product := a.product(trade.ProductId)
+
+	var updates []BarUpdate
+	if a.spec.Type == BarTypeTime {
+		if _, duplicate := product.seen[trade.TradeId]; !duplicate || trade.TradeId == 0 {
+			if trade.TradeId != 0 {
+				product.seen[trade.TradeId] = trade.Time
+			}
+			updates = a.addTimeTrade(product, trade)
+		}
+	} else if trade.TradeId == 0 || trade.TradeId > product.lastTradeId {
+		// sequence bars never revise, trade ids only ever increase so older ones are duplicates
+		if trade.TradeId != 0 {
+			product.lastTradeId = trade.TradeId
+		}
+		updates = a.addSequenceTrade(product, trade)
+	}
+	a.mutex.Unlock()
+
+	a.notify(updates)
+}
+
+func (a *CandleAggregator) addTimeTrade(product *aggregatorProduct, trade TradeTick) []BarUpdate {
+	start := trade.Time.UTC().Truncate(a.spec.Interval)
+	updates := make([]BarUpdate, 0, 2)
+
+	if trade.Time.After(product.watermark) {
+		product.watermark = trade.Time
+	}
+
+	if product.isLate(start) {
+		if !start.Add(a.spec.Interval + a.lateTradeWindow).After(product.watermark) {
+			a.lateTradesDropped++
+			return updates
+		}
+
+		index := sort.Search(len(product.closed), func(i int) bool {
+			return !product.closed[i].Time.Before(start)
+		})
+
+		if index == len(product.closed) || !product.closed[index].Time.Equal(start) {
+			bar := newBar(trade.ProductId, start, trade)
+			bar.End = start.Add(a.spec.Interval)
+			product.closed = append(product.closed, nil)
+			copy(product.closed[index+1:], product.closed[index:])
+			product.closed[index] = bar
+			bar.add(trade, trade.Size, false)
+		} else {
+			product.closed[index].add(trade, trade.Size, true)
+		}
+
+		return append(updates, BarUpdate{Bar: *product.closed[index], Closed: true, Revised: true})
+	}
+
+	if product.current != nil && start.After(product.current.Time) {
+		updates = append(updates, a.closeTimeBar(product))
+	}
+
+	if product.current == nil {
+		product.current = newBar(trade.ProductId, start, trade)
+		product.current.End = start.Add(a.spec.Interval)
+	}
+
+	product.current.add(trade, trade.Size, false)
+
+	return append(updates, BarUpdate{Bar: *product.current})
+}
+
+func (a *CandleAggregator) closeTimeBar(product *aggregatorProduct) BarUpdate {
+	closed := product.current
+	product.current = nil
+	product.closed = append(product.closed, closed)
+
+	// bars and trade ids past the late trade window can no longer change
+	horizon := product.watermark.Add(-a.lateTradeWindow - a.spec.Interval)
+	for len(product.closed) > 0 && !product.closed[0].End.After(horizon) {
+		product.closed = product.closed[1:]
+	}
+
+	for tradeId, tradeTime := range product.seen {
+		if tradeTime.Before(horizon) {
+			delete(product.seen, tradeId)
+		}
+	}
+
+	return BarUpdate{Bar: *closed, Closed: true}
+}
+
+func (a *CandleAggregator) addSequenceTrade(product *aggregatorProduct, trade TradeTick) []BarUpdate {
+	updates := make([]BarUpdate, 0, 1)
+	remaining := trade.Size
+
+	for {
+		if product.current == nil {
+			product.current = newBar(trade.ProductId, trade.Time, trade)
+		}
+
+		bar := product.current
+		size := remaining
+		switch a.spec.Type {
+		case BarTypeVolume:
+			size = math.Min(remaining, a.spec.Threshold-bar.Volume)
+		case BarTypeDollar:
+			size = math.Min(remaining, (a.spec.Threshold-bar.Value)/trade.Price)
+		}
+
+		bar.add(trade, size, false)
+		bar.End = trade.Time
+		remaining -= size
+
+		full := false
+		switch a.spec.Type {
+		case BarTypeTick:
+			full = float64(bar.Trades) >= a.spec.Threshold
+		case BarTypeVolume:
+			full = bar.Volume >= a.spec.Threshold-incrementEpsilon
+		case BarTypeDollar:
+			full = bar.Value >= a.spec.Threshold-incrementEpsilon
+		}
+
+		if !full {
+			updates = append(updates, BarUpdate{Bar: *bar})
+			break
+		}
+
+		product.current = nil
+		updates = append(updates, BarUpdate{Bar: *bar, Closed: true})
+
+		if remaining <= incrementEpsilon {
+			break
+		}
+	}
+
+	return updates
+}
+
+// Flush closes time bars whose interval ended before now, for quiet markets where no later trade
+// arrives to close them.
+func (a *CandleAggregator) Flush(now time.Time) {
+	if a.spec.Type != BarTypeTime {
+		return
+	}
+
+	a.mutex.Lock()
+	updates := make([]BarUpdate, 0)
+	for _, product := range a.products {
+		if product.current != nil && !product.current.End.After(now) {
+			if now.After(product.watermark) {
+				product.watermark = now
+			}
+			updates = append(updates, a.closeTimeBar(product))
+		}
+	}
+	a.mutex.Unlock()
+
+	a.notify(updates)
+}
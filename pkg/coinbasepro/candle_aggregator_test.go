@@ -0,0 +1,176 @@
+package coinbasepro
+
+import (
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"testing"
+	"time"
+)
+
+var aggregatorEpoch = time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testTrade(tradeId int64, seconds int, price, size float64) TradeTick {
+	return TradeTick{ProductId: "BTC-USD", TradeId: tradeId, Price: price, Size: size, Time: aggregatorEpoch.Add(time.Duration(seconds) * time.Second)}
+}
+
+func newTestAggregator(t *testing.T, spec BarSpec, lateTradeWindow time.Duration) (*CandleAggregator, *[]BarUpdate) {
+	aggregator, err := NewCandleAggregator(spec, lateTradeWindow)
+	assert.Assert(t, is.Nil(err))
+
+	updates := make([]BarUpdate, 0)
+	aggregator.Subscribe(func(update BarUpdate) {
+		updates = append(updates, update)
+	})
+
+	return aggregator, &updates
+}
+
+func closedBars(updates []BarUpdate) []BarUpdate {
+	closed := make([]BarUpdate, 0)
+	for _, update := range updates {
+		if update.Closed {
+			closed = append(closed, update)
+		}
+	}
+
+	return closed
+}
+
+func TestCandleAggregator(t *testing.T) {
+	t.Run("should build time bars from match messages", func(t *testing.T) {
+		aggregator, updates := newTestAggregator(t, BarSpec{Type: BarTypeTime, Interval: 30 * time.Second}, 0)
+
+		source := &sliceMessageSource{messages: []Message{
+			{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 1, Price: "100", Size: "1", Time: "2021-03-01T12:00:01Z"},
+			{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 2, Price: "105", Size: "2", Time: "2021-03-01T12:00:10Z"},
+			{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 2, Price: "105", Size: "2", Time: "2021-03-01T12:00:10Z"},
+			{Type: MessageTypeTicker, ProductId: "BTC-USD", Price: "1"},
+			{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 3, Price: "98", Size: "1", Time: "2021-03-01T12:00:20Z"},
+			{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 4, Price: "99", Size: "1", Time: "2021-03-01T12:00:31Z"},
+		}}
+		assert.Error(t, aggregator.Consume(source), "end of messages")
+
+		assert.Equal(t, len(*updates), 5)
+		closed := closedBars(*updates)
+		assert.Equal(t, len(closed), 1)
+
+		bar := closed[0].Bar
+		assert.Equal(t, bar.Time, aggregatorEpoch)
+		assert.Equal(t, bar.End, aggregatorEpoch.Add(30*time.Second))
+		assert.DeepEqual(t, bar.Candle, Candle{Time: aggregatorEpoch, Open: 100, High: 105, Low: 98, Close: 98, Volume: 4})
+		assert.Equal(t, bar.Trades, 3)
+		assert.Equal(t, bar.Value, 100+210+98.0)
+
+		current, found := aggregator.Current("BTC-USD")
+		assert.Assert(t, found)
+		assert.Equal(t, current.Open, 99.0)
+
+		aggregator.Flush(aggregatorEpoch.Add(time.Minute))
+		assert.Equal(t, len(closedBars(*updates)), 2)
+		_, found = aggregator.Current("BTC-USD")
+		assert.Assert(t, !found)
+	})
+
+	t.Run("should revise closed bars with late trades inside the window", func(t *testing.T) {
+		aggregator, updates := newTestAggregator(t, BarSpec{Type: BarTypeTime, Interval: time.Minute}, 30*time.Second)
+
+		aggregator.AddTrade(testTrade(1, 10, 100, 1))
+		aggregator.AddTrade(testTrade(3, 70, 101, 1))
+		aggregator.AddTrade(testTrade(2, 50, 90, 0.5))
+		aggregator.AddTrade(testTrade(2, 50, 90, 0.5))
+		aggregator.AddTrade(testTrade(5, 130, 102, 1))
+		aggregator.AddTrade(testTrade(4, 20, 80, 1))
+
+		revised := make([]BarUpdate, 0)
+		for _, update := range *updates {
+			if update.Revised {
+				revised = append(revised, update)
+			}
+		}
+
+		assert.Equal(t, len(revised), 1)
+		assert.DeepEqual(t, revised[0].Bar.Candle, Candle{Time: aggregatorEpoch, Open: 100, High: 100, Low: 90, Close: 100, Volume: 1.5})
+		assert.Equal(t, aggregator.LateTradesDropped(), 1)
+	})
+
+	t.Run("should keep the close of a bar on its newest trade", func(t *testing.T) {
+		aggregator, updates := newTestAggregator(t, BarSpec{Type: BarTypeTime, Interval: time.Minute}, 0)
+
+		aggregator.AddTrade(testTrade(2, 20, 101, 1))
+		aggregator.AddTrade(testTrade(3, 30, 103, 1))
+		aggregator.AddTrade(testTrade(1, 10, 99, 1))
+
+		current := (*updates)[len(*updates)-1].Bar
+		assert.DeepEqual(t, current.Candle, Candle{Time: aggregatorEpoch, Open: 99, High: 103, Low: 99, Close: 103, Volume: 3})
+		assert.Equal(t, current.FirstTradeId, int64(1))
+		assert.Equal(t, current.LastTradeId, int64(3))
+	})
+
+	t.Run("should build bars from REST trades listed newest first", func(t *testing.T) {
+		trades := []Trade{
+			{TradeId: 4, Price: "104", Size: "1", Time: "2021-03-01T12:01:10Z"},
+			{TradeId: 3, Price: "103", Size: "1", Time: "2021-03-01T12:00:50Z"},
+			{TradeId: 2, Price: "102", Size: "1", Time: "2021-03-01T12:00:20Z"},
+			{TradeId: 1, Price: "101", Size: "1", Time: "2021-03-01T12:00:10Z"},
+		}
+
+		timeBars, updates := newTestAggregator(t, BarSpec{Type: BarTypeTime, Interval: time.Minute}, 0)
+		assert.Assert(t, is.Nil(timeBars.AddTrades("BTC-USD", trades)))
+		closed := closedBars(*updates)
+		assert.Equal(t, len(closed), 1)
+		assert.DeepEqual(t, closed[0].Bar.Candle, Candle{Time: aggregatorEpoch, Open: 101, High: 103, Low: 101, Close: 103, Volume: 3})
+
+		tickBars, updates := newTestAggregator(t, BarSpec{Type: BarTypeTick, Threshold: 2}, 0)
+		assert.Assert(t, is.Nil(tickBars.AddTrades("BTC-USD", trades)))
+		closed = closedBars(*updates)
+		assert.Equal(t, len(closed), 2)
+		assert.Equal(t, closed[0].Bar.Open, 101.0)
+		assert.Equal(t, closed[1].Bar.Close, 104.0)
+
+		err := tickBars.AddTrades("BTC-USD", []Trade{{TradeId: 5, Price: "x", Size: "1"}})
+		assert.Assert(t, err != nil)
+	})
+
+	t.Run("should split trades across volume bars", func(t *testing.T) {
+		aggregator, updates := newTestAggregator(t, BarSpec{Type: BarTypeVolume, Threshold: 2}, 0)
+
+		aggregator.AddTrade(testTrade(1, 1, 100, 1.5))
+		aggregator.AddTrade(testTrade(2, 2, 101, 3))
+		aggregator.AddTrade(testTrade(1, 3, 100, 1.5))
+
+		closed := closedBars(*updates)
+		assert.Equal(t, len(closed), 2)
+		assert.Equal(t, closed[0].Bar.Volume, 2.0)
+		assert.Equal(t, closed[0].Bar.Close, 101.0)
+		assert.Equal(t, closed[1].Bar.Volume, 2.0)
+		assert.Equal(t, closed[1].Bar.Open, 101.0)
+
+		current, _ := aggregator.Current("BTC-USD")
+		assert.Equal(t, current.Volume, 0.5)
+	})
+
+	t.Run("should close tick and dollar bars on their thresholds", func(t *testing.T) {
+		ticks, tickUpdates := newTestAggregator(t, BarSpec{Type: BarTypeTick, Threshold: 2}, 0)
+		dollars, dollarUpdates := newTestAggregator(t, BarSpec{Type: BarTypeDollar, Threshold: 1000}, 0)
+
+		for i, price := range []float64{100, 200, 300, 400, 500} {
+			trade := testTrade(int64(i+1), i, price, 2)
+			ticks.AddTrade(trade)
+			dollars.AddTrade(trade)
+		}
+
+		assert.Equal(t, len(closedBars(*tickUpdates)), 2)
+		assert.Equal(t, closedBars(*tickUpdates)[1].Bar.Close, 400.0)
+
+		closed := closedBars(*dollarUpdates)
+		assert.Equal(t, len(closed), 3)
+		for _, update := range closed {
+			assert.Assert(t, update.Bar.Value > 1000-1e-6 && update.Bar.Value < 1000+1e-6, update.Bar.Value)
+		}
+	})
+
+	t.Run("should reject invalid specs", func(t *testing.T) {
+		_, err := NewCandleAggregator(BarSpec{Type: BarTypeVolume}, 0)
+		assert.Error(t, err, InvalidBarSpecErrorMessage)
+	})
+}
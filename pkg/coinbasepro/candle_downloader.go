@@ -0,0 +1,251 @@
+package coinbasepro
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// Public endpoints are limited per IP address, below the limit of private endpoints.
+const defaultPublicRequestsPerSecond = 3
+const defaultPublicBurst = 6
+
+type CandleRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// CandleChunks splits [start, end) into ranges of at most MaxCandlesPerRequest buckets. Start is
+// aligned down and end up to the granularity, so every chunk holds whole buckets and the bucket
+// end falls in, which may still be in progress, is included.
+func CandleChunks(start, end time.Time, granularity int) ([]CandleRange, error) {
+	if !ValidGranularity(granularity) {
+		return nil, errors.New(InvalidGranularityErrorMessage)
+	}
+
+	bucket := time.Duration(granularity) * time.Second
+	chunkLength := bucket * MaxCandlesPerRequest
+
+	end = end.UTC()
+	if aligned := end.Truncate(bucket); aligned.Before(end) {
+		end = aligned.Add(bucket)
+	}
+
+	chunks := make([]CandleRange, 0)
+	for chunkStart := start.UTC().Truncate(bucket); chunkStart.Before(end); chunkStart = chunkStart.Add(chunkLength) {
+		chunkEnd := chunkStart.Add(chunkLength)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+
+		chunks = append(chunks, CandleRange{Start: chunkStart, End: chunkEnd})
+	}
+
+	return chunks, nil
+}
+
+// CandleDownloader fetches arbitrary ranges of candles by splitting them into chunks the candles
+// endpoint accepts.
+type CandleDownloader struct {
+	api         MarketDataAPI
+	rateLimiter *RateLimiter
+
+	// FillMissing inserts flat, zero volume candles for closed buckets without trades.
+	FillMissing bool
+	// OnProgress is called after every chunk with the number of chunks done and in total.
+	OnProgress func(done, total int)
+
+	now func() time.Time
+}
+
+func NewCandleDownloader(api MarketDataAPI) *CandleDownloader {
+	return &CandleDownloader{api: api, rateLimiter: NewRateLimiter(defaultPublicRequestsPerSecond, defaultPublicBurst), now: time.Now}
+}
+
+// SetClock replaces the time source that decides which buckets are closed and may be filled.
+func (d *CandleDownloader) SetClock(now func() time.Time) {
+	d.now = now
+}
+
+// SetRateLimiter replaces the limiter applied between chunk requests, nil disables it.
+func (d *CandleDownloader) SetRateLimiter(rateLimiter *RateLimiter) {
+	d.rateLimiter = rateLimiter
+}
+
+// Download calls fn with the candles of each chunk in ascending time order, without duplicates.
+func (d *CandleDownloader) Download(productId string, start, end time.Time, granularity int, fn func(candles []Candle) error) error {
+	chunks, err := CandleChunks(start, end, granularity)
+	if err != nil {
+		return err
+	}
+
+	bucket := time.Duration(granularity) * time.Second
+	var last *Candle
+
+	for i, chunk := range chunks {
+		if d.rateLimiter != nil {
+			d.rateLimiter.Wait()
+		}
+
+		candles, err := d.api.GetCandles(productId, CandleFilter{Start: chunk.Start, End: chunk.End.Add(-bucket), Granularity: granularity})
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(candles, func(i, j int) bool {
+			return candles[i].Time.Before(candles[j].Time)
+		})
+
+		unique := make([]Candle, 0, len(candles))
+		for _, candle := range candles {
+			if candle.Time.Before(start) || !candle.Time.Before(chunk.End) {
+				continue
+			}
+
+			if last != nil && !candle.Time.After(last.Time) {
+				continue
+			}
+
+			if d.FillMissing && last != nil {
+				for missing := last.Time.Add(bucket); missing.Before(candle.Time); missing = missing.Add(bucket) {
+					unique = append(unique, flatCandle(missing, last.Close))
+				}
+			}
+
+			unique = append(unique, candle)
+			last = &unique[len(unique)-1]
+		}
+
+		if d.FillMissing && last != nil {
+			// buckets still in progress may not be published yet, only fill closed ones
+			closed := d.now().Add(-bucket)
+			for missing := last.Time.Add(bucket); missing.Before(chunk.End) && !missing.After(closed); missing = missing.Add(bucket) {
+				unique = append(unique, flatCandle(missing, last.Close))
+			}
+
+			if len(unique) > 0 {
+				last = &unique[len(unique)-1]
+			}
+		}
+
+		if len(unique) > 0 {
+			if err := fn(unique); err != nil {
+				return err
+			}
+		}
+
+		if d.OnProgress != nil {
+			d.OnProgress(i+1, len(chunks))
+		}
+	}
+
+	return nil
+}
+
+func flatCandle(at time.Time, price float64) Candle {
+	return Candle{Time: at, Low: price, High: price, Open: price, Close: price}
+}
+
+// DownloadToFile downloads into path, resuming after the last candle already in the file if a
+// previous download was interrupted. Parquet files cannot be appended to, so they are spooled to
+// a JSON Lines file next to path and converted once the download completes. It returns the number
+// of candles written by this call.
+func (d *CandleDownloader) DownloadToFile(productId string, start, end time.Time, granularity int, path string, format CandleFormat) (int, error) {
+	spoolPath, spoolFormat := path, format
+	if format == CandleFormatParquet {
+		spoolPath, spoolFormat = path+".partial.jsonl", CandleFormatJSONLines
+	} else if format != CandleFormatCSV && format != CandleFormatJSONLines {
+		return 0, errors.New(UnsupportedCandleFormatErrorMessage)
+	}
+
+	existing, err := readCompleteCandles(spoolPath, spoolFormat)
+	if err != nil {
+		return 0, err
+	}
+
+	resumeFrom := start
+	if len(existing) > 0 {
+		if next := existing[len(existing)-1].Time.Add(time.Duration(granularity) * time.Second); next.After(resumeFrom) {
+			resumeFrom = next
+		}
+	}
+
+	file, err := os.OpenFile(spoolPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	var writer CandleWriter
+	if spoolFormat == CandleFormatCSV {
+		writer, err = NewCSVCandleWriter(file, len(existing) == 0)
+		if err != nil {
+			file.Close()
+			return 0, err
+		}
+	} else {
+		writer = NewJSONLinesCandleWriter(file)
+	}
+
+	written := 0
+	err = d.Download(productId, resumeFrom, end, granularity, func(candles []Candle) error {
+		written += len(candles)
+		return writer.Write(candles)
+	})
+
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil || format != CandleFormatParquet {
+		return written, err
+	}
+
+	candles, err := ReadCandles(spoolPath, CandleFormatJSONLines)
+	if err != nil {
+		return written, err
+	}
+
+	output, err := os.Create(path + ".tmp")
+	if err != nil {
+		return written, err
+	}
+
+	parquetWriter := NewParquetCandleWriter(output)
+	if err := parquetWriter.Write(candles); err != nil {
+		return written, err
+	}
+
+	if err := parquetWriter.Close(); err != nil {
+		return written, err
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return written, err
+	}
+
+	return written, os.Remove(spoolPath)
+}
+
+// readCompleteCandles reads the candles already in a file, dropping a last line torn by an
+// interrupted write. A missing file has no candles.
+func readCompleteCandles(path string, format CandleFormat) ([]Candle, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contents) > 0 && contents[len(contents)-1] != '\n' {
+		if err := os.Truncate(path, int64(bytes.LastIndexByte(contents, '\n')+1)); err != nil {
+			return nil, err
+		}
+	}
+
+	return ReadCandles(path, format)
+}
@@ -0,0 +1,312 @@
+package coinbasepro_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro/mock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var candleEpoch = time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// newCandleApi serves minute candles with a gap at minute 500, newest first and with both ends of
+// the requested range included as the exchange does. After failAfter calls it errors.
+func newCandleApi(t *testing.T, failAfter int) *mock.MarketDataAPI {
+	api := &mock.MarketDataAPI{}
+	calls := 0
+	api.GetCandlesFunc = func(productId string, filter coinbasepro.CandleFilter) ([]coinbasepro.Candle, error) {
+		calls++
+		if failAfter > 0 && calls > failAfter {
+			return nil, errors.New("connection reset")
+		}
+
+		bucket := time.Duration(filter.Granularity) * time.Second
+		assert.Check(t, filter.End.Sub(filter.Start)/bucket < coinbasepro.MaxCandlesPerRequest)
+
+		candles := make([]coinbasepro.Candle, 0)
+		for at := filter.End; !at.Before(filter.Start); at = at.Add(-bucket) {
+			minute := float64(at.Sub(candleEpoch) / time.Minute)
+			if minute == 500 {
+				continue
+			}
+
+			candles = append(candles, coinbasepro.Candle{Time: at, Low: minute, High: minute + 2, Open: minute, Close: minute + 1, Volume: 1})
+		}
+
+		return candles, nil
+	}
+
+	return api
+}
+
+func TestCandleChunks(t *testing.T) {
+	chunks, err := coinbasepro.CandleChunks(candleEpoch.Add(30*time.Second), candleEpoch.Add(24*time.Hour), 60)
+	assert.Assert(t, is.Nil(err))
+	assert.Equal(t, len(chunks), 5)
+	assert.Equal(t, chunks[0].Start, candleEpoch)
+	assert.Equal(t, chunks[0].End, candleEpoch.Add(300*time.Minute))
+	assert.Equal(t, chunks[4].End, candleEpoch.Add(24*time.Hour))
+
+	chunks, err = coinbasepro.CandleChunks(candleEpoch, candleEpoch.Add(365*24*time.Hour), 86400)
+	assert.Assert(t, is.Nil(err))
+	assert.Equal(t, len(chunks), 2)
+
+	chunks, err = coinbasepro.CandleChunks(candleEpoch, candleEpoch.Add(300*time.Minute+20*time.Second), 60)
+	assert.Assert(t, is.Nil(err))
+	assert.Equal(t, len(chunks), 2)
+	assert.DeepEqual(t, chunks[1], coinbasepro.CandleRange{Start: candleEpoch.Add(300 * time.Minute), End: candleEpoch.Add(301 * time.Minute)})
+
+	_, err = coinbasepro.CandleChunks(candleEpoch, candleEpoch.Add(time.Hour), 120)
+	assert.Error(t, err, coinbasepro.InvalidGranularityErrorMessage)
+}
+
+func TestCandleUnmarshal(t *testing.T) {
+	var candles []coinbasepro.Candle
+	err := json.Unmarshal([]byte(`[[1614556800, 1.5, 3, 2, 2.5, 100.25]]`), &candles)
+	assert.Assert(t, is.Nil(err))
+	assert.DeepEqual(t, candles, []coinbasepro.Candle{{Time: candleEpoch, Low: 1.5, High: 3, Open: 2, Close: 2.5, Volume: 100.25}})
+
+	encoded, err := json.Marshal(candles[0])
+	assert.Assert(t, is.Nil(err))
+
+	var decoded coinbasepro.Candle
+	assert.Assert(t, is.Nil(json.Unmarshal(encoded, &decoded)))
+	assert.DeepEqual(t, decoded, candles[0])
+}
+
+func TestCandleDownloader(t *testing.T) {
+	t.Run("should chunk, deduplicate and fill missing buckets", func(t *testing.T) {
+		api := newCandleApi(t, 0)
+		downloader := coinbasepro.NewCandleDownloader(api)
+		downloader.SetRateLimiter(nil)
+		downloader.FillMissing = true
+
+		progress := make([]int, 0)
+		downloader.OnProgress = func(done, total int) {
+			progress = append(progress, done)
+			assert.Equal(t, total, 4)
+		}
+
+		candles := make([]coinbasepro.Candle, 0)
+		err := downloader.Download("BTC-USD", candleEpoch, candleEpoch.Add(1000*time.Minute), 60, func(chunk []coinbasepro.Candle) error {
+			candles = append(candles, chunk...)
+			return nil
+		})
+		assert.Assert(t, is.Nil(err))
+
+		assert.Equal(t, len(candles), 1000)
+		for i, candle := range candles {
+			assert.Equal(t, candle.Time, candleEpoch.Add(time.Duration(i)*time.Minute))
+		}
+		assert.DeepEqual(t, candles[500], coinbasepro.Candle{Time: candleEpoch.Add(500 * time.Minute), Low: 500, High: 500, Open: 500, Close: 500})
+		assert.DeepEqual(t, progress, []int{1, 2, 3, 4})
+		assert.Equal(t, api.CallCount("GetCandles"), 4)
+	})
+
+	t.Run("should include the bucket in progress and only fill closed buckets", func(t *testing.T) {
+		now := candleEpoch.Add(10*time.Minute + 30*time.Second)
+		api := newCandleApi(t, 0)
+		published := api.GetCandlesFunc
+		api.GetCandlesFunc = func(productId string, filter coinbasepro.CandleFilter) ([]coinbasepro.Candle, error) {
+			candles, err := published(productId, filter)
+			recent := make([]coinbasepro.Candle, 0)
+			for _, candle := range candles {
+				if !candle.Time.After(candleEpoch.Add(5 * time.Minute)) {
+					recent = append(recent, candle)
+				}
+			}
+			return recent, err
+		}
+
+		downloader := coinbasepro.NewCandleDownloader(api)
+		downloader.SetRateLimiter(nil)
+		downloader.SetClock(func() time.Time { return now })
+		downloader.FillMissing = true
+
+		candles := make([]coinbasepro.Candle, 0)
+		err := downloader.Download("BTC-USD", candleEpoch, now, 60, func(chunk []coinbasepro.Candle) error {
+			candles = append(candles, chunk...)
+			return nil
+		})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, api.Calls()[0].Args[1].(coinbasepro.CandleFilter).End, candleEpoch.Add(10*time.Minute))
+		assert.Equal(t, len(candles), 10)
+		assert.Equal(t, candles[9].Time, candleEpoch.Add(9*time.Minute))
+		assert.Equal(t, candles[9].Volume, 0.0)
+
+		api.GetCandlesFunc = published
+		candles = candles[:0]
+		err = downloader.Download("BTC-USD", candleEpoch, now, 60, func(chunk []coinbasepro.Candle) error {
+			candles = append(candles, chunk...)
+			return nil
+		})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(candles), 11)
+		assert.Equal(t, candles[10].Close, 11.0)
+	})
+
+	t.Run("should resume an interrupted csv download", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "candles.csv")
+
+		downloader := coinbasepro.NewCandleDownloader(newCandleApi(t, 2))
+		downloader.SetRateLimiter(nil)
+		written, err := downloader.DownloadToFile("BTC-USD", candleEpoch, candleEpoch.Add(1000*time.Minute), 60, path, coinbasepro.CandleFormatCSV)
+		assert.Error(t, err, "connection reset")
+		assert.Equal(t, written, 599)
+
+		api := newCandleApi(t, 0)
+		downloader = coinbasepro.NewCandleDownloader(api)
+		downloader.SetRateLimiter(nil)
+		written, err = downloader.DownloadToFile("BTC-USD", candleEpoch, candleEpoch.Add(1000*time.Minute), 60, path, coinbasepro.CandleFormatCSV)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, written, 400)
+
+		resumedFilter := api.Calls()[0].Args[1].(coinbasepro.CandleFilter)
+		assert.Equal(t, resumedFilter.Start, candleEpoch.Add(600*time.Minute))
+
+		candles, err := coinbasepro.ReadCandles(path, coinbasepro.CandleFormatCSV)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(candles), 999)
+		assert.DeepEqual(t, candles[0], coinbasepro.Candle{Time: candleEpoch, Low: 0, High: 2, Open: 0, Close: 1, Volume: 1})
+	})
+
+	t.Run("should drop a torn json lines record when resuming", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "candles.jsonl")
+		assert.Assert(t, is.Nil(ioutil.WriteFile(path, []byte(`{"time":"2021-03-01T00:00:00Z","low":0,"high":2,"open":0,"close":1,"volume":1}`+"\n"+`{"time":"2021-03-01T00:01:00Z","lo`), 0644)))
+
+		downloader := coinbasepro.NewCandleDownloader(newCandleApi(t, 0))
+		downloader.SetRateLimiter(nil)
+		written, err := downloader.DownloadToFile("BTC-USD", candleEpoch, candleEpoch.Add(10*time.Minute), 60, path, coinbasepro.CandleFormatJSONLines)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, written, 9)
+
+		candles, err := coinbasepro.ReadCandles(path, coinbasepro.CandleFormatJSONLines)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(candles), 10)
+	})
+
+	t.Run("should write parquet files", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "candles.parquet")
+
+		format, err := coinbasepro.CandleFormatFromPath(path)
+		assert.Assert(t, is.Nil(err))
+
+		downloader := coinbasepro.NewCandleDownloader(newCandleApi(t, 0))
+		downloader.SetRateLimiter(nil)
+		written, err := downloader.DownloadToFile("BTC-USD", candleEpoch, candleEpoch.Add(400*time.Minute), 60, path, format)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, written, 400)
+
+		contents, err := ioutil.ReadFile(path)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, string(contents[:4]), "PAR1")
+		assert.Equal(t, string(contents[len(contents)-4:]), "PAR1")
+
+		footerLength := int(binary.LittleEndian.Uint32(contents[len(contents)-8:]))
+		assert.Assert(t, footerLength > 0 && footerLength < len(contents)-12)
+		metadata, _ := readThriftStruct(contents[len(contents)-8-footerLength:])
+		assert.Equal(t, metadata[3], int64(400))
+
+		rowGroup := metadata[4].([]interface{})[0].(map[int16]interface{})
+		assert.Equal(t, rowGroup[3], int64(400))
+		columns := rowGroup[1].([]interface{})
+		assert.Equal(t, len(columns), 6)
+
+		// column 4 is close, the api closes every minute one above its index
+		columnMetadata := columns[4].(map[int16]interface{})[3].(map[int16]interface{})
+		assert.DeepEqual(t, columnMetadata[3], []interface{}{"close"})
+		offset := int(columnMetadata[9].(int64))
+		pageHeader, headerLength := readThriftStruct(contents[offset:])
+		assert.Equal(t, pageHeader[5].(map[int16]interface{})[1], int32(400))
+
+		values := contents[offset+headerLength:]
+		for _, row := range []int{0, 123, 399} {
+			assert.Equal(t, math.Float64frombits(binary.LittleEndian.Uint64(values[row*8:])), float64(row+1))
+		}
+
+		_, err = os.Stat(path + ".partial.jsonl")
+		assert.Assert(t, os.IsNotExist(err))
+	})
+}
+
+// readThriftStruct decodes a Thrift compact protocol struct into its fields by id, enough to read
+// back Parquet metadata. It returns the fields and the number of bytes read.
+func readThriftStruct(data []byte) (map[int16]interface{}, int) {
+	position := 0
+	varint := func() uint64 {
+		value, length := binary.Uvarint(data[position:])
+		position += length
+		return value
+	}
+	zigzag := func() int64 {
+		value := varint()
+		return int64(value>>1) ^ -int64(value&1)
+	}
+
+	var readValue func(fieldType byte) interface{}
+	readValue = func(fieldType byte) interface{} {
+		switch fieldType {
+		case 1, 2:
+			return fieldType == 1
+		case 3:
+			position++
+			return int8(data[position-1])
+		case 4, 5:
+			return int32(zigzag())
+		case 6:
+			return zigzag()
+		case 7:
+			position += 8
+			return math.Float64frombits(binary.LittleEndian.Uint64(data[position-8:]))
+		case 8:
+			length := int(varint())
+			position += length
+			return string(data[position-length : position])
+		case 9, 10:
+			header := data[position]
+			position++
+			size := int(header >> 4)
+			if size == 15 {
+				size = int(varint())
+			}
+			list := make([]interface{}, size)
+			for i := range list {
+				list[i] = readValue(header & 0x0f)
+			}
+			return list
+		case 12:
+			fields, length := readThriftStruct(data[position:])
+			position += length
+			return fields
+		}
+
+		panic(fmt.Sprintf("unsupported thrift type %d", fieldType))
+	}
+
+	fields := make(map[int16]interface{})
+	var lastId int16
+	for {
+		header := data[position]
+		position++
+		if header == 0 {
+			return fields, position
+		}
+
+		id := lastId + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(zigzag())
+		}
+		lastId = id
+		fields[id] = readValue(header & 0x0f)
+	}
+}
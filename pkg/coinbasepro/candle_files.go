@@ -0,0 +1,217 @@
+package coinbasepro
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CandleFormat string
+
+const CandleFormatCSV CandleFormat = "csv"
+const CandleFormatJSONLines CandleFormat = "jsonl"
+const CandleFormatParquet CandleFormat = "parquet"
+
+const UnsupportedCandleFormatErrorMessage = "unsupported candle format, use .csv, .jsonl or .parquet"
+
+var candleCsvHeader = []string{"time", "low", "high", "open", "close", "volume"}
+
+// CandleFormatFromPath picks the format from the file extension.
+func CandleFormatFromPath(path string) (CandleFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return CandleFormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return CandleFormatJSONLines, nil
+	case ".parquet":
+		return CandleFormatParquet, nil
+	default:
+		return "", errors.New(UnsupportedCandleFormatErrorMessage)
+	}
+}
+
+// CandleWriter writes candles in ascending time order. Close flushes, formats such as Parquet only
+// write anything once closed.
+type CandleWriter interface {
+	Write(candles []Candle) error
+	Close() error
+}
+
+type csvCandleWriter struct {
+	writer *csv.Writer
+	closer io.Closer
+}
+
+func NewCSVCandleWriter(writer io.WriteCloser, header bool) (CandleWriter, error) {
+	csvWriter := csv.NewWriter(writer)
+	if header {
+		if err := csvWriter.Write(candleCsvHeader); err != nil {
+			return nil, err
+		}
+	}
+
+	return &csvCandleWriter{writer: csvWriter, closer: writer}, nil
+}
+
+func formatCandleValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func (w *csvCandleWriter) Write(candles []Candle) error {
+	for _, candle := range candles {
+		err := w.writer.Write([]string{
+			candle.Time.UTC().Format(time.RFC3339),
+			formatCandleValue(candle.Low),
+			formatCandleValue(candle.High),
+			formatCandleValue(candle.Open),
+			formatCandleValue(candle.Close),
+			formatCandleValue(candle.Volume),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvCandleWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.closer.Close()
+		return err
+	}
+
+	return w.closer.Close()
+}
+
+type jsonLinesCandleWriter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+func NewJSONLinesCandleWriter(writer io.WriteCloser) CandleWriter {
+	buffered := bufio.NewWriter(writer)
+	return &jsonLinesCandleWriter{writer: buffered, encoder: json.NewEncoder(buffered), closer: writer}
+}
+
+func (w *jsonLinesCandleWriter) Write(candles []Candle) error {
+	for _, candle := range candles {
+		if err := w.encoder.Encode(candle); err != nil {
+			return err
+		}
+	}
+
+	return w.writer.Flush()
+}
+
+func (w *jsonLinesCandleWriter) Close() error {
+	if err := w.writer.Flush(); err != nil {
+		w.closer.Close()
+		return err
+	}
+
+	return w.closer.Close()
+}
+
+type parquetCandleWriter struct {
+	candles []Candle
+	writer  io.WriteCloser
+}
+
+// NewParquetCandleWriter buffers candles in memory and writes a single row group on Close.
+func NewParquetCandleWriter(writer io.WriteCloser) CandleWriter {
+	return &parquetCandleWriter{writer: writer}
+}
+
+func (w *parquetCandleWriter) Write(candles []Candle) error {
+	w.candles = append(w.candles, candles...)
+	return nil
+}
+
+func (w *parquetCandleWriter) Close() error {
+	if err := writeCandlesParquet(w.writer, w.candles); err != nil {
+		w.writer.Close()
+		return err
+	}
+
+	return w.writer.Close()
+}
+
+// ReadCandles reads a CSV or JSON Lines candle file written by a CandleWriter.
+func ReadCandles(path string, format CandleFormat) ([]Candle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	candles := make([]Candle, 0)
+	switch format {
+	case CandleFormatCSV:
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = len(candleCsvHeader)
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return candles, nil
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			if record[0] == candleCsvHeader[0] {
+				continue
+			}
+
+			candle, err := parseCandleRecord(record)
+			if err != nil {
+				return nil, err
+			}
+			candles = append(candles, candle)
+		}
+	case CandleFormatJSONLines:
+		decoder := json.NewDecoder(file)
+		for {
+			var candle Candle
+			err := decoder.Decode(&candle)
+			if err == io.EOF {
+				return candles, nil
+			}
+
+			if err != nil {
+				return nil, err
+			}
+			candles = append(candles, candle)
+		}
+	default:
+		return nil, fmt.Errorf("reading %s candle files is not supported", format)
+	}
+}
+
+func parseCandleRecord(record []string) (Candle, error) {
+	candleTime, err := time.Parse(time.RFC3339, record[0])
+	if err != nil {
+		return Candle{}, err
+	}
+
+	values := make([]float64, 5)
+	for i := range values {
+		if values[i], err = strconv.ParseFloat(record[i+1], 64); err != nil {
+			return Candle{}, err
+		}
+	}
+
+	return Candle{Time: candleTime, Low: values[0], High: values[1], Open: values[2], Close: values[3], Volume: values[4]}, nil
+}
@@ -0,0 +1,232 @@
+package coinbasepro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// A minimal Parquet writer for candles: one row group, one uncompressed PLAIN encoded data page per
+// column and all columns required, which keeps the format within reach of a few hundred lines of
+// stdlib code. Metadata is Thrift compact protocol as the format requires.
+
+const parquetMagic = "PAR1"
+
+const (
+	parquetTypeInt64  = 2
+	parquetTypeDouble = 5
+
+	parquetRepetitionRequired = 0
+	parquetConvertedTimestamp = 9
+	parquetEncodingPlain      = 0
+	parquetEncodingRle        = 3
+	parquetCodecUncompressed  = 0
+	parquetPageTypeData       = 0
+)
+
+const (
+	thriftTypeI32    = 5
+	thriftTypeI64    = 6
+	thriftTypeBinary = 8
+	thriftTypeList   = 9
+	thriftTypeStruct = 12
+)
+
+type thriftCompactWriter struct {
+	buffer     bytes.Buffer
+	lastFields []int16
+}
+
+func newThriftCompactWriter() *thriftCompactWriter {
+	return &thriftCompactWriter{lastFields: []int16{0}}
+}
+
+func (w *thriftCompactWriter) varint(value uint64) {
+	var encoded [binary.MaxVarintLen64]byte
+	length := binary.PutUvarint(encoded[:], value)
+	w.buffer.Write(encoded[:length])
+}
+
+func (w *thriftCompactWriter) zigzag(value int64) {
+	w.varint(uint64((value << 1) ^ (value >> 63)))
+}
+
+func (w *thriftCompactWriter) fieldHeader(id int16, fieldType byte) {
+	last := &w.lastFields[len(w.lastFields)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buffer.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		w.buffer.WriteByte(fieldType)
+		w.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftCompactWriter) i32(id int16, value int32) {
+	w.fieldHeader(id, thriftTypeI32)
+	w.zigzag(int64(value))
+}
+
+func (w *thriftCompactWriter) i64(id int16, value int64) {
+	w.fieldHeader(id, thriftTypeI64)
+	w.zigzag(value)
+}
+
+func (w *thriftCompactWriter) binary(id int16, value string) {
+	w.fieldHeader(id, thriftTypeBinary)
+	w.varint(uint64(len(value)))
+	w.buffer.WriteString(value)
+}
+
+func (w *thriftCompactWriter) listHeader(id int16, elementType byte, size int) {
+	w.fieldHeader(id, thriftTypeList)
+	if size < 15 {
+		w.buffer.WriteByte(byte(size)<<4 | elementType)
+	} else {
+		w.buffer.WriteByte(0xf0 | elementType)
+		w.varint(uint64(size))
+	}
+}
+
+func (w *thriftCompactWriter) i32List(id int16, values ...int32) {
+	w.listHeader(id, thriftTypeI32, len(values))
+	for _, value := range values {
+		w.zigzag(int64(value))
+	}
+}
+
+func (w *thriftCompactWriter) binaryList(id int16, values ...string) {
+	w.listHeader(id, thriftTypeBinary, len(values))
+	for _, value := range values {
+		w.varint(uint64(len(value)))
+		w.buffer.WriteString(value)
+	}
+}
+
+// beginStruct starts a struct field, or a list element when id is zero.
+func (w *thriftCompactWriter) beginStruct(id int16) {
+	if id != 0 {
+		w.fieldHeader(id, thriftTypeStruct)
+	}
+	w.lastFields = append(w.lastFields, 0)
+}
+
+func (w *thriftCompactWriter) endStruct() {
+	w.buffer.WriteByte(0)
+	w.lastFields = w.lastFields[:len(w.lastFields)-1]
+}
+
+type parquetColumn struct {
+	name          string
+	parquetType   int32
+	convertedType int32
+	values        func(candle Candle) uint64
+}
+
+var candleParquetColumns = []parquetColumn{
+	{name: "time", parquetType: parquetTypeInt64, convertedType: parquetConvertedTimestamp, values: func(c Candle) uint64 {
+		return uint64(c.Time.UnixNano() / 1e6)
+	}},
+	{name: "low", parquetType: parquetTypeDouble, values: func(c Candle) uint64 { return math.Float64bits(c.Low) }},
+	{name: "high", parquetType: parquetTypeDouble, values: func(c Candle) uint64 { return math.Float64bits(c.High) }},
+	{name: "open", parquetType: parquetTypeDouble, values: func(c Candle) uint64 { return math.Float64bits(c.Open) }},
+	{name: "close", parquetType: parquetTypeDouble, values: func(c Candle) uint64 { return math.Float64bits(c.Close) }},
+	{name: "volume", parquetType: parquetTypeDouble, values: func(c Candle) uint64 { return math.Float64bits(c.Volume) }},
+}
+
+type parquetColumnChunk struct {
+	offset int64
+	size   int64
+}
+
+func writeCandlesParquet(writer io.Writer, candles []Candle) error {
+	output := bytes.Buffer{}
+	output.WriteString(parquetMagic)
+
+	chunks := make([]parquetColumnChunk, 0, len(candleParquetColumns))
+	for _, column := range candleParquetColumns {
+		page := make([]byte, 8*len(candles))
+		for i, candle := range candles {
+			binary.LittleEndian.PutUint64(page[i*8:], column.values(candle))
+		}
+
+		header := newThriftCompactWriter()
+		header.i32(1, parquetPageTypeData)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.beginStruct(5)
+		header.i32(1, int32(len(candles)))
+		header.i32(2, parquetEncodingPlain)
+		header.i32(3, parquetEncodingRle)
+		header.i32(4, parquetEncodingRle)
+		header.endStruct()
+		header.endStruct()
+
+		offset := int64(output.Len())
+		output.Write(header.buffer.Bytes())
+		output.Write(page)
+		chunks = append(chunks, parquetColumnChunk{offset: offset, size: int64(output.Len()) - offset})
+	}
+
+	footer := newThriftCompactWriter()
+	footer.i32(1, 1)
+
+	footer.listHeader(2, thriftTypeStruct, len(candleParquetColumns)+1)
+	footer.beginStruct(0)
+	footer.binary(4, "candle")
+	footer.i32(5, int32(len(candleParquetColumns)))
+	footer.endStruct()
+	for _, column := range candleParquetColumns {
+		footer.beginStruct(0)
+		footer.i32(1, column.parquetType)
+		footer.i32(3, parquetRepetitionRequired)
+		footer.binary(4, column.name)
+		if column.convertedType != 0 {
+			footer.i32(6, column.convertedType)
+		}
+		footer.endStruct()
+	}
+
+	footer.i64(3, int64(len(candles)))
+
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.size
+	}
+
+	footer.listHeader(4, thriftTypeStruct, 1)
+	footer.beginStruct(0)
+	footer.listHeader(1, thriftTypeStruct, len(chunks))
+	for i, column := range candleParquetColumns {
+		footer.beginStruct(0)
+		footer.i64(2, chunks[i].offset)
+		footer.beginStruct(3)
+		footer.i32(1, column.parquetType)
+		footer.i32List(2, parquetEncodingPlain, parquetEncodingRle)
+		footer.binaryList(3, column.name)
+		footer.i32(4, parquetCodecUncompressed)
+		footer.i64(5, int64(len(candles)))
+		footer.i64(6, chunks[i].size)
+		footer.i64(7, chunks[i].size)
+		footer.i64(9, chunks[i].offset)
+		footer.endStruct()
+		footer.endStruct()
+	}
+	footer.i64(2, totalSize)
+	footer.i64(3, int64(len(candles)))
+	footer.endStruct()
+
+	footer.binary(6, "coinbasepro-trader")
+	footer.endStruct()
+
+	output.Write(footer.buffer.Bytes())
+
+	var footerLength [4]byte
+	binary.LittleEndian.PutUint32(footerLength[:], uint32(footer.buffer.Len()))
+	output.Write(footerLength[:])
+	output.WriteString(parquetMagic)
+
+	_, err := writer.Write(output.Bytes())
+	return err
+}
@@ -0,0 +1,109 @@
+package coinbasepro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const InvalidGranularityErrorMessage = "granularity must be one of 60, 300, 900, 3600, 21600 or 86400 seconds"
+
+// MaxCandlesPerRequest is the most buckets the candles endpoint returns for one request.
+const MaxCandlesPerRequest = 300
+
+var CandleGranularities = []int{60, 300, 900, 3600, 21600, 86400}
+
+func ValidGranularity(granularity int) bool {
+	for _, valid := range CandleGranularities {
+		if granularity == valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Candle is an OHLCV bar starting at Time. It is shared by the downloader, aggregators, indicators
+// and the backtester.
+type Candle struct {
+	Time   time.Time `json:"time"`
+	Low    float64   `json:"low"`
+	High   float64   `json:"high"`
+	Open   float64   `json:"open"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
+
+// UnmarshalJSON accepts both the exchange format, an array of [time, low, high, open, close,
+// volume], and the object format Candle marshals to.
+func (c *Candle) UnmarshalJSON(data []byte) error {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		type plainCandle Candle
+		return json.Unmarshal(data, (*plainCandle)(c))
+	}
+
+	var fields []float64
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	if len(fields) < 6 {
+		return fmt.Errorf("malformed candle %s", data)
+	}
+
+	c.Time = time.Unix(int64(fields[0]), 0).UTC()
+	c.Low = fields[1]
+	c.High = fields[2]
+	c.Open = fields[3]
+	c.Close = fields[4]
+	c.Volume = fields[5]
+
+	return nil
+}
+
+type CandleFilter struct {
+	Start       time.Time
+	End         time.Time
+	Granularity int
+}
+
+func (f CandleFilter) query() url.Values {
+	query := url.Values{}
+	if !f.Start.IsZero() {
+		query.Set("start", f.Start.UTC().Format(time.RFC3339))
+	}
+
+	if !f.End.IsZero() {
+		query.Set("end", f.End.UTC().Format(time.RFC3339))
+	}
+
+	if f.Granularity != 0 {
+		query.Set("granularity", strconv.Itoa(f.Granularity))
+	}
+
+	return query
+}
+
+// GetCandles returns up to MaxCandlesPerRequest candles, newest first as the exchange sends them.
+func (t *Client) GetCandles(productId string, filter CandleFilter) ([]Candle, error) {
+	if filter.Granularity != 0 && !ValidGranularity(filter.Granularity) {
+		return nil, errors.New(InvalidGranularityErrorMessage)
+	}
+
+	requestPath := fmt.Sprintf("/products/%s/candles", productId)
+	if query := filter.query().Encode(); query != "" {
+		requestPath += "?" + query
+	}
+
+	var candles []Candle
+	_, err := t.executeRequest("GET", requestPath, nil, &candles, defaultMaxRetriesOn429)
+	if err != nil {
+		return nil, err
+	}
+
+	return candles, nil
+}
@@ -6,8 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -15,10 +15,16 @@ func createTimestamp() string {
 	return strconv.FormatInt(time.Now().Unix(), 10)
 }
 
-const coinbaseProBaseurlKey = "COINBASE_PRO_BASEURL"
-const coinbaseProKeyKey = "COINBASE_PRO_KEY"
-const coinbaseProPassphraseKey = "COINBASE_PRO_PASSPHRASE"
-const coinbaseProSecretKey = "COINBASE_PRO_SECRET"
+const coinbaseProEnvPrefix = "COINBASE_PRO_"
+const baseurlEnvSuffix = "BASEURL"
+const keyEnvSuffix = "KEY"
+const passphraseEnvSuffix = "PASSPHRASE"
+const secretEnvSuffix = "SECRET"
+
+const coinbaseProBaseurlKey = coinbaseProEnvPrefix + baseurlEnvSuffix
+const coinbaseProKeyKey = coinbaseProEnvPrefix + keyEnvSuffix
+const coinbaseProPassphraseKey = coinbaseProEnvPrefix + passphraseEnvSuffix
+const coinbaseProSecretKey = coinbaseProEnvPrefix + secretEnvSuffix
 
 const coinbaseProAccessKeyHeader = "CB-ACCESS-KEY"
 const coinbaseProAccessSignatureHeader = "CB-ACCESS-SIGN"
@@ -33,6 +39,8 @@ const contentTypeHeaderValue = "Content-Type"
 const UnsupportedHttpMethodErrorMessage = "supplied an unsupported or invalid http method"
 
 const waitTimeOn429 = 300 * time.Millisecond
+const defaultMaxRetriesOn429 = 3
+const defaultHttpTimeout = 10 * time.Second
 
 var allowedHttpMethods = map[string]bool{ "GET":true, "POST":true, "DELETE":true }
 
@@ -42,31 +50,17 @@ type Client struct {
 	passphrase string
 	secret string
 	httpClient *http.Client
+	rateLimiter *RateLimiter
+	interceptorsMutex sync.RWMutex
+	interceptors []Interceptor
 }
 
 func NewClient() (*Client, error) {
-	baseUrl := os.Getenv(coinbaseProBaseurlKey)
-	key := os.Getenv(coinbaseProKeyKey)
-	passphrase := os.Getenv(coinbaseProPassphraseKey)
-	secret := os.Getenv(coinbaseProSecretKey)
-
-	if baseUrl == "" {
-		return nil, errors.New("missing COINBASE_PRO_BASEURL")
-	}
-
-	if key == "" {
-		return nil, errors.New("missing COINBASE_PRO_KEY")
-	}
-
-	if passphrase == "" {
-		return nil, errors.New("missing COINBASE_PRO_PASSPHRASE")
-	}
-
-	if secret == "" {
-		return nil, errors.New("missing COINBASE_PRO_SECRET")
-	}
+	return newClientFromEnv(coinbaseProEnvPrefix)
+}
 
-	return NewClientWithOptions(baseUrl, key, passphrase, secret)
+func newClientFromEnv(prefix string) (*Client, error) {
+	return NewClientWithCredentialProvider(NewEnvCredentialProvider(prefix))
 }
 
 func NewClientWithOptions(baseUrl, key, passphrase, secret string) (*Client, error) {
@@ -76,13 +70,18 @@ func NewClientWithOptions(baseUrl, key, passphrase, secret string) (*Client, err
 		passphrase: passphrase,
 		secret: secret,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: defaultHttpTimeout,
 		},
+		rateLimiter: newDefaultRateLimiter(),
 	}
 
 	return &client, nil
 }
 
+func (t *Client) SetRateLimiter(rateLimiter *RateLimiter) {
+	t.rateLimiter = rateLimiter
+}
+
 func allowedHttpMethod(httpMethod string) bool {
 	_, found := allowedHttpMethods[httpMethod]
 	return found
@@ -129,19 +128,46 @@ func (t *Client) buildRequest(httpMethod, requestPath string, requestData interf
 }
 
 func (t *Client) sendRequest(req *http.Request, maxRetriesOn429 int) (res *http.Response, err error) {
+	return t.sendCall(&Call{}, req, maxRetriesOn429)
+}
+
+func (t *Client) sendCall(call *Call, req *http.Request, maxRetriesOn429 int) (res *http.Response, err error) {
 	if maxRetriesOn429 < 1 {
 		maxRetriesOn429 = 1
 	}
 
 	for tries := 0; tries < maxRetriesOn429; tries++ {
+		if tries > 0 {
+			call.Retries++
+
+			if req.GetBody != nil {
+				req.Body, err = req.GetBody()
+				if err != nil {
+					break
+				}
+			}
+		}
+
+		if t.rateLimiter != nil {
+			call.RateLimitWait += t.rateLimiter.Wait()
+		}
+
 		res, err = t.httpClient.Do(req)
 		if err != nil {
 			break
 		}
 
+		call.StatusCode = res.StatusCode
+		call.ResponseHeader = res.Header
+
 		if res.StatusCode == http.StatusTooManyRequests {
-			time.Sleep(waitTimeOn429)
-			continue
+			call.Hits429++
+
+			if tries < maxRetriesOn429-1 {
+				res.Body.Close()
+				time.Sleep(waitTimeOn429)
+				continue
+			}
 		}
 
 		break
@@ -178,20 +204,42 @@ func (t *Client) parseJsonResponse(res *http.Response, result interface{}) (inte
 }
 
 func (t *Client) executeRequest(httpMethod, requestPath string, requestBody interface{}, responseBody interface{}, maxRetiresOn429 int) (interface{}, error) {
-	req, err := t.buildRequest(httpMethod, requestPath, requestBody)
+	return t.executeCall(newCall(httpMethod, requestPath, requestBody), responseBody, maxRetiresOn429)
+}
+
+func (t *Client) executeCall(call *Call, responseBody interface{}, maxRetiresOn429 int) (interface{}, error) {
+	interceptors := t.interceptorChain()
+
+	for _, interceptor := range interceptors {
+		if err := interceptor.BeforeSign(call); err != nil {
+			return nil, t.finishCall(call, interceptors, err)
+		}
+	}
+
+	req, err := t.buildRequest(call.Method, call.Path, call.Body)
 	if err != nil {
-		return nil, err
+		return nil, t.finishCall(call, interceptors, err)
+	}
+
+	call.Request = req
+	for _, interceptor := range interceptors {
+		if err := interceptor.AfterSign(call); err != nil {
+			return nil, t.finishCall(call, interceptors, err)
+		}
 	}
 
-	res, err := t.sendRequest(req, maxRetiresOn429)
+	res, err := t.sendCall(call, req, maxRetiresOn429)
 	if err != nil {
-		return nil, err
+		return nil, t.finishCall(call, interceptors, err)
 	}
 
 	parsedResponse, err := t.parseJsonResponse(res, responseBody)
 	if err != nil {
-		return nil, err
+		return nil, t.finishCall(call, interceptors, err)
 	}
 
+	call.Response = parsedResponse
+	t.finishCall(call, interceptors, nil)
+
 	return parsedResponse, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,295 @@
+package coinbasepro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const coinbaseProAccountsKey = "COINBASE_PRO_ACCOUNTS"
+const profileIdEnvSuffix = "PROFILE_ID"
+
+const ClientNotFoundErrorMessage = "no client registered for alias"
+const DuplicateClientAliasErrorMessage = "a client is already registered for alias"
+const MissingClientAliasErrorMessage = "client alias is required"
+
+type AccountConfig struct {
+	Alias               string  `json:"alias"`
+	ProfileId           string  `json:"profile_id,omitempty"`
+	BaseUrl             string  `json:"base_url"`
+	Key                 string  `json:"key"`
+	Passphrase          string  `json:"passphrase"`
+	Secret              string  `json:"secret"`
+	SecretsDir          string  `json:"secrets_dir,omitempty"`
+	SecretsPrefix       string  `json:"secrets_prefix,omitempty"`
+	Keystore            string  `json:"keystore,omitempty"`
+	KeystorePasswordEnv string  `json:"keystore_password_env,omitempty"`
+	RequestsPerSecond   float64 `json:"requests_per_second,omitempty"`
+	Burst               int     `json:"burst,omitempty"`
+}
+
+type clientPoolConfig struct {
+	Accounts []AccountConfig `json:"accounts"`
+}
+
+type clientPoolEntry struct {
+	profileId string
+	client    *Client
+}
+
+// ClientPool holds one Client per account alias. Every client has its own credentials and
+// rate limiter while sharing a single http.Transport and therefore its connection pool.
+type ClientPool struct {
+	mutex     sync.RWMutex
+	transport http.RoundTripper
+	entries   map[string]clientPoolEntry
+}
+
+func NewClientPool() *ClientPool {
+	return NewClientPoolWithTransport(http.DefaultTransport.(*http.Transport).Clone())
+}
+
+func NewClientPoolWithTransport(transport http.RoundTripper) *ClientPool {
+	return &ClientPool{
+		transport: transport,
+		entries:   make(map[string]clientPoolEntry),
+	}
+}
+
+// LoadClientPoolFromEnv builds a pool from COINBASE_PRO_<ALIAS>_* variables. When no aliases are
+// given they are read from the comma separated COINBASE_PRO_ACCOUNTS variable.
+func LoadClientPoolFromEnv(aliases ...string) (*ClientPool, error) {
+	if len(aliases) == 0 {
+		aliases = splitAliases(os.Getenv(coinbaseProAccountsKey))
+	}
+
+	if len(aliases) == 0 {
+		return nil, fmt.Errorf("missing %s", coinbaseProAccountsKey)
+	}
+
+	pool := NewClientPool()
+	for _, alias := range aliases {
+		prefix := aliasEnvPrefix(alias)
+		client, err := newClientFromEnv(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := pool.register(alias, os.Getenv(prefix+profileIdEnvSuffix), client); err != nil {
+			return nil, err
+		}
+	}
+
+	return pool, nil
+}
+
+func LoadClientPoolFromFile(path string) (*ClientPool, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config clientPoolConfig
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return nil, fmt.Errorf("invalid client pool config %s: %w", path, err)
+	}
+
+	pool := NewClientPool()
+	for _, account := range config.Accounts {
+		if _, err := pool.Add(account); err != nil {
+			return nil, err
+		}
+	}
+
+	return pool, nil
+}
+
+func splitAliases(value string) []string {
+	aliases := make([]string, 0)
+	for _, alias := range strings.Split(value, ",") {
+		alias = strings.TrimSpace(alias)
+		if alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return aliases
+}
+
+func aliasEnvPrefix(alias string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_", " ", "_").Replace(alias))
+	return fmt.Sprintf("%s%s_", coinbaseProEnvPrefix, normalized)
+}
+
+func (a AccountConfig) String() string {
+	return fmt.Sprintf("AccountConfig{Alias: %s, ProfileId: %s, BaseUrl: %s, Key: %s, Passphrase: %s, Secret: %s}", a.Alias, a.ProfileId, a.BaseUrl, redactKey(a.Key), redact(a.Passphrase), redact(a.Secret))
+}
+
+func (a AccountConfig) GoString() string {
+	return a.String()
+}
+
+// credentialProvider picks where the account's secrets come from: a secrets directory, an encrypted
+// keystore whose password is read from the environment variable named by KeystorePasswordEnv, or the
+// inline values.
+func (a AccountConfig) credentialProvider() (CredentialProvider, error) {
+	if a.SecretsDir != "" {
+		return NewFileCredentialProvider(a.SecretsDir, a.SecretsPrefix), nil
+	}
+
+	if a.Keystore != "" {
+		return NewKeystoreCredentialProvider(a.Keystore, os.Getenv(a.KeystorePasswordEnv), a.Alias), nil
+	}
+
+	if a.BaseUrl == "" {
+		return nil, fmt.Errorf("missing base_url for %s", a.Alias)
+	}
+
+	if a.Key == "" {
+		return nil, fmt.Errorf("missing key for %s", a.Alias)
+	}
+
+	if a.Passphrase == "" {
+		return nil, fmt.Errorf("missing passphrase for %s", a.Alias)
+	}
+
+	if a.Secret == "" {
+		return nil, fmt.Errorf("missing secret for %s", a.Alias)
+	}
+
+	return staticCredentialProvider{Credentials{BaseUrl: a.BaseUrl, Key: a.Key, Passphrase: a.Passphrase, Secret: a.Secret}}, nil
+}
+
+type staticCredentialProvider struct {
+	credentials Credentials
+}
+
+func (p staticCredentialProvider) Credentials() (Credentials, error) {
+	return p.credentials, nil
+}
+
+func (t *ClientPool) Add(account AccountConfig) (*Client, error) {
+	provider, err := account.credentialProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewClientWithCredentialProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.RequestsPerSecond > 0 || account.Burst > 0 {
+		client.SetRateLimiter(NewRateLimiter(account.RequestsPerSecond, account.Burst))
+	}
+
+	if err := t.register(account.Alias, account.ProfileId, client); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (t *ClientPool) AddWithProvider(alias, profileId string, provider CredentialProvider) (*Client, error) {
+	client, err := NewClientWithCredentialProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.register(alias, profileId, client); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (t *ClientPool) register(alias, profileId string, client *Client) error {
+	if alias == "" {
+		return errors.New(MissingClientAliasErrorMessage)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, found := t.entries[alias]; found {
+		return fmt.Errorf("%s: %s", DuplicateClientAliasErrorMessage, alias)
+	}
+
+	client.httpClient = &http.Client{
+		Transport: t.transport,
+		Timeout:   client.httpClient.Timeout,
+	}
+
+	t.entries[alias] = clientPoolEntry{
+		profileId: profileId,
+		client:    client,
+	}
+
+	return nil
+}
+
+func (t *ClientPool) Get(alias string) (*Client, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	entry, found := t.entries[alias]
+	if !found {
+		return nil, fmt.Errorf("%s: %s", ClientNotFoundErrorMessage, alias)
+	}
+
+	return entry.client, nil
+}
+
+func (t *ClientPool) ProfileId(alias string) (string, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	entry, found := t.entries[alias]
+	if !found {
+		return "", fmt.Errorf("%s: %s", ClientNotFoundErrorMessage, alias)
+	}
+
+	return entry.profileId, nil
+}
+
+func (t *ClientPool) Remove(alias string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.entries, alias)
+}
+
+func (t *ClientPool) Aliases() []string {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	aliases := make([]string, 0, len(t.entries))
+	for alias := range t.entries {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	return aliases
+}
+
+// Each calls fn for every registered client in alias order, stopping at the first error.
+func (t *ClientPool) Each(fn func(alias string, client *Client) error) error {
+	for _, alias := range t.Aliases() {
+		client, err := t.Get(alias)
+		if err != nil {
+			continue
+		}
+
+		if err := fn(alias, client); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
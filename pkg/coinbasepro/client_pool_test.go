@@ -0,0 +1,163 @@
+package coinbasepro
+
+import (
+	"encoding/json"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testAccounts = []AccountConfig{
+	{Alias: "alpha", ProfileId: "profile-a", BaseUrl: testBaseUrl, Key: "alphaKey", Passphrase: "alphaPassphrase", Secret: testSecret},
+	{Alias: "beta", ProfileId: "profile-b", BaseUrl: testBaseUrl, Key: "betaKey", Passphrase: "betaPassphrase", Secret: testSecret, RequestsPerSecond: 2, Burst: 1},
+}
+
+func setAccountEnvVars(t *testing.T, accounts []AccountConfig) {
+	for _, account := range accounts {
+		prefix := aliasEnvPrefix(account.Alias)
+		values := map[string]string{
+			prefix + baseurlEnvSuffix:    account.BaseUrl,
+			prefix + keyEnvSuffix:        account.Key,
+			prefix + passphraseEnvSuffix: account.Passphrase,
+			prefix + secretEnvSuffix:     account.Secret,
+			prefix + profileIdEnvSuffix:  account.ProfileId,
+		}
+
+		for key, value := range values {
+			os.Setenv(key, value)
+			key := key
+			t.Cleanup(func() { os.Unsetenv(key) })
+		}
+	}
+}
+
+func TestLoadClientPoolFromEnv(t *testing.T) {
+	t.Run("should create a client per alias with its own credentials", func(t *testing.T) {
+		setAccountEnvVars(t, testAccounts)
+
+		pool, err := LoadClientPoolFromEnv("alpha", "beta")
+		assert.Assert(t, is.Nil(err), "unexpected error loading client pool", err)
+
+		assert.DeepEqual(t, pool.Aliases(), []string{"alpha", "beta"})
+
+		alpha, err := pool.Get("alpha")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, alpha.key, "alphaKey")
+
+		beta, err := pool.Get("beta")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, beta.key, "betaKey")
+
+		profileId, err := pool.ProfileId("beta")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, profileId, "profile-b")
+	})
+
+	t.Run("should read aliases from COINBASE_PRO_ACCOUNTS", func(t *testing.T) {
+		setAccountEnvVars(t, testAccounts)
+		os.Setenv(coinbaseProAccountsKey, "alpha, beta")
+		defer os.Unsetenv(coinbaseProAccountsKey)
+
+		pool, err := LoadClientPoolFromEnv()
+		assert.Assert(t, is.Nil(err), "unexpected error loading client pool", err)
+		assert.DeepEqual(t, pool.Aliases(), []string{"alpha", "beta"})
+	})
+
+	t.Run("should error when an alias is missing credentials", func(t *testing.T) {
+		setAccountEnvVars(t, testAccounts[:1])
+
+		_, err := LoadClientPoolFromEnv("alpha", "gamma")
+		assert.Error(t, err, "missing COINBASE_PRO_GAMMA_BASEURL")
+	})
+
+	t.Run("should error when no aliases are configured", func(t *testing.T) {
+		os.Unsetenv(coinbaseProAccountsKey)
+
+		_, err := LoadClientPoolFromEnv()
+		assert.Error(t, err, "missing COINBASE_PRO_ACCOUNTS")
+	})
+}
+
+func TestLoadClientPoolFromFile(t *testing.T) {
+	contents, err := json.Marshal(clientPoolConfig{Accounts: testAccounts})
+	assert.Assert(t, is.Nil(err))
+
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	assert.Assert(t, is.Nil(ioutil.WriteFile(path, contents, 0600)))
+
+	pool, err := LoadClientPoolFromFile(path)
+	assert.Assert(t, is.Nil(err), "unexpected error loading client pool", err)
+
+	beta, err := pool.Get("beta")
+	assert.Assert(t, is.Nil(err))
+	assert.Equal(t, beta.passphrase, "betaPassphrase")
+	assert.Equal(t, beta.rateLimiter.requestsPerSecond, float64(2))
+}
+
+func TestClientPool(t *testing.T) {
+	t.Run("should share one transport across clients", func(t *testing.T) {
+		pool := NewClientPool()
+		for _, account := range testAccounts {
+			_, err := pool.Add(account)
+			assert.Assert(t, is.Nil(err))
+		}
+
+		alpha, _ := pool.Get("alpha")
+		beta, _ := pool.Get("beta")
+		assert.Assert(t, alpha.httpClient != beta.httpClient)
+		assert.Assert(t, alpha.httpClient.Transport == beta.httpClient.Transport)
+		assert.Assert(t, alpha.rateLimiter != beta.rateLimiter)
+	})
+
+	t.Run("should reject duplicate and unknown aliases", func(t *testing.T) {
+		pool := NewClientPool()
+		_, err := pool.Add(testAccounts[0])
+		assert.Assert(t, is.Nil(err))
+
+		_, err = pool.Add(testAccounts[0])
+		assert.Error(t, err, DuplicateClientAliasErrorMessage+": alpha")
+
+		_, err = pool.Get("gamma")
+		assert.Error(t, err, ClientNotFoundErrorMessage+": gamma")
+
+		pool.Remove("alpha")
+		_, err = pool.Get("alpha")
+		assert.Error(t, err, ClientNotFoundErrorMessage+": alpha")
+	})
+
+	t.Run("should apply each client's own rate limit budget", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		pool := NewClientPool()
+		slow := AccountConfig{Alias: "slow", BaseUrl: ts.URL, Key: testKey, Passphrase: testPassphrase, Secret: testSecret, RequestsPerSecond: 20, Burst: 1}
+		fast := AccountConfig{Alias: "fast", BaseUrl: ts.URL, Key: testKey, Passphrase: testPassphrase, Secret: testSecret}
+
+		slowClient, err := pool.Add(slow)
+		assert.Assert(t, is.Nil(err))
+		fastClient, err := pool.Add(fast)
+		assert.Assert(t, is.Nil(err))
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := fastClient.executeRequest("GET", "/test", nil, nil, 0)
+			assert.Assert(t, is.Nil(err))
+		}
+		assert.Assert(t, time.Since(start) < 50*time.Millisecond)
+
+		start = time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := slowClient.executeRequest("GET", "/test", nil, nil, 0)
+			assert.Assert(t, is.Nil(err))
+		}
+		assert.Assert(t, time.Since(start) >= 90*time.Millisecond)
+	})
+}
@@ -129,7 +129,7 @@ func TestBuildRequest(t *testing.T) {
 		assert.Assert(t, is.Nil(err), "unexpected error from client.buildRequest", err)
 
 		decoder := json.NewDecoder(req.Body)
-		err = decoder.Decode(nil)
+		err = decoder.Decode(&struct{}{})
 		assert.Error(t, err, "EOF", "expected EOF that represents an empty http request body", err)
 
 
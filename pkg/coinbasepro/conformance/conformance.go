@@ -0,0 +1,307 @@
+// Package conformance holds tests that every implementation of the coinbasepro api interfaces is
+// expected to pass, so fakes, the paper client and the real client all behave alike.
+package conformance
+
+import (
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"net/http"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type TradingAPIConfig struct {
+	New       func(t *testing.T) coinbasepro.TradingAPI
+	ProductId string
+	// RestingPrice and RestingSize describe a limit buy that rests on the book without filling.
+	RestingPrice string
+	RestingSize  string
+}
+
+func (c TradingAPIConfig) restingOrder() coinbasepro.Order {
+	return coinbasepro.Order{
+		ClientOid: coinbasepro.NewClientOid(""),
+		Type:      coinbasepro.OrderTypeLimit,
+		Side:      coinbasepro.OrderSideBuy,
+		ProductId: c.ProductId,
+		Price:     c.RestingPrice,
+		Size:      c.RestingSize,
+	}
+}
+
+func isOpen(order coinbasepro.Order) bool {
+	return order.Status == "pending" || order.Status == "open" || order.Status == "active"
+}
+
+func assertNotFound(t *testing.T, err error) {
+	t.Helper()
+
+	var apiError coinbasepro.ApiError
+	assert.Assert(t, errors.As(err, &apiError), "expected an ApiError, got %v", err)
+	assert.Equal(t, apiError.StatusCode, http.StatusNotFound)
+}
+
+func containsOrder(orders []coinbasepro.Order, orderId string) bool {
+	for _, order := range orders {
+		if order.Id == orderId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RunTradingAPI checks the order lifecycle every TradingAPI has to follow.
+func RunTradingAPI(t *testing.T, config TradingAPIConfig) {
+	t.Run("places resting orders that can be looked up", func(t *testing.T) {
+		api := config.New(t)
+		request := config.restingOrder()
+
+		placed, err := api.PlaceOrder(request)
+		assert.Assert(t, is.Nil(err), "unexpected error placing order", err)
+		assert.Assert(t, placed.Id != "")
+		assert.Equal(t, placed.ClientOid, request.ClientOid)
+		assert.Equal(t, placed.ProductId, config.ProductId)
+		assert.Assert(t, isOpen(placed), placed.Status)
+
+		byId, err := api.GetOrder(placed.Id)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, byId.Id, placed.Id)
+
+		byClientOid, err := api.GetOrderByClientOid(request.ClientOid)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, byClientOid.Id, placed.Id)
+
+		open, err := api.ListOrders(coinbasepro.OrderFilter{ProductId: config.ProductId})
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, containsOrder(open, placed.Id))
+	})
+
+	t.Run("cancels orders by id and client_oid", func(t *testing.T) {
+		api := config.New(t)
+
+		byId, err := api.PlaceOrder(config.restingOrder())
+		assert.Assert(t, is.Nil(err))
+		byClientOid, err := api.PlaceOrder(config.restingOrder())
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, is.Nil(api.CancelOrder(byId.Id)))
+		assert.Assert(t, is.Nil(api.CancelOrderByClientOid(byClientOid.ClientOid)))
+
+		for _, orderId := range []string{byId.Id, byClientOid.Id} {
+			cancelled, err := api.GetOrder(orderId)
+			if err != nil {
+				assertNotFound(t, err)
+			} else {
+				assert.Equal(t, cancelled.Status, "done")
+			}
+		}
+
+		open, err := api.ListOrders(coinbasepro.OrderFilter{ProductId: config.ProductId})
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, !containsOrder(open, byId.Id))
+		assert.Assert(t, !containsOrder(open, byClientOid.Id))
+
+		assertNotFound(t, api.CancelOrder(byId.Id))
+	})
+
+	t.Run("unknown orders are not found", func(t *testing.T) {
+		api := config.New(t)
+
+		_, err := api.GetOrder("ffffffff-ffff-4fff-8fff-ffffffffffff")
+		assertNotFound(t, err)
+
+		_, err = api.GetOrderByClientOid(coinbasepro.NewClientOid(""))
+		assertNotFound(t, err)
+	})
+
+	t.Run("fills require a filter", func(t *testing.T) {
+		api := config.New(t)
+
+		_, err := api.GetFills(coinbasepro.FillFilter{})
+		assert.Error(t, err, coinbasepro.MissingFillFilterErrorMessage)
+
+		fills, err := api.GetFills(coinbasepro.FillFilter{ProductId: config.ProductId})
+		assert.Assert(t, is.Nil(err))
+		for _, fill := range fills {
+			assert.Equal(t, fill.ProductId, config.ProductId)
+		}
+	})
+}
+
+// RunAccountsAPI checks that accounts can be listed and fetched individually.
+func RunAccountsAPI(t *testing.T, newAPI func(t *testing.T) coinbasepro.AccountsAPI) {
+	t.Run("lists accounts that can be fetched by id", func(t *testing.T) {
+		api := newAPI(t)
+
+		accounts, err := api.GetAccounts()
+		assert.Assert(t, is.Nil(err), "unexpected error listing accounts", err)
+		assert.Assert(t, len(accounts) > 0, "conformance needs at least one account")
+
+		for _, account := range accounts {
+			fetched, err := api.GetAccount(account.Id)
+			assert.Assert(t, is.Nil(err))
+			assert.Equal(t, fetched.Currency, account.Currency)
+			assert.Equal(t, fetched.Balance, account.Balance)
+		}
+	})
+
+	t.Run("unknown accounts are not found", func(t *testing.T) {
+		_, err := newAPI(t).GetAccount("ffffffff-ffff-4fff-8fff-ffffffffffff")
+		assertNotFound(t, err)
+	})
+}
+
+type MarketDataAPIConfig struct {
+	New       func(t *testing.T) coinbasepro.MarketDataAPI
+	ProductId string
+	// Candles is a range the product has candles for.
+	Candles coinbasepro.CandleFilter
+}
+
+func parsePrices(t *testing.T, entries []coinbasepro.BookEntry) []float64 {
+	t.Helper()
+
+	prices := make([]float64, 0, len(entries))
+	for _, entry := range entries {
+		price, err := strconv.ParseFloat(entry.Price, 64)
+		assert.Assert(t, is.Nil(err), "unparsable book price %q", entry.Price)
+		prices = append(prices, price)
+	}
+
+	return prices
+}
+
+// RunMarketDataAPI checks the public market data every MarketDataAPI has to serve for a product.
+func RunMarketDataAPI(t *testing.T, config MarketDataAPIConfig) {
+	t.Run("lists products that can be fetched by id", func(t *testing.T) {
+		api := config.New(t)
+
+		products, err := api.GetProducts()
+		assert.Assert(t, is.Nil(err), "unexpected error listing products", err)
+
+		found := false
+		for _, product := range products {
+			fetched, err := api.GetProduct(product.Id)
+			assert.Assert(t, is.Nil(err))
+			assert.Equal(t, fetched.BaseCurrency, product.BaseCurrency)
+			assert.Equal(t, fetched.QuoteCurrency, product.QuoteCurrency)
+			found = found || product.Id == config.ProductId
+		}
+		assert.Assert(t, found, "%s is not listed", config.ProductId)
+	})
+
+	t.Run("unknown products are not found", func(t *testing.T) {
+		_, err := config.New(t).GetProduct("XXX-YYY")
+		assertNotFound(t, err)
+	})
+
+	t.Run("books are sorted best price first", func(t *testing.T) {
+		api := config.New(t)
+
+		for _, level := range []int{1, 2} {
+			book, err := api.GetProductBook(config.ProductId, level)
+			assert.Assert(t, is.Nil(err), "unexpected error getting level %d book", level, err)
+			assert.Assert(t, len(book.Bids) > 0 && len(book.Asks) > 0, "conformance needs a two sided book")
+
+			bids, asks := parsePrices(t, book.Bids), parsePrices(t, book.Asks)
+			assert.Assert(t, sort.IsSorted(sort.Reverse(sort.Float64Slice(bids))), "bids %v", bids)
+			assert.Assert(t, sort.Float64sAreSorted(asks), "asks %v", asks)
+			assert.Assert(t, bids[0] < asks[0], "crossed book %v / %v", bids[0], asks[0])
+			if level == 1 {
+				assert.Equal(t, len(book.Bids), 1)
+				assert.Equal(t, len(book.Asks), 1)
+			}
+		}
+	})
+
+	t.Run("ticker and trades are newest first", func(t *testing.T) {
+		api := config.New(t)
+
+		trades, err := api.GetTrades(config.ProductId)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, len(trades) > 0, "conformance needs at least one trade")
+		for i := 1; i < len(trades); i++ {
+			assert.Assert(t, trades[i].TradeId < trades[i-1].TradeId, "trade %d listed after %d", trades[i].TradeId, trades[i-1].TradeId)
+		}
+
+		ticker, err := api.GetTicker(config.ProductId)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, ticker.TradeId >= trades[0].TradeId)
+	})
+
+	t.Run("candles are newest first and within the range", func(t *testing.T) {
+		candles, err := config.New(t).GetCandles(config.ProductId, config.Candles)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, len(candles) > 0, "conformance needs candles in the configured range")
+
+		for i, candle := range candles {
+			assert.Assert(t, !candle.Time.Before(config.Candles.Start) && !candle.Time.After(config.Candles.End), candle.Time)
+			assert.Assert(t, candle.Low <= candle.High)
+			if i > 0 {
+				assert.Assert(t, candle.Time.Before(candles[i-1].Time), candle.Time)
+			}
+		}
+
+		_, err = config.New(t).GetCandles(config.ProductId, coinbasepro.CandleFilter{Granularity: 7})
+		assert.Error(t, err, coinbasepro.InvalidGranularityErrorMessage)
+	})
+
+	t.Run("server time agrees with itself", func(t *testing.T) {
+		serverTime, err := config.New(t).GetTime()
+		assert.Assert(t, is.Nil(err))
+
+		iso, err := time.Parse(time.RFC3339, serverTime.Iso)
+		assert.Assert(t, is.Nil(err))
+		difference := iso.Sub(time.Unix(int64(serverTime.Epoch), 0))
+		assert.Assert(t, difference > -time.Second && difference < time.Second, "%s and %v differ by %v", serverTime.Iso, serverTime.Epoch, difference)
+	})
+}
+
+// RunFundingAPI checks that transfers can be listed, filtered and fetched individually.
+func RunFundingAPI(t *testing.T, newAPI func(t *testing.T) coinbasepro.FundingAPI) {
+	t.Run("lists transfers that can be fetched by id", func(t *testing.T) {
+		api := newAPI(t)
+
+		transfers, err := api.GetTransfers(coinbasepro.TransferFilter{})
+		assert.Assert(t, is.Nil(err), "unexpected error listing transfers", err)
+		assert.Assert(t, len(transfers) > 0, "conformance needs at least one transfer")
+
+		for _, transfer := range transfers {
+			fetched, err := api.GetTransfer(transfer.Id)
+			assert.Assert(t, is.Nil(err))
+			assert.Equal(t, fetched.Type, transfer.Type)
+			assert.Equal(t, fetched.Amount, transfer.Amount)
+		}
+	})
+
+	t.Run("filters transfers by type", func(t *testing.T) {
+		api := newAPI(t)
+
+		for _, transferType := range []string{"deposit", "withdraw"} {
+			transfers, err := api.GetTransfers(coinbasepro.TransferFilter{Type: transferType})
+			assert.Assert(t, is.Nil(err))
+			for _, transfer := range transfers {
+				assert.Equal(t, transfer.Type, transferType)
+			}
+		}
+	})
+
+	t.Run("unknown transfers are not found", func(t *testing.T) {
+		_, err := newAPI(t).GetTransfer("ffffffff-ffff-4fff-8fff-ffffffffffff")
+		assertNotFound(t, err)
+	})
+
+	t.Run("lists payment methods", func(t *testing.T) {
+		methods, err := newAPI(t).GetPaymentMethods()
+		assert.Assert(t, is.Nil(err))
+		for _, method := range methods {
+			assert.Assert(t, method.Id != "")
+			assert.Assert(t, method.Currency != "")
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package conformance
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro/mock"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var candleEpoch = time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+var notFound = coinbasepro.ApiError{StatusCode: http.StatusNotFound, Message: "NotFound"}
+
+func newPaperClient(t *testing.T) *coinbasepro.PaperClient {
+	paper := coinbasepro.NewPaperClient(map[string]float64{"USD": 1000}, coinbasepro.DefaultFeeTier)
+	t.Cleanup(func() { paper.Close() })
+
+	return paper
+}
+
+// newMarketData serves a fixed BTC-USD market from memory the way the exchange does.
+func newMarketData() *mock.MarketDataAPI {
+	products := []coinbasepro.Product{
+		{Id: "BTC-USD", BaseCurrency: "BTC", QuoteCurrency: "USD"},
+		{Id: "ETH-BTC", BaseCurrency: "ETH", QuoteCurrency: "BTC"},
+	}
+	book := coinbasepro.ProductBook{
+		Sequence: 1,
+		Bids:     []coinbasepro.BookEntry{{Price: "99", Size: "1", NumOrders: 1}, {Price: "98", Size: "2", NumOrders: 3}},
+		Asks:     []coinbasepro.BookEntry{{Price: "101", Size: "1", NumOrders: 2}, {Price: "102", Size: "5", NumOrders: 1}},
+	}
+
+	return &mock.MarketDataAPI{
+		GetProductsFunc: func() ([]coinbasepro.Product, error) {
+			return products, nil
+		},
+		GetProductFunc: func(productId string) (coinbasepro.Product, error) {
+			for _, product := range products {
+				if product.Id == productId {
+					return product, nil
+				}
+			}
+
+			return coinbasepro.Product{}, notFound
+		},
+		GetTickerFunc: func(productId string) (coinbasepro.Ticker, error) {
+			return coinbasepro.Ticker{TradeId: 3, Price: "100", Size: "0.5", Bid: "99", Ask: "101", Time: "2021-03-01T00:02:00Z"}, nil
+		},
+		GetProductBookFunc: func(productId string, level int) (coinbasepro.ProductBook, error) {
+			if level == 1 {
+				return coinbasepro.ProductBook{Sequence: book.Sequence, Bids: book.Bids[:1], Asks: book.Asks[:1]}, nil
+			}
+
+			return book, nil
+		},
+		GetTradesFunc: func(productId string) ([]coinbasepro.Trade, error) {
+			return []coinbasepro.Trade{
+				{TradeId: 3, Price: "100", Size: "0.5", Side: coinbasepro.OrderSideBuy, Time: "2021-03-01T00:02:00Z"},
+				{TradeId: 2, Price: "101", Size: "1", Side: coinbasepro.OrderSideSell, Time: "2021-03-01T00:01:00Z"},
+			}, nil
+		},
+		GetCandlesFunc: func(productId string, filter coinbasepro.CandleFilter) ([]coinbasepro.Candle, error) {
+			if filter.Granularity != 0 && !coinbasepro.ValidGranularity(filter.Granularity) {
+				return nil, errors.New(coinbasepro.InvalidGranularityErrorMessage)
+			}
+
+			candles := make([]coinbasepro.Candle, 0)
+			bucket := time.Duration(filter.Granularity) * time.Second
+			for at := filter.End; bucket > 0 && !at.Before(filter.Start); at = at.Add(-bucket) {
+				candles = append(candles, coinbasepro.Candle{Time: at, Low: 99, High: 101, Open: 100, Close: 100, Volume: 1})
+			}
+
+			return candles, nil
+		},
+		GetTimeFunc: func() (coinbasepro.ServerTime, error) {
+			return coinbasepro.ServerTime{Iso: "2021-03-01T00:02:30Z", Epoch: float64(candleEpoch.Add(150*time.Second).Unix()) + 0.25}, nil
+		},
+	}
+}
+
+func newFunding() *mock.FundingAPI {
+	transfers := []coinbasepro.Transfer{
+		{Id: "00000000-0000-4000-8000-000000000001", Type: "deposit", Amount: "100.00", CreatedAt: "2021-03-01T00:00:00Z"},
+		{Id: "00000000-0000-4000-8000-000000000002", Type: "withdraw", Amount: "25.00", CreatedAt: "2021-03-02T00:00:00Z"},
+	}
+
+	return &mock.FundingAPI{
+		GetTransfersFunc: func(filter coinbasepro.TransferFilter) ([]coinbasepro.Transfer, error) {
+			matching := make([]coinbasepro.Transfer, 0)
+			for _, transfer := range transfers {
+				if filter.Type == "" || transfer.Type == filter.Type {
+					matching = append(matching, transfer)
+				}
+			}
+
+			return matching, nil
+		},
+		GetTransferFunc: func(transferId string) (coinbasepro.Transfer, error) {
+			for _, transfer := range transfers {
+				if transfer.Id == transferId {
+					return transfer, nil
+				}
+			}
+
+			return coinbasepro.Transfer{}, notFound
+		},
+		GetPaymentMethodsFunc: func() ([]coinbasepro.PaymentMethod, error) {
+			return []coinbasepro.PaymentMethod{{Id: "bank-1", Type: "ach_bank_account", Name: "Bank", Currency: "USD", AllowDeposit: true, AllowWithdraw: true}}, nil
+		},
+	}
+}
+
+func writeResult(writer http.ResponseWriter, result interface{}, err error) {
+	var apiError coinbasepro.ApiError
+	switch {
+	case errors.As(err, &apiError):
+		writer.WriteHeader(apiError.StatusCode)
+		result = apiError
+	case err != nil:
+		writer.WriteHeader(http.StatusBadRequest)
+		result = coinbasepro.ApiError{Message: err.Error()}
+	}
+
+	json.NewEncoder(writer).Encode(result)
+}
+
+func parseTime(value string) time.Time {
+	parsed, _ := time.Parse(time.RFC3339, value)
+	return parsed
+}
+
+// serve exposes the market data and funding apis over http, so the real client can be checked
+// against the same data as the mocks.
+func serve(marketData coinbasepro.MarketDataAPI, funding coinbasepro.FundingAPI) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+		segments := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+
+		switch {
+		case request.URL.Path == "/products":
+			products, err := marketData.GetProducts()
+			writeResult(writer, products, err)
+		case len(segments) == 2 && segments[0] == "products":
+			product, err := marketData.GetProduct(segments[1])
+			writeResult(writer, product, err)
+		case len(segments) == 3 && segments[2] == "ticker":
+			ticker, err := marketData.GetTicker(segments[1])
+			writeResult(writer, ticker, err)
+		case len(segments) == 3 && segments[2] == "book":
+			level, _ := strconv.Atoi(query.Get("level"))
+			book, err := marketData.GetProductBook(segments[1], level)
+			writeResult(writer, book, err)
+		case len(segments) == 3 && segments[2] == "trades":
+			trades, err := marketData.GetTrades(segments[1])
+			writeResult(writer, trades, err)
+		case len(segments) == 3 && segments[2] == "candles":
+			granularity, _ := strconv.Atoi(query.Get("granularity"))
+			filter := coinbasepro.CandleFilter{Start: parseTime(query.Get("start")), End: parseTime(query.Get("end")), Granularity: granularity}
+			candles, err := marketData.GetCandles(segments[1], filter)
+			writeResult(writer, candles, err)
+		case request.URL.Path == "/time":
+			serverTime, err := marketData.GetTime()
+			writeResult(writer, serverTime, err)
+		case request.URL.Path == "/transfers":
+			transfers, err := funding.GetTransfers(coinbasepro.TransferFilter{Type: query.Get("type"), ProfileId: query.Get("profile_id")})
+			writeResult(writer, transfers, err)
+		case len(segments) == 2 && segments[0] == "transfers":
+			transfer, err := funding.GetTransfer(segments[1])
+			writeResult(writer, transfer, err)
+		case request.URL.Path == "/payment-methods":
+			methods, err := funding.GetPaymentMethods()
+			writeResult(writer, methods, err)
+		default:
+			writeResult(writer, nil, notFound)
+		}
+	}
+}
+
+func newServedClient(t *testing.T) *coinbasepro.Client {
+	server := httptest.NewServer(serve(newMarketData(), newFunding()))
+	t.Cleanup(server.Close)
+
+	client, err := coinbasepro.NewClientWithOptions(server.URL, "key", "passphrase", base64.StdEncoding.EncodeToString([]byte("secret")))
+	assert.Assert(t, is.Nil(err))
+	client.SetRateLimiter(coinbasepro.NewRateLimiter(1000, 1000))
+
+	return client
+}
+
+func TestPaperClientConformance(t *testing.T) {
+	RunTradingAPI(t, TradingAPIConfig{
+		New: func(t *testing.T) coinbasepro.TradingAPI {
+			return newPaperClient(t)
+		},
+		ProductId:    "BTC-USD",
+		RestingPrice: "100",
+		RestingSize:  "1",
+	})
+
+	RunAccountsAPI(t, func(t *testing.T) coinbasepro.AccountsAPI {
+		return newPaperClient(t)
+	})
+}
+
+func TestMockConformance(t *testing.T) {
+	RunMarketDataAPI(t, MarketDataAPIConfig{
+		New: func(t *testing.T) coinbasepro.MarketDataAPI {
+			return newMarketData()
+		},
+		ProductId: "BTC-USD",
+		Candles:   coinbasepro.CandleFilter{Start: candleEpoch, End: candleEpoch.Add(time.Hour), Granularity: 300},
+	})
+
+	RunFundingAPI(t, func(t *testing.T) coinbasepro.FundingAPI {
+		return newFunding()
+	})
+}
+
+func TestClientConformance(t *testing.T) {
+	RunMarketDataAPI(t, MarketDataAPIConfig{
+		New: func(t *testing.T) coinbasepro.MarketDataAPI {
+			return newServedClient(t)
+		},
+		ProductId: "BTC-USD",
+		Candles:   coinbasepro.CandleFilter{Start: candleEpoch, End: candleEpoch.Add(time.Hour), Granularity: 300},
+	})
+
+	RunFundingAPI(t, func(t *testing.T) coinbasepro.FundingAPI {
+		return newServedClient(t)
+	})
+}
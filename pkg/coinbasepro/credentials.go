@@ -0,0 +1,244 @@
+package coinbasepro
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+const fileEnvSuffix = "_FILE"
+
+const MissingCredentialsErrorMessage = "credential provider returned incomplete credentials"
+
+type Credentials struct {
+	BaseUrl    string `json:"base_url"`
+	Key        string `json:"key"`
+	Passphrase string `json:"passphrase"`
+	Secret     string `json:"secret"`
+}
+
+func (c Credentials) String() string {
+	return fmt.Sprintf("Credentials{BaseUrl: %s, Key: %s, Passphrase: %s, Secret: %s}", c.BaseUrl, redactKey(c.Key), redact(c.Passphrase), redact(c.Secret))
+}
+
+func (c Credentials) GoString() string {
+	return c.String()
+}
+
+func (c Credentials) validate() error {
+	if c.BaseUrl == "" || c.Key == "" || c.Passphrase == "" || c.Secret == "" {
+		return errors.New(MissingCredentialsErrorMessage)
+	}
+
+	return nil
+}
+
+type CredentialProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// EnvCredentialProvider reads <Prefix>BASEURL, <Prefix>KEY, <Prefix>PASSPHRASE and <Prefix>SECRET.
+// Each variable may instead be supplied as <NAME>_FILE pointing at a file holding the value.
+type EnvCredentialProvider struct {
+	Prefix string
+}
+
+func NewEnvCredentialProvider(prefix string) *EnvCredentialProvider {
+	if prefix == "" {
+		prefix = coinbaseProEnvPrefix
+	}
+
+	return &EnvCredentialProvider{Prefix: prefix}
+}
+
+func (p *EnvCredentialProvider) Credentials() (Credentials, error) {
+	var credentials Credentials
+	fields := []struct {
+		suffix string
+		value  *string
+	}{
+		{baseurlEnvSuffix, &credentials.BaseUrl},
+		{keyEnvSuffix, &credentials.Key},
+		{passphraseEnvSuffix, &credentials.Passphrase},
+		{secretEnvSuffix, &credentials.Secret},
+	}
+
+	for _, field := range fields {
+		name := p.Prefix + field.suffix
+		value, err := lookupEnvOrFile(name)
+		if err != nil {
+			return Credentials{}, err
+		}
+
+		if value == "" {
+			return Credentials{}, fmt.Errorf("missing %s", name)
+		}
+
+		*field.value = value
+	}
+
+	return credentials, nil
+}
+
+func lookupEnvOrFile(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+
+	path := os.Getenv(name + fileEnvSuffix)
+	if path == "" {
+		return "", nil
+	}
+
+	return readSecretFile(path)
+}
+
+func readSecretFile(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read secret file %s: %w", path, err)
+	}
+
+	return strings.TrimSpace(string(contents)), nil
+}
+
+// FileCredentialProvider reads one file per credential from Dir, as laid out by Docker secrets
+// (/run/secrets/coinbase_pro_key with Prefix "coinbase_pro_") or a Kubernetes secret volume
+// (/etc/coinbase/key with no prefix).
+type FileCredentialProvider struct {
+	Dir    string
+	Prefix string
+}
+
+func NewFileCredentialProvider(dir, prefix string) *FileCredentialProvider {
+	return &FileCredentialProvider{Dir: dir, Prefix: prefix}
+}
+
+func (p *FileCredentialProvider) Credentials() (Credentials, error) {
+	var credentials Credentials
+	fields := []struct {
+		suffix string
+		value  *string
+	}{
+		{baseurlEnvSuffix, &credentials.BaseUrl},
+		{keyEnvSuffix, &credentials.Key},
+		{passphraseEnvSuffix, &credentials.Passphrase},
+		{secretEnvSuffix, &credentials.Secret},
+	}
+
+	for _, field := range fields {
+		path := filepath.Join(p.Dir, strings.ToLower(p.Prefix+field.suffix))
+		value, err := readSecretFile(path)
+		if err != nil {
+			return Credentials{}, err
+		}
+
+		if value == "" {
+			return Credentials{}, fmt.Errorf("empty secret file %s", path)
+		}
+
+		*field.value = value
+	}
+
+	return credentials, nil
+}
+
+func NewClientWithCredentialProvider(provider CredentialProvider) (*Client, error) {
+	credentials, err := provider.Credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := credentials.validate(); err != nil {
+		return nil, err
+	}
+
+	return NewClientWithOptions(credentials.BaseUrl, credentials.Key, credentials.Passphrase, credentials.Secret)
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return redactedValue
+}
+
+// redactKey keeps the last four characters of an api key so log lines can still tell keys apart.
+func redactKey(key string) string {
+	if len(key) <= 8 {
+		return redact(key)
+	}
+
+	return redactedValue + key[len(key)-4:]
+}
+
+func (t *Client) String() string {
+	return fmt.Sprintf("Client{baseUrl: %s, key: %s, passphrase: %s, secret: %s}", t.baseUrl, redactKey(t.key), redact(t.passphrase), redact(t.secret))
+}
+
+func (t *Client) GoString() string {
+	return t.String()
+}
+
+func (t *Client) redactString(value string) string {
+	for _, secret := range []string{t.secret, t.passphrase, t.key} {
+		if secret != "" {
+			value = strings.ReplaceAll(value, secret, redactedValue)
+		}
+	}
+
+	return value
+}
+
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e redactedError) Error() string {
+	return e.message
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError makes sure no credential ever leaves the client inside an error message.
+func (t *Client) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	message := err.Error()
+	redacted := t.redactString(message)
+	if redacted == message {
+		return err
+	}
+
+	return redactedError{message: redacted, err: err}
+}
+
+var sensitiveHeaders = []string{coinbaseProAccessKeyHeader, coinbaseProAccessSignatureHeader, coinbaseProAccessPassphraseHeader}
+
+// RedactedHeader returns a copy of header that is safe to log.
+func RedactedHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if value := redacted.Get(name); value != "" {
+			if name == coinbaseProAccessKeyHeader {
+				redacted.Set(name, redactKey(value))
+				continue
+			}
+
+			redacted.Set(name, redactedValue)
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,170 @@
+package coinbasepro
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testLongKey = "0123456789abcdef"
+
+var testCredentials = Credentials{
+	BaseUrl:    testBaseUrl,
+	Key:        testLongKey,
+	Passphrase: testPassphrase,
+	Secret:     testSecret,
+}
+
+func TestEnvCredentialProvider(t *testing.T) {
+	t.Run("reads credentials from prefixed environment variables", func(t *testing.T) {
+		resetEnvVars()
+
+		credentials, err := NewEnvCredentialProvider("").Credentials()
+		assert.Assert(t, is.Nil(err), "unexpected error reading credentials", err)
+		assert.DeepEqual(t, credentials, Credentials{BaseUrl: testBaseUrl, Key: testKey, Passphrase: testPassphrase, Secret: testSecret})
+	})
+
+	t.Run("falls back to _FILE variables", func(t *testing.T) {
+		resetEnvVars()
+		os.Setenv(coinbaseProSecretKey, "")
+
+		path := filepath.Join(t.TempDir(), "secret")
+		assert.Assert(t, is.Nil(ioutil.WriteFile(path, []byte("c2VjcmV0ZnJvbWZpbGU=\n"), 0600)))
+		os.Setenv(coinbaseProSecretKey+fileEnvSuffix, path)
+		defer os.Unsetenv(coinbaseProSecretKey + fileEnvSuffix)
+
+		credentials, err := NewEnvCredentialProvider("").Credentials()
+		assert.Assert(t, is.Nil(err), "unexpected error reading credentials", err)
+		assert.Equal(t, credentials.Secret, "c2VjcmV0ZnJvbWZpbGU=")
+	})
+}
+
+func TestFileCredentialProvider(t *testing.T) {
+	dir := t.TempDir()
+	for name, value := range map[string]string{"baseurl": testBaseUrl, "key": testKey, "passphrase": testPassphrase, "secret": testSecret + "\n"} {
+		assert.Assert(t, is.Nil(ioutil.WriteFile(filepath.Join(dir, "coinbase_pro_"+name), []byte(value), 0600)))
+	}
+
+	t.Run("reads one file per credential", func(t *testing.T) {
+		credentials, err := NewFileCredentialProvider(dir, "coinbase_pro_").Credentials()
+		assert.Assert(t, is.Nil(err), "unexpected error reading credentials", err)
+		assert.DeepEqual(t, credentials, Credentials{BaseUrl: testBaseUrl, Key: testKey, Passphrase: testPassphrase, Secret: testSecret})
+	})
+
+	t.Run("errors without leaking contents when files are missing", func(t *testing.T) {
+		_, err := NewFileCredentialProvider(dir, "").Credentials()
+		assert.ErrorContains(t, err, "unable to read secret file")
+	})
+}
+
+func TestKeystoreCredentialProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	entries := map[string]Credentials{"default": testCredentials, "beta": {BaseUrl: testBaseUrl, Key: "betaKey", Passphrase: "betaPassphrase", Secret: testSecret}}
+
+	err := writeKeystore(path, "correct horse", entries, 1000)
+	assert.Assert(t, is.Nil(err), "unexpected error writing keystore", err)
+
+	t.Run("does not store secrets in plain text", func(t *testing.T) {
+		contents, err := ioutil.ReadFile(path)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, !strings.Contains(string(contents), testPassphrase))
+		assert.Assert(t, !strings.Contains(string(contents), testSecret))
+	})
+
+	t.Run("decrypts credentials for an alias", func(t *testing.T) {
+		credentials, err := NewKeystoreCredentialProvider(path, "correct horse", "beta").Credentials()
+		assert.Assert(t, is.Nil(err), "unexpected error reading keystore", err)
+		assert.Equal(t, credentials.Key, "betaKey")
+
+		client, err := NewClientWithCredentialProvider(NewKeystoreCredentialProvider(path, "correct horse", ""))
+		assert.Assert(t, is.Nil(err), "unexpected error creating client from keystore", err)
+		assert.Equal(t, client.key, testLongKey)
+	})
+
+	t.Run("rejects the wrong password", func(t *testing.T) {
+		_, err := NewKeystoreCredentialProvider(path, "battery staple", "").Credentials()
+		assert.Error(t, err, KeystoreDecryptionErrorMessage)
+	})
+
+	t.Run("rejects corrupted files without panicking", func(t *testing.T) {
+		contents, err := ioutil.ReadFile(path)
+		assert.Assert(t, is.Nil(err))
+
+		var file keystoreFile
+		assert.Assert(t, is.Nil(json.Unmarshal(contents, &file)))
+
+		corrupt := func(change func(file *keystoreFile)) string {
+			copied := file
+			change(&copied)
+			corruptPath := filepath.Join(t.TempDir(), "corrupt.json")
+			contents, err := json.Marshal(copied)
+			assert.Assert(t, is.Nil(err))
+			assert.Assert(t, is.Nil(ioutil.WriteFile(corruptPath, contents, 0600)))
+			return corruptPath
+		}
+
+		_, err = ReadKeystore(corrupt(func(file *keystoreFile) { file.Nonce = []byte{0, 0, 0} }), "correct horse")
+		assert.Error(t, err, KeystoreDecryptionErrorMessage)
+
+		tooSlow := corrupt(func(file *keystoreFile) { file.Iterations = maxKeystoreIterations + 1 })
+		_, err = ReadKeystore(tooSlow, "correct horse")
+		assert.Error(t, err, "unsupported keystore "+tooSlow)
+	})
+
+	t.Run("errors for unknown aliases", func(t *testing.T) {
+		_, err := NewKeystoreCredentialProvider(path, "correct horse", "gamma").Credentials()
+		assert.Error(t, err, KeystoreAliasNotFoundErrorMessage+": gamma")
+	})
+
+	t.Run("derives keys using PBKDF2-HMAC-SHA256", func(t *testing.T) {
+		expected := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+		derived := pbkdf2Sha256([]byte("passwd"), []byte("salt"), 1, 64)
+		assert.Equal(t, hex.EncodeToString(derived), expected)
+	})
+}
+
+func TestRedaction(t *testing.T) {
+	client, err := NewClientWithOptions(testBaseUrl, testLongKey, testPassphrase, testSecret)
+	assert.Assert(t, is.Nil(err))
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		t.Run(fmt.Sprintf("client redacts credentials with %s", format), func(t *testing.T) {
+			output := fmt.Sprintf(format, client)
+			assert.Assert(t, !strings.Contains(output, testPassphrase), output)
+			assert.Assert(t, !strings.Contains(output, testSecret), output)
+			assert.Assert(t, !strings.Contains(output, testLongKey), output)
+			assert.Assert(t, strings.Contains(output, "cdef"), output)
+		})
+
+		t.Run(fmt.Sprintf("credentials redact secrets with %s", format), func(t *testing.T) {
+			output := fmt.Sprintf(format, testCredentials)
+			assert.Assert(t, !strings.Contains(output, testPassphrase), output)
+			assert.Assert(t, !strings.Contains(output, testSecret), output)
+		})
+	}
+
+	t.Run("errors never contain credentials", func(t *testing.T) {
+		err := client.redactError(errors.New("bad passphrase " + testPassphrase))
+		assert.Error(t, err, "bad passphrase "+redactedValue)
+	})
+
+	t.Run("headers are redacted for logging", func(t *testing.T) {
+		req, err := client.buildRequest("GET", "/test", nil)
+		assert.Assert(t, is.Nil(err))
+
+		header := RedactedHeader(req.Header)
+		assert.Equal(t, header.Get(coinbaseProAccessPassphraseHeader), redactedValue)
+		assert.Equal(t, header.Get(coinbaseProAccessSignatureHeader), redactedValue)
+		assert.Equal(t, header.Get(coinbaseProAccessKeyHeader), redactedValue+"cdef")
+		assert.Equal(t, req.Header.Get(coinbaseProAccessPassphraseHeader), testPassphrase)
+		assert.Equal(t, header.Get(acceptHeaderKey), req.Header.Get(acceptHeaderKey))
+	})
+}
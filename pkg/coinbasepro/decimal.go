@@ -0,0 +1,56 @@
+package coinbasepro
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const incrementEpsilon = 1e-9
+
+func parseDecimal(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
+
+func decimalPlaces(increment string) int {
+	increment = strings.TrimRight(increment, "0")
+	index := strings.Index(increment, ".")
+	if index < 0 {
+		return 0
+	}
+
+	return len(increment) - index - 1
+}
+
+func formatDecimal(value float64, places int) string {
+	return strconv.FormatFloat(value, 'f', places, 64)
+}
+
+func floorToIncrement(value, increment float64) float64 {
+	if increment <= 0 {
+		return value
+	}
+
+	return math.Floor(value/increment+incrementEpsilon) * increment
+}
+
+func roundToIncrement(value, increment float64) float64 {
+	if increment <= 0 {
+		return value
+	}
+
+	return math.Round(value/increment) * increment
+}
+
+func isMultipleOfIncrement(value, increment float64) bool {
+	if increment <= 0 {
+		return true
+	}
+
+	steps := value / increment
+	return math.Abs(steps-math.Round(steps)) < 1e-6
+}
@@ -0,0 +1,11 @@
+package coinbasepro
+
+import "testing"
+
+// NewFakeExchangeClient exposes the in memory fake exchange to the external test package.
+func NewFakeExchangeClient(t *testing.T, accounts ...Account) *Client {
+	exchange := newFakeExchange(t)
+	exchange.setAccounts(accounts...)
+
+	return exchange.client(t)
+}
@@ -0,0 +1,197 @@
+package coinbasepro
+
+import (
+	"encoding/json"
+	"fmt"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeExchange is a tiny in memory stand in for the order endpoints of the exchange. With
+// rejectPostOnly set post only orders come back rejected, as if they would have crossed the book.
+type fakeExchange struct {
+	mutex          sync.Mutex
+	nextId         int
+	orders         map[string]*Order
+	fills          []Fill
+	accounts       []Account
+	cancelled      []string
+	rejectPostOnly bool
+	fillPages      int
+	server         *httptest.Server
+}
+
+func newFakeExchange(t *testing.T) *fakeExchange {
+	exchange := &fakeExchange{orders: make(map[string]*Order)}
+	exchange.server = httptest.NewServer(http.HandlerFunc(exchange.serveHTTP))
+	t.Cleanup(exchange.server.Close)
+
+	return exchange
+}
+
+func (e *fakeExchange) client(t *testing.T) *Client {
+	client, err := NewClientWithOptions(e.server.URL, testKey, testPassphrase, testSecret)
+	assert.Assert(t, is.Nil(err), "unexpected error creating client using NewClientWithOptions", err)
+	client.SetRateLimiter(NewRateLimiter(1000, 1000))
+
+	return client
+}
+
+// addFill adds a fill as the newest one, fills are listed newest first like on the exchange.
+func (e *fakeExchange) addFill(fill Fill) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.fills = append([]Fill{fill}, e.fills...)
+}
+
+func (e *fakeExchange) setAccounts(accounts ...Account) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.accounts = accounts
+}
+
+func (e *fakeExchange) setOrderStatus(orderId, status, filledSize string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if order, found := e.orders[orderId]; found {
+		order.Status = status
+		order.FilledSize = filledSize
+	}
+}
+
+func (e *fakeExchange) writeJson(writer http.ResponseWriter, status int, body interface{}) {
+	bytes, _ := json.Marshal(body)
+	writer.WriteHeader(status)
+	writer.Write(bytes)
+}
+
+// page picks the slice of a list the limit and after parameters ask for and sets the CB-AFTER
+// cursor when more items follow.
+func (e *fakeExchange) page(writer http.ResponseWriter, request *http.Request, total int) (int, int) {
+	start, _ := strconv.Atoi(request.URL.Query().Get("after"))
+	limit, err := strconv.Atoi(request.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = paginationLimit
+	}
+
+	if start > total {
+		start = total
+	}
+
+	end := start + limit
+	if end > total {
+		end = total
+	}
+
+	if end < total {
+		writer.Header().Set(paginationAfterHeader, strconv.Itoa(end))
+	}
+
+	return start, end
+}
+
+func (e *fakeExchange) findOrder(reference string) (*Order, bool) {
+	if strings.HasPrefix(reference, "client:") {
+		clientOid := strings.TrimPrefix(reference, "client:")
+		for _, order := range e.orders {
+			if order.ClientOid == clientOid {
+				return order, true
+			}
+		}
+
+		return nil, false
+	}
+
+	order, found := e.orders[reference]
+	return order, found
+}
+
+func (e *fakeExchange) serveHTTP(writer http.ResponseWriter, request *http.Request) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	segments := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+
+	switch {
+	case request.Method == "POST" && request.URL.Path == "/orders":
+		var order Order
+		if err := json.NewDecoder(request.Body).Decode(&order); err != nil {
+			e.writeJson(writer, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		e.nextId++
+		order.Id = fmt.Sprintf("00000000-0000-4000-8000-%012d", e.nextId)
+		order.Status = "pending"
+		order.FilledSize = "0"
+		if order.PostOnly && e.rejectPostOnly {
+			order.Status = "rejected"
+			order.RejectReason = "post only"
+		}
+		e.orders[order.Id] = &order
+		e.writeJson(writer, http.StatusOK, order)
+	case request.Method == "GET" && request.URL.Path == "/orders":
+		orders := make([]Order, 0)
+		for _, order := range e.orders {
+			if order.Status == "open" || order.Status == "pending" || order.Status == "active" {
+				orders = append(orders, *order)
+			}
+		}
+		sort.Slice(orders, func(i, j int) bool { return orders[i].Id < orders[j].Id })
+		start, end := e.page(writer, request, len(orders))
+		e.writeJson(writer, http.StatusOK, orders[start:end])
+	case request.Method == "GET" && len(segments) == 2 && segments[0] == "orders":
+		order, found := e.findOrder(segments[1])
+		if !found {
+			e.writeJson(writer, http.StatusNotFound, map[string]string{"message": "NotFound"})
+			return
+		}
+		e.writeJson(writer, http.StatusOK, order)
+	case request.Method == "DELETE" && len(segments) == 2 && segments[0] == "orders":
+		order, found := e.findOrder(segments[1])
+		if !found || order.Status == "done" {
+			e.writeJson(writer, http.StatusNotFound, map[string]string{"message": "order not found"})
+			return
+		}
+		order.Status = "done"
+		order.DoneReason = "canceled"
+		e.cancelled = append(e.cancelled, order.Id)
+		e.writeJson(writer, http.StatusOK, order.Id)
+	case request.Method == "GET" && request.URL.Path == "/fills":
+		e.fillPages++
+		orderId := request.URL.Query().Get("order_id")
+		productId := request.URL.Query().Get("product_id")
+		fills := make([]Fill, 0)
+		for _, fill := range e.fills {
+			if (orderId == "" || fill.OrderId == orderId) && (productId == "" || fill.ProductId == productId) {
+				fills = append(fills, fill)
+			}
+		}
+		start, end := e.page(writer, request, len(fills))
+		e.writeJson(writer, http.StatusOK, fills[start:end])
+	case request.Method == "GET" && request.URL.Path == "/accounts":
+		accounts := make([]Account, 0, len(e.accounts))
+		accounts = append(accounts, e.accounts...)
+		e.writeJson(writer, http.StatusOK, accounts)
+	case request.Method == "GET" && len(segments) == 2 && segments[0] == "accounts":
+		for _, account := range e.accounts {
+			if account.Id == segments[1] {
+				e.writeJson(writer, http.StatusOK, account)
+				return
+			}
+		}
+		e.writeJson(writer, http.StatusNotFound, map[string]string{"message": "NotFound"})
+	default:
+		e.writeJson(writer, http.StatusNotFound, map[string]string{"message": "NotFound"})
+	}
+}
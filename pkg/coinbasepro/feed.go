@@ -0,0 +1,261 @@
+package coinbasepro
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+)
+
+const DefaultWebsocketUrl = "wss://ws-feed.pro.coinbase.com"
+const coinbaseProWebsocketUrlKey = "COINBASE_PRO_WEBSOCKET_URL"
+const websocketVerifyPath = "/users/self/verify"
+
+const ChannelHeartbeat = "heartbeat"
+const ChannelTicker = "ticker"
+const ChannelLevel2 = "level2"
+const ChannelMatches = "matches"
+const ChannelFull = "full"
+const ChannelUser = "user"
+const ChannelStatus = "status"
+
+const MessageTypeSubscribe = "subscribe"
+const MessageTypeSubscriptions = "subscriptions"
+const MessageTypeHeartbeat = "heartbeat"
+const MessageTypeTicker = "ticker"
+const MessageTypeSnapshot = "snapshot"
+const MessageTypeL2Update = "l2update"
+const MessageTypeReceived = "received"
+const MessageTypeOpen = "open"
+const MessageTypeDone = "done"
+const MessageTypeMatch = "match"
+const MessageTypeLastMatch = "last_match"
+const MessageTypeChange = "change"
+const MessageTypeActivate = "activate"
+const MessageTypeError = "error"
+const MessageTypeStatus = "status"
+
+const FeedNotConnectedErrorMessage = "feed is not connected, call Subscribe first"
+
+// Message is the union of every message type sent by the websocket feed, fields that do not apply
+// to a type are left empty.
+type Message struct {
+	Type      string `json:"type"`
+	Sequence  int64  `json:"sequence,omitempty"`
+	Time      string `json:"time,omitempty"`
+	ProductId string `json:"product_id,omitempty"`
+	ProfileId string `json:"profile_id,omitempty"`
+	UserId    string `json:"user_id,omitempty"`
+
+	OrderId       string `json:"order_id,omitempty"`
+	ClientOid     string `json:"client_oid,omitempty"`
+	OrderType     string `json:"order_type,omitempty"`
+	Side          string `json:"side,omitempty"`
+	Price         string `json:"price,omitempty"`
+	Size          string `json:"size,omitempty"`
+	Funds         string `json:"funds,omitempty"`
+	RemainingSize string `json:"remaining_size,omitempty"`
+	NewSize       string `json:"new_size,omitempty"`
+	OldSize       string `json:"old_size,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	StopType      string `json:"stop_type,omitempty"`
+	StopPrice     string `json:"stop_price,omitempty"`
+
+	TradeId      int64  `json:"trade_id,omitempty"`
+	MakerOrderId string `json:"maker_order_id,omitempty"`
+	TakerOrderId string `json:"taker_order_id,omitempty"`
+
+	BestBid   string `json:"best_bid,omitempty"`
+	BestAsk   string `json:"best_ask,omitempty"`
+	LastSize  string `json:"last_size,omitempty"`
+	Open24h   string `json:"open_24h,omitempty"`
+	Volume24h string `json:"volume_24h,omitempty"`
+	Low24h    string `json:"low_24h,omitempty"`
+	High24h   string `json:"high_24h,omitempty"`
+
+	Bids    [][]string `json:"bids,omitempty"`
+	Asks    [][]string `json:"asks,omitempty"`
+	Changes [][]string `json:"changes,omitempty"`
+
+	LastTradeId int64  `json:"last_trade_id,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+// Channel is the feed channel a message type is delivered on.
+func (m Message) Channel() string {
+	switch m.Type {
+	case MessageTypeHeartbeat:
+		return ChannelHeartbeat
+	case MessageTypeTicker:
+		return ChannelTicker
+	case MessageTypeSnapshot, MessageTypeL2Update:
+		return ChannelLevel2
+	case MessageTypeMatch, MessageTypeLastMatch:
+		return ChannelMatches
+	case MessageTypeReceived, MessageTypeOpen, MessageTypeDone, MessageTypeChange, MessageTypeActivate:
+		return ChannelFull
+	case MessageTypeStatus:
+		return ChannelStatus
+	default:
+		return m.Type
+	}
+}
+
+type Subscription struct {
+	ProductIds []string
+	Channels   []string
+}
+
+type subscribeRequest struct {
+	Type       string   `json:"type"`
+	ProductIds []string `json:"product_ids"`
+	Channels   []string `json:"channels"`
+	Signature  string   `json:"signature,omitempty"`
+	Key        string   `json:"key,omitempty"`
+	Passphrase string   `json:"passphrase,omitempty"`
+	Timestamp  string   `json:"timestamp,omitempty"`
+}
+
+// MessageSource is anything that produces feed messages, a live Feed or a replay of recorded data.
+type MessageSource interface {
+	Read() (Message, error)
+}
+
+type Feed struct {
+	url      string
+	client   *Client
+	metrics  *Metrics
+	recorder *MarketDataRecorder
+
+	mutex sync.Mutex
+	conn  *websocketConn
+}
+
+// NewFeed creates an unauthenticated feed for public market data channels. An empty url reads
+// COINBASE_PRO_WEBSOCKET_URL and falls back to DefaultWebsocketUrl.
+func NewFeed(websocketUrl string) *Feed {
+	return &Feed{url: resolveWebsocketUrl(websocketUrl)}
+}
+
+// NewFeed creates a feed that authenticates its subscriptions with the client's credentials, which
+// is required for the user channel.
+func (t *Client) NewFeed(websocketUrl string) *Feed {
+	return &Feed{url: resolveWebsocketUrl(websocketUrl), client: t}
+}
+
+func resolveWebsocketUrl(websocketUrl string) string {
+	if websocketUrl != "" {
+		return websocketUrl
+	}
+
+	if websocketUrl = os.Getenv(coinbaseProWebsocketUrlKey); websocketUrl != "" {
+		return websocketUrl
+	}
+
+	return DefaultWebsocketUrl
+}
+
+func (t *Feed) UseMetrics(metrics *Metrics) {
+	t.metrics = metrics
+}
+
+// Record writes every message read from the feed to recorder as it was received.
+func (t *Feed) Record(recorder *MarketDataRecorder) {
+	t.recorder = recorder
+}
+
+func (t *Feed) connection() *websocketConn {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.conn
+}
+
+func (t *Feed) Subscribe(subscription Subscription) error {
+	t.mutex.Lock()
+	if t.conn == nil {
+		conn, err := dialWebsocket(t.url)
+		if err != nil {
+			t.mutex.Unlock()
+			return err
+		}
+		t.conn = conn
+	}
+	conn := t.conn
+	t.mutex.Unlock()
+
+	request := subscribeRequest{
+		Type:       MessageTypeSubscribe,
+		ProductIds: subscription.ProductIds,
+		Channels:   subscription.Channels,
+	}
+
+	if t.client != nil {
+		timestamp := createTimestamp()
+		signature, err := createSignature(t.client.secret, timestamp, "GET", websocketVerifyPath, "")
+		if err != nil {
+			return t.client.redactError(err)
+		}
+
+		request.Signature = signature
+		request.Key = t.client.key
+		request.Passphrase = t.client.passphrase
+		request.Timestamp = timestamp
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	return conn.WriteMessage(payload)
+}
+
+// Read blocks until the next message arrives. Error messages sent by the exchange are returned as
+// an error alongside the message.
+func (t *Feed) Read() (Message, error) {
+	conn := t.connection()
+	if conn == nil {
+		return Message{}, errors.New(FeedNotConnectedErrorMessage)
+	}
+
+	payload, err := conn.ReadMessage()
+	if err != nil {
+		return Message{}, err
+	}
+
+	var message Message
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return Message{}, err
+	}
+
+	if t.recorder != nil {
+		if err := t.recorder.RecordWebsocket(payload); err != nil && t.recorder.OnError != nil {
+			t.recorder.OnError(err)
+		}
+	}
+
+	if t.metrics != nil {
+		t.metrics.ObserveWebsocketMessage(message.Channel(), message.Type)
+	}
+
+	if message.Type == MessageTypeError {
+		return message, errors.New(message.Message)
+	}
+
+	return message, nil
+}
+
+func (t *Feed) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.conn == nil {
+		return nil
+	}
+
+	err := t.conn.Close()
+	t.conn = nil
+
+	return err
+}
@@ -0,0 +1,130 @@
+package coinbasepro
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestWebsocketServer accepts websocket connections and hands the server side to handler.
+func newTestWebsocketServer(t *testing.T, handler func(conn *websocketConn)) string {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Assert(t, strings.EqualFold(request.Header.Get("Upgrade"), "websocket"))
+
+		hijacker, ok := writer.(http.Hijacker)
+		assert.Assert(t, ok)
+
+		conn, readWriter, err := hijacker.Hijack()
+		assert.Assert(t, is.Nil(err))
+		defer conn.Close()
+
+		accept := websocketAccept(request.Header.Get("Sec-WebSocket-Key"))
+		fmt.Fprintf(readWriter, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", accept)
+		readWriter.Flush()
+
+		handler(&websocketConn{conn: conn, reader: bufio.NewReader(readWriter)})
+	}))
+	t.Cleanup(ts.Close)
+
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+func writeTestMessages(t *testing.T, conn *websocketConn, messages ...Message) {
+	for _, message := range messages {
+		payload, err := json.Marshal(message)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(conn.WriteMessage(payload)))
+	}
+}
+
+func TestFeed(t *testing.T) {
+	t.Run("should sign subscriptions and decode messages", func(t *testing.T) {
+		subscriptions := make(chan subscribeRequest, 1)
+		websocketUrl := newTestWebsocketServer(t, func(conn *websocketConn) {
+			payload, err := conn.ReadMessage()
+			assert.Assert(t, is.Nil(err))
+
+			var request subscribeRequest
+			assert.Assert(t, is.Nil(json.Unmarshal(payload, &request)))
+			subscriptions <- request
+
+			assert.Assert(t, is.Nil(conn.writeFrame(websocketOpPing, []byte("ping"))))
+			writeTestMessages(t, conn,
+				Message{Type: MessageTypeSubscriptions},
+				Message{Type: MessageTypeReceived, OrderId: "order-1", ClientOid: "client-1", ProductId: "BTC-USD"},
+				Message{Type: MessageTypeError, Message: "Failed to subscribe"},
+			)
+
+			_, opcode, _, err := conn.readFrame()
+			assert.Assert(t, is.Nil(err))
+			assert.Equal(t, opcode, byte(websocketOpPong))
+		})
+
+		client, err := NewClientWithOptions(testBaseUrl, testKey, testPassphrase, testSecret)
+		assert.Assert(t, is.Nil(err))
+
+		metrics := NewMetrics()
+		feed := client.NewFeed(websocketUrl)
+		feed.UseMetrics(metrics)
+		defer feed.Close()
+
+		err = feed.Subscribe(Subscription{ProductIds: []string{"BTC-USD"}, Channels: []string{ChannelUser}})
+		assert.Assert(t, is.Nil(err), "unexpected error subscribing", err)
+
+		request := <-subscriptions
+		assert.Equal(t, request.Type, MessageTypeSubscribe)
+		assert.DeepEqual(t, request.Channels, []string{ChannelUser})
+		assert.Equal(t, request.Key, testKey)
+		assert.Equal(t, request.Passphrase, testPassphrase)
+
+		expectedSignature, err := createSignature(testSecret, request.Timestamp, "GET", websocketVerifyPath, "")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, request.Signature, expectedSignature)
+
+		message, err := feed.Read()
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, message.Type, MessageTypeSubscriptions)
+
+		message, err = feed.Read()
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, message.OrderId, "order-1")
+		assert.Equal(t, message.Channel(), ChannelFull)
+
+		_, err = feed.Read()
+		assert.Error(t, err, "Failed to subscribe")
+
+		assert.Equal(t, metrics.websocketMessages.value(ChannelFull, MessageTypeReceived), float64(1))
+	})
+
+	t.Run("public feeds do not send credentials", func(t *testing.T) {
+		subscriptions := make(chan subscribeRequest, 1)
+		websocketUrl := newTestWebsocketServer(t, func(conn *websocketConn) {
+			payload, err := conn.ReadMessage()
+			assert.Assert(t, is.Nil(err))
+
+			var request subscribeRequest
+			assert.Assert(t, is.Nil(json.Unmarshal(payload, &request)))
+			subscriptions <- request
+		})
+
+		feed := NewFeed(websocketUrl)
+		defer feed.Close()
+
+		assert.Assert(t, is.Nil(feed.Subscribe(Subscription{ProductIds: []string{"BTC-USD"}, Channels: []string{ChannelMatches}})))
+
+		request := <-subscriptions
+		assert.Equal(t, request.Key, "")
+		assert.Equal(t, request.Signature, "")
+	})
+
+	t.Run("reading before subscribing errors", func(t *testing.T) {
+		_, err := NewFeed("ws://localhost:1").Read()
+		assert.Error(t, err, FeedNotConnectedErrorMessage)
+	})
+}
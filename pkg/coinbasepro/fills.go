@@ -0,0 +1,89 @@
+package coinbasepro
+
+import (
+	"errors"
+	"net/url"
+	"time"
+)
+
+const MissingFillFilterErrorMessage = "fills require an order_id or product_id filter"
+
+type Fill struct {
+	TradeId   int64  `json:"trade_id"`
+	ProductId string `json:"product_id"`
+	OrderId   string `json:"order_id"`
+	ProfileId string `json:"profile_id,omitempty"`
+	Price     string `json:"price"`
+	Size      string `json:"size"`
+	Fee       string `json:"fee"`
+	Side      string `json:"side"`
+	Liquidity string `json:"liquidity"`
+	Settled   bool   `json:"settled"`
+	CreatedAt string `json:"created_at"`
+	UsdVolume string `json:"usd_volume,omitempty"`
+}
+
+// FillFilter selects fills. Since drops fills created before it, fills are listed newest first so
+// the client stops paginating at the first older one.
+type FillFilter struct {
+	OrderId   string
+	ProductId string
+	ProfileId string
+	Since     time.Time
+}
+
+// Includes reports whether fill passes the filter. Fills whose time does not parse are kept.
+func (f FillFilter) Includes(fill Fill) bool {
+	if (f.OrderId != "" && fill.OrderId != f.OrderId) || (f.ProductId != "" && fill.ProductId != f.ProductId) {
+		return false
+	}
+
+	if f.Since.IsZero() {
+		return true
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, fill.CreatedAt)
+	return err != nil || !createdAt.Before(f.Since)
+}
+
+func (f FillFilter) query() url.Values {
+	query := url.Values{}
+	if f.OrderId != "" {
+		query.Set("order_id", f.OrderId)
+	}
+
+	if f.ProductId != "" {
+		query.Set("product_id", f.ProductId)
+	}
+
+	if f.ProfileId != "" {
+		query.Set("profile_id", f.ProfileId)
+	}
+
+	return query
+}
+
+func (t *Client) GetFills(filter FillFilter) ([]Fill, error) {
+	if filter.OrderId == "" && filter.ProductId == "" {
+		return nil, errors.New(MissingFillFilterErrorMessage)
+	}
+
+	fills := make([]Fill, 0)
+	err := t.getAllPages("/fills", filter.query(), func() (interface{}, func() int) {
+		var page []Fill
+		return &page, func() int {
+			for _, fill := range page {
+				if !filter.Includes(fill) {
+					return 0
+				}
+				fills = append(fills, fill)
+			}
+			return len(page)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fills, nil
+}
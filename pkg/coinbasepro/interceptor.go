@@ -0,0 +1,150 @@
+package coinbasepro
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Call describes a single api call as it moves through the interceptor chain. Interceptors may
+// change Path and Body in BeforeSign; everything else is filled in by the client as the call
+// progresses and is read only.
+type Call struct {
+	Method string
+	Path   string
+	Body   interface{}
+
+	Request        *http.Request
+	Response       interface{}
+	ResponseHeader http.Header
+	StartedAt      time.Time
+	StatusCode     int
+	Retries        int
+	Hits429        int
+	RateLimitWait  time.Duration
+	Latency        time.Duration
+	Err            error
+	ApiError       *ApiError
+
+	values map[interface{}]interface{}
+}
+
+// Endpoint is Method and Path with ids replaced by placeholders, suitable as a low cardinality label.
+func (c *Call) Endpoint() string {
+	return EndpointLabel(c.Method, c.Path)
+}
+
+// SetValue stores per call state so an interceptor can carry data from BeforeSign to AfterResponse.
+func (c *Call) SetValue(key, value interface{}) {
+	if c.values == nil {
+		c.values = make(map[interface{}]interface{})
+	}
+
+	c.values[key] = value
+}
+
+func (c *Call) Value(key interface{}) interface{} {
+	return c.values[key]
+}
+
+// Interceptor hooks into every api call. BeforeSign and AfterSign may abort the call by returning
+// an error, AfterResponse is always called once the call has finished, including aborted calls.
+type Interceptor interface {
+	BeforeSign(call *Call) error
+	AfterSign(call *Call) error
+	AfterResponse(call *Call)
+}
+
+// InterceptorFuncs adapts plain functions to the Interceptor interface, nil functions are skipped.
+type InterceptorFuncs struct {
+	OnBeforeSign    func(call *Call) error
+	OnAfterSign     func(call *Call) error
+	OnAfterResponse func(call *Call)
+}
+
+func (f InterceptorFuncs) BeforeSign(call *Call) error {
+	if f.OnBeforeSign == nil {
+		return nil
+	}
+
+	return f.OnBeforeSign(call)
+}
+
+func (f InterceptorFuncs) AfterSign(call *Call) error {
+	if f.OnAfterSign == nil {
+		return nil
+	}
+
+	return f.OnAfterSign(call)
+}
+
+func (f InterceptorFuncs) AfterResponse(call *Call) {
+	if f.OnAfterResponse != nil {
+		f.OnAfterResponse(call)
+	}
+}
+
+// Use appends interceptors to the chain, they run in the order they were added.
+func (t *Client) Use(interceptors ...Interceptor) {
+	t.interceptorsMutex.Lock()
+	defer t.interceptorsMutex.Unlock()
+
+	t.interceptors = append(t.interceptors, interceptors...)
+}
+
+func (t *Client) interceptorChain() []Interceptor {
+	t.interceptorsMutex.RLock()
+	defer t.interceptorsMutex.RUnlock()
+
+	return t.interceptors
+}
+
+func newCall(httpMethod, requestPath string, requestBody interface{}) *Call {
+	return &Call{
+		Method:    httpMethod,
+		Path:      requestPath,
+		Body:      requestBody,
+		StartedAt: time.Now(),
+	}
+}
+
+func (t *Client) finishCall(call *Call, interceptors []Interceptor, err error) error {
+	err = t.redactError(err)
+
+	call.Latency = time.Since(call.StartedAt)
+	call.Err = err
+
+	var apiError ApiError
+	if errors.As(err, &apiError) {
+		call.ApiError = &apiError
+	}
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptors[i].AfterResponse(call)
+	}
+
+	return err
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+var numericPattern = regexp.MustCompile(`^[0-9]+$`)
+
+func EndpointLabel(httpMethod, requestPath string) string {
+	if index := strings.Index(requestPath, "?"); index >= 0 {
+		requestPath = requestPath[:index]
+	}
+
+	segments := strings.Split(requestPath, "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, "client:"):
+			segments[i] = ":client_oid"
+		case uuidPattern.MatchString(segment), numericPattern.MatchString(segment):
+			segments[i] = ":id"
+		}
+	}
+
+	return httpMethod + " " + strings.Join(segments, "/")
+}
@@ -0,0 +1,181 @@
+package coinbasepro
+
+import (
+	"bytes"
+	"errors"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+	t.Run("should run hooks in order with call details", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		client, err := NewClientWithOptions(ts.URL, testKey, testPassphrase, testSecret)
+		assert.Assert(t, is.Nil(err), "unexpected error creating client using NewClientWithOptions", err)
+
+		events := make([]string, 0)
+		record := func(name string) InterceptorFuncs {
+			return InterceptorFuncs{
+				OnBeforeSign: func(call *Call) error {
+					assert.Assert(t, call.Request == nil)
+					events = append(events, name+".before")
+					return nil
+				},
+				OnAfterSign: func(call *Call) error {
+					assert.Equal(t, call.Request.Header.Get(coinbaseProAccessKeyHeader), testKey)
+					events = append(events, name+".signed")
+					return nil
+				},
+				OnAfterResponse: func(call *Call) {
+					assert.Equal(t, call.StatusCode, http.StatusOK)
+					assert.Assert(t, call.Latency > 0)
+					assert.Assert(t, is.Nil(call.Err))
+					events = append(events, name+".after")
+				},
+			}
+		}
+		client.Use(record("first"), record("second"))
+
+		_, err = client.executeRequest("GET", "/test", nil, nil, 0)
+		assert.Assert(t, is.Nil(err), "unexpected error from client.executeRequest", err)
+
+		assert.DeepEqual(t, events, []string{"first.before", "second.before", "first.signed", "second.signed", "second.after", "first.after"})
+	})
+
+	t.Run("should allow rewriting the request before signing", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			assert.Equal(t, request.URL.Path, "/rewritten")
+			writer.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		client, err := NewClientWithOptions(ts.URL, testKey, testPassphrase, testSecret)
+		assert.Assert(t, is.Nil(err))
+
+		client.Use(InterceptorFuncs{OnBeforeSign: func(call *Call) error {
+			call.Path = "/rewritten"
+			return nil
+		}})
+
+		_, err = client.executeRequest("GET", "/test", nil, nil, 0)
+		assert.Assert(t, is.Nil(err))
+	})
+
+	t.Run("should abort calls rejected before signing", func(t *testing.T) {
+		requests := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			requests++
+			writer.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		client, err := NewClientWithOptions(ts.URL, testKey, testPassphrase, testSecret)
+		assert.Assert(t, is.Nil(err))
+
+		rejection := errors.New("rejected")
+		var finished *Call
+		client.Use(InterceptorFuncs{
+			OnBeforeSign:    func(call *Call) error { return rejection },
+			OnAfterResponse: func(call *Call) { finished = call },
+		})
+
+		_, err = client.executeRequest("POST", "/orders", nil, nil, 0)
+		assert.Assert(t, errors.Is(err, rejection))
+		assert.Equal(t, requests, 0)
+		assert.Assert(t, errors.Is(finished.Err, rejection))
+	})
+
+	t.Run("should report retries and decoded api errors", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusTooManyRequests)
+			writer.Write([]byte(`{"message":"Too Many Requests"}`))
+		}))
+		defer ts.Close()
+
+		client, err := NewClientWithOptions(ts.URL, testKey, testPassphrase, testSecret)
+		assert.Assert(t, is.Nil(err))
+
+		var finished *Call
+		client.Use(InterceptorFuncs{OnAfterResponse: func(call *Call) { finished = call }})
+
+		_, err = client.executeRequest("GET", "/orders/4a9b1f2c-3d4e-4f50-8a6b-7c8d9e0f1a2b", nil, nil, 3)
+		assert.Error(t, err, "429 - Too Many Requests")
+
+		assert.Equal(t, finished.Retries, 2)
+		assert.Equal(t, finished.Hits429, 3)
+		assert.Equal(t, finished.ApiError.Message, "Too Many Requests")
+		assert.Equal(t, finished.Endpoint(), "GET /orders/:id")
+	})
+}
+
+func TestEndpointLabel(t *testing.T) {
+	testCases := map[string]string{
+		"/products/BTC-USD/book?level=2":                        "GET /products/BTC-USD/book",
+		"/orders/client:4a9b1f2c-3d4e-4f50-8a6b-7c8d9e0f1a2b":   "GET /orders/:client_oid",
+		"/accounts/4a9b1f2c-3d4e-4f50-8a6b-7c8d9e0f1a2b/ledger": "GET /accounts/:id/ledger",
+		"/transfers/12345": "GET /transfers/:id",
+	}
+
+	for path, expected := range testCases {
+		assert.Equal(t, EndpointLabel("GET", path), expected)
+	}
+}
+
+func TestLoggingInterceptor(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusBadRequest)
+		writer.Write([]byte(`{"message":"size too small"}`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClientWithOptions(ts.URL, testLongKey, testPassphrase, testSecret)
+	assert.Assert(t, is.Nil(err))
+
+	output := bytes.Buffer{}
+	interceptor := NewLoggingInterceptor(log.New(&output, "", 0))
+	interceptor.Debug = true
+	client.Use(interceptor)
+
+	_, err = client.executeRequest("POST", "/orders", nil, nil, 0)
+	assert.Error(t, err, "400 - size too small")
+
+	line := output.String()
+	assert.Assert(t, strings.Contains(line, "method=POST path=/orders"), line)
+	assert.Assert(t, strings.Contains(line, "status=400"), line)
+	assert.Assert(t, strings.Contains(line, `api_error="size too small"`), line)
+	assert.Assert(t, strings.Contains(line, "header.cb-access-passphrase="+redactedValue), line)
+	assert.Assert(t, !strings.Contains(line, testPassphrase), line)
+	assert.Assert(t, !strings.Contains(line, testLongKey), line)
+}
+
+func TestTimingInterceptor(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client, err := NewClientWithOptions(ts.URL, testKey, testPassphrase, testSecret)
+	assert.Assert(t, is.Nil(err))
+
+	timing := NewTimingInterceptor()
+	client.Use(timing)
+
+	for i := 0; i < 3; i++ {
+		_, err = client.executeRequest("GET", "/time", nil, nil, 0)
+		assert.Assert(t, is.Nil(err))
+	}
+
+	timings := timing.Timings()
+	assert.Equal(t, timings["GET /time"].Calls, 3)
+	assert.Assert(t, timings["GET /time"].AverageLatency() > 0)
+	assert.Assert(t, timings["GET /time"].MinLatency <= timings["GET /time"].MaxLatency)
+}
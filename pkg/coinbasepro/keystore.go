@@ -0,0 +1,217 @@
+package coinbasepro
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+const keystoreVersion = 1
+const keystoreKdf = "pbkdf2-sha256"
+const keystoreKeyLength = 32
+const keystoreSaltLength = 16
+const defaultKeystoreIterations = 210000
+const maxKeystoreIterations = 10000000
+const defaultKeystoreAlias = "default"
+
+const KeystoreDecryptionErrorMessage = "unable to decrypt keystore, wrong password or corrupted file"
+const KeystoreAliasNotFoundErrorMessage = "keystore has no credentials for alias"
+const MissingKeystorePasswordErrorMessage = "keystore password is required"
+
+type keystoreFile struct {
+	Version    int    `json:"version"`
+	Kdf        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// KeystoreCredentialProvider reads credentials from a local file encrypted with AES-256-GCM under a
+// key derived from Password, so secrets are never stored in plain text on disk.
+type KeystoreCredentialProvider struct {
+	Path     string
+	Password string
+	Alias    string
+}
+
+func NewKeystoreCredentialProvider(path, password, alias string) *KeystoreCredentialProvider {
+	if alias == "" {
+		alias = defaultKeystoreAlias
+	}
+
+	return &KeystoreCredentialProvider{Path: path, Password: password, Alias: alias}
+}
+
+func (p *KeystoreCredentialProvider) Credentials() (Credentials, error) {
+	entries, err := ReadKeystore(p.Path, p.Password)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	alias := p.Alias
+	if alias == "" {
+		alias = defaultKeystoreAlias
+	}
+
+	credentials, found := entries[alias]
+	if !found {
+		return Credentials{}, fmt.Errorf("%s: %s", KeystoreAliasNotFoundErrorMessage, alias)
+	}
+
+	return credentials, nil
+}
+
+func (p *KeystoreCredentialProvider) String() string {
+	return fmt.Sprintf("KeystoreCredentialProvider{Path: %s, Password: %s, Alias: %s}", p.Path, redact(p.Password), p.Alias)
+}
+
+func (p *KeystoreCredentialProvider) GoString() string {
+	return p.String()
+}
+
+func ReadKeystore(path, password string) (map[string]Credentials, error) {
+	if password == "" {
+		return nil, errors.New(MissingKeystorePasswordErrorMessage)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file keystoreFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("invalid keystore %s: %w", path, err)
+	}
+
+	if file.Version != keystoreVersion || file.Kdf != keystoreKdf || file.Iterations < 1 || file.Iterations > maxKeystoreIterations {
+		return nil, fmt.Errorf("unsupported keystore %s", path)
+	}
+
+	gcm, err := newKeystoreCipher(password, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(file.Nonce) != gcm.NonceSize() {
+		return nil, errors.New(KeystoreDecryptionErrorMessage)
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New(KeystoreDecryptionErrorMessage)
+	}
+
+	entries := make(map[string]Credentials)
+	if err := json.Unmarshal(plaintext, &entries); err != nil {
+		return nil, errors.New(KeystoreDecryptionErrorMessage)
+	}
+
+	return entries, nil
+}
+
+func WriteKeystore(path, password string, entries map[string]Credentials) error {
+	return writeKeystore(path, password, entries, defaultKeystoreIterations)
+}
+
+func writeKeystore(path, password string, entries map[string]Credentials, iterations int) error {
+	if password == "" {
+		return errors.New(MissingKeystorePasswordErrorMessage)
+	}
+
+	plaintext, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, keystoreSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	gcm, err := newKeystoreCipher(password, salt, iterations)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	file := keystoreFile{
+		Version:    keystoreVersion,
+		Kdf:        keystoreKdf,
+		Iterations: iterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}
+
+	contents, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporaryPath := path + ".tmp"
+	if err := ioutil.WriteFile(temporaryPath, contents, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryPath, path)
+}
+
+func newKeystoreCipher(password string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key := pbkdf2Sha256([]byte(password), salt, iterations, keystoreKeyLength)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2Sha256 implements RFC 8018 PBKDF2 with HMAC-SHA256 as the pseudorandom function.
+func pbkdf2Sha256(password, salt []byte, iterations, keyLength int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLength := prf.Size()
+	blocks := (keyLength + hashLength - 1) / hashLength
+
+	derived := make([]byte, 0, blocks*hashLength)
+	counter := make([]byte, 4)
+	for block := 1; block <= blocks; block++ {
+		binary.BigEndian.PutUint32(counter, uint32(block))
+
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(counter)
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+
+		for iteration := 1; iteration < iterations; iteration++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+
+		derived = append(derived, t...)
+	}
+
+	return derived[:keyLength]
+}
@@ -0,0 +1,520 @@
+package coinbasepro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const TradingHaltedErrorMessage = "trading is halted by the kill switch"
+const DuplicateKillSwitchTargetErrorMessage = "a kill switch target is already registered with this name"
+
+const (
+	KillSwitchStepEngaged        = "engaged"
+	KillSwitchStepBlocked        = "blocked_order"
+	KillSwitchStepListFailed     = "list_failed"
+	KillSwitchStepCancelled      = "cancelled"
+	KillSwitchStepCancelFailed   = "cancel_failed"
+	KillSwitchStepConfirmed      = "confirmed"
+	KillSwitchStepUnconfirmed    = "unconfirmed"
+	KillSwitchStepFlattened      = "flattened"
+	KillSwitchStepFlattenFailed  = "flatten_failed"
+	KillSwitchStepFlattenSkipped = "flatten_skipped"
+	KillSwitchStepCompleted      = "completed"
+	KillSwitchStepReset          = "reset"
+)
+
+var openOrderStatuses = []string{"open", "pending", "active"}
+
+// KillSwitchEvent is one step taken by the kill switch, every event is written to the audit store.
+type KillSwitchEvent struct {
+	Time    time.Time `json:"time"`
+	Step    string    `json:"step"`
+	Target  string    `json:"target,omitempty"`
+	OrderId string    `json:"order_id,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+type KillSwitchReport struct {
+	Reason      string              `json:"reason"`
+	StartedAt   time.Time           `json:"started_at"`
+	FinishedAt  time.Time           `json:"finished_at"`
+	Cancelled   map[string][]string `json:"cancelled"`
+	Unconfirmed []string            `json:"unconfirmed,omitempty"`
+	Flattened   []Order             `json:"flattened,omitempty"`
+}
+
+// Confirmed reports whether every target was seen without open orders.
+func (r KillSwitchReport) Confirmed() bool {
+	return len(r.Unconfirmed) == 0
+}
+
+type killSwitchTarget struct {
+	name string
+	api  TradingAPI
+}
+
+// KillSwitch halts new order placement and cancels every open order on all of its targets, one
+// per profile. It is an Interceptor: add it to each Client, Add does that for clients, to reject
+// orders once engaged. Orders the kill switch places itself while flattening are let through.
+// Targets that are not a *Client, such as a PaperClient, have their orders cancelled but keep
+// accepting new ones, whatever places orders on them has to check Halted itself.
+type KillSwitch struct {
+	Logger *log.Logger
+	// MaxAttempts bounds the rounds of listing and cancelling per target, RetryDelay is the pause
+	// between rounds.
+	MaxAttempts int
+	RetryDelay  time.Duration
+	// FlattenProducts are sold at market for the available base currency balance once orders are
+	// cancelled, on targets that also implement AccountsAPI. Products sharing a base currency split
+	// the balance, the first one sells all of it. Pass Exempt to RiskInterceptor.SetExemption on
+	// the targets' risk interceptors or their limits can block the sells.
+	FlattenProducts []string
+	// Products rounds flatten sizes down to base_increment and skips sizes below base_min_size.
+	// Without it the balance is sold as it is.
+	Products *ProductCatalogue
+
+	audit Store
+
+	mutex     sync.Mutex
+	targets   []killSwitchTarget
+	halted    bool
+	reason    string
+	exempt    map[string]bool
+	events    []KillSwitchEvent
+	engageRun sync.Mutex
+}
+
+// NewKillSwitch records every step to audit, which may be nil to only log them.
+func NewKillSwitch(audit Store, logger *log.Logger) *KillSwitch {
+	if logger == nil {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	return &KillSwitch{
+		Logger:      logger,
+		MaxAttempts: 5,
+		RetryDelay:  time.Second,
+		audit:       audit,
+		exempt:      make(map[string]bool),
+	}
+}
+
+// Add registers a target whose orders are cancelled when the switch is engaged. Clients get the
+// kill switch added as an interceptor so they stop placing orders, other targets are not blocked.
+func (k *KillSwitch) Add(name string, api TradingAPI) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	for _, target := range k.targets {
+		if target.name == name {
+			return errors.New(DuplicateKillSwitchTargetErrorMessage)
+		}
+	}
+
+	k.targets = append(k.targets, killSwitchTarget{name: name, api: api})
+	sort.Slice(k.targets, func(i, j int) bool {
+		return k.targets[i].name < k.targets[j].name
+	})
+
+	if client, ok := api.(*Client); ok {
+		client.Use(k)
+	}
+
+	return nil
+}
+
+// AddPool registers every client of pool under its alias.
+func (k *KillSwitch) AddPool(pool *ClientPool) error {
+	return pool.Each(func(alias string, client *Client) error {
+		return k.Add(alias, client)
+	})
+}
+
+func (k *KillSwitch) Halted() bool {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	return k.halted
+}
+
+// Events returns every step recorded since the kill switch was created.
+func (k *KillSwitch) Events() []KillSwitchEvent {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	return append([]KillSwitchEvent(nil), k.events...)
+}
+
+func (k *KillSwitch) record(event KillSwitchEvent) {
+	event.Time = time.Now().UTC()
+
+	k.mutex.Lock()
+	k.events = append(k.events, event)
+	k.mutex.Unlock()
+
+	fields := []string{logField("kill_switch", event.Step)}
+	for _, field := range [][2]string{{"target", event.Target}, {"order_id", event.OrderId}, {"detail", event.Detail}, {"error", event.Error}} {
+		if field[1] != "" {
+			fields = append(fields, logField(field[0], field[1]))
+		}
+	}
+	k.Logger.Println(strings.Join(fields, " "))
+
+	if k.audit == nil {
+		return
+	}
+
+	record, err := NewRecord(RecordKindKillSwitch, event)
+	if err == nil {
+		_, err = k.audit.Append(record)
+	}
+
+	if err != nil {
+		k.Logger.Println(logField("kill_switch", "audit_failed"), logField("error", err.Error()))
+	}
+}
+
+// Engage halts trading, then cancels open orders on every target until none are left or
+// MaxAttempts is reached, and finally flattens FlattenProducts. Trading stays halted until Reset.
+func (k *KillSwitch) Engage(reason string) KillSwitchReport {
+	k.mutex.Lock()
+	k.halted = true
+	k.reason = reason
+	targets := append([]killSwitchTarget(nil), k.targets...)
+	k.mutex.Unlock()
+
+	k.engageRun.Lock()
+	defer k.engageRun.Unlock()
+
+	report := KillSwitchReport{Reason: reason, StartedAt: time.Now().UTC(), Cancelled: make(map[string][]string)}
+	k.record(KillSwitchEvent{Step: KillSwitchStepEngaged, Detail: reason})
+
+	for _, target := range targets {
+		cancelled, confirmed := k.cancelAll(target)
+		report.Cancelled[target.name] = cancelled
+		if !confirmed {
+			report.Unconfirmed = append(report.Unconfirmed, target.name)
+		}
+	}
+
+	for _, target := range targets {
+		report.Flattened = append(report.Flattened, k.flatten(target)...)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	k.record(KillSwitchEvent{Step: KillSwitchStepCompleted, Detail: fmt.Sprintf("cancelled=%d unconfirmed=%d flattened=%d", countCancelled(report), len(report.Unconfirmed), len(report.Flattened))})
+
+	return report
+}
+
+func countCancelled(report KillSwitchReport) int {
+	count := 0
+	for _, ids := range report.Cancelled {
+		count += len(ids)
+	}
+
+	return count
+}
+
+// cancelAll cancels open orders until a listing comes back empty.
+func (k *KillSwitch) cancelAll(target killSwitchTarget) ([]string, bool) {
+	cancelled := make([]string, 0)
+	for attempt := 1; attempt <= k.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(k.RetryDelay)
+		}
+
+		orders, err := target.api.ListOrders(OrderFilter{Statuses: openOrderStatuses})
+		if err != nil {
+			k.record(KillSwitchEvent{Step: KillSwitchStepListFailed, Target: target.name, Detail: fmt.Sprintf("attempt=%d", attempt), Error: err.Error()})
+			continue
+		}
+
+		if len(orders) == 0 {
+			k.record(KillSwitchEvent{Step: KillSwitchStepConfirmed, Target: target.name, Detail: fmt.Sprintf("attempt=%d", attempt)})
+			return cancelled, true
+		}
+
+		for _, order := range orders {
+			if err := target.api.CancelOrder(order.Id); err != nil {
+				k.record(KillSwitchEvent{Step: KillSwitchStepCancelFailed, Target: target.name, OrderId: order.Id, Error: err.Error()})
+				continue
+			}
+
+			cancelled = append(cancelled, order.Id)
+			k.record(KillSwitchEvent{Step: KillSwitchStepCancelled, Target: target.name, OrderId: order.Id, Detail: order.ProductId})
+		}
+	}
+
+	k.record(KillSwitchEvent{Step: KillSwitchStepUnconfirmed, Target: target.name, Detail: fmt.Sprintf("attempts=%d", k.MaxAttempts)})
+	return cancelled, false
+}
+
+func (k *KillSwitch) flatten(target killSwitchTarget) []Order {
+	flattened := make([]Order, 0)
+	if len(k.FlattenProducts) == 0 {
+		return flattened
+	}
+
+	accountsApi, ok := target.api.(AccountsAPI)
+	if !ok {
+		return flattened
+	}
+
+	accounts, err := accountsApi.GetAccounts()
+	if err != nil {
+		k.record(KillSwitchEvent{Step: KillSwitchStepFlattenFailed, Target: target.name, Error: err.Error()})
+		return flattened
+	}
+
+	available := make(map[string]string)
+	for _, account := range accounts {
+		available[account.Currency] = account.Available
+	}
+
+	used := make(map[string]float64)
+	for _, productId := range k.FlattenProducts {
+		base, _, err := splitProductId(productId)
+		if err != nil {
+			k.record(KillSwitchEvent{Step: KillSwitchStepFlattenFailed, Target: target.name, Detail: productId, Error: err.Error()})
+			continue
+		}
+
+		balance, err := parseDecimal(available[base])
+		if err != nil || balance-used[base] <= incrementEpsilon {
+			continue
+		}
+
+		size, sizeValue, err := k.flattenSize(productId, balance-used[base])
+		if err != nil {
+			k.record(KillSwitchEvent{Step: KillSwitchStepFlattenFailed, Target: target.name, Detail: productId, Error: err.Error()})
+			continue
+		}
+
+		if size == "" {
+			k.record(KillSwitchEvent{Step: KillSwitchStepFlattenSkipped, Target: target.name, Detail: fmt.Sprintf("%s %s %s below base_min_size", productId, formatDecimal(balance-used[base], -1), base)})
+			continue
+		}
+
+		order := Order{
+			ClientOid: NewClientOid(""),
+			Type:      OrderTypeMarket,
+			Side:      OrderSideSell,
+			ProductId: productId,
+			Size:      size,
+		}
+
+		k.mutex.Lock()
+		k.exempt[order.ClientOid] = true
+		k.mutex.Unlock()
+
+		placed, err := target.api.PlaceOrder(order)
+
+		k.mutex.Lock()
+		delete(k.exempt, order.ClientOid)
+		k.mutex.Unlock()
+
+		if err != nil {
+			k.record(KillSwitchEvent{Step: KillSwitchStepFlattenFailed, Target: target.name, Detail: productId, Error: err.Error()})
+			continue
+		}
+
+		used[base] += sizeValue
+		flattened = append(flattened, placed)
+		k.record(KillSwitchEvent{Step: KillSwitchStepFlattened, Target: target.name, OrderId: placed.Id, Detail: fmt.Sprintf("%s sell %s", productId, order.Size)})
+	}
+
+	return flattened
+}
+
+// flattenSize is the size to sell of what is left of a balance, empty when it is too small to sell.
+func (k *KillSwitch) flattenSize(productId string, remaining float64) (string, float64, error) {
+	if k.Products == nil {
+		return formatDecimal(remaining, -1), remaining, nil
+	}
+
+	product, err := k.Products.Product(productId)
+	if err != nil {
+		return "", 0, err
+	}
+
+	increment, err := parseDecimal(product.BaseIncrement)
+	if err != nil {
+		return "", 0, err
+	}
+
+	minSize := 0.0
+	if product.BaseMinSize != "" {
+		if minSize, err = parseDecimal(product.BaseMinSize); err != nil {
+			return "", 0, err
+		}
+	}
+
+	sizeValue := floorToIncrement(remaining, increment)
+	if sizeValue <= incrementEpsilon || sizeValue < minSize-incrementEpsilon {
+		return "", 0, nil
+	}
+
+	return formatDecimal(sizeValue, decimalPlaces(product.BaseIncrement)), sizeValue, nil
+}
+
+// Reset lets orders through again, it does not restore anything that was cancelled.
+func (k *KillSwitch) Reset(reason string) {
+	k.mutex.Lock()
+	k.halted = false
+	k.reason = ""
+	k.mutex.Unlock()
+
+	k.record(KillSwitchEvent{Step: KillSwitchStepReset, Detail: reason})
+}
+
+// Exempt reports whether order is one the kill switch is placing while flattening.
+func (k *KillSwitch) Exempt(order Order) bool {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	return order.ClientOid != "" && k.exempt[order.ClientOid]
+}
+
+func (k *KillSwitch) BeforeSign(call *Call) error {
+	if call.Method != "POST" || call.Path != "/orders" {
+		return nil
+	}
+
+	clientOid := ""
+	switch body := call.Body.(type) {
+	case Order:
+		clientOid = body.ClientOid
+	case *Order:
+		clientOid = body.ClientOid
+	}
+
+	k.mutex.Lock()
+	blocked := k.halted && (clientOid == "" || !k.exempt[clientOid])
+	k.mutex.Unlock()
+
+	if blocked {
+		k.record(KillSwitchEvent{Step: KillSwitchStepBlocked, Detail: clientOid})
+		return errors.New(TradingHaltedErrorMessage)
+	}
+
+	return nil
+}
+
+func (k *KillSwitch) AfterSign(call *Call) error {
+	return nil
+}
+
+func (k *KillSwitch) AfterResponse(call *Call) {
+}
+
+// WatchSignals engages the kill switch whenever one of signals is received, SIGUSR1 when none
+// are given. It returns a function that stops watching.
+func (k *KillSwitch) WatchSignals(signals ...os.Signal) func() {
+	if len(signals) == 0 {
+		signals = defaultKillSwitchSignals
+	}
+
+	// signal.Notify without signals would relay every signal
+	if len(signals) == 0 {
+		return func() {}
+	}
+
+	received := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(received, signals...)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-received:
+				k.Engage("signal " + sig.String())
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(received)
+			close(done)
+		})
+	}
+}
+
+// WatchFile engages the kill switch when a flag file appears at path. The file is left in place,
+// remove it before calling Reset or the switch engages again.
+func (k *KillSwitch) WatchFile(path string, interval time.Duration) func() {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := os.Stat(path); err == nil && !k.Halted() {
+				k.Engage("flag file " + path)
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+type killSwitchStatus struct {
+	Halted bool   `json:"halted"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Handler serves the kill switch over http: GET returns whether trading is halted, POST engages
+// the switch and returns the report and DELETE resets it. The optional reason query parameter is
+// recorded in the audit log.
+func (k *KillSwitch) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		reason := request.URL.Query().Get("reason")
+
+		var body interface{}
+		switch request.Method {
+		case http.MethodGet:
+			k.mutex.Lock()
+			body = killSwitchStatus{Halted: k.halted, Reason: k.reason}
+			k.mutex.Unlock()
+		case http.MethodPost:
+			if reason == "" {
+				reason = "http " + request.RemoteAddr
+			}
+			body = k.Engage(reason)
+		case http.MethodDelete:
+			k.Reset(reason)
+			body = killSwitchStatus{Halted: false}
+		default:
+			writer.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(body)
+	})
+}
@@ -0,0 +1,255 @@
+package coinbasepro
+
+import (
+	"encoding/json"
+	"errors"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// flakyTrading fails the first listings and every cancellation of the orders in failCancels.
+type flakyTrading struct {
+	TradingAPI
+	failListings int
+	failCancels  map[string]bool
+}
+
+func (f *flakyTrading) ListOrders(filter OrderFilter) ([]Order, error) {
+	if f.failListings > 0 {
+		f.failListings--
+		return nil, errors.New("connection reset")
+	}
+
+	return f.TradingAPI.ListOrders(filter)
+}
+
+func (f *flakyTrading) CancelOrder(orderId string) error {
+	if f.failCancels[orderId] {
+		return errors.New("503 - service unavailable")
+	}
+
+	return f.TradingAPI.CancelOrder(orderId)
+}
+
+func newTestKillSwitch(t *testing.T) (*KillSwitch, *JournalStore) {
+	store, err := OpenJournalStore(filepath.Join(t.TempDir(), "audit.jsonl"))
+	assert.Assert(t, is.Nil(err))
+	t.Cleanup(func() { store.Close() })
+
+	killSwitch := NewKillSwitch(store, log.New(ioutil.Discard, "", 0))
+	killSwitch.RetryDelay = 0
+
+	return killSwitch, store
+}
+
+func auditSteps(t *testing.T, store *JournalStore) []string {
+	steps := make([]string, 0)
+	err := store.Replay(func(record Record) error {
+		var event KillSwitchEvent
+		if err := record.Decode(&event); err != nil {
+			return err
+		}
+
+		assert.Equal(t, record.Kind, RecordKindKillSwitch)
+		steps = append(steps, event.Step)
+		return nil
+	})
+	assert.Assert(t, is.Nil(err))
+
+	return steps
+}
+
+func TestKillSwitch(t *testing.T) {
+	t.Run("should halt trading and cancel every open order", func(t *testing.T) {
+		killSwitch, store := newTestKillSwitch(t)
+
+		exchange := newFakeExchange(t)
+		client := exchange.client(t)
+		paper := newTestPaperClient(t)
+		assert.Assert(t, is.Nil(killSwitch.Add("main", client)))
+		assert.Assert(t, is.Nil(killSwitch.Add("paper", &flakyTrading{TradingAPI: paper, failListings: 1})))
+		assert.Error(t, killSwitch.Add("main", client), DuplicateKillSwitchTargetErrorMessage)
+
+		open, err := client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+		resting, err := paper.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "90", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		report := killSwitch.Engage("test")
+		assert.Assert(t, report.Confirmed())
+		assert.DeepEqual(t, report.Cancelled, map[string][]string{"main": {open.Id}, "paper": {resting.Id}})
+		assert.Assert(t, killSwitch.Halted())
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "1"})
+		assert.Error(t, err, TradingHaltedErrorMessage)
+		assert.Equal(t, len(exchange.orders), 1)
+
+		killSwitch.Reset("resolved")
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		assert.DeepEqual(t, auditSteps(t, store), []string{
+			KillSwitchStepEngaged,
+			KillSwitchStepCancelled, KillSwitchStepConfirmed,
+			KillSwitchStepListFailed, KillSwitchStepCancelled, KillSwitchStepConfirmed,
+			KillSwitchStepCompleted, KillSwitchStepBlocked, KillSwitchStepReset,
+		})
+	})
+
+	t.Run("should report targets whose orders could not be cancelled", func(t *testing.T) {
+		killSwitch, _ := newTestKillSwitch(t)
+		killSwitch.MaxAttempts = 3
+
+		paper := newTestPaperClient(t)
+		stuck, err := paper.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "90", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(killSwitch.Add("paper", &flakyTrading{TradingAPI: paper, failCancels: map[string]bool{stuck.Id: true}})))
+
+		report := killSwitch.Engage("test")
+		assert.Assert(t, !report.Confirmed())
+		assert.DeepEqual(t, report.Unconfirmed, []string{"paper"})
+
+		failures := 0
+		for _, event := range killSwitch.Events() {
+			if event.Step == KillSwitchStepCancelFailed {
+				failures++
+			}
+		}
+		assert.Equal(t, failures, 3)
+	})
+
+	t.Run("should flatten positions with market orders while halted", func(t *testing.T) {
+		killSwitch, _ := newTestKillSwitch(t)
+		killSwitch.FlattenProducts = []string{"BTC-USD", "ETH-USD"}
+
+		exchange := newFakeExchange(t)
+		exchange.setAccounts(
+			Account{Id: "1", Currency: "BTC", Balance: "0.5", Available: "0.5", Hold: "0"},
+			Account{Id: "2", Currency: "ETH", Balance: "0", Available: "0", Hold: "0"},
+		)
+		assert.Assert(t, is.Nil(killSwitch.Add("main", exchange.client(t))))
+
+		report := killSwitch.Engage("test")
+		assert.Equal(t, len(report.Flattened), 1)
+		assert.Equal(t, report.Flattened[0].ProductId, "BTC-USD")
+		assert.Equal(t, report.Flattened[0].Type, OrderTypeMarket)
+		assert.Equal(t, report.Flattened[0].Side, OrderSideSell)
+		assert.Equal(t, report.Flattened[0].Size, "0.5")
+	})
+
+	t.Run("should flatten past the risk limits", func(t *testing.T) {
+		killSwitch, _ := newTestKillSwitch(t)
+		killSwitch.FlattenProducts = []string{"BTC-USD"}
+
+		client, risk, exchange, _ := newTestRiskClient(t, RiskLimits{MaxDailyLoss: 10, MaxOrderNotional: 1000})
+		risk.SetPosition("BTC-USD", 1, 150)
+		risk.HandleMessage(Message{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 1, MakerOrderId: "other", TakerOrderId: "another", Price: "100", Size: "1"})
+		sell, err := client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideSell, Price: "100", Size: "0.5"})
+		assert.Assert(t, is.Nil(err))
+		risk.HandleMessage(Message{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 2, MakerOrderId: sell.Id, Price: "100", Size: "0.5"})
+		assert.Equal(t, risk.DailyPnl(), -25.0)
+
+		exchange.setAccounts(Account{Id: "1", Currency: "BTC", Balance: "0.5", Available: "0.5", Hold: "0"})
+		assert.Assert(t, is.Nil(killSwitch.Add("main", client)))
+
+		report := killSwitch.Engage("test")
+		assert.Equal(t, len(report.Flattened), 0)
+
+		killSwitch.Reset("test")
+		risk.SetExemption(killSwitch.Exempt)
+		report = killSwitch.Engage("test")
+		assert.Equal(t, len(report.Flattened), 1)
+		assert.Equal(t, report.Flattened[0].Size, "0.5")
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideSell, Type: OrderTypeMarket, Size: "0.5"})
+		assertRejected(t, err, RiskViolationDailyLoss)
+	})
+
+	t.Run("should flatten sizes the products accept", func(t *testing.T) {
+		killSwitch, _ := newTestKillSwitch(t)
+		killSwitch.FlattenProducts = []string{"BTC-USD", "ETH-BTC"}
+		killSwitch.Products = newTestProductCatalogue(t)
+
+		exchange := newFakeExchange(t)
+		exchange.setAccounts(
+			Account{Id: "1", Currency: "BTC", Balance: "0.123456789", Available: "0.123456789", Hold: "0"},
+			Account{Id: "2", Currency: "ETH", Balance: "0.0125", Available: "0.0125", Hold: "0"},
+		)
+		assert.Assert(t, is.Nil(killSwitch.Add("main", exchange.client(t))))
+
+		report := killSwitch.Engage("test")
+		assert.Equal(t, len(report.Flattened), 2)
+		assert.Equal(t, report.Flattened[0].Size, "0.12345678")
+		assert.Equal(t, report.Flattened[1].Size, "0.012")
+
+		killSwitch.Reset("test")
+		exchange.setAccounts(Account{Id: "1", Currency: "BTC", Balance: "0.00005", Available: "0.00005", Hold: "0"})
+
+		report = killSwitch.Engage("test")
+		assert.Equal(t, len(report.Flattened), 0)
+		events := killSwitch.Events()
+		assert.Equal(t, events[len(events)-2].Step, KillSwitchStepFlattenSkipped)
+		assert.Equal(t, events[len(events)-2].Detail, "BTC-USD 0.00005 BTC below base_min_size")
+	})
+
+	t.Run("should not sell a balance twice for products with the same base currency", func(t *testing.T) {
+		killSwitch, _ := newTestKillSwitch(t)
+		killSwitch.FlattenProducts = []string{"BTC-USD", "BTC-EUR"}
+
+		exchange := newFakeExchange(t)
+		exchange.setAccounts(Account{Id: "1", Currency: "BTC", Balance: "0.5", Available: "0.5", Hold: "0"})
+		assert.Assert(t, is.Nil(killSwitch.Add("main", exchange.client(t))))
+
+		report := killSwitch.Engage("test")
+		assert.Equal(t, len(report.Flattened), 1)
+		assert.Equal(t, report.Flattened[0].ProductId, "BTC-USD")
+		assert.Equal(t, report.Flattened[0].Size, "0.5")
+	})
+
+	t.Run("should be triggered over http and by a flag file", func(t *testing.T) {
+		killSwitch, _ := newTestKillSwitch(t)
+		server := httptest.NewServer(killSwitch.Handler())
+		defer server.Close()
+
+		response, err := http.Post(server.URL+"?reason=drill", "application/json", nil)
+		assert.Assert(t, is.Nil(err))
+		var report KillSwitchReport
+		assert.Assert(t, is.Nil(json.NewDecoder(response.Body).Decode(&report)))
+		response.Body.Close()
+		assert.Equal(t, report.Reason, "drill")
+
+		response, err = http.Get(server.URL)
+		assert.Assert(t, is.Nil(err))
+		var status killSwitchStatus
+		assert.Assert(t, is.Nil(json.NewDecoder(response.Body).Decode(&status)))
+		response.Body.Close()
+		assert.DeepEqual(t, status, killSwitchStatus{Halted: true, Reason: "drill"})
+
+		request, _ := http.NewRequest(http.MethodDelete, server.URL, nil)
+		response, err = http.DefaultClient.Do(request)
+		assert.Assert(t, is.Nil(err))
+		response.Body.Close()
+		assert.Assert(t, !killSwitch.Halted())
+
+		path := filepath.Join(t.TempDir(), "HALT")
+		stop := killSwitch.WatchFile(path, 5*time.Millisecond)
+		defer stop()
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Assert(t, !killSwitch.Halted())
+
+		assert.Assert(t, is.Nil(ioutil.WriteFile(path, nil, 0644)))
+		deadline := time.Now().Add(2 * time.Second)
+		for !killSwitch.Halted() && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.Assert(t, killSwitch.Halted())
+	})
+}
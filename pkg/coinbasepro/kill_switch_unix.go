@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package coinbasepro
+
+import (
+	"os"
+	"syscall"
+)
+
+var defaultKillSwitchSignals = []os.Signal{syscall.SIGUSR1}
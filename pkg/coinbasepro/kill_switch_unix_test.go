@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package coinbasepro
+
+import (
+	"gotest.tools/v3/assert"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestKillSwitchSignal(t *testing.T) {
+	killSwitch, _ := newTestKillSwitch(t)
+	stop := killSwitch.WatchSignals()
+	defer stop()
+
+	assert.Assert(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1) == nil)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !killSwitch.Halted() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Assert(t, killSwitch.Halted())
+	assert.Equal(t, killSwitch.Events()[0].Detail, "signal user defined signal 1")
+}
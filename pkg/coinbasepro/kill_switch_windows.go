@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package coinbasepro
+
+import (
+	"os"
+)
+
+// Windows has no SIGUSR1, the kill switch only watches signals that are passed explicitly.
+var defaultKillSwitchSignals = []os.Signal{}
@@ -0,0 +1,77 @@
+package coinbasepro
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LoggingInterceptor writes one logfmt line per api call. With Debug set the redacted request
+// headers are logged as well.
+type LoggingInterceptor struct {
+	Logger *log.Logger
+	Debug  bool
+}
+
+func NewLoggingInterceptor(logger *log.Logger) *LoggingInterceptor {
+	if logger == nil {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	return &LoggingInterceptor{Logger: logger}
+}
+
+func (l *LoggingInterceptor) BeforeSign(call *Call) error {
+	return nil
+}
+
+func (l *LoggingInterceptor) AfterSign(call *Call) error {
+	return nil
+}
+
+func (l *LoggingInterceptor) AfterResponse(call *Call) {
+	fields := []string{
+		logField("method", call.Method),
+		logField("path", call.Path),
+		logField("endpoint", call.Endpoint()),
+		logField("status", strconv.Itoa(call.StatusCode)),
+		logField("latency_ms", strconv.FormatFloat(float64(call.Latency.Microseconds())/1000, 'f', 3, 64)),
+		logField("retries", strconv.Itoa(call.Retries)),
+	}
+
+	if call.RateLimitWait > 0 {
+		fields = append(fields, logField("rate_limit_wait_ms", strconv.FormatInt(call.RateLimitWait.Milliseconds(), 10)))
+	}
+
+	if call.ApiError != nil {
+		fields = append(fields, logField("api_error", call.ApiError.Message))
+	} else if call.Err != nil {
+		fields = append(fields, logField("error", call.Err.Error()))
+	}
+
+	if l.Debug && call.Request != nil {
+		header := RedactedHeader(call.Request.Header)
+		names := make([]string, 0, len(header))
+		for name := range header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fields = append(fields, logField("header."+strings.ToLower(name), header.Get(name)))
+		}
+	}
+
+	l.Logger.Println(strings.Join(fields, " "))
+}
+
+func logField(key, value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\"=") {
+		return fmt.Sprintf("%s=%q", key, value)
+	}
+
+	return key + "=" + value
+}
@@ -0,0 +1,122 @@
+package coinbasepro
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+type Ticker struct {
+	TradeId int64  `json:"trade_id"`
+	Price   string `json:"price"`
+	Size    string `json:"size"`
+	Bid     string `json:"bid"`
+	Ask     string `json:"ask"`
+	Volume  string `json:"volume"`
+	Time    string `json:"time"`
+}
+
+type Trade struct {
+	TradeId int64  `json:"trade_id"`
+	Price   string `json:"price"`
+	Size    string `json:"size"`
+	Side    string `json:"side"`
+	Time    string `json:"time"`
+}
+
+// BookEntry is one row of a product book. Levels 1 and 2 aggregate NumOrders orders per price,
+// level 3 lists individual orders with their OrderId.
+type BookEntry struct {
+	Price     string
+	Size      string
+	NumOrders int
+	OrderId   string
+}
+
+func (e *BookEntry) UnmarshalJSON(data []byte) error {
+	var fields []interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	if len(fields) < 3 {
+		return fmt.Errorf("malformed book entry %s", data)
+	}
+
+	e.Price = fmt.Sprint(fields[0])
+	e.Size = fmt.Sprint(fields[1])
+
+	switch third := fields[2].(type) {
+	case float64:
+		e.NumOrders = int(third)
+	case string:
+		e.OrderId = third
+	}
+
+	return nil
+}
+
+// MarshalJSON writes the entry in the array form the exchange sends.
+func (e BookEntry) MarshalJSON() ([]byte, error) {
+	if e.OrderId != "" {
+		return json.Marshal([]interface{}{e.Price, e.Size, e.OrderId})
+	}
+
+	return json.Marshal([]interface{}{e.Price, e.Size, e.NumOrders})
+}
+
+type ProductBook struct {
+	Sequence int64       `json:"sequence"`
+	Bids     []BookEntry `json:"bids"`
+	Asks     []BookEntry `json:"asks"`
+}
+
+type ServerTime struct {
+	Iso   string  `json:"iso"`
+	Epoch float64 `json:"epoch"`
+}
+
+func (t *Client) GetTicker(productId string) (Ticker, error) {
+	var ticker Ticker
+	_, err := t.executeRequest("GET", fmt.Sprintf("/products/%s/ticker", productId), nil, &ticker, defaultMaxRetriesOn429)
+	if err != nil {
+		return Ticker{}, err
+	}
+
+	return ticker, nil
+}
+
+// GetProductBook returns the book at level 1 (best bid and ask), 2 (top 50 aggregated) or 3 (full).
+func (t *Client) GetProductBook(productId string, level int) (ProductBook, error) {
+	query := url.Values{}
+	query.Set("level", strconv.Itoa(level))
+
+	var book ProductBook
+	_, err := t.executeRequest("GET", fmt.Sprintf("/products/%s/book?%s", productId, query.Encode()), nil, &book, defaultMaxRetriesOn429)
+	if err != nil {
+		return ProductBook{}, err
+	}
+
+	return book, nil
+}
+
+func (t *Client) GetTrades(productId string) ([]Trade, error) {
+	var trades []Trade
+	_, err := t.executeRequest("GET", fmt.Sprintf("/products/%s/trades", productId), nil, &trades, defaultMaxRetriesOn429)
+	if err != nil {
+		return nil, err
+	}
+
+	return trades, nil
+}
+
+func (t *Client) GetTime() (ServerTime, error) {
+	var serverTime ServerTime
+	_, err := t.executeRequest("GET", "/time", nil, &serverTime, defaultMaxRetriesOn429)
+	if err != nil {
+		return ServerTime{}, err
+	}
+
+	return serverTime, nil
+}
@@ -0,0 +1,409 @@
+package coinbasepro
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const RecordSourceWebsocket = "websocket"
+const RecordSourceRest = "rest"
+
+const MarketDataRecorderClosedErrorMessage = "market data recorder is closed"
+
+const recordingExtension = ".ndjson.gz"
+const recordingIndexExtension = ".idx"
+const recordingDayLayout = "2006-01-02"
+
+// RecordedMessage is one websocket message or REST response as it was received. Payload is the
+// raw websocket message or the decoded REST response encoded again.
+type RecordedMessage struct {
+	Time      time.Time       `json:"time"`
+	Source    string          `json:"source"`
+	ProductId string          `json:"product_id"`
+	Path      string          `json:"path,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// recording is the open file of one product and day. Records are written in gzip members of
+// ChunkInterval each, and the index lists the time and offset every member starts at, so a replay
+// can start reading in the middle of a day.
+type recording struct {
+	day        string
+	file       *os.File
+	index      *os.File
+	chunk      *gzip.Writer
+	chunkStart time.Time
+}
+
+func (r *recording) closeChunk() error {
+	if r.chunk == nil {
+		return nil
+	}
+
+	err := r.chunk.Close()
+	r.chunk = nil
+	if err != nil {
+		return err
+	}
+
+	return r.file.Sync()
+}
+
+func (r *recording) close() error {
+	err := r.closeChunk()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if closeErr := r.index.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// MarketDataRecorder writes websocket messages and REST market data responses to gzip compressed
+// files of newline delimited JSON, one per product and UTC day under dir/<product_id>/. Feed it
+// with Feed.Record and by adding it to a Client with Use. It is safe for concurrent use.
+type MarketDataRecorder struct {
+	dir           string
+	chunkInterval time.Duration
+
+	// OnError receives errors writing records that came through the client or a feed, which never
+	// fail the api call or the read itself.
+	OnError func(err error)
+
+	mutex      sync.Mutex
+	now        func() time.Time
+	recordings map[string]*recording
+	closed     bool
+}
+
+// NewMarketDataRecorder records into dir, starting a new compressed chunk at most every
+// chunkInterval, a minute when zero. A record is only durable once its chunk is closed, by the
+// next chunk, Flush or Close.
+func NewMarketDataRecorder(dir string, chunkInterval time.Duration) (*MarketDataRecorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	if chunkInterval <= 0 {
+		chunkInterval = time.Minute
+	}
+
+	return &MarketDataRecorder{
+		dir:           dir,
+		chunkInterval: chunkInterval,
+		now:           time.Now,
+		recordings:    make(map[string]*recording),
+	}, nil
+}
+
+func (r *MarketDataRecorder) SetClock(now func() time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.now = now
+}
+
+// RecordWebsocket records a raw websocket message. Messages without a product, like subscription
+// confirmations, are skipped.
+func (r *MarketDataRecorder) RecordWebsocket(payload []byte) error {
+	var header struct {
+		ProductId string `json:"product_id"`
+	}
+
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return err
+	}
+
+	if header.ProductId == "" {
+		return nil
+	}
+
+	return r.write(RecordedMessage{Source: RecordSourceWebsocket, ProductId: header.ProductId, Payload: append(json.RawMessage(nil), payload...)})
+}
+
+func (r *MarketDataRecorder) write(record RecordedMessage) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return errors.New(MarketDataRecorderClosedErrorMessage)
+	}
+
+	if record.Time.IsZero() {
+		record.Time = r.now().UTC()
+	}
+
+	current, err := r.recording(record.ProductId, record.Time)
+	if err != nil {
+		return err
+	}
+
+	if current.chunk == nil || record.Time.Sub(current.chunkStart) >= r.chunkInterval {
+		if err := current.closeChunk(); err != nil {
+			return err
+		}
+
+		info, err := current.file.Stat()
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(current.index, "%d %d\n", record.Time.UnixNano(), info.Size()); err != nil {
+			return err
+		}
+
+		current.chunk = gzip.NewWriter(current.file)
+		current.chunkStart = record.Time
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = current.chunk.Write(append(line, '\n'))
+	return err
+}
+
+// recording returns the open file for a product on the day of at, closing the previous day's.
+func (r *MarketDataRecorder) recording(productId string, at time.Time) (*recording, error) {
+	day := at.UTC().Format(recordingDayLayout)
+	if current, found := r.recordings[productId]; found {
+		if current.day == day {
+			return current, nil
+		}
+
+		delete(r.recordings, productId)
+		if err := current.close(); err != nil {
+			return nil, err
+		}
+	}
+
+	productDir := filepath.Join(r.dir, productId)
+	if err := os.MkdirAll(productDir, 0700); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(productDir, day+recordingExtension)
+	if err := repairRecording(path); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := os.OpenFile(filepath.Join(productDir, day+recordingIndexExtension), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	current := &recording{day: day, file: file, index: index}
+	r.recordings[productId] = current
+
+	return current, nil
+}
+
+// repairRecording truncates a recording left by a crash back to its last complete chunk, so the
+// records appended after a restart are not hidden behind a torn chunk. Only the last chunk can be
+// torn, every earlier one was closed before the next was indexed.
+func repairRecording(path string) error {
+	indexPath := strings.TrimSuffix(path, recordingExtension) + recordingIndexExtension
+	info, err := os.Stat(path)
+	if err == nil {
+		_, err = os.Stat(indexPath)
+	}
+
+	// without both files there is nothing to line the chunks up with
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	entries, err := readRecordingIndex(path)
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	for len(entries) > 0 {
+		last := entries[len(entries)-1][1]
+		if last < size && completeChunk(path, last) {
+			break
+		}
+
+		if last < size {
+			size = last
+		}
+		entries = entries[:len(entries)-1]
+	}
+
+	if len(entries) == 0 {
+		size = 0
+	}
+
+	if size != info.Size() {
+		if err := os.Truncate(path, size); err != nil {
+			return err
+		}
+	}
+
+	var indexLength int64
+	for _, entry := range entries {
+		indexLength += int64(len(fmt.Sprintf("%d %d\n", entry[0], entry[1])))
+	}
+
+	return os.Truncate(indexPath, indexLength)
+}
+
+// completeChunk reports whether the gzip member at offset reads through to its trailer.
+func completeChunk(path string, offset int64) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return false
+	}
+
+	reader, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		return false
+	}
+	reader.Multistream(false)
+
+	_, err = io.Copy(ioutil.Discard, reader)
+	return err == nil
+}
+
+// Flush closes the open chunks so everything recorded so far is on disk.
+func (r *MarketDataRecorder) Flush() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var firstErr error
+	for _, current := range r.recordings {
+		if err := current.closeChunk(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func (r *MarketDataRecorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.closed = true
+
+	var firstErr error
+	for productId, current := range r.recordings {
+		if err := current.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(r.recordings, productId)
+	}
+
+	return firstErr
+}
+
+// marketDataProduct returns the product of a REST market data path like /products/BTC-USD/book.
+func marketDataProduct(requestPath string) (string, bool) {
+	if index := strings.Index(requestPath, "?"); index >= 0 {
+		requestPath = requestPath[:index]
+	}
+
+	segments := strings.Split(strings.Trim(requestPath, "/"), "/")
+	if len(segments) != 3 || segments[0] != "products" {
+		return "", false
+	}
+
+	switch segments[2] {
+	case "ticker", "trades", "book":
+		return segments[1], true
+	}
+
+	return "", false
+}
+
+func (r *MarketDataRecorder) BeforeSign(call *Call) error {
+	return nil
+}
+
+func (r *MarketDataRecorder) AfterSign(call *Call) error {
+	return nil
+}
+
+// AfterResponse records successful ticker, trades and book responses.
+func (r *MarketDataRecorder) AfterResponse(call *Call) {
+	productId, found := marketDataProduct(call.Path)
+	if call.Err != nil || call.Method != "GET" || !found {
+		return
+	}
+
+	payload, err := json.Marshal(call.Response)
+	if err == nil {
+		err = r.write(RecordedMessage{Source: RecordSourceRest, ProductId: productId, Path: call.Path, Payload: payload})
+	}
+
+	if err != nil && r.OnError != nil {
+		r.OnError(err)
+	}
+}
+
+// recordingFiles lists the recordings of a product in day order.
+func recordingFiles(dir, productId string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, productId, "*"+recordingExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// readRecordingIndex returns the chunk offsets of a recording in the order they were written.
+func readRecordingIndex(path string) ([][2]int64, error) {
+	file, err := os.Open(strings.TrimSuffix(path, recordingExtension) + recordingIndexExtension)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := make([][2]int64, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var at, offset int64
+		if _, err := fmt.Sscanf(scanner.Text(), "%d %d", &at, &offset); err != nil {
+			// a line torn by a crash ends the index
+			break
+		}
+		entries = append(entries, [2]int64{at, offset})
+	}
+
+	return entries, scanner.Err()
+}
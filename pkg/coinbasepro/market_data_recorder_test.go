@@ -0,0 +1,259 @@
+package coinbasepro
+
+import (
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, replay *MarketDataReplay) []Message {
+	messages := make([]Message, 0)
+	for {
+		message, err := replay.Read()
+		if err == io.EOF {
+			return messages
+		}
+		assert.Assert(t, is.Nil(err))
+		messages = append(messages, message)
+	}
+}
+
+func TestMarketDataRecorder(t *testing.T) {
+	start := time.Date(2021, 3, 1, 23, 59, 0, 0, time.UTC)
+
+	record := func(t *testing.T, dir string) {
+		recorder, err := NewMarketDataRecorder(dir, time.Second)
+		assert.Assert(t, is.Nil(err))
+
+		now := start
+		recorder.SetClock(func() time.Time { return now })
+
+		assert.Assert(t, is.Nil(recorder.RecordWebsocket([]byte(`{"type":"subscriptions","channels":[]}`))))
+		assert.Assert(t, is.Nil(recorder.RecordWebsocket([]byte(`{"type":"match","product_id":"BTC-USD","trade_id":1,"price":"100.00","size":"1"}`))))
+
+		now = start.Add(500 * time.Millisecond)
+		assert.Assert(t, is.Nil(recorder.RecordWebsocket([]byte(`{"type":"match","product_id":"ETH-USD","trade_id":7,"price":"10.00","size":"2"}`))))
+
+		now = start.Add(2 * time.Second)
+		recorder.AfterResponse(&Call{Method: "GET", Path: "/products/BTC-USD/ticker", Response: &Ticker{TradeId: 2, Price: "101.00", Size: "0.5", Bid: "100.99", Ask: "101.01", Volume: "1000", Time: "2021-03-01T23:59:02Z"}})
+		recorder.AfterResponse(&Call{Method: "GET", Path: "/accounts", Response: &[]Account{}})
+
+		now = start.Add(90 * time.Second)
+		recorder.AfterResponse(&Call{Method: "GET", Path: "/products/BTC-USD/trades", Response: &[]Trade{
+			{TradeId: 4, Price: "103.00", Size: "1", Side: "sell", Time: "2021-03-02T00:00:30Z"},
+			{TradeId: 3, Price: "102.00", Size: "1", Side: "buy", Time: "2021-03-02T00:00:29Z"},
+		}})
+
+		now = start.Add(91 * time.Second)
+		recorder.AfterResponse(&Call{Method: "GET", Path: "/products/BTC-USD/book?level=2", Response: &ProductBook{
+			Sequence: 9,
+			Bids:     []BookEntry{{Price: "102.00", Size: "1.5", NumOrders: 2}},
+			Asks:     []BookEntry{{Price: "103.00", Size: "2", NumOrders: 1}},
+		}})
+
+		assert.Assert(t, is.Nil(recorder.Close()))
+		assert.Error(t, recorder.RecordWebsocket([]byte(`{"type":"match","product_id":"BTC-USD"}`)), MarketDataRecorderClosedErrorMessage)
+	}
+
+	t.Run("should rotate files daily per product", func(t *testing.T) {
+		dir := t.TempDir()
+		record(t, dir)
+
+		btc, err := recordingFiles(dir, "BTC-USD")
+		assert.Assert(t, is.Nil(err))
+		assert.DeepEqual(t, btc, []string{
+			filepath.Join(dir, "BTC-USD", "2021-03-01.ndjson.gz"),
+			filepath.Join(dir, "BTC-USD", "2021-03-02.ndjson.gz"),
+		})
+
+		eth, err := recordingFiles(dir, "ETH-USD")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(eth), 1)
+
+		index, err := readRecordingIndex(btc[0])
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(index), 2)
+		assert.Equal(t, index[0][1], int64(0))
+		assert.Assert(t, index[1][1] > 0)
+	})
+
+	t.Run("should replay websocket and rest data as feed messages in time order", func(t *testing.T) {
+		dir := t.TempDir()
+		record(t, dir)
+
+		replay, err := NewMarketDataReplay(dir, ReplayOptions{ProductIds: []string{"BTC-USD", "ETH-USD"}})
+		assert.Assert(t, is.Nil(err))
+		defer replay.Close()
+
+		messages := readAll(t, replay)
+		assert.Equal(t, len(messages), 6)
+
+		assert.Equal(t, messages[0].ProductId, "BTC-USD")
+		assert.Equal(t, messages[0].TradeId, int64(1))
+		assert.Equal(t, messages[1].ProductId, "ETH-USD")
+
+		assert.DeepEqual(t, messages[2], Message{
+			Type: MessageTypeTicker, ProductId: "BTC-USD", TradeId: 2, Price: "101.00", BestBid: "100.99", BestAsk: "101.01",
+			LastSize: "0.5", Volume24h: "1000", Time: "2021-03-01T23:59:02Z",
+		})
+
+		assert.Equal(t, messages[3].Type, MessageTypeMatch)
+		assert.Equal(t, messages[3].TradeId, int64(3))
+		assert.Equal(t, messages[4].TradeId, int64(4))
+		assert.Equal(t, messages[4].Side, "sell")
+
+		assert.Equal(t, messages[5].Type, MessageTypeSnapshot)
+		assert.DeepEqual(t, messages[5].Bids, [][]string{{"102.00", "1.5"}})
+		assert.DeepEqual(t, messages[5].Asks, [][]string{{"103.00", "2"}})
+	})
+
+	t.Run("should start from the chunk covering the start time and stop at the end time", func(t *testing.T) {
+		dir := t.TempDir()
+		record(t, dir)
+
+		replay, err := NewMarketDataReplay(dir, ReplayOptions{
+			ProductIds: []string{"BTC-USD"},
+			Start:      start.Add(time.Second),
+			End:        start.Add(90 * time.Second),
+		})
+		assert.Assert(t, is.Nil(err))
+		defer replay.Close()
+
+		messages := readAll(t, replay)
+		assert.Equal(t, len(messages), 3)
+		assert.Equal(t, messages[0].Type, MessageTypeTicker)
+		assert.Equal(t, messages[2].TradeId, int64(4))
+	})
+
+	t.Run("should pace records by the replay speed", func(t *testing.T) {
+		dir := t.TempDir()
+		record(t, dir)
+
+		for _, test := range []struct {
+			speed float64
+			slept time.Duration
+		}{{1, 91 * time.Second}, {10, 9100 * time.Millisecond}, {0, 0}} {
+			replay, err := NewMarketDataReplay(dir, ReplayOptions{ProductIds: []string{"BTC-USD", "ETH-USD"}, Speed: test.speed})
+			assert.Assert(t, is.Nil(err))
+
+			clock := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			var slept time.Duration
+			replay.now = func() time.Time { return clock }
+			replay.sleep = func(d time.Duration) {
+				slept += d
+				clock = clock.Add(d)
+			}
+
+			assert.Equal(t, len(readAll(t, replay)), 6)
+			assert.Equal(t, slept, test.slept, "speed %v", test.speed)
+			replay.Close()
+		}
+	})
+
+	t.Run("should treat a torn chunk as the end of its file", func(t *testing.T) {
+		dir := t.TempDir()
+		record(t, dir)
+
+		path := filepath.Join(dir, "BTC-USD", "2021-03-01.ndjson.gz")
+		info, err := os.Stat(path)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(os.Truncate(path, info.Size()-5)))
+
+		replay, err := NewMarketDataReplay(dir, ReplayOptions{ProductIds: []string{"BTC-USD"}})
+		assert.Assert(t, is.Nil(err))
+		defer replay.Close()
+
+		messages := readAll(t, replay)
+		assert.Equal(t, messages[0].TradeId, int64(1))
+		assert.Equal(t, messages[len(messages)-1].Type, MessageTypeSnapshot)
+	})
+
+	t.Run("should drop a torn chunk before recording after a restart", func(t *testing.T) {
+		dir := t.TempDir()
+		record(t, dir)
+
+		path := filepath.Join(dir, "BTC-USD", "2021-03-01.ndjson.gz")
+		info, err := os.Stat(path)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(os.Truncate(path, info.Size()-5)))
+
+		index, err := os.OpenFile(strings.TrimSuffix(path, recordingExtension)+recordingIndexExtension, os.O_WRONLY|os.O_APPEND, 0600)
+		assert.Assert(t, is.Nil(err))
+		_, err = index.WriteString("16146431")
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(index.Close()))
+
+		recorder, err := NewMarketDataRecorder(dir, time.Second)
+		assert.Assert(t, is.Nil(err))
+		recorder.SetClock(func() time.Time { return start.Add(10 * time.Second) })
+		assert.Assert(t, is.Nil(recorder.RecordWebsocket([]byte(`{"type":"match","product_id":"BTC-USD","trade_id":9,"price":"100.00","size":"1"}`))))
+		assert.Assert(t, is.Nil(recorder.Close()))
+
+		entries, err := readRecordingIndex(path)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(entries), 2)
+
+		replay, err := NewMarketDataReplay(dir, ReplayOptions{ProductIds: []string{"BTC-USD"}})
+		assert.Assert(t, is.Nil(err))
+		defer replay.Close()
+
+		tradeIds := make([]int64, 0)
+		for _, message := range readAll(t, replay) {
+			tradeIds = append(tradeIds, message.TradeId)
+		}
+		assert.DeepEqual(t, tradeIds, []int64{1, 9, 3, 4, 0})
+	})
+
+	t.Run("should record messages read from a feed", func(t *testing.T) {
+		websocketUrl := newTestWebsocketServer(t, func(conn *websocketConn) {
+			_, err := conn.ReadMessage()
+			assert.Assert(t, is.Nil(err))
+
+			writeTestMessages(t, conn,
+				Message{Type: MessageTypeSubscriptions},
+				Message{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 5, Price: "100.00", Size: "1"},
+				Message{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 6, Price: "100.00", Size: "1"},
+			)
+		})
+
+		dir := t.TempDir()
+		recorder, err := NewMarketDataRecorder(dir, 0)
+		assert.Assert(t, is.Nil(err))
+		errs := make([]error, 0)
+		recorder.OnError = func(err error) { errs = append(errs, err) }
+
+		feed := NewFeed(websocketUrl)
+		feed.Record(recorder)
+		defer feed.Close()
+
+		assert.Assert(t, is.Nil(feed.Subscribe(Subscription{ProductIds: []string{"BTC-USD"}, Channels: []string{ChannelMatches}})))
+		for i := 0; i < 2; i++ {
+			_, err := feed.Read()
+			assert.Assert(t, is.Nil(err))
+		}
+		assert.Assert(t, is.Nil(recorder.Close()))
+
+		_, err = feed.Read()
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(errs), 1)
+		assert.Error(t, errs[0], MarketDataRecorderClosedErrorMessage)
+
+		replay, err := NewMarketDataReplay(dir, ReplayOptions{ProductIds: []string{"BTC-USD"}})
+		assert.Assert(t, is.Nil(err))
+		defer replay.Close()
+
+		messages := readAll(t, replay)
+		assert.Equal(t, len(messages), 1)
+		assert.Equal(t, messages[0].TradeId, int64(5))
+	})
+
+	t.Run("should require a product", func(t *testing.T) {
+		_, err := NewMarketDataReplay(t.TempDir(), ReplayOptions{})
+		assert.Error(t, err, MissingReplayProductsErrorMessage)
+	})
+}
@@ -0,0 +1,323 @@
+package coinbasepro
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const MissingReplayProductsErrorMessage = "a replay needs at least one product"
+
+// ReplayOptions select what a MarketDataReplay reads and how fast. Start and End bound the records
+// by the time they were received, zero values leave that side open. Speed 1 replays at the
+// original pace, 10 ten times faster and 0 as fast as possible.
+type ReplayOptions struct {
+	ProductIds []string
+	Start      time.Time
+	End        time.Time
+	Speed      float64
+}
+
+// recordingCursor reads the records of one product across its daily files in order.
+type recordingCursor struct {
+	paths  []string
+	start  time.Time
+	file   *os.File
+	reader *gzip.Reader
+	lines  *bufio.Reader
+	next   *RecordedMessage
+}
+
+func (c *recordingCursor) open(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	if !c.start.IsZero() {
+		entries, err := readRecordingIndex(path)
+		if err != nil {
+			file.Close()
+			return err
+		}
+
+		for _, entry := range entries {
+			if entry[0] > c.start.UnixNano() {
+				break
+			}
+			offset = entry[1]
+		}
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	c.file, c.reader, c.lines = file, reader, bufio.NewReader(reader)
+	return nil
+}
+
+func (c *recordingCursor) closeFile() {
+	if c.file != nil {
+		c.file.Close()
+		c.file, c.reader, c.lines = nil, nil, nil
+	}
+}
+
+// advance loads the next record into next, leaving it nil at the end of the recordings. A chunk
+// torn by a crash while recording ends its file.
+func (c *recordingCursor) advance() error {
+	c.next = nil
+
+	for {
+		if c.lines == nil {
+			if len(c.paths) == 0 {
+				return nil
+			}
+
+			path := c.paths[0]
+			c.paths = c.paths[1:]
+			if err := c.open(path); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		line, err := c.lines.ReadBytes('\n')
+		if err != nil {
+			c.closeFile()
+			if err == io.EOF || err == io.ErrUnexpectedEOF || errors.Is(err, gzip.ErrChecksum) || errors.Is(err, gzip.ErrHeader) {
+				continue
+			}
+
+			return err
+		}
+
+		var record RecordedMessage
+		if err := json.Unmarshal(line, &record); err != nil {
+			c.closeFile()
+			continue
+		}
+
+		if record.Time.Before(c.start) {
+			continue
+		}
+
+		c.next = &record
+		return nil
+	}
+}
+
+// MarketDataReplay is a MessageSource reading the files written by a MarketDataRecorder. Records
+// of every product are merged in the order they were received and decoded into the same messages
+// a live Feed produces, REST responses included: a ticker becomes a ticker message, trades become
+// match messages and a book becomes a level2 snapshot.
+type MarketDataReplay struct {
+	options ReplayOptions
+	cursors []*recordingCursor
+	pending []Message
+
+	now       func() time.Time
+	sleep     func(time.Duration)
+	wallStart time.Time
+	dataStart time.Time
+}
+
+func NewMarketDataReplay(dir string, options ReplayOptions) (*MarketDataReplay, error) {
+	if len(options.ProductIds) == 0 {
+		return nil, errors.New(MissingReplayProductsErrorMessage)
+	}
+
+	replay := &MarketDataReplay{options: options, now: time.Now, sleep: time.Sleep}
+	for _, productId := range options.ProductIds {
+		paths, err := recordingFiles(dir, productId)
+		if err != nil {
+			return nil, err
+		}
+
+		selected := make([]string, 0, len(paths))
+		for _, path := range paths {
+			day := strings.TrimSuffix(filepath.Base(path), recordingExtension)
+			if (!options.Start.IsZero() && day < options.Start.UTC().Format(recordingDayLayout)) ||
+				(!options.End.IsZero() && day > options.End.UTC().Format(recordingDayLayout)) {
+				continue
+			}
+			selected = append(selected, path)
+		}
+
+		cursor := &recordingCursor{paths: selected, start: options.Start}
+		if err := cursor.advance(); err != nil {
+			replay.Close()
+			return nil, err
+		}
+		replay.cursors = append(replay.cursors, cursor)
+	}
+
+	return replay, nil
+}
+
+// ReadRecord returns the next record in time order, io.EOF after the last one.
+func (r *MarketDataReplay) ReadRecord() (RecordedMessage, error) {
+	var earliest *recordingCursor
+	for _, cursor := range r.cursors {
+		if cursor.next != nil && (earliest == nil || cursor.next.Time.Before(earliest.next.Time)) {
+			earliest = cursor
+		}
+	}
+
+	if earliest == nil || (!r.options.End.IsZero() && earliest.next.Time.After(r.options.End)) {
+		return RecordedMessage{}, io.EOF
+	}
+
+	record := *earliest.next
+	if err := earliest.advance(); err != nil {
+		return RecordedMessage{}, err
+	}
+
+	r.wait(record.Time)
+
+	return record, nil
+}
+
+// wait holds a record back until its time has come at the replay speed.
+func (r *MarketDataReplay) wait(at time.Time) {
+	if r.options.Speed <= 0 {
+		return
+	}
+
+	if r.wallStart.IsZero() {
+		r.wallStart, r.dataStart = r.now(), at
+		return
+	}
+
+	due := r.wallStart.Add(time.Duration(float64(at.Sub(r.dataStart)) / r.options.Speed))
+	if delay := due.Sub(r.now()); delay > 0 {
+		r.sleep(delay)
+	}
+}
+
+// Read returns the next decoded message, io.EOF after the last one.
+func (r *MarketDataReplay) Read() (Message, error) {
+	for len(r.pending) == 0 {
+		record, err := r.ReadRecord()
+		if err != nil {
+			return Message{}, err
+		}
+
+		if r.pending, err = DecodeRecordedMessage(record); err != nil {
+			return Message{}, err
+		}
+	}
+
+	message := r.pending[0]
+	r.pending = r.pending[1:]
+
+	return message, nil
+}
+
+func (r *MarketDataReplay) Close() error {
+	for _, cursor := range r.cursors {
+		cursor.closeFile()
+	}
+
+	return nil
+}
+
+// DecodeRecordedMessage turns a record into the feed messages it stands for.
+func DecodeRecordedMessage(record RecordedMessage) ([]Message, error) {
+	if record.Source == RecordSourceWebsocket {
+		var message Message
+		if err := json.Unmarshal(record.Payload, &message); err != nil {
+			return nil, err
+		}
+
+		return []Message{message}, nil
+	}
+
+	path := record.Path
+	if index := strings.Index(path, "?"); index >= 0 {
+		path = path[:index]
+	}
+	received := record.Time.UTC().Format(time.RFC3339Nano)
+
+	switch {
+	case strings.HasSuffix(path, "/ticker"):
+		var ticker Ticker
+		if err := json.Unmarshal(record.Payload, &ticker); err != nil {
+			return nil, err
+		}
+
+		return []Message{{
+			Type:      MessageTypeTicker,
+			ProductId: record.ProductId,
+			TradeId:   ticker.TradeId,
+			Price:     ticker.Price,
+			BestBid:   ticker.Bid,
+			BestAsk:   ticker.Ask,
+			LastSize:  ticker.Size,
+			Volume24h: ticker.Volume,
+			Time:      ticker.Time,
+		}}, nil
+	case strings.HasSuffix(path, "/trades"):
+		var trades []Trade
+		if err := json.Unmarshal(record.Payload, &trades); err != nil {
+			return nil, err
+		}
+
+		// the exchange lists the newest trade first
+		sort.SliceStable(trades, func(i, j int) bool {
+			return trades[i].TradeId < trades[j].TradeId
+		})
+
+		messages := make([]Message, 0, len(trades))
+		for _, trade := range trades {
+			messages = append(messages, Message{
+				Type:      MessageTypeMatch,
+				ProductId: record.ProductId,
+				TradeId:   trade.TradeId,
+				Side:      trade.Side,
+				Price:     trade.Price,
+				Size:      trade.Size,
+				Time:      trade.Time,
+			})
+		}
+
+		return messages, nil
+	case strings.HasSuffix(path, "/book"):
+		var book ProductBook
+		if err := json.Unmarshal(record.Payload, &book); err != nil {
+			return nil, err
+		}
+
+		snapshot := Message{Type: MessageTypeSnapshot, ProductId: record.ProductId, Time: received, Bids: [][]string{}, Asks: [][]string{}}
+		for _, entry := range book.Bids {
+			snapshot.Bids = append(snapshot.Bids, []string{entry.Price, entry.Size})
+		}
+		for _, entry := range book.Asks {
+			snapshot.Asks = append(snapshot.Asks, []string{entry.Price, entry.Size})
+		}
+
+		return []Message{snapshot}, nil
+	}
+
+	return nil, nil
+}
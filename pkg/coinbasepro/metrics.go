@@ -0,0 +1,257 @@
+package coinbasepro
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+var defaultWaitBuckets = []float64{0, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5}
+
+// Metrics collects api usage in the Prometheus text exposition format. Add it to a client with
+// client.Use(metrics) and serve Handler() on /metrics.
+type Metrics struct {
+	requests          *counterVec
+	retries           *counterVec
+	rateLimited       *counterVec
+	latency           *histogramVec
+	rateLimiterWait   *histogramVec
+	websocketMessages *counterVec
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:          newCounterVec("coinbasepro_requests_total", "Api requests by endpoint and http status code.", "endpoint", "status"),
+		retries:           newCounterVec("coinbasepro_request_retries_total", "Api request retries by endpoint.", "endpoint"),
+		rateLimited:       newCounterVec("coinbasepro_rate_limited_total", "Responses with http status 429 by endpoint.", "endpoint"),
+		latency:           newHistogramVec("coinbasepro_request_duration_seconds", "Api request latency including retries.", defaultLatencyBuckets, "endpoint"),
+		rateLimiterWait:   newHistogramVec("coinbasepro_rate_limiter_wait_seconds", "Time spent waiting on the client side rate limiter.", defaultWaitBuckets, "endpoint"),
+		websocketMessages: newCounterVec("coinbasepro_websocket_messages_total", "Websocket feed messages by channel and type.", "channel", "type"),
+	}
+}
+
+func (m *Metrics) BeforeSign(call *Call) error {
+	return nil
+}
+
+func (m *Metrics) AfterSign(call *Call) error {
+	return nil
+}
+
+func (m *Metrics) AfterResponse(call *Call) {
+	endpoint := call.Endpoint()
+
+	status := strconv.Itoa(call.StatusCode)
+	if call.StatusCode == 0 {
+		status = "error"
+	}
+
+	m.requests.add(1, endpoint, status)
+	m.latency.observe(call.Latency.Seconds(), endpoint)
+	m.rateLimiterWait.observe(call.RateLimitWait.Seconds(), endpoint)
+
+	if call.Retries > 0 {
+		m.retries.add(float64(call.Retries), endpoint)
+	}
+
+	if call.Hits429 > 0 {
+		m.rateLimited.add(float64(call.Hits429), endpoint)
+	}
+}
+
+func (m *Metrics) ObserveWebsocketMessage(channel, messageType string) {
+	m.websocketMessages.add(1, channel, messageType)
+}
+
+func (m *Metrics) WriteTo(writer io.Writer) (int64, error) {
+	buffer := bytes.Buffer{}
+	m.requests.write(&buffer)
+	m.retries.write(&buffer)
+	m.rateLimited.write(&buffer)
+	m.latency.write(&buffer)
+	m.rateLimiterWait.write(&buffer)
+	m.websocketMessages.write(&buffer)
+
+	return buffer.WriteTo(writer)
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set(contentTypeHeaderKey, metricsContentType)
+		m.WriteTo(writer)
+	})
+}
+
+type metricSeries struct {
+	labelValues []string
+	value       float64
+	buckets     []uint64
+	count       uint64
+}
+
+type metricVec struct {
+	mutex      sync.Mutex
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+	series     map[string]*metricSeries
+}
+
+func newMetricVec(name, help, metricType string, labelNames []string) metricVec {
+	return metricVec{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		series:     make(map[string]*metricSeries),
+	}
+}
+
+func (v *metricVec) get(labelValues []string, buckets int) *metricSeries {
+	key := strings.Join(labelValues, "\xff")
+	series, found := v.series[key]
+	if !found {
+		series = &metricSeries{labelValues: labelValues, buckets: make([]uint64, buckets)}
+		v.series[key] = series
+	}
+
+	return series
+}
+
+func (v *metricVec) sortedSeries() []*metricSeries {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	series := make([]*metricSeries, 0, len(keys))
+	for _, key := range keys {
+		series = append(series, v.series[key])
+	}
+
+	return series
+}
+
+func (v *metricVec) writeHeader(buffer *bytes.Buffer) {
+	fmt.Fprintf(buffer, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(buffer, "# TYPE %s %s\n", v.name, v.metricType)
+}
+
+func formatLabels(names, values []string, extra ...string) string {
+	pairs := make([]string, 0, len(names)+len(extra)/2)
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(values[i])))
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extra[i], escapeLabelValue(extra[i+1])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatMetricValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+type counterVec struct {
+	metricVec
+}
+
+func newCounterVec(name, help string, labelNames ...string) *counterVec {
+	return &counterVec{newMetricVec(name, help, "counter", labelNames)}
+}
+
+func (c *counterVec) add(value float64, labelValues ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.get(labelValues, 0).value += value
+}
+
+func (c *counterVec) value(labelValues ...string) float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.get(labelValues, 0).value
+}
+
+func (c *counterVec) write(buffer *bytes.Buffer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.writeHeader(buffer)
+	for _, series := range c.sortedSeries() {
+		fmt.Fprintf(buffer, "%s%s %s\n", c.name, formatLabels(c.labelNames, series.labelValues), formatMetricValue(series.value))
+	}
+}
+
+type histogramVec struct {
+	metricVec
+	upperBounds []float64
+}
+
+func newHistogramVec(name, help string, upperBounds []float64, labelNames ...string) *histogramVec {
+	return &histogramVec{
+		metricVec:   newMetricVec(name, help, "histogram", labelNames),
+		upperBounds: upperBounds,
+	}
+}
+
+func (h *histogramVec) observe(value float64, labelValues ...string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	series := h.get(labelValues, len(h.upperBounds))
+	series.value += value
+	series.count++
+
+	for i, upperBound := range h.upperBounds {
+		if value <= upperBound {
+			series.buckets[i]++
+		}
+	}
+}
+
+func (h *histogramVec) write(buffer *bytes.Buffer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.writeHeader(buffer)
+	for _, series := range h.sortedSeries() {
+		for i, upperBound := range h.upperBounds {
+			labels := formatLabels(h.labelNames, series.labelValues, "le", formatMetricValue(upperBound))
+			fmt.Fprintf(buffer, "%s_bucket%s %d\n", h.name, labels, series.buckets[i])
+		}
+
+		labels := formatLabels(h.labelNames, series.labelValues, "le", "+Inf")
+		fmt.Fprintf(buffer, "%s_bucket%s %d\n", h.name, labels, series.count)
+
+		labels = formatLabels(h.labelNames, series.labelValues)
+		fmt.Fprintf(buffer, "%s_sum%s %s\n", h.name, labels, formatMetricValue(series.value))
+		fmt.Fprintf(buffer, "%s_count%s %d\n", h.name, labels, series.count)
+	}
+}
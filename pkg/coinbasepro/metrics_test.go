@@ -0,0 +1,83 @@
+package coinbasepro
+
+import (
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	statuses := []int{http.StatusOK, http.StatusTooManyRequests, http.StatusOK, http.StatusNotFound}
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		status := statuses[requests%len(statuses)]
+		requests++
+
+		writer.WriteHeader(status)
+		if status != http.StatusOK {
+			writer.Write([]byte(`{"message":"nope"}`))
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClientWithOptions(ts.URL, testKey, testPassphrase, testSecret)
+	assert.Assert(t, is.Nil(err), "unexpected error creating client using NewClientWithOptions", err)
+
+	metrics := NewMetrics()
+	client.Use(metrics)
+
+	_, err = client.executeRequest("GET", "/time", nil, nil, 3)
+	assert.Assert(t, is.Nil(err))
+	_, err = client.executeRequest("GET", "/time", nil, nil, 3)
+	assert.Assert(t, is.Nil(err))
+	_, err = client.executeRequest("GET", "/orders/4a9b1f2c-3d4e-4f50-8a6b-7c8d9e0f1a2b", nil, nil, 0)
+	assert.Error(t, err, "404 - nope")
+
+	metrics.ObserveWebsocketMessage("matches", "match")
+	metrics.ObserveWebsocketMessage("matches", "match")
+
+	t.Run("should count requests, retries and 429s per endpoint", func(t *testing.T) {
+		assert.Equal(t, metrics.requests.value("GET /time", "200"), float64(2))
+		assert.Equal(t, metrics.requests.value("GET /orders/:id", "404"), float64(1))
+		assert.Equal(t, metrics.retries.value("GET /time"), float64(1))
+		assert.Equal(t, metrics.rateLimited.value("GET /time"), float64(1))
+		assert.Equal(t, metrics.websocketMessages.value("matches", "match"), float64(2))
+	})
+
+	t.Run("should serve the prometheus text format", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, recorder.Code, http.StatusOK)
+		assert.Equal(t, recorder.Header().Get(contentTypeHeaderKey), metricsContentType)
+
+		body, err := ioutil.ReadAll(recorder.Body)
+		assert.Assert(t, is.Nil(err))
+		output := string(body)
+
+		expectedLines := []string{
+			"# TYPE coinbasepro_requests_total counter",
+			`coinbasepro_requests_total{endpoint="GET /time",status="200"} 2`,
+			`coinbasepro_rate_limited_total{endpoint="GET /time"} 1`,
+			"# TYPE coinbasepro_request_duration_seconds histogram",
+			`coinbasepro_request_duration_seconds_bucket{endpoint="GET /time",le="+Inf"} 2`,
+			`coinbasepro_request_duration_seconds_count{endpoint="GET /orders/:id"} 1`,
+			`coinbasepro_rate_limiter_wait_seconds_bucket{endpoint="GET /time",le="0"} 2`,
+			`coinbasepro_websocket_messages_total{channel="matches",type="match"} 2`,
+		}
+
+		for _, line := range expectedLines {
+			assert.Assert(t, strings.Contains(output, line+"\n"), "missing %q in\n%s", line, output)
+		}
+	})
+}
+
+func TestFormatLabels(t *testing.T) {
+	labels := formatLabels([]string{"path"}, []string{"a \"quoted\"\\path\n"}, "le", "0.5")
+	assert.Equal(t, labels, `{path="a \"quoted\"\\path\n",le="0.5"}`)
+	assert.Equal(t, formatLabels(nil, nil), "")
+}
@@ -0,0 +1,265 @@
+// Package mock provides hand written test doubles for the coinbasepro api interfaces. Every method
+// delegates to the matching Func field and records the call, methods without a Func return
+// ErrNotMocked.
+package mock
+
+import (
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"sync"
+)
+
+var ErrNotMocked = errors.New("mock: method not configured")
+
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+type recorder struct {
+	mutex sync.Mutex
+	calls []Call
+}
+
+func (r *recorder) record(method string, args ...interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns every call made so far, in order.
+func (r *recorder) Calls() []Call {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	calls := make([]Call, len(r.calls))
+	copy(calls, r.calls)
+
+	return calls
+}
+
+func (r *recorder) CallCount(method string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	count := 0
+	for _, call := range r.calls {
+		if call.Method == method {
+			count++
+		}
+	}
+
+	return count
+}
+
+type MarketDataAPI struct {
+	recorder
+	GetProductsFunc    func() ([]coinbasepro.Product, error)
+	GetProductFunc     func(productId string) (coinbasepro.Product, error)
+	GetTickerFunc      func(productId string) (coinbasepro.Ticker, error)
+	GetProductBookFunc func(productId string, level int) (coinbasepro.ProductBook, error)
+	GetTradesFunc      func(productId string) ([]coinbasepro.Trade, error)
+	GetCandlesFunc     func(productId string, filter coinbasepro.CandleFilter) ([]coinbasepro.Candle, error)
+	GetTimeFunc        func() (coinbasepro.ServerTime, error)
+}
+
+func (m *MarketDataAPI) GetProducts() ([]coinbasepro.Product, error) {
+	m.record("GetProducts")
+	if m.GetProductsFunc == nil {
+		return nil, ErrNotMocked
+	}
+
+	return m.GetProductsFunc()
+}
+
+func (m *MarketDataAPI) GetProduct(productId string) (coinbasepro.Product, error) {
+	m.record("GetProduct", productId)
+	if m.GetProductFunc == nil {
+		return coinbasepro.Product{}, ErrNotMocked
+	}
+
+	return m.GetProductFunc(productId)
+}
+
+func (m *MarketDataAPI) GetTicker(productId string) (coinbasepro.Ticker, error) {
+	m.record("GetTicker", productId)
+	if m.GetTickerFunc == nil {
+		return coinbasepro.Ticker{}, ErrNotMocked
+	}
+
+	return m.GetTickerFunc(productId)
+}
+
+func (m *MarketDataAPI) GetProductBook(productId string, level int) (coinbasepro.ProductBook, error) {
+	m.record("GetProductBook", productId, level)
+	if m.GetProductBookFunc == nil {
+		return coinbasepro.ProductBook{}, ErrNotMocked
+	}
+
+	return m.GetProductBookFunc(productId, level)
+}
+
+func (m *MarketDataAPI) GetTrades(productId string) ([]coinbasepro.Trade, error) {
+	m.record("GetTrades", productId)
+	if m.GetTradesFunc == nil {
+		return nil, ErrNotMocked
+	}
+
+	return m.GetTradesFunc(productId)
+}
+
+func (m *MarketDataAPI) GetCandles(productId string, filter coinbasepro.CandleFilter) ([]coinbasepro.Candle, error) {
+	m.record("GetCandles", productId, filter)
+	if m.GetCandlesFunc == nil {
+		return nil, ErrNotMocked
+	}
+
+	return m.GetCandlesFunc(productId, filter)
+}
+
+func (m *MarketDataAPI) GetTime() (coinbasepro.ServerTime, error) {
+	m.record("GetTime")
+	if m.GetTimeFunc == nil {
+		return coinbasepro.ServerTime{}, ErrNotMocked
+	}
+
+	return m.GetTimeFunc()
+}
+
+type TradingAPI struct {
+	recorder
+	PlaceOrderFunc             func(order coinbasepro.Order) (coinbasepro.Order, error)
+	CancelOrderFunc            func(orderId string) error
+	CancelOrderByClientOidFunc func(clientOid string) error
+	GetOrderFunc               func(orderId string) (coinbasepro.Order, error)
+	GetOrderByClientOidFunc    func(clientOid string) (coinbasepro.Order, error)
+	ListOrdersFunc             func(filter coinbasepro.OrderFilter) ([]coinbasepro.Order, error)
+	GetFillsFunc               func(filter coinbasepro.FillFilter) ([]coinbasepro.Fill, error)
+}
+
+func (m *TradingAPI) PlaceOrder(order coinbasepro.Order) (coinbasepro.Order, error) {
+	m.record("PlaceOrder", order)
+	if m.PlaceOrderFunc == nil {
+		return coinbasepro.Order{}, ErrNotMocked
+	}
+
+	return m.PlaceOrderFunc(order)
+}
+
+func (m *TradingAPI) CancelOrder(orderId string) error {
+	m.record("CancelOrder", orderId)
+	if m.CancelOrderFunc == nil {
+		return ErrNotMocked
+	}
+
+	return m.CancelOrderFunc(orderId)
+}
+
+func (m *TradingAPI) CancelOrderByClientOid(clientOid string) error {
+	m.record("CancelOrderByClientOid", clientOid)
+	if m.CancelOrderByClientOidFunc == nil {
+		return ErrNotMocked
+	}
+
+	return m.CancelOrderByClientOidFunc(clientOid)
+}
+
+func (m *TradingAPI) GetOrder(orderId string) (coinbasepro.Order, error) {
+	m.record("GetOrder", orderId)
+	if m.GetOrderFunc == nil {
+		return coinbasepro.Order{}, ErrNotMocked
+	}
+
+	return m.GetOrderFunc(orderId)
+}
+
+func (m *TradingAPI) GetOrderByClientOid(clientOid string) (coinbasepro.Order, error) {
+	m.record("GetOrderByClientOid", clientOid)
+	if m.GetOrderByClientOidFunc == nil {
+		return coinbasepro.Order{}, ErrNotMocked
+	}
+
+	return m.GetOrderByClientOidFunc(clientOid)
+}
+
+func (m *TradingAPI) ListOrders(filter coinbasepro.OrderFilter) ([]coinbasepro.Order, error) {
+	m.record("ListOrders", filter)
+	if m.ListOrdersFunc == nil {
+		return nil, ErrNotMocked
+	}
+
+	return m.ListOrdersFunc(filter)
+}
+
+func (m *TradingAPI) GetFills(filter coinbasepro.FillFilter) ([]coinbasepro.Fill, error) {
+	m.record("GetFills", filter)
+	if m.GetFillsFunc == nil {
+		return nil, ErrNotMocked
+	}
+
+	return m.GetFillsFunc(filter)
+}
+
+type AccountsAPI struct {
+	recorder
+	GetAccountsFunc func() ([]coinbasepro.Account, error)
+	GetAccountFunc  func(accountId string) (coinbasepro.Account, error)
+}
+
+func (m *AccountsAPI) GetAccounts() ([]coinbasepro.Account, error) {
+	m.record("GetAccounts")
+	if m.GetAccountsFunc == nil {
+		return nil, ErrNotMocked
+	}
+
+	return m.GetAccountsFunc()
+}
+
+func (m *AccountsAPI) GetAccount(accountId string) (coinbasepro.Account, error) {
+	m.record("GetAccount", accountId)
+	if m.GetAccountFunc == nil {
+		return coinbasepro.Account{}, ErrNotMocked
+	}
+
+	return m.GetAccountFunc(accountId)
+}
+
+type FundingAPI struct {
+	recorder
+	GetTransfersFunc      func(filter coinbasepro.TransferFilter) ([]coinbasepro.Transfer, error)
+	GetTransferFunc       func(transferId string) (coinbasepro.Transfer, error)
+	GetPaymentMethodsFunc func() ([]coinbasepro.PaymentMethod, error)
+}
+
+func (m *FundingAPI) GetTransfers(filter coinbasepro.TransferFilter) ([]coinbasepro.Transfer, error) {
+	m.record("GetTransfers", filter)
+	if m.GetTransfersFunc == nil {
+		return nil, ErrNotMocked
+	}
+
+	return m.GetTransfersFunc(filter)
+}
+
+func (m *FundingAPI) GetTransfer(transferId string) (coinbasepro.Transfer, error) {
+	m.record("GetTransfer", transferId)
+	if m.GetTransferFunc == nil {
+		return coinbasepro.Transfer{}, ErrNotMocked
+	}
+
+	return m.GetTransferFunc(transferId)
+}
+
+func (m *FundingAPI) GetPaymentMethods() ([]coinbasepro.PaymentMethod, error) {
+	m.record("GetPaymentMethods")
+	if m.GetPaymentMethodsFunc == nil {
+		return nil, ErrNotMocked
+	}
+
+	return m.GetPaymentMethodsFunc()
+}
+
+var _ coinbasepro.MarketDataAPI = (*MarketDataAPI)(nil)
+var _ coinbasepro.TradingAPI = (*TradingAPI)(nil)
+var _ coinbasepro.AccountsAPI = (*AccountsAPI)(nil)
+var _ coinbasepro.FundingAPI = (*FundingAPI)(nil)
@@ -0,0 +1,49 @@
+package mock
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"testing"
+)
+
+func TestTradingAPI(t *testing.T) {
+	api := &TradingAPI{
+		PlaceOrderFunc: func(order coinbasepro.Order) (coinbasepro.Order, error) {
+			order.Id = "order-1"
+			order.Status = "pending"
+			return order, nil
+		},
+	}
+
+	var trading coinbasepro.TradingAPI = api
+	placed, err := trading.PlaceOrder(coinbasepro.Order{ProductId: "BTC-USD", Side: "buy"})
+	assert.Assert(t, is.Nil(err))
+	assert.Equal(t, placed.Id, "order-1")
+
+	err = trading.CancelOrder("order-1")
+	assert.Equal(t, err, ErrNotMocked)
+
+	assert.Equal(t, api.CallCount("PlaceOrder"), 1)
+	assert.DeepEqual(t, api.Calls()[1], Call{Method: "CancelOrder", Args: []interface{}{"order-1"}})
+}
+
+func TestOrderManagerWithMock(t *testing.T) {
+	api := &TradingAPI{
+		PlaceOrderFunc: func(order coinbasepro.Order) (coinbasepro.Order, error) {
+			order.Id = "00000000-0000-4000-8000-000000000001"
+			order.Status = "open"
+			return order, nil
+		},
+	}
+
+	manager, err := coinbasepro.NewOrderManager(api, "b07")
+	assert.Assert(t, is.Nil(err))
+
+	order, err := manager.Place(coinbasepro.Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+	assert.Assert(t, is.Nil(err))
+	assert.Equal(t, order.State, coinbasepro.OrderStateOpen)
+
+	placed := api.Calls()[0].Args[0].(coinbasepro.Order)
+	assert.Equal(t, placed.ClientOid, order.ClientOid)
+}
@@ -0,0 +1,206 @@
+package coinbasepro
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type PriceLevel struct {
+	Price float64
+	Size  float64
+}
+
+// OrderBook is an aggregated level2 book for a single product, kept up to date from snapshot and
+// l2update messages. It is safe for concurrent use.
+type OrderBook struct {
+	productId string
+
+	mutex     sync.RWMutex
+	bids      []PriceLevel
+	asks      []PriceLevel
+	updatedAt time.Time
+}
+
+func NewOrderBook(productId string) *OrderBook {
+	return &OrderBook{productId: productId}
+}
+
+func (b *OrderBook) ProductId() string {
+	return b.productId
+}
+
+// HandleMessage applies level2 messages for this product and ignores everything else.
+func (b *OrderBook) HandleMessage(message Message) error {
+	if message.ProductId != "" && message.ProductId != b.productId {
+		return nil
+	}
+
+	switch message.Type {
+	case MessageTypeSnapshot:
+		bids, err := parsePriceLevels(message.Bids)
+		if err != nil {
+			return err
+		}
+
+		asks, err := parsePriceLevels(message.Asks)
+		if err != nil {
+			return err
+		}
+
+		b.mutex.Lock()
+		b.bids = make([]PriceLevel, 0, len(bids))
+		b.asks = make([]PriceLevel, 0, len(asks))
+		for _, level := range bids {
+			b.setLevel(OrderSideBuy, level.Price, level.Size)
+		}
+		for _, level := range asks {
+			b.setLevel(OrderSideSell, level.Price, level.Size)
+		}
+		b.updatedAt = time.Now()
+		b.mutex.Unlock()
+	case MessageTypeL2Update:
+		for _, change := range message.Changes {
+			if len(change) < 3 {
+				return fmt.Errorf("malformed l2update change %v", change)
+			}
+
+			price, err := parseDecimal(change[1])
+			if err != nil {
+				return err
+			}
+
+			size, err := parseDecimal(change[2])
+			if err != nil {
+				return err
+			}
+
+			b.Set(change[0], price, size)
+		}
+	}
+
+	return nil
+}
+
+func parsePriceLevels(levels [][]string) ([]PriceLevel, error) {
+	parsed := make([]PriceLevel, 0, len(levels))
+	for _, level := range levels {
+		if len(level) < 2 {
+			return nil, fmt.Errorf("malformed price level %v", level)
+		}
+
+		price, err := parseDecimal(level[0])
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := parseDecimal(level[1])
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, PriceLevel{Price: price, Size: size})
+	}
+
+	return parsed, nil
+}
+
+// Set replaces the size resting at a price, a size of zero removes the level. Side is the order
+// side, buy for bids and sell for asks.
+func (b *OrderBook) Set(side string, price, size float64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.setLevel(side, price, size)
+	b.updatedAt = time.Now()
+}
+
+func (b *OrderBook) setLevel(side string, price, size float64) {
+	levels := &b.asks
+	better := func(i int) bool { return (*levels)[i].Price >= price }
+	if side == OrderSideBuy {
+		levels = &b.bids
+		better = func(i int) bool { return (*levels)[i].Price <= price }
+	}
+
+	index := sort.Search(len(*levels), better)
+	found := index < len(*levels) && (*levels)[index].Price == price
+
+	switch {
+	case found && size <= 0:
+		*levels = append((*levels)[:index], (*levels)[index+1:]...)
+	case found:
+		(*levels)[index].Size = size
+	case size > 0:
+		*levels = append(*levels, PriceLevel{})
+		copy((*levels)[index+1:], (*levels)[index:])
+		(*levels)[index] = PriceLevel{Price: price, Size: size}
+	}
+}
+
+func (b *OrderBook) BestBid() (PriceLevel, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if len(b.bids) == 0 {
+		return PriceLevel{}, false
+	}
+
+	return b.bids[0], true
+}
+
+func (b *OrderBook) BestAsk() (PriceLevel, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if len(b.asks) == 0 {
+		return PriceLevel{}, false
+	}
+
+	return b.asks[0], true
+}
+
+func (b *OrderBook) Mid() (float64, bool) {
+	bid, hasBid := b.BestBid()
+	ask, hasAsk := b.BestAsk()
+	if !hasBid || !hasAsk {
+		return 0, false
+	}
+
+	return (bid.Price + ask.Price) / 2, true
+}
+
+// Bids returns up to depth levels from the best bid down, a depth of zero returns every level.
+func (b *OrderBook) Bids(depth int) []PriceLevel {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return copyLevels(b.bids, depth)
+}
+
+// Asks returns up to depth levels from the best ask up, a depth of zero returns every level.
+func (b *OrderBook) Asks(depth int) []PriceLevel {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return copyLevels(b.asks, depth)
+}
+
+func copyLevels(levels []PriceLevel, depth int) []PriceLevel {
+	if depth <= 0 || depth > len(levels) {
+		depth = len(levels)
+	}
+
+	copied := make([]PriceLevel, depth)
+	copy(copied, levels[:depth])
+
+	return copied
+}
+
+func (b *OrderBook) UpdatedAt() time.Time {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.updatedAt
+}
@@ -0,0 +1,603 @@
+package coinbasepro
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+type OrderState string
+
+const OrderStatePending OrderState = "pending"
+const OrderStateOpen OrderState = "open"
+const OrderStatePartiallyFilled OrderState = "partially_filled"
+const OrderStateDone OrderState = "done"
+const OrderStateCancelled OrderState = "cancelled"
+const OrderStateRejected OrderState = "rejected"
+
+const UpdateSourceLocal = "local"
+const UpdateSourceRest = "rest"
+const UpdateSourceWebsocket = "websocket"
+
+const doneReasonCanceled = "canceled"
+
+const OrderNotTrackedErrorMessage = "order is not tracked by this order manager"
+const InvalidClientOidPrefixErrorMessage = "client_oid prefix must be at most 8 hexadecimal characters"
+
+var clientOidPrefixPattern = regexp.MustCompile(`^[0-9a-f]{0,8}$`)
+
+func (s OrderState) IsTerminal() bool {
+	return s == OrderStateDone || s == OrderStateCancelled || s == OrderStateRejected
+}
+
+func (s OrderState) rank() int {
+	switch s {
+	case OrderStatePending:
+		return 0
+	case OrderStateOpen:
+		return 1
+	case OrderStatePartiallyFilled:
+		return 2
+	default:
+		return 3
+	}
+}
+
+type ManagedOrder struct {
+	ClientOid     string     `json:"client_oid"`
+	OrderId       string     `json:"order_id"`
+	ProductId     string     `json:"product_id"`
+	Side          string     `json:"side"`
+	Type          string     `json:"type"`
+	Price         string     `json:"price,omitempty"`
+	Size          string     `json:"size,omitempty"`
+	Funds         string     `json:"funds,omitempty"`
+	FilledSize    float64    `json:"filled_size"`
+	ExecutedValue float64    `json:"executed_value"`
+	State         OrderState `json:"state"`
+	DoneReason    string     `json:"done_reason,omitempty"`
+	RejectReason  string     `json:"reject_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (o ManagedOrder) AverageFillPrice() float64 {
+	if o.FilledSize == 0 {
+		return 0
+	}
+
+	return o.ExecutedValue / o.FilledSize
+}
+
+type OrderUpdate struct {
+	Order    ManagedOrder
+	Previous OrderState
+	Source   string
+}
+
+// trackedOrder keeps the fills seen on the websocket apart from the last REST snapshot, the same
+// fill usually arrives through both. The order reports whichever of the two has seen more.
+type trackedOrder struct {
+	ManagedOrder
+	trades       map[int64]bool
+	matchedSize  float64
+	matchedValue float64
+	restSize     float64
+	restValue    float64
+}
+
+func (o *trackedOrder) reconcileFills() {
+	if o.restSize >= o.matchedSize {
+		o.FilledSize, o.ExecutedValue = o.restSize, o.restValue
+	} else {
+		o.FilledSize, o.ExecutedValue = o.matchedSize, o.matchedValue
+	}
+}
+
+// OrderManager places orders through a Client and follows each of them to a terminal state using
+// both REST polling and websocket user channel messages. It is safe for concurrent use.
+type OrderManager struct {
+	client          TradingAPI
+	clientOidPrefix string
+
+	mutex    sync.RWMutex
+	orders   map[string]*trackedOrder
+	orderIds map[string]string
+
+	subscribersMutex sync.RWMutex
+	subscribers      map[int]func(OrderUpdate)
+	nextSubscriberId int
+}
+
+func NewOrderManager(client TradingAPI, clientOidPrefix string) (*OrderManager, error) {
+	if client == nil {
+		return nil, errors.New("client is required")
+	}
+
+	if !clientOidPrefixPattern.MatchString(clientOidPrefix) {
+		return nil, errors.New(InvalidClientOidPrefixErrorMessage)
+	}
+
+	return &OrderManager{
+		client:          client,
+		clientOidPrefix: clientOidPrefix,
+		orders:          make(map[string]*trackedOrder),
+		orderIds:        make(map[string]string),
+		subscribers:     make(map[int]func(OrderUpdate)),
+	}, nil
+}
+
+// NewClientOid returns a random version 4 uuid whose first characters are replaced by prefix, so
+// orders placed by one bot can be recognised by their client_oid.
+func NewClientOid(prefix string) string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	uuid := []byte(fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16]))
+	copy(uuid, prefix)
+
+	return string(uuid)
+}
+
+func (t *OrderManager) ClientOidPrefix() string {
+	return t.clientOidPrefix
+}
+
+// Subscribe registers fn to be called after every order state change and returns a function that
+// removes the subscription.
+func (t *OrderManager) Subscribe(fn func(OrderUpdate)) func() {
+	t.subscribersMutex.Lock()
+	defer t.subscribersMutex.Unlock()
+
+	id := t.nextSubscriberId
+	t.nextSubscriberId++
+	t.subscribers[id] = fn
+
+	return func() {
+		t.subscribersMutex.Lock()
+		defer t.subscribersMutex.Unlock()
+
+		delete(t.subscribers, id)
+	}
+}
+
+func (t *OrderManager) notify(updates []OrderUpdate) {
+	if len(updates) == 0 {
+		return
+	}
+
+	t.subscribersMutex.RLock()
+	subscribers := make([]func(OrderUpdate), 0, len(t.subscribers))
+	for _, subscriber := range t.subscribers {
+		subscribers = append(subscribers, subscriber)
+	}
+	t.subscribersMutex.RUnlock()
+
+	for _, update := range updates {
+		for _, subscriber := range subscribers {
+			subscriber(update)
+		}
+	}
+}
+
+func (t *OrderManager) Place(order Order) (ManagedOrder, error) {
+	if order.ClientOid == "" {
+		order.ClientOid = NewClientOid(t.clientOidPrefix)
+	}
+
+	now := time.Now()
+	tracked := &trackedOrder{
+		ManagedOrder: ManagedOrder{
+			ClientOid: order.ClientOid,
+			ProductId: order.ProductId,
+			Side:      order.Side,
+			Type:      order.orderType(),
+			Price:     order.Price,
+			Size:      order.Size,
+			Funds:     order.Funds,
+			State:     OrderStatePending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		trades: make(map[int64]bool),
+	}
+
+	t.mutex.Lock()
+	if _, found := t.orders[order.ClientOid]; found {
+		t.mutex.Unlock()
+		return ManagedOrder{}, fmt.Errorf("client_oid %s is already tracked", order.ClientOid)
+	}
+	t.orders[order.ClientOid] = tracked
+	t.mutex.Unlock()
+
+	t.notify([]OrderUpdate{{Order: tracked.ManagedOrder, Source: UpdateSourceLocal}})
+
+	placed, err := t.client.PlaceOrder(order)
+	if err != nil {
+		var apiError ApiError
+		var riskRejection RiskRejection
+		if errors.As(err, &apiError) && apiError.StatusCode >= 400 && apiError.StatusCode < 500 && apiError.StatusCode != http.StatusTooManyRequests {
+			t.update(order.ClientOid, UpdateSourceLocal, func(o *trackedOrder) {
+				o.State = OrderStateRejected
+				o.RejectReason = apiError.Message
+			})
+		} else if errors.As(err, &riskRejection) {
+			t.update(order.ClientOid, UpdateSourceLocal, func(o *trackedOrder) {
+				o.State = OrderStateRejected
+				o.RejectReason = riskRejection.Error()
+			})
+		}
+
+		snapshot, _ := t.Order(order.ClientOid)
+		return snapshot, err
+	}
+
+	placed.ClientOid = order.ClientOid
+	t.applyRestOrder(placed)
+
+	snapshot, _ := t.Order(order.ClientOid)
+	return snapshot, nil
+}
+
+// Track adopts an order that was placed elsewhere, for example before a restart.
+func (t *OrderManager) Track(order Order) ManagedOrder {
+	if order.ClientOid == "" {
+		order.ClientOid = order.Id
+	}
+
+	t.mutex.Lock()
+	if _, found := t.orders[order.ClientOid]; !found {
+		now := time.Now()
+		t.orders[order.ClientOid] = &trackedOrder{
+			ManagedOrder: ManagedOrder{
+				ClientOid: order.ClientOid,
+				OrderId:   order.Id,
+				ProductId: order.ProductId,
+				Side:      order.Side,
+				Type:      order.orderType(),
+				Price:     order.Price,
+				Size:      order.Size,
+				Funds:     order.Funds,
+				State:     OrderStatePending,
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+			trades: make(map[int64]bool),
+		}
+	}
+	t.mutex.Unlock()
+
+	t.applyRestOrder(order)
+
+	snapshot, _ := t.Order(order.ClientOid)
+	return snapshot
+}
+
+func (t *OrderManager) Cancel(orderIdOrClientOid string) error {
+	snapshot, found := t.Order(orderIdOrClientOid)
+	if !found {
+		return fmt.Errorf("%s: %s", OrderNotTrackedErrorMessage, orderIdOrClientOid)
+	}
+
+	if snapshot.State.IsTerminal() {
+		return nil
+	}
+
+	var err error
+	if snapshot.OrderId != "" {
+		err = t.client.CancelOrder(snapshot.OrderId)
+	} else {
+		err = t.client.CancelOrderByClientOid(snapshot.ClientOid)
+	}
+
+	if err != nil {
+		var apiError ApiError
+		if errors.As(err, &apiError) && (apiError.StatusCode == http.StatusNotFound || apiError.StatusCode == http.StatusBadRequest) {
+			// the order may have finished in the meantime, find out how
+			t.pollOrder(snapshot.ClientOid)
+		}
+
+		return err
+	}
+
+	t.update(snapshot.ClientOid, UpdateSourceRest, func(o *trackedOrder) {
+		o.State = OrderStateCancelled
+		o.DoneReason = doneReasonCanceled
+	})
+
+	return nil
+}
+
+func (t *OrderManager) resolve(orderIdOrClientOid string) (string, bool) {
+	if _, found := t.orders[orderIdOrClientOid]; found {
+		return orderIdOrClientOid, true
+	}
+
+	clientOid, found := t.orderIds[orderIdOrClientOid]
+	return clientOid, found
+}
+
+func (t *OrderManager) Order(orderIdOrClientOid string) (ManagedOrder, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	clientOid, found := t.resolve(orderIdOrClientOid)
+	if !found {
+		return ManagedOrder{}, false
+	}
+
+	return t.orders[clientOid].ManagedOrder, true
+}
+
+func (t *OrderManager) Orders() []ManagedOrder {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	orders := make([]ManagedOrder, 0, len(t.orders))
+	for _, order := range t.orders {
+		orders = append(orders, order.ManagedOrder)
+	}
+
+	return orders
+}
+
+func (t *OrderManager) OpenOrders() []ManagedOrder {
+	orders := make([]ManagedOrder, 0)
+	for _, order := range t.Orders() {
+		if !order.State.IsTerminal() {
+			orders = append(orders, order)
+		}
+	}
+
+	return orders
+}
+
+// Forget stops tracking orders that reached a terminal state before the given time.
+func (t *OrderManager) Forget(before time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for clientOid, order := range t.orders {
+		if order.State.IsTerminal() && order.UpdatedAt.Before(before) {
+			delete(t.orders, clientOid)
+			delete(t.orderIds, order.OrderId)
+		}
+	}
+}
+
+// update applies change to the order under the lock and notifies subscribers when anything moved.
+// States only ever move forward, a terminal state is final.
+func (t *OrderManager) update(clientOid, source string, change func(o *trackedOrder)) {
+	t.mutex.Lock()
+	order, found := t.orders[clientOid]
+	if !found {
+		t.mutex.Unlock()
+		return
+	}
+
+	before := order.ManagedOrder
+	change(order)
+
+	if order.State.rank() < before.State.rank() || (before.State.IsTerminal() && order.State != before.State) {
+		order.State = before.State
+		order.DoneReason = before.DoneReason
+		order.RejectReason = before.RejectReason
+	}
+
+	if !order.State.IsTerminal() && order.FilledSize > 0 {
+		order.State = OrderStatePartiallyFilled
+	}
+
+	if order.OrderId != "" {
+		t.orderIds[order.OrderId] = clientOid
+	}
+
+	changed := order.ManagedOrder != before
+	if changed {
+		order.UpdatedAt = time.Now()
+	}
+	snapshot := order.ManagedOrder
+	t.mutex.Unlock()
+
+	if changed {
+		t.notify([]OrderUpdate{{Order: snapshot, Previous: before.State, Source: source}})
+	}
+}
+
+func (t *OrderManager) applyRestOrder(order Order) {
+	t.mutex.RLock()
+	clientOid, found := t.resolve(order.ClientOid)
+	if !found {
+		clientOid, found = t.resolve(order.Id)
+	}
+	t.mutex.RUnlock()
+
+	if !found {
+		return
+	}
+
+	filledSize, _ := parseDecimal(order.FilledSize)
+	executedValue, _ := parseDecimal(order.ExecutedValue)
+
+	t.update(clientOid, UpdateSourceRest, func(o *trackedOrder) {
+		if order.Id != "" {
+			o.OrderId = order.Id
+		}
+
+		if filledSize > o.restSize {
+			o.restSize, o.restValue = filledSize, executedValue
+		}
+		o.reconcileFills()
+
+		switch order.Status {
+		case "open", "active":
+			o.State = OrderStateOpen
+		case "rejected":
+			o.State = OrderStateRejected
+			o.RejectReason = order.RejectReason
+		case "done":
+			o.DoneReason = order.DoneReason
+			if order.DoneReason == doneReasonCanceled {
+				o.State = OrderStateCancelled
+			} else {
+				o.State = OrderStateDone
+			}
+		}
+	})
+}
+
+// Poll refreshes every non terminal order over REST.
+func (t *OrderManager) Poll() error {
+	var firstErr error
+	for _, order := range t.OpenOrders() {
+		if err := t.pollOrder(order.ClientOid); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Refresh polls one order over REST, including terminal ones, and returns its latest state. Use it
+// to learn the final filled size of a cancelled order before replacing it.
+func (t *OrderManager) Refresh(orderIdOrClientOid string) (ManagedOrder, error) {
+	t.mutex.RLock()
+	clientOid, found := t.resolve(orderIdOrClientOid)
+	t.mutex.RUnlock()
+
+	if !found {
+		return ManagedOrder{}, fmt.Errorf("%s: %s", OrderNotTrackedErrorMessage, orderIdOrClientOid)
+	}
+
+	err := t.pollOrder(clientOid)
+	snapshot, _ := t.Order(clientOid)
+
+	return snapshot, err
+}
+
+func (t *OrderManager) pollOrder(clientOid string) error {
+	snapshot, found := t.Order(clientOid)
+	if !found {
+		return fmt.Errorf("%s: %s", OrderNotTrackedErrorMessage, clientOid)
+	}
+
+	var order Order
+	var err error
+	if snapshot.OrderId != "" {
+		order, err = t.client.GetOrder(snapshot.OrderId)
+	} else {
+		order, err = t.client.GetOrderByClientOid(clientOid)
+	}
+
+	if err != nil {
+		var apiError ApiError
+		if errors.As(err, &apiError) && apiError.StatusCode == http.StatusNotFound {
+			// cancelled orders without fills are purged by the exchange, orders that were never
+			// accepted cannot be found by client_oid either
+			t.update(clientOid, UpdateSourceRest, func(o *trackedOrder) {
+				if o.OrderId != "" {
+					o.State = OrderStateCancelled
+					o.DoneReason = doneReasonCanceled
+				} else {
+					o.State = OrderStateRejected
+					o.RejectReason = apiError.Message
+				}
+			})
+
+			return nil
+		}
+
+		return err
+	}
+
+	order.ClientOid = clientOid
+	t.applyRestOrder(order)
+
+	return nil
+}
+
+// HandleMessage applies a websocket message from the user or full channel. Messages about orders
+// that are not tracked are ignored.
+func (t *OrderManager) HandleMessage(message Message) {
+	switch message.Type {
+	case MessageTypeReceived:
+		t.mutex.RLock()
+		clientOid, found := t.resolve(message.ClientOid)
+		t.mutex.RUnlock()
+
+		if !found {
+			return
+		}
+
+		t.update(clientOid, UpdateSourceWebsocket, func(o *trackedOrder) {
+			o.OrderId = message.OrderId
+		})
+	case MessageTypeOpen, MessageTypeActivate:
+		t.updateByOrderId(message.OrderId, func(o *trackedOrder) {
+			o.State = OrderStateOpen
+		})
+	case MessageTypeChange:
+		t.updateByOrderId(message.OrderId, func(o *trackedOrder) {
+			if message.NewSize != "" {
+				o.Size = message.NewSize
+			}
+		})
+	case MessageTypeMatch, MessageTypeLastMatch:
+		size, _ := parseDecimal(message.Size)
+		price, _ := parseDecimal(message.Price)
+
+		for _, orderId := range []string{message.MakerOrderId, message.TakerOrderId} {
+			t.updateByOrderId(orderId, func(o *trackedOrder) {
+				if o.trades[message.TradeId] {
+					return
+				}
+
+				o.trades[message.TradeId] = true
+				o.matchedSize += size
+				o.matchedValue += size * price
+				o.reconcileFills()
+			})
+		}
+	case MessageTypeDone:
+		t.updateByOrderId(message.OrderId, func(o *trackedOrder) {
+			o.DoneReason = message.Reason
+			if message.Reason == doneReasonCanceled {
+				o.State = OrderStateCancelled
+			} else {
+				o.State = OrderStateDone
+			}
+		})
+	}
+}
+
+func (t *OrderManager) updateByOrderId(orderId string, change func(o *trackedOrder)) {
+	if orderId == "" {
+		return
+	}
+
+	t.mutex.RLock()
+	clientOid, found := t.orderIds[orderId]
+	t.mutex.RUnlock()
+
+	if found {
+		t.update(clientOid, UpdateSourceWebsocket, change)
+	}
+}
+
+// Consume feeds every message from source into HandleMessage until source returns an error.
+func (t *OrderManager) Consume(source MessageSource) error {
+	for {
+		message, err := source.Read()
+		if err != nil {
+			return err
+		}
+
+		t.HandleMessage(message)
+	}
+}
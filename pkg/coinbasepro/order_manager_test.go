@@ -0,0 +1,216 @@
+package coinbasepro
+
+import (
+	"errors"
+	"fmt"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestOrderManager(t *testing.T) (*OrderManager, *fakeExchange) {
+	exchange := newFakeExchange(t)
+
+	manager, err := NewOrderManager(exchange.client(t), "b07")
+	assert.Assert(t, is.Nil(err), "unexpected error creating order manager", err)
+
+	return manager, exchange
+}
+
+type sliceMessageSource struct {
+	messages []Message
+}
+
+func (s *sliceMessageSource) Read() (Message, error) {
+	if len(s.messages) == 0 {
+		return Message{}, errors.New("end of messages")
+	}
+
+	message := s.messages[0]
+	s.messages = s.messages[1:]
+
+	return message, nil
+}
+
+func TestNewClientOid(t *testing.T) {
+	clientOid := NewClientOid("b07")
+	assert.Assert(t, uuidPattern.MatchString(clientOid), clientOid)
+	assert.Assert(t, strings.HasPrefix(clientOid, "b07"), clientOid)
+	assert.Assert(t, NewClientOid("b07") != clientOid)
+
+	_, err := NewOrderManager(&Client{}, "not-hex")
+	assert.Error(t, err, InvalidClientOidPrefixErrorMessage)
+}
+
+func TestOrderManager(t *testing.T) {
+	t.Run("should place orders with generated client_oids", func(t *testing.T) {
+		manager, _ := newTestOrderManager(t)
+
+		updates := make([]OrderUpdate, 0)
+		manager.Subscribe(func(update OrderUpdate) {
+			updates = append(updates, update)
+		})
+
+		order, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err), "unexpected error placing order", err)
+
+		assert.Assert(t, strings.HasPrefix(order.ClientOid, "b07"))
+		assert.Assert(t, order.OrderId != "")
+		assert.Equal(t, order.State, OrderStatePending)
+
+		byOrderId, found := manager.Order(order.OrderId)
+		assert.Assert(t, found)
+		assert.Equal(t, byOrderId.ClientOid, order.ClientOid)
+
+		assert.Equal(t, len(updates), 2)
+		assert.Equal(t, updates[0].Source, UpdateSourceLocal)
+		assert.Equal(t, updates[1].Source, UpdateSourceRest)
+		assert.Equal(t, updates[1].Order.OrderId, order.OrderId)
+	})
+
+	t.Run("should follow an order through websocket events", func(t *testing.T) {
+		manager, _ := newTestOrderManager(t)
+
+		states := make([]OrderState, 0)
+		manager.Subscribe(func(update OrderUpdate) {
+			if len(states) == 0 || states[len(states)-1] != update.Order.State {
+				states = append(states, update.Order.State)
+			}
+		})
+
+		order, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "2"})
+		assert.Assert(t, is.Nil(err))
+
+		source := &sliceMessageSource{messages: []Message{
+			{Type: MessageTypeReceived, OrderId: order.OrderId, ClientOid: order.ClientOid},
+			{Type: MessageTypeOpen, OrderId: order.OrderId, RemainingSize: "2"},
+			{Type: MessageTypeMatch, TradeId: 1, MakerOrderId: order.OrderId, TakerOrderId: "someone-else", Price: "100", Size: "0.5"},
+			{Type: MessageTypeMatch, TradeId: 1, MakerOrderId: order.OrderId, TakerOrderId: "someone-else", Price: "100", Size: "0.5"},
+			{Type: MessageTypeMatch, TradeId: 2, MakerOrderId: order.OrderId, TakerOrderId: "someone-else", Price: "99", Size: "1.5"},
+			{Type: MessageTypeDone, OrderId: order.OrderId, Reason: "filled", RemainingSize: "0"},
+			{Type: MessageTypeOpen, OrderId: order.OrderId},
+		}}
+
+		err = manager.Consume(source)
+		assert.Error(t, err, "end of messages")
+
+		tracked, found := manager.Order(order.ClientOid)
+		assert.Assert(t, found)
+		assert.Equal(t, tracked.State, OrderStateDone)
+		assert.Equal(t, tracked.FilledSize, 2.0)
+		assert.Equal(t, tracked.AverageFillPrice(), (50+148.5)/2)
+		assert.DeepEqual(t, states, []OrderState{OrderStatePending, OrderStateOpen, OrderStatePartiallyFilled, OrderStateDone})
+		assert.Equal(t, len(manager.OpenOrders()), 0)
+	})
+
+	t.Run("should reconcile state from REST polling", func(t *testing.T) {
+		manager, exchange := newTestOrderManager(t)
+
+		filled, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+		partial, err := manager.Place(Order{ProductId: "BTC-USD", Side: "sell", Price: "110", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		exchange.setOrderStatus(filled.OrderId, "done", "1")
+		exchange.setOrderStatus(partial.OrderId, "open", "0.25")
+
+		assert.Assert(t, is.Nil(manager.Poll()))
+
+		tracked, _ := manager.Order(filled.OrderId)
+		assert.Equal(t, tracked.State, OrderStateDone)
+		assert.Equal(t, tracked.FilledSize, 1.0)
+
+		tracked, _ = manager.Order(partial.OrderId)
+		assert.Equal(t, tracked.State, OrderStatePartiallyFilled)
+		assert.Equal(t, tracked.FilledSize, 0.25)
+	})
+
+	t.Run("should not count a fill twice when it is seen by REST and then the websocket", func(t *testing.T) {
+		manager, _ := newTestOrderManager(t)
+
+		tracked := manager.Track(Order{Id: "order-1", ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "2", FilledSize: "1", ExecutedValue: "100", Status: "open"})
+		assert.Equal(t, tracked.FilledSize, 1.0)
+
+		manager.HandleMessage(Message{Type: MessageTypeMatch, TradeId: 7, MakerOrderId: "order-1", Size: "1", Price: "100"})
+		tracked, _ = manager.Order("order-1")
+		assert.Equal(t, tracked.FilledSize, 1.0)
+		assert.Equal(t, tracked.ExecutedValue, 100.0)
+
+		manager.HandleMessage(Message{Type: MessageTypeMatch, TradeId: 8, MakerOrderId: "order-1", Size: "0.5", Price: "98"})
+		tracked, _ = manager.Order("order-1")
+		assert.Equal(t, tracked.FilledSize, 1.5)
+		assert.Equal(t, tracked.ExecutedValue, 149.0)
+
+		manager.Track(Order{Id: "order-1", ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "2", FilledSize: "1.5", ExecutedValue: "149", Status: "open"})
+		tracked, _ = manager.Order("order-1")
+		assert.Equal(t, tracked.FilledSize, 1.5)
+		assert.Equal(t, tracked.ExecutedValue, 149.0)
+	})
+
+	t.Run("should cancel orders and resolve cancel races", func(t *testing.T) {
+		manager, exchange := newTestOrderManager(t)
+
+		open, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+		raced, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, is.Nil(manager.Cancel(open.ClientOid)))
+		tracked, _ := manager.Order(open.ClientOid)
+		assert.Equal(t, tracked.State, OrderStateCancelled)
+		assert.DeepEqual(t, exchange.cancelled, []string{open.OrderId})
+
+		exchange.setOrderStatus(open.OrderId, "done", "0.4")
+		refreshed, err := manager.Refresh(open.ClientOid)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, refreshed.State, OrderStateCancelled)
+		assert.Equal(t, refreshed.FilledSize, 0.4)
+
+		exchange.setOrderStatus(raced.OrderId, "done", "1")
+		err = manager.Cancel(raced.OrderId)
+		assert.Error(t, err, "404 - order not found")
+
+		tracked, _ = manager.Order(raced.OrderId)
+		assert.Equal(t, tracked.State, OrderStateDone)
+
+		err = manager.Cancel("unknown")
+		assert.Error(t, err, OrderNotTrackedErrorMessage+": unknown")
+	})
+
+	t.Run("should mark orders rejected by the exchange", func(t *testing.T) {
+		manager, _ := newTestOrderManager(t)
+		manager.client.(*Client).Use(InterceptorFuncs{OnBeforeSign: func(call *Call) error {
+			return ApiError{StatusCode: 400, Message: "size too small"}
+		}})
+
+		order, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "0.000001"})
+		assert.Error(t, err, "400 - size too small")
+		assert.Equal(t, order.State, OrderStateRejected)
+		assert.Equal(t, order.RejectReason, "size too small")
+	})
+
+	t.Run("should be safe for concurrent use", func(t *testing.T) {
+		manager, _ := newTestOrderManager(t)
+
+		waitGroup := sync.WaitGroup{}
+		for strategy := 0; strategy < 4; strategy++ {
+			waitGroup.Add(1)
+			go func(strategy int) {
+				defer waitGroup.Done()
+
+				for i := 0; i < 5; i++ {
+					order, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: fmt.Sprint(100 + strategy), Size: "1"})
+					assert.Check(t, is.Nil(err))
+					manager.HandleMessage(Message{Type: MessageTypeOpen, OrderId: order.OrderId})
+					manager.Orders()
+				}
+			}(strategy)
+		}
+		waitGroup.Wait()
+
+		assert.Equal(t, len(manager.Orders()), 20)
+		assert.Equal(t, len(manager.OpenOrders()), 20)
+	})
+}
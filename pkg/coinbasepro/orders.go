@@ -0,0 +1,130 @@
+package coinbasepro
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const OrderTypeLimit = "limit"
+const OrderTypeMarket = "market"
+
+const OrderSideBuy = "buy"
+const OrderSideSell = "sell"
+
+type Order struct {
+	Id          string `json:"id,omitempty"`
+	ClientOid   string `json:"client_oid,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Side        string `json:"side"`
+	ProductId   string `json:"product_id"`
+	Price       string `json:"price,omitempty"`
+	Size        string `json:"size,omitempty"`
+	Funds       string `json:"funds,omitempty"`
+	Stp         string `json:"stp,omitempty"`
+	Stop        string `json:"stop,omitempty"`
+	StopPrice   string `json:"stop_price,omitempty"`
+	TimeInForce string `json:"time_in_force,omitempty"`
+	CancelAfter string `json:"cancel_after,omitempty"`
+	PostOnly    bool   `json:"post_only,omitempty"`
+
+	CreatedAt     string `json:"created_at,omitempty"`
+	DoneAt        string `json:"done_at,omitempty"`
+	DoneReason    string `json:"done_reason,omitempty"`
+	RejectReason  string `json:"reject_reason,omitempty"`
+	FillFees      string `json:"fill_fees,omitempty"`
+	FilledSize    string `json:"filled_size,omitempty"`
+	ExecutedValue string `json:"executed_value,omitempty"`
+	Status        string `json:"status,omitempty"`
+	Settled       bool   `json:"settled,omitempty"`
+}
+
+func (o Order) orderType() string {
+	if o.Type == "" {
+		return OrderTypeLimit
+	}
+
+	return o.Type
+}
+
+func (t *Client) PlaceOrder(order Order) (Order, error) {
+	var placed Order
+	_, err := t.executeRequest("POST", "/orders", order, &placed, defaultMaxRetriesOn429)
+	if err != nil {
+		return Order{}, err
+	}
+
+	return placed, nil
+}
+
+func (t *Client) CancelOrder(orderId string) error {
+	var cancelledId string
+	_, err := t.executeRequest("DELETE", fmt.Sprintf("/orders/%s", orderId), nil, &cancelledId, defaultMaxRetriesOn429)
+	return err
+}
+
+func (t *Client) CancelOrderByClientOid(clientOid string) error {
+	var cancelledId string
+	_, err := t.executeRequest("DELETE", fmt.Sprintf("/orders/client:%s", clientOid), nil, &cancelledId, defaultMaxRetriesOn429)
+	return err
+}
+
+func (t *Client) GetOrder(orderId string) (Order, error) {
+	var order Order
+	_, err := t.executeRequest("GET", fmt.Sprintf("/orders/%s", orderId), nil, &order, defaultMaxRetriesOn429)
+	if err != nil {
+		return Order{}, err
+	}
+
+	return order, nil
+}
+
+func (t *Client) GetOrderByClientOid(clientOid string) (Order, error) {
+	var order Order
+	_, err := t.executeRequest("GET", fmt.Sprintf("/orders/client:%s", clientOid), nil, &order, defaultMaxRetriesOn429)
+	if err != nil {
+		return Order{}, err
+	}
+
+	return order, nil
+}
+
+type OrderFilter struct {
+	ProductId string
+	ProfileId string
+	Statuses  []string
+}
+
+func (f OrderFilter) query() url.Values {
+	query := url.Values{}
+	if f.ProductId != "" {
+		query.Set("product_id", f.ProductId)
+	}
+
+	if f.ProfileId != "" {
+		query.Set("profile_id", f.ProfileId)
+	}
+
+	for _, status := range f.Statuses {
+		query.Add("status", status)
+	}
+
+	return query
+}
+
+// ListOrders returns open and pending orders unless other statuses are asked for in the filter,
+// following pagination until every matching order is listed.
+func (t *Client) ListOrders(filter OrderFilter) ([]Order, error) {
+	orders := make([]Order, 0)
+	err := t.getAllPages("/orders", filter.query(), func() (interface{}, func() int) {
+		var page []Order
+		return &page, func() int {
+			orders = append(orders, page...)
+			return len(page)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,31 @@
+package coinbasepro
+
+import (
+	"net/url"
+	"strconv"
+)
+
+const paginationAfterHeader = "CB-AFTER"
+const paginationLimit = 100
+
+// getAllPages follows the CB-AFTER cursor of a paginated list endpoint until a page comes back
+// short or without a cursor. page returns a fresh value to decode the next page into and a function
+// that collects it once decoded and says how many items it held, less than a full page to stop.
+func (t *Client) getAllPages(requestPath string, query url.Values, page func() (interface{}, func() int)) error {
+	query.Set("limit", strconv.Itoa(paginationLimit))
+
+	for {
+		body, collect := page()
+		call := newCall("GET", requestPath+"?"+query.Encode(), nil)
+		if _, err := t.executeCall(call, body, defaultMaxRetriesOn429); err != nil {
+			return err
+		}
+
+		after := call.ResponseHeader.Get(paginationAfterHeader)
+		if collect() < paginationLimit || after == "" || after == query.Get("after") {
+			return nil
+		}
+
+		query.Set("after", after)
+	}
+}
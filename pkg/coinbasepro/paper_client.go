@@ -0,0 +1,839 @@
+package coinbasepro
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const PaperClientClosedErrorMessage = "paper client is closed"
+const InsufficientFundsErrorMessage = "Insufficient funds"
+
+const doneReasonFilled = "filled"
+
+type FeeTier struct {
+	MakerFeeRate float64
+	TakerFeeRate float64
+}
+
+// DefaultFeeTier is the fee tier of an account without any trading volume.
+var DefaultFeeTier = FeeTier{MakerFeeRate: 0.005, TakerFeeRate: 0.005}
+
+type paperBalance struct {
+	balance float64
+	hold    float64
+}
+
+type paperOrder struct {
+	order    Order
+	sequence int64
+
+	price float64
+	size  float64
+	funds float64
+
+	filledSize    float64
+	executedValue float64
+	fillFees      float64
+
+	holdCurrency string
+	hold         float64
+	holdRate     float64
+}
+
+func (o *paperOrder) remainingSize() float64 {
+	return o.size - o.filledSize
+}
+
+func (o *paperOrder) isResting() bool {
+	return o.order.Status == "open"
+}
+
+// snapshot returns the order as the exchange would report it.
+func (o *paperOrder) snapshot() Order {
+	order := o.order
+	order.FilledSize = formatDecimal(o.filledSize, 8)
+	order.ExecutedValue = formatDecimal(o.executedValue, 8)
+	order.FillFees = formatDecimal(o.fillFees, 8)
+	order.Settled = order.Status == "done"
+
+	return order
+}
+
+type paperFill struct {
+	price float64
+	size  float64
+}
+
+// DefaultPaperEventLimit is how many unread user channel messages a PaperClient keeps.
+const DefaultPaperEventLimit = 10000
+
+// PaperClient is a TradingAPI that never leaves the process. Orders are matched against order books
+// built from live or recorded market data passed to HandleMessage, balances and holds are simulated,
+// and the resulting order lifecycle is published as user channel messages through Read. At most
+// the event limit of unread messages are kept, the oldest is dropped to make room for a new one
+// and counted, so a client nobody reads from never blocks trading or grows without bound. A
+// reader sees the gap in Sequence.
+type PaperClient struct {
+	profileId string
+
+	mutex       sync.Mutex
+	fees        FeeTier
+	books       map[string]*OrderBook
+	balances    map[string]*paperBalance
+	orders      map[string]*paperOrder
+	fills       []Fill
+	nextOrderId int64
+	nextTradeId int64
+	now         func() time.Time
+
+	eventsMutex sync.Mutex
+	eventsReady *sync.Cond
+	events      []Message
+	eventLimit  int
+	dropped     int
+	sequence    int64
+	closed      bool
+}
+
+func NewPaperClient(balances map[string]float64, fees FeeTier) *PaperClient {
+	client := &PaperClient{
+		profileId:  "paper",
+		fees:       fees,
+		books:      make(map[string]*OrderBook),
+		balances:   make(map[string]*paperBalance),
+		orders:     make(map[string]*paperOrder),
+		fills:      make([]Fill, 0),
+		now:        time.Now,
+		eventLimit: DefaultPaperEventLimit,
+	}
+	client.eventsReady = sync.NewCond(&client.eventsMutex)
+
+	for currency, amount := range balances {
+		client.balances[currency] = &paperBalance{balance: amount}
+	}
+
+	return client
+}
+
+func (p *PaperClient) SetFeeTier(fees FeeTier) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.fees = fees
+}
+
+// SetClock replaces the time source, used when replaying historical data.
+func (p *PaperClient) SetClock(now func() time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.now = now
+}
+
+func (p *PaperClient) Deposit(currency string, amount float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.balance(currency).balance += amount
+}
+
+func (p *PaperClient) Balance(currency string) Balance {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	balance := p.balance(currency)
+	return Balance{Balance: balance.balance, Available: balance.balance - balance.hold, Hold: balance.hold}
+}
+
+func (p *PaperClient) balance(currency string) *paperBalance {
+	balance, found := p.balances[currency]
+	if !found {
+		balance = &paperBalance{}
+		p.balances[currency] = balance
+	}
+
+	return balance
+}
+
+// OrderBook returns the simulated book for a product, creating an empty one if needed.
+func (p *PaperClient) OrderBook(productId string) *OrderBook {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.book(productId)
+}
+
+func (p *PaperClient) book(productId string) *OrderBook {
+	book, found := p.books[productId]
+	if !found {
+		book = NewOrderBook(productId)
+		p.books[productId] = book
+	}
+
+	return book
+}
+
+func (p *PaperClient) GetAccounts() ([]Account, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	accounts := make([]Account, 0, len(p.balances))
+	for currency, balance := range p.balances {
+		accounts = append(accounts, Account{
+			Id:             currency,
+			Currency:       currency,
+			Balance:        formatDecimal(balance.balance, 8),
+			Available:      formatDecimal(balance.balance-balance.hold, 8),
+			Hold:           formatDecimal(balance.hold, 8),
+			ProfileId:      p.profileId,
+			TradingEnabled: true,
+		})
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Currency < accounts[j].Currency
+	})
+
+	return accounts, nil
+}
+
+func (p *PaperClient) GetAccount(accountId string) (Account, error) {
+	accounts, _ := p.GetAccounts()
+	for _, account := range accounts {
+		if account.Id == accountId {
+			return account, nil
+		}
+	}
+
+	return Account{}, ApiError{StatusCode: http.StatusNotFound, Message: "NotFound"}
+}
+
+func splitProductId(productId string) (string, string, error) {
+	parts := strings.Split(productId, "-")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid product_id"}
+	}
+
+	return parts[0], parts[1], nil
+}
+
+func (p *PaperClient) PlaceOrder(order Order) (Order, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	base, quote, err := splitProductId(order.ProductId)
+	if err != nil {
+		return Order{}, err
+	}
+
+	if order.Side != OrderSideBuy && order.Side != OrderSideSell {
+		return Order{}, ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid side"}
+	}
+
+	placed := &paperOrder{order: order}
+	placed.order.Type = order.orderType()
+	if placed.price, err = parseDecimal(order.Price); err != nil {
+		return Order{}, ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid price"}
+	}
+
+	if placed.size, err = parseDecimal(order.Size); err != nil {
+		return Order{}, ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid size"}
+	}
+
+	if placed.funds, err = parseDecimal(order.Funds); err != nil {
+		return Order{}, ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid funds"}
+	}
+
+	switch placed.order.Type {
+	case OrderTypeLimit:
+		if placed.price <= 0 || placed.size <= 0 {
+			return Order{}, ApiError{StatusCode: http.StatusBadRequest, Message: "Limit orders require price and size"}
+		}
+	case OrderTypeMarket:
+		if placed.size <= 0 && (placed.funds <= 0 || order.Side == OrderSideSell) {
+			return Order{}, ApiError{StatusCode: http.StatusBadRequest, Message: "Market orders require size or funds"}
+		}
+	default:
+		return Order{}, ApiError{StatusCode: http.StatusBadRequest, Message: "Invalid order type"}
+	}
+
+	book := p.book(order.ProductId)
+	plan := p.planTakerFills(book, placed)
+
+	if placed.order.Type == OrderTypeLimit && order.PostOnly && len(plan) > 0 {
+		p.nextOrderId++
+		placed.sequence = p.nextOrderId
+		placed.order.Id = paperOrderId(placed.sequence)
+		placed.order.CreatedAt = p.now().UTC().Format(time.RFC3339Nano)
+		placed.order.Status = "rejected"
+		placed.order.RejectReason = "post only"
+		p.orders[placed.order.Id] = placed
+
+		return placed.snapshot(), nil
+	}
+
+	if order.TimeInForce == "FOK" && !plannedFully(placed, plan) {
+		return Order{}, ApiError{StatusCode: http.StatusBadRequest, Message: "Order could not be filled completely"}
+	}
+
+	placed.holdRate = math.Max(p.fees.MakerFeeRate, p.fees.TakerFeeRate)
+	if order.Side == OrderSideBuy {
+		placed.holdCurrency = quote
+		switch {
+		case placed.order.Type == OrderTypeLimit:
+			placed.hold = placed.price * placed.size * (1 + placed.holdRate)
+		case placed.funds > 0:
+			placed.hold = placed.funds
+		default:
+			placed.hold = plannedCost(plan) * (1 + p.fees.TakerFeeRate)
+		}
+	} else {
+		placed.holdCurrency = base
+		placed.hold = placed.size
+	}
+
+	holdBalance := p.balance(placed.holdCurrency)
+	if placed.hold > holdBalance.balance-holdBalance.hold+incrementEpsilon {
+		return Order{}, ApiError{StatusCode: http.StatusBadRequest, Message: InsufficientFundsErrorMessage}
+	}
+	holdBalance.hold += placed.hold
+
+	p.nextOrderId++
+	placed.sequence = p.nextOrderId
+	placed.order.Id = paperOrderId(placed.sequence)
+	placed.order.CreatedAt = p.now().UTC().Format(time.RFC3339Nano)
+	placed.order.Status = "pending"
+	p.orders[placed.order.Id] = placed
+
+	p.emit(Message{
+		Type:      MessageTypeReceived,
+		ProductId: order.ProductId,
+		OrderId:   placed.order.Id,
+		ClientOid: order.ClientOid,
+		OrderType: placed.order.Type,
+		Side:      order.Side,
+		Price:     order.Price,
+		Size:      order.Size,
+		Funds:     order.Funds,
+	})
+
+	for _, fill := range plan {
+		p.fill(placed, fill.price, fill.size, "T")
+		p.consumeLevel(book, oppositeSide(order.Side), fill.price, fill.size)
+	}
+
+	switch {
+	case placed.order.Type == OrderTypeMarket:
+		p.finish(placed, doneReasonFilled)
+	case placed.remainingSize() <= incrementEpsilon:
+		p.finish(placed, doneReasonFilled)
+	case order.TimeInForce == "IOC" || order.TimeInForce == "FOK":
+		p.finish(placed, doneReasonCanceled)
+	default:
+		placed.order.Status = "open"
+		p.emit(Message{
+			Type:          MessageTypeOpen,
+			ProductId:     order.ProductId,
+			OrderId:       placed.order.Id,
+			Side:          order.Side,
+			Price:         order.Price,
+			RemainingSize: formatDecimal(placed.remainingSize(), 8),
+		})
+	}
+
+	return placed.snapshot(), nil
+}
+
+func paperOrderId(sequence int64) string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", sequence)
+}
+
+func oppositeSide(side string) string {
+	if side == OrderSideBuy {
+		return OrderSideSell
+	}
+
+	return OrderSideBuy
+}
+
+// planTakerFills works out how an incoming order would take liquidity from the book without
+// changing it.
+func (p *PaperClient) planTakerFills(book *OrderBook, order *paperOrder) []paperFill {
+	levels := book.Asks(0)
+	crosses := func(price float64) bool { return price <= order.price }
+	if order.order.Side == OrderSideSell {
+		levels = book.Bids(0)
+		crosses = func(price float64) bool { return price >= order.price }
+	}
+
+	remainingSize := order.size
+	remainingFunds := order.funds
+	plan := make([]paperFill, 0)
+
+	for _, level := range levels {
+		if order.order.Type == OrderTypeLimit && !crosses(level.Price) {
+			break
+		}
+
+		size := level.Size
+		if order.size > 0 {
+			size = math.Min(size, remainingSize)
+			remainingSize -= size
+		} else {
+			size = math.Min(size, remainingFunds/(level.Price*(1+p.fees.TakerFeeRate)))
+			remainingFunds -= size * level.Price * (1 + p.fees.TakerFeeRate)
+		}
+
+		if size <= incrementEpsilon {
+			break
+		}
+
+		plan = append(plan, paperFill{price: level.Price, size: size})
+	}
+
+	return plan
+}
+
+func plannedFully(order *paperOrder, plan []paperFill) bool {
+	var size float64
+	for _, fill := range plan {
+		size += fill.size
+	}
+
+	return order.size-size <= incrementEpsilon
+}
+
+func plannedCost(plan []paperFill) float64 {
+	var cost float64
+	for _, fill := range plan {
+		cost += fill.price * fill.size
+	}
+
+	return cost
+}
+
+func (p *PaperClient) consumeLevel(book *OrderBook, side string, price, size float64) {
+	levels := book.Asks(0)
+	if side == OrderSideBuy {
+		levels = book.Bids(0)
+	}
+
+	for _, level := range levels {
+		if level.Price == price {
+			book.Set(side, price, level.Size-size)
+			return
+		}
+	}
+}
+
+// fill executes part of an order, moving balances, releasing the matching part of the hold and
+// publishing a match message.
+func (p *PaperClient) fill(order *paperOrder, price, size float64, liquidity string) {
+	base, quote, _ := splitProductId(order.order.ProductId)
+
+	feeRate := p.fees.TakerFeeRate
+	if liquidity == "M" {
+		feeRate = p.fees.MakerFeeRate
+	}
+
+	value := price * size
+	fee := value * feeRate
+
+	release := size
+	if order.order.Side == OrderSideBuy {
+		p.balance(quote).balance -= value + fee
+		p.balance(base).balance += size
+
+		release = value + fee
+		if order.order.Type == OrderTypeLimit {
+			release = size * order.price * (1 + order.holdRate)
+		}
+	} else {
+		p.balance(base).balance -= size
+		p.balance(quote).balance += value - fee
+	}
+
+	release = math.Min(release, order.hold)
+	order.hold -= release
+	p.balance(order.holdCurrency).hold -= release
+
+	order.filledSize += size
+	order.executedValue += value
+	order.fillFees += fee
+
+	p.nextTradeId++
+	createdAt := p.now().UTC().Format(time.RFC3339Nano)
+	p.fills = append(p.fills, Fill{
+		TradeId:   p.nextTradeId,
+		ProductId: order.order.ProductId,
+		OrderId:   order.order.Id,
+		ProfileId: p.profileId,
+		Price:     formatDecimal(price, 8),
+		Size:      formatDecimal(size, 8),
+		Fee:       formatDecimal(fee, 8),
+		Side:      order.order.Side,
+		Liquidity: liquidity,
+		Settled:   true,
+		CreatedAt: createdAt,
+	})
+
+	match := Message{
+		Type:      MessageTypeMatch,
+		ProductId: order.order.ProductId,
+		TradeId:   p.nextTradeId,
+		Price:     formatDecimal(price, 8),
+		Size:      formatDecimal(size, 8),
+	}
+
+	if liquidity == "M" {
+		match.MakerOrderId = order.order.Id
+		match.Side = order.order.Side
+	} else {
+		match.TakerOrderId = order.order.Id
+		match.Side = oppositeSide(order.order.Side)
+	}
+
+	p.emit(match)
+}
+
+func (p *PaperClient) finish(order *paperOrder, reason string) {
+	p.balance(order.holdCurrency).hold -= order.hold
+	order.hold = 0
+
+	if reason == doneReasonFilled && order.filledSize <= incrementEpsilon {
+		reason = doneReasonCanceled
+	}
+
+	order.order.Status = "done"
+	order.order.DoneReason = reason
+	order.order.DoneAt = p.now().UTC().Format(time.RFC3339Nano)
+
+	remainingSize := 0.0
+	if order.size > 0 {
+		remainingSize = math.Max(order.remainingSize(), 0)
+	}
+
+	p.emit(Message{
+		Type:          MessageTypeDone,
+		ProductId:     order.order.ProductId,
+		OrderId:       order.order.Id,
+		Side:          order.order.Side,
+		Price:         order.order.Price,
+		Reason:        reason,
+		RemainingSize: formatDecimal(remainingSize, 8),
+	})
+}
+
+// restingOrders returns the open orders for a product in price time priority for one side.
+func (p *PaperClient) restingOrders(productId, side string) []*paperOrder {
+	resting := make([]*paperOrder, 0)
+	for _, order := range p.orders {
+		if order.isResting() && order.order.ProductId == productId && order.order.Side == side {
+			resting = append(resting, order)
+		}
+	}
+
+	sort.Slice(resting, func(i, j int) bool {
+		if resting[i].price != resting[j].price {
+			if side == OrderSideBuy {
+				return resting[i].price > resting[j].price
+			}
+
+			return resting[i].price < resting[j].price
+		}
+
+		return resting[i].sequence < resting[j].sequence
+	})
+
+	return resting
+}
+
+// matchAgainstBook fills resting orders that the book has moved through, as if the liquidity now
+// on the other side had arrived as takers.
+func (p *PaperClient) matchAgainstBook(productId string) {
+	book := p.book(productId)
+
+	for _, side := range []string{OrderSideBuy, OrderSideSell} {
+		for _, order := range p.restingOrders(productId, side) {
+			for order.remainingSize() > incrementEpsilon {
+				level, found := book.BestAsk()
+				crosses := level.Price <= order.price
+				if side == OrderSideSell {
+					level, found = book.BestBid()
+					crosses = level.Price >= order.price
+				}
+
+				if !found || !crosses {
+					break
+				}
+
+				size := math.Min(level.Size, order.remainingSize())
+				p.fill(order, order.price, size, "M")
+				book.Set(oppositeSide(side), level.Price, level.Size-size)
+			}
+
+			if order.remainingSize() <= incrementEpsilon {
+				p.finish(order, doneReasonFilled)
+			}
+		}
+	}
+}
+
+// matchAgainstTrade fills resting orders priced through a trade printed on the exchange, the trade
+// size is shared out in price time priority.
+func (p *PaperClient) matchAgainstTrade(productId string, price, size float64) {
+	for _, side := range []string{OrderSideBuy, OrderSideSell} {
+		remaining := size
+		for _, order := range p.restingOrders(productId, side) {
+			crossed := order.price > price
+			if side == OrderSideSell {
+				crossed = order.price < price
+			}
+
+			if !crossed || remaining <= incrementEpsilon {
+				break
+			}
+
+			filled := math.Min(remaining, order.remainingSize())
+			remaining -= filled
+			p.fill(order, order.price, filled, "M")
+
+			if order.remainingSize() <= incrementEpsilon {
+				p.finish(order, doneReasonFilled)
+			}
+		}
+	}
+}
+
+// HandleMessage feeds market data into the simulation, level2 messages update the books and
+// matches printed on the exchange can fill resting orders.
+func (p *PaperClient) HandleMessage(message Message) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	switch message.Type {
+	case MessageTypeSnapshot, MessageTypeL2Update:
+		if err := p.book(message.ProductId).HandleMessage(message); err != nil {
+			return err
+		}
+
+		p.matchAgainstBook(message.ProductId)
+	case MessageTypeMatch, MessageTypeLastMatch:
+		if _, own := p.orders[message.MakerOrderId]; own {
+			return nil
+		}
+
+		if _, own := p.orders[message.TakerOrderId]; own {
+			return nil
+		}
+
+		price, err := parseDecimal(message.Price)
+		if err != nil {
+			return err
+		}
+
+		size, err := parseDecimal(message.Size)
+		if err != nil {
+			return err
+		}
+
+		p.matchAgainstTrade(message.ProductId, price, size)
+	}
+
+	return nil
+}
+
+// Consume feeds every message from source into HandleMessage until source fails.
+func (p *PaperClient) Consume(source MessageSource) error {
+	for {
+		message, err := source.Read()
+		if err != nil {
+			return err
+		}
+
+		if err := p.HandleMessage(message); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *PaperClient) CancelOrder(orderId string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	order, found := p.orders[orderId]
+	if !found || !order.isResting() {
+		return ApiError{StatusCode: http.StatusNotFound, Message: "order not found"}
+	}
+
+	p.finish(order, doneReasonCanceled)
+
+	return nil
+}
+
+func (p *PaperClient) CancelOrderByClientOid(clientOid string) error {
+	order, err := p.GetOrderByClientOid(clientOid)
+	if err != nil {
+		return err
+	}
+
+	return p.CancelOrder(order.Id)
+}
+
+func (p *PaperClient) GetOrder(orderId string) (Order, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	order, found := p.orders[orderId]
+	if !found {
+		return Order{}, ApiError{StatusCode: http.StatusNotFound, Message: "NotFound"}
+	}
+
+	return order.snapshot(), nil
+}
+
+func (p *PaperClient) GetOrderByClientOid(clientOid string) (Order, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, order := range p.orders {
+		if clientOid != "" && order.order.ClientOid == clientOid {
+			return order.snapshot(), nil
+		}
+	}
+
+	return Order{}, ApiError{StatusCode: http.StatusNotFound, Message: "NotFound"}
+}
+
+func (p *PaperClient) ListOrders(filter OrderFilter) ([]Order, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{"open", "pending", "active"}
+	}
+
+	matched := make([]*paperOrder, 0)
+	for _, order := range p.orders {
+		if filter.ProductId != "" && order.order.ProductId != filter.ProductId {
+			continue
+		}
+
+		for _, status := range statuses {
+			if status == "all" || status == order.order.Status {
+				matched = append(matched, order)
+				break
+			}
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].sequence > matched[j].sequence
+	})
+
+	orders := make([]Order, 0, len(matched))
+	for _, order := range matched {
+		orders = append(orders, order.snapshot())
+	}
+
+	return orders, nil
+}
+
+func (p *PaperClient) GetFills(filter FillFilter) ([]Fill, error) {
+	if filter.OrderId == "" && filter.ProductId == "" {
+		return nil, errors.New(MissingFillFilterErrorMessage)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	fills := make([]Fill, 0)
+	for i := len(p.fills) - 1; i >= 0; i-- {
+		if filter.Includes(p.fills[i]) {
+			fills = append(fills, p.fills[i])
+		}
+	}
+
+	return fills, nil
+}
+
+func (p *PaperClient) emit(message Message) {
+	p.eventsMutex.Lock()
+	defer p.eventsMutex.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	p.sequence++
+	message.Sequence = p.sequence
+	message.ProfileId = p.profileId
+	if message.Time == "" {
+		message.Time = p.now().UTC().Format(time.RFC3339Nano)
+	}
+
+	if len(p.events) >= p.eventLimit {
+		dropped := len(p.events) - p.eventLimit + 1
+		p.events = p.events[dropped:]
+		p.dropped += dropped
+	}
+
+	p.events = append(p.events, message)
+	p.eventsReady.Signal()
+}
+
+// Read returns the next user channel message, blocking until one is available. It makes a
+// PaperClient a MessageSource for OrderManager.Consume.
+func (p *PaperClient) Read() (Message, error) {
+	p.eventsMutex.Lock()
+	defer p.eventsMutex.Unlock()
+
+	for len(p.events) == 0 && !p.closed {
+		p.eventsReady.Wait()
+	}
+
+	if len(p.events) == 0 {
+		return Message{}, errors.New(PaperClientClosedErrorMessage)
+	}
+
+	message := p.events[0]
+	p.events = p.events[1:]
+
+	return message, nil
+}
+
+// SetEventLimit sets how many unread messages are kept before the oldest are dropped.
+func (p *PaperClient) SetEventLimit(limit int) {
+	p.eventsMutex.Lock()
+	defer p.eventsMutex.Unlock()
+
+	if limit < 1 {
+		limit = 1
+	}
+	p.eventLimit = limit
+}
+
+// EventsDropped is the number of messages dropped because nothing read them in time.
+func (p *PaperClient) EventsDropped() int {
+	p.eventsMutex.Lock()
+	defer p.eventsMutex.Unlock()
+
+	return p.dropped
+}
+
+func (p *PaperClient) Close() error {
+	p.eventsMutex.Lock()
+	defer p.eventsMutex.Unlock()
+
+	p.closed = true
+	p.eventsReady.Broadcast()
+
+	return nil
+}
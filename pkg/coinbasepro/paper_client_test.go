@@ -0,0 +1,191 @@
+package coinbasepro
+
+import (
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"math"
+	"testing"
+)
+
+func newTestPaperClient(t *testing.T) *PaperClient {
+	paper := NewPaperClient(map[string]float64{"USD": 10000, "BTC": 2}, FeeTier{MakerFeeRate: 0.001, TakerFeeRate: 0.002})
+	t.Cleanup(func() { paper.Close() })
+
+	err := paper.HandleMessage(Message{
+		Type:      MessageTypeSnapshot,
+		ProductId: "BTC-USD",
+		Bids:      [][]string{{"99", "1"}, {"98", "2"}},
+		Asks:      [][]string{{"101", "1"}, {"102", "2"}},
+	})
+	assert.Assert(t, is.Nil(err))
+
+	return paper
+}
+
+func assertClose(t *testing.T, actual, expected float64) {
+	t.Helper()
+	assert.Assert(t, math.Abs(actual-expected) < 1e-9, "expected %v, got %v", expected, actual)
+}
+
+func readTypes(t *testing.T, paper *PaperClient, count int) []string {
+	types := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		message, err := paper.Read()
+		assert.Assert(t, is.Nil(err))
+		types = append(types, message.Type)
+	}
+
+	return types
+}
+
+func TestOrderBook(t *testing.T) {
+	book := NewOrderBook("BTC-USD")
+	assert.Assert(t, is.Nil(book.HandleMessage(Message{
+		Type:      MessageTypeSnapshot,
+		ProductId: "BTC-USD",
+		Bids:      [][]string{{"98", "2"}, {"99", "1"}},
+		Asks:      [][]string{{"102", "2"}, {"101", "1"}},
+	})))
+
+	assert.Assert(t, is.Nil(book.HandleMessage(Message{
+		Type:      MessageTypeL2Update,
+		ProductId: "BTC-USD",
+		Changes:   [][]string{{"buy", "99", "0"}, {"buy", "100", "3"}, {"sell", "101.5", "4"}},
+	})))
+	assert.Assert(t, is.Nil(book.HandleMessage(Message{Type: MessageTypeL2Update, ProductId: "ETH-USD", Changes: [][]string{{"buy", "1000", "1"}}})))
+
+	assert.DeepEqual(t, book.Bids(0), []PriceLevel{{Price: 100, Size: 3}, {Price: 98, Size: 2}})
+	assert.DeepEqual(t, book.Asks(2), []PriceLevel{{Price: 101, Size: 1}, {Price: 101.5, Size: 4}})
+
+	mid, found := book.Mid()
+	assert.Assert(t, found)
+	assert.Equal(t, mid, 100.5)
+
+	assert.Error(t, book.HandleMessage(Message{Type: MessageTypeL2Update, Changes: [][]string{{"buy", "1"}}}), "malformed l2update change [buy 1]")
+}
+
+func TestPaperClient(t *testing.T) {
+	t.Run("should take liquidity and rest the remainder", func(t *testing.T) {
+		paper := newTestPaperClient(t)
+
+		order, err := paper.PlaceOrder(Order{ClientOid: "b07-1", ProductId: "BTC-USD", Side: OrderSideBuy, Price: "101", Size: "1.5"})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, order.Status, "open")
+		assert.Equal(t, order.FilledSize, "1.00000000")
+
+		ask, _ := paper.OrderBook("BTC-USD").BestAsk()
+		assert.Equal(t, ask.Price, 102.0)
+
+		usd := paper.Balance("USD")
+		assertClose(t, usd.Balance, 10000-101-0.202)
+		assertClose(t, usd.Hold, 0.5*101*1.002)
+		assertClose(t, paper.Balance("BTC").Balance, 3)
+
+		assert.DeepEqual(t, readTypes(t, paper, 3), []string{MessageTypeReceived, MessageTypeMatch, MessageTypeOpen})
+
+		assert.Assert(t, is.Nil(paper.HandleMessage(Message{Type: MessageTypeMatch, ProductId: "BTC-USD", Price: "100", Size: "0.2"})))
+		assert.Assert(t, is.Nil(paper.HandleMessage(Message{Type: MessageTypeL2Update, ProductId: "BTC-USD", Changes: [][]string{{"sell", "100.5", "5"}}})))
+
+		order, err = paper.GetOrderByClientOid("b07-1")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, order.Status, "done")
+		assert.Equal(t, order.DoneReason, "filled")
+		assert.Equal(t, order.FilledSize, "1.50000000")
+
+		fills, err := paper.GetFills(FillFilter{OrderId: order.Id})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(fills), 3)
+		assert.Equal(t, fills[0].Liquidity, "M")
+		assert.Equal(t, fills[0].Size, "0.30000000")
+		assert.Equal(t, fills[2].Liquidity, "T")
+
+		usd = paper.Balance("USD")
+		assertClose(t, usd.Hold, 0)
+		assertClose(t, usd.Balance, 10000-101*1.002-0.5*101*1.001)
+		ask, _ = paper.OrderBook("BTC-USD").BestAsk()
+		assert.Equal(t, ask, PriceLevel{Price: 100.5, Size: 4.7})
+
+		assert.DeepEqual(t, readTypes(t, paper, 3), []string{MessageTypeMatch, MessageTypeMatch, MessageTypeDone})
+	})
+
+	t.Run("should fill market orders by size and funds", func(t *testing.T) {
+		paper := newTestPaperClient(t)
+
+		sold, err := paper.PlaceOrder(Order{Type: OrderTypeMarket, ProductId: "BTC-USD", Side: OrderSideSell, Size: "2"})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, sold.Status, "done")
+		assert.Equal(t, sold.ExecutedValue, "197.00000000")
+		assertClose(t, paper.Balance("USD").Balance, 10000+197*0.998)
+
+		bought, err := paper.PlaceOrder(Order{Type: OrderTypeMarket, ProductId: "BTC-USD", Side: OrderSideBuy, Funds: "101.202"})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, bought.FilledSize, "1.00000000")
+		assertClose(t, paper.Balance("USD").Hold, 0)
+	})
+
+	t.Run("should reject orders it cannot accept", func(t *testing.T) {
+		paper := newTestPaperClient(t)
+
+		_, err := paper.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "1000"})
+		assert.Error(t, err, "400 - "+InsufficientFundsErrorMessage)
+
+		_, err = paper.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideSell, Price: "100", Size: "3"})
+		assert.Error(t, err, "400 - "+InsufficientFundsErrorMessage)
+
+		rejected, err := paper.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "101", Size: "1", PostOnly: true})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, rejected.Status, "rejected")
+		assert.Equal(t, rejected.RejectReason, "post only")
+
+		_, err = paper.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "102", Size: "5", TimeInForce: "FOK"})
+		assert.Error(t, err, "400 - Order could not be filled completely")
+
+		_, err = paper.PlaceOrder(Order{ProductId: "BTCUSD", Side: OrderSideBuy, Price: "1", Size: "1"})
+		assert.Error(t, err, "400 - Invalid product_id")
+
+		_, err = paper.GetFills(FillFilter{})
+		assert.Error(t, err, MissingFillFilterErrorMessage)
+	})
+
+	t.Run("should release holds when orders are cancelled", func(t *testing.T) {
+		paper := newTestPaperClient(t)
+
+		order, err := paper.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideSell, Price: "110", Size: "1.5"})
+		assert.Assert(t, is.Nil(err))
+		assertClose(t, paper.Balance("BTC").Available, 0.5)
+
+		open, err := paper.ListOrders(OrderFilter{ProductId: "BTC-USD"})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(open), 1)
+
+		assert.Assert(t, is.Nil(paper.CancelOrder(order.Id)))
+		assertClose(t, paper.Balance("BTC").Available, 2)
+		assert.Error(t, paper.CancelOrder(order.Id), "404 - order not found")
+
+		accounts, err := paper.GetAccounts()
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, accounts[0].Currency, "BTC")
+		assert.Equal(t, accounts[0].Available, "2.00000000")
+
+		assert.Assert(t, is.Nil(paper.Close()))
+		assert.DeepEqual(t, readTypes(t, paper, 3), []string{MessageTypeReceived, MessageTypeOpen, MessageTypeDone})
+		_, err = paper.Read()
+		assert.Error(t, err, PaperClientClosedErrorMessage)
+	})
+
+	t.Run("should drop the oldest unread messages past the event limit", func(t *testing.T) {
+		paper := newTestPaperClient(t)
+		paper.SetEventLimit(4)
+
+		for i := 0; i < 3; i++ {
+			_, err := paper.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "90", Size: "0.1"})
+			assert.Assert(t, is.Nil(err))
+		}
+		assert.Equal(t, paper.EventsDropped(), 2)
+
+		message, err := paper.Read()
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, message.Sequence, int64(3))
+		assert.DeepEqual(t, readTypes(t, paper, 3), []string{MessageTypeOpen, MessageTypeReceived, MessageTypeOpen})
+	})
+}
@@ -0,0 +1,368 @@
+package coinbasepro
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const ProductNotFoundErrorMessage = "product not found"
+const ProductTradingDisabledErrorMessage = "trading is disabled for product"
+const ProductCancelOnlyErrorMessage = "product is in cancel only mode"
+const ProductLimitOnlyErrorMessage = "product only accepts limit orders"
+const ProductPostOnlyErrorMessage = "product only accepts post only limit orders"
+const InvalidOrderSideErrorMessage = "order side must be buy or sell"
+const InvalidOrderTypeErrorMessage = "order type must be limit or market"
+const InvalidPriceIncrementErrorMessage = "price is not a multiple of quote_increment"
+const InvalidSizeIncrementErrorMessage = "size is not a multiple of base_increment"
+const InvalidFundsIncrementErrorMessage = "funds is not a multiple of quote_increment"
+const SizeBelowMinimumErrorMessage = "size is below base_min_size"
+const SizeAboveMaximumErrorMessage = "size is above base_max_size"
+const FundsBelowMinimumErrorMessage = "funds are below min_market_funds"
+const FundsAboveMaximumErrorMessage = "funds are above max_market_funds"
+const MissingPriceErrorMessage = "limit orders require a price"
+const MissingSizeErrorMessage = "limit orders require a size"
+const MissingSizeOrFundsErrorMessage = "market orders require a size or funds"
+
+const defaultCatalogueRefreshInterval = 5 * time.Minute
+
+type ProductCatalogue struct {
+	api             MarketDataAPI
+	refreshInterval time.Duration
+
+	mutex            sync.RWMutex
+	products         map[string]Product
+	lastRefresh      time.Time
+	lastRefreshError error
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewProductCatalogue loads the products from api, a Client or anything else serving market data.
+func NewProductCatalogue(api MarketDataAPI, refreshInterval time.Duration) (*ProductCatalogue, error) {
+	if api == nil {
+		return nil, errors.New("market data api is required")
+	}
+
+	if refreshInterval <= 0 {
+		refreshInterval = defaultCatalogueRefreshInterval
+	}
+
+	catalogue := ProductCatalogue{
+		api:             api,
+		refreshInterval: refreshInterval,
+		products:        make(map[string]Product),
+	}
+
+	if err := catalogue.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return &catalogue, nil
+}
+
+func (t *ProductCatalogue) Refresh() error {
+	products, err := t.api.GetProducts()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.lastRefreshError = err
+	if err != nil {
+		return err
+	}
+
+	refreshed := make(map[string]Product, len(products))
+	for _, product := range products {
+		refreshed[product.Id] = product
+	}
+
+	t.products = refreshed
+	t.lastRefresh = time.Now()
+
+	return nil
+}
+
+// Start refreshes the catalogue in the background until Stop is called.
+func (t *ProductCatalogue) Start() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.stop != nil {
+		return
+	}
+
+	t.stop = make(chan struct{})
+	t.stopped = make(chan struct{})
+
+	go t.refreshLoop(t.stop, t.stopped)
+}
+
+func (t *ProductCatalogue) Stop() {
+	t.mutex.Lock()
+	stop, stopped := t.stop, t.stopped
+	t.stop, t.stopped = nil, nil
+	t.mutex.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-stopped
+}
+
+func (t *ProductCatalogue) refreshLoop(stop, stopped chan struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(t.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			_ = t.Refresh()
+		}
+	}
+}
+
+func (t *ProductCatalogue) LastRefresh() (time.Time, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return t.lastRefresh, t.lastRefreshError
+}
+
+func (t *ProductCatalogue) Products() []Product {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	products := make([]Product, 0, len(t.products))
+	for _, product := range t.products {
+		products = append(products, product)
+	}
+
+	return products
+}
+
+func (t *ProductCatalogue) Product(productId string) (Product, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	product, found := t.products[productId]
+	if !found {
+		return Product{}, fmt.Errorf("%s: %s", ProductNotFoundErrorMessage, productId)
+	}
+
+	return product, nil
+}
+
+func (t *ProductCatalogue) TradingStatus(productId string) (TradingStatus, error) {
+	product, err := t.Product(productId)
+	if err != nil {
+		return TradingStatus{}, err
+	}
+
+	return product.TradingStatus(), nil
+}
+
+// NormalizePrice rounds price to the nearest quote_increment of the product.
+func (t *ProductCatalogue) NormalizePrice(productId string, price float64) (string, error) {
+	product, err := t.Product(productId)
+	if err != nil {
+		return "", err
+	}
+
+	increment, err := parseDecimal(product.QuoteIncrement)
+	if err != nil {
+		return "", err
+	}
+
+	return formatDecimal(roundToIncrement(price, increment), decimalPlaces(product.QuoteIncrement)), nil
+}
+
+// NormalizeSize rounds size down to a multiple of base_increment so it never exceeds what was asked for.
+func (t *ProductCatalogue) NormalizeSize(productId string, size float64) (string, error) {
+	product, err := t.Product(productId)
+	if err != nil {
+		return "", err
+	}
+
+	increment, err := parseDecimal(product.BaseIncrement)
+	if err != nil {
+		return "", err
+	}
+
+	return formatDecimal(floorToIncrement(size, increment), decimalPlaces(product.BaseIncrement)), nil
+}
+
+// NormalizeFunds rounds funds down to a multiple of quote_increment.
+func (t *ProductCatalogue) NormalizeFunds(productId string, funds float64) (string, error) {
+	product, err := t.Product(productId)
+	if err != nil {
+		return "", err
+	}
+
+	increment, err := parseDecimal(product.QuoteIncrement)
+	if err != nil {
+		return "", err
+	}
+
+	return formatDecimal(floorToIncrement(funds, increment), decimalPlaces(product.QuoteIncrement)), nil
+}
+
+func (t *ProductCatalogue) ValidateOrder(order Order) error {
+	product, err := t.Product(order.ProductId)
+	if err != nil {
+		return err
+	}
+
+	return validateOrderForProduct(product, order)
+}
+
+func validateOrderForProduct(product Product, order Order) error {
+	if product.TradingDisabled {
+		return fmt.Errorf("%s: %s", ProductTradingDisabledErrorMessage, product.Id)
+	}
+
+	if product.CancelOnly {
+		return fmt.Errorf("%s: %s", ProductCancelOnlyErrorMessage, product.Id)
+	}
+
+	if order.Side != OrderSideBuy && order.Side != OrderSideSell {
+		return errors.New(InvalidOrderSideErrorMessage)
+	}
+
+	quoteIncrement, err := parseDecimal(product.QuoteIncrement)
+	if err != nil {
+		return err
+	}
+
+	baseIncrement, err := parseDecimal(product.BaseIncrement)
+	if err != nil {
+		return err
+	}
+
+	switch order.orderType() {
+	case OrderTypeLimit:
+		if product.PostOnly && !order.PostOnly {
+			return fmt.Errorf("%s: %s", ProductPostOnlyErrorMessage, product.Id)
+		}
+
+		if order.Price == "" {
+			return errors.New(MissingPriceErrorMessage)
+		}
+
+		if order.Size == "" {
+			return errors.New(MissingSizeErrorMessage)
+		}
+
+		price, err := parseDecimal(order.Price)
+		if err != nil {
+			return err
+		}
+
+		if !isMultipleOfIncrement(price, quoteIncrement) {
+			return fmt.Errorf("%s: %s (%s)", InvalidPriceIncrementErrorMessage, order.Price, product.QuoteIncrement)
+		}
+
+		// min and max market funds only bound market orders, a limit order is checked by size alone
+		_, err = validateSize(product, order.Size, baseIncrement)
+		return err
+	case OrderTypeMarket:
+		if product.LimitOnly {
+			return fmt.Errorf("%s: %s", ProductLimitOnlyErrorMessage, product.Id)
+		}
+
+		if product.PostOnly {
+			return fmt.Errorf("%s: %s", ProductPostOnlyErrorMessage, product.Id)
+		}
+
+		if order.Size == "" && order.Funds == "" {
+			return errors.New(MissingSizeOrFundsErrorMessage)
+		}
+
+		if order.Size != "" {
+			_, err := validateSize(product, order.Size, baseIncrement)
+			return err
+		}
+
+		funds, err := parseDecimal(order.Funds)
+		if err != nil {
+			return err
+		}
+
+		if !isMultipleOfIncrement(funds, quoteIncrement) {
+			return fmt.Errorf("%s: %s (%s)", InvalidFundsIncrementErrorMessage, order.Funds, product.QuoteIncrement)
+		}
+
+		return validateFunds(product, funds)
+	default:
+		return errors.New(InvalidOrderTypeErrorMessage)
+	}
+}
+
+func validateSize(product Product, sizeValue string, baseIncrement float64) (float64, error) {
+	size, err := parseDecimal(sizeValue)
+	if err != nil {
+		return 0, err
+	}
+
+	if !isMultipleOfIncrement(size, baseIncrement) {
+		return 0, fmt.Errorf("%s: %s (%s)", InvalidSizeIncrementErrorMessage, sizeValue, product.BaseIncrement)
+	}
+
+	if product.BaseMinSize != "" {
+		minSize, err := parseDecimal(product.BaseMinSize)
+		if err != nil {
+			return 0, err
+		}
+
+		if size < minSize {
+			return 0, fmt.Errorf("%s: %s < %s", SizeBelowMinimumErrorMessage, sizeValue, product.BaseMinSize)
+		}
+	}
+
+	if product.BaseMaxSize != "" {
+		maxSize, err := parseDecimal(product.BaseMaxSize)
+		if err != nil {
+			return 0, err
+		}
+
+		if maxSize > 0 && size > maxSize {
+			return 0, fmt.Errorf("%s: %s > %s", SizeAboveMaximumErrorMessage, sizeValue, product.BaseMaxSize)
+		}
+	}
+
+	return size, nil
+}
+
+func validateFunds(product Product, funds float64) error {
+	if product.MinMarketFunds != "" {
+		minFunds, err := parseDecimal(product.MinMarketFunds)
+		if err != nil {
+			return err
+		}
+
+		if funds < minFunds {
+			return fmt.Errorf("%s: %v < %s", FundsBelowMinimumErrorMessage, funds, product.MinMarketFunds)
+		}
+	}
+
+	if product.MaxMarketFunds != "" {
+		maxFunds, err := parseDecimal(product.MaxMarketFunds)
+		if err != nil {
+			return err
+		}
+
+		if maxFunds > 0 && funds > maxFunds {
+			return fmt.Errorf("%s: %v > %s", FundsAboveMaximumErrorMessage, funds, product.MaxMarketFunds)
+		}
+	}
+
+	return nil
+}
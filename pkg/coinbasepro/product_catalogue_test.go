@@ -0,0 +1,227 @@
+package coinbasepro
+
+import (
+	"encoding/json"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testProducts = []Product{
+	{
+		Id:             "BTC-USD",
+		BaseCurrency:   "BTC",
+		QuoteCurrency:  "USD",
+		BaseMinSize:    "0.0001",
+		BaseMaxSize:    "280",
+		QuoteIncrement: "0.01",
+		BaseIncrement:  "0.00000001",
+		MinMarketFunds: "5",
+		MaxMarketFunds: "1000000",
+		Status:         "online",
+	},
+	{
+		Id:             "ETH-BTC",
+		BaseCurrency:   "ETH",
+		QuoteCurrency:  "BTC",
+		QuoteIncrement: "0.00001",
+		BaseIncrement:  "0.001",
+		MinMarketFunds: "0.001",
+		LimitOnly:      true,
+		Status:         "online",
+	},
+	{
+		Id:             "XRP-USD",
+		QuoteIncrement: "0.0001",
+		BaseIncrement:  "1",
+		CancelOnly:     true,
+		Status:         "online",
+	},
+	{
+		Id:             "DOGE-USD",
+		QuoteIncrement: "0.0001",
+		BaseIncrement:  "1",
+		PostOnly:       true,
+		Status:         "online",
+	},
+}
+
+func newProductsTestServer(t *testing.T, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if requests != nil {
+			atomic.AddInt32(requests, 1)
+		}
+
+		assert.Equal(t, request.URL.Path, "/products")
+
+		responseBodyBytes, err := json.Marshal(testProducts)
+		assert.Assert(t, is.Nil(err), "unexpected error marshaling testProducts", err)
+
+		writer.WriteHeader(http.StatusOK)
+		writer.Write(responseBodyBytes)
+	}))
+}
+
+func newTestProductCatalogue(t *testing.T) *ProductCatalogue {
+	ts := newProductsTestServer(t, nil)
+	t.Cleanup(ts.Close)
+
+	client, err := NewClientWithOptions(ts.URL, testKey, testPassphrase, testSecret)
+	assert.Assert(t, is.Nil(err), "unexpected error creating client using NewClientWithOptions", err)
+
+	catalogue, err := NewProductCatalogue(client, time.Minute)
+	assert.Assert(t, is.Nil(err), "unexpected error creating product catalogue", err)
+
+	return catalogue
+}
+
+// staticProducts serves a fixed product list without a client.
+type staticProducts struct {
+	MarketDataAPI
+	products []Product
+}
+
+func (s staticProducts) GetProducts() ([]Product, error) {
+	return s.products, nil
+}
+
+func TestProductCatalogue(t *testing.T) {
+	t.Run("should load products on creation", func(t *testing.T) {
+		catalogue := newTestProductCatalogue(t)
+
+		assert.Equal(t, len(catalogue.Products()), len(testProducts))
+
+		product, err := catalogue.Product("BTC-USD")
+		assert.Assert(t, is.Nil(err), "unexpected error looking up product", err)
+		assert.DeepEqual(t, product, testProducts[0])
+
+		_, err = catalogue.Product("NOPE-USD")
+		assert.Error(t, err, ProductNotFoundErrorMessage+": NOPE-USD")
+	})
+
+	t.Run("should load products from any market data api", func(t *testing.T) {
+		catalogue, err := NewProductCatalogue(staticProducts{products: testProducts[:1]}, time.Minute)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(catalogue.Products()), 1)
+
+		_, err = NewProductCatalogue(nil, time.Minute)
+		assert.Error(t, err, "market data api is required")
+	})
+
+	t.Run("should refresh products in the background", func(t *testing.T) {
+		var requests int32
+		ts := newProductsTestServer(t, &requests)
+		defer ts.Close()
+
+		client, err := NewClientWithOptions(ts.URL, testKey, testPassphrase, testSecret)
+		assert.Assert(t, is.Nil(err), "unexpected error creating client using NewClientWithOptions", err)
+
+		catalogue, err := NewProductCatalogue(client, 10*time.Millisecond)
+		assert.Assert(t, is.Nil(err), "unexpected error creating product catalogue", err)
+
+		catalogue.Start()
+		time.Sleep(55 * time.Millisecond)
+		catalogue.Stop()
+
+		assert.Assert(t, atomic.LoadInt32(&requests) >= 3, "expected background refreshes", requests)
+
+		lastRefresh, err := catalogue.LastRefresh()
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, !lastRefresh.IsZero())
+	})
+
+	t.Run("should expose trading status flags", func(t *testing.T) {
+		catalogue := newTestProductCatalogue(t)
+
+		status, err := catalogue.TradingStatus("XRP-USD")
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, status.CancelOnly)
+		assert.Assert(t, !status.CanPlaceOrders())
+
+		status, err = catalogue.TradingStatus("BTC-USD")
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, status.CanPlaceOrders())
+	})
+}
+
+func TestNormalize(t *testing.T) {
+	catalogue := newTestProductCatalogue(t)
+
+	testCases := []struct {
+		name      string
+		normalize func(string, float64) (string, error)
+		productId string
+		value     float64
+		expected  string
+	}{
+		{"price rounds to nearest quote increment", catalogue.NormalizePrice, "BTC-USD", 45123.456, "45123.46"},
+		{"price keeps exact increments", catalogue.NormalizePrice, "BTC-USD", 0.29, "0.29"},
+		{"price with small increment", catalogue.NormalizePrice, "ETH-BTC", 0.0712345, "0.07123"},
+		{"size rounds down to base increment", catalogue.NormalizeSize, "ETH-BTC", 1.23456, "1.234"},
+		{"size keeps exact increments", catalogue.NormalizeSize, "BTC-USD", 0.00000003, "0.00000003"},
+		{"whole number increments", catalogue.NormalizeSize, "XRP-USD", 10.9, "10"},
+		{"funds round down to quote increment", catalogue.NormalizeFunds, "BTC-USD", 10.009, "10.00"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			value, err := testCase.normalize(testCase.productId, testCase.value)
+			assert.Assert(t, is.Nil(err))
+			assert.Equal(t, value, testCase.expected)
+		})
+	}
+
+	t.Run("errors for unknown products", func(t *testing.T) {
+		_, err := catalogue.NormalizePrice("NOPE-USD", 1)
+		assert.ErrorContains(t, err, ProductNotFoundErrorMessage)
+	})
+}
+
+func TestValidateOrder(t *testing.T) {
+	catalogue := newTestProductCatalogue(t)
+
+	testCases := []struct {
+		name     string
+		order    Order
+		expected string
+	}{
+		{"valid limit order", Order{ProductId: "BTC-USD", Side: "buy", Price: "45000.01", Size: "0.001"}, ""},
+		{"valid market order with funds", Order{ProductId: "BTC-USD", Side: "sell", Type: "market", Funds: "10.50"}, ""},
+		{"valid market order with size", Order{ProductId: "BTC-USD", Side: "sell", Type: "market", Size: "0.01"}, ""},
+		{"valid post only order", Order{ProductId: "DOGE-USD", Side: "buy", Price: "0.1", Size: "10", PostOnly: true}, ""},
+		{"unknown product", Order{ProductId: "NOPE-USD", Side: "buy"}, ProductNotFoundErrorMessage},
+		{"invalid side", Order{ProductId: "BTC-USD", Side: "hold", Price: "1", Size: "1"}, InvalidOrderSideErrorMessage},
+		{"invalid type", Order{ProductId: "BTC-USD", Side: "buy", Type: "stop"}, InvalidOrderTypeErrorMessage},
+		{"price off increment", Order{ProductId: "BTC-USD", Side: "buy", Price: "45000.001", Size: "0.001"}, InvalidPriceIncrementErrorMessage},
+		{"size off increment", Order{ProductId: "ETH-BTC", Side: "buy", Price: "0.07", Size: "0.0015"}, InvalidSizeIncrementErrorMessage},
+		{"size below minimum", Order{ProductId: "BTC-USD", Side: "buy", Price: "45000", Size: "0.00001"}, SizeBelowMinimumErrorMessage},
+		{"size above maximum", Order{ProductId: "BTC-USD", Side: "buy", Price: "1", Size: "300"}, SizeAboveMaximumErrorMessage},
+		{"limit notional below min market funds", Order{ProductId: "BTC-USD", Side: "buy", Price: "1.00", Size: "0.001"}, ""},
+		{"limit notional above max market funds", Order{ProductId: "BTC-USD", Side: "buy", Price: "45000", Size: "100"}, ""},
+		{"market funds below minimum", Order{ProductId: "BTC-USD", Side: "buy", Type: "market", Funds: "1"}, FundsBelowMinimumErrorMessage},
+		{"market funds above maximum", Order{ProductId: "BTC-USD", Side: "buy", Type: "market", Funds: "2000000"}, FundsAboveMaximumErrorMessage},
+		{"market funds off increment", Order{ProductId: "BTC-USD", Side: "buy", Type: "market", Funds: "10.001"}, InvalidFundsIncrementErrorMessage},
+		{"market without size or funds", Order{ProductId: "BTC-USD", Side: "buy", Type: "market"}, MissingSizeOrFundsErrorMessage},
+		{"limit without price", Order{ProductId: "BTC-USD", Side: "buy", Size: "1"}, MissingPriceErrorMessage},
+		{"market order on limit only product", Order{ProductId: "ETH-BTC", Side: "buy", Type: "market", Size: "1"}, ProductLimitOnlyErrorMessage},
+		{"order on cancel only product", Order{ProductId: "XRP-USD", Side: "buy", Price: "1", Size: "1"}, ProductCancelOnlyErrorMessage},
+		{"taker order on post only product", Order{ProductId: "DOGE-USD", Side: "buy", Price: "0.1", Size: "10"}, ProductPostOnlyErrorMessage},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := catalogue.ValidateOrder(testCase.order)
+			if testCase.expected == "" {
+				assert.Assert(t, is.Nil(err), "unexpected validation error", err)
+				return
+			}
+
+			assert.Assert(t, err != nil && strings.HasPrefix(err.Error(), testCase.expected), "expected %q, got %v", testCase.expected, err)
+		})
+	}
+}
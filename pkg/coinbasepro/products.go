@@ -0,0 +1,68 @@
+package coinbasepro
+
+import "fmt"
+
+type Product struct {
+	Id              string `json:"id"`
+	BaseCurrency    string `json:"base_currency"`
+	QuoteCurrency   string `json:"quote_currency"`
+	BaseMinSize     string `json:"base_min_size"`
+	BaseMaxSize     string `json:"base_max_size"`
+	QuoteIncrement  string `json:"quote_increment"`
+	BaseIncrement   string `json:"base_increment"`
+	DisplayName     string `json:"display_name"`
+	MinMarketFunds  string `json:"min_market_funds"`
+	MaxMarketFunds  string `json:"max_market_funds"`
+	MarginEnabled   bool   `json:"margin_enabled"`
+	PostOnly        bool   `json:"post_only"`
+	LimitOnly       bool   `json:"limit_only"`
+	CancelOnly      bool   `json:"cancel_only"`
+	TradingDisabled bool   `json:"trading_disabled"`
+	Status          string `json:"status"`
+	StatusMessage   string `json:"status_message"`
+}
+
+type TradingStatus struct {
+	Status          string
+	StatusMessage   string
+	PostOnly        bool
+	LimitOnly       bool
+	CancelOnly      bool
+	TradingDisabled bool
+}
+
+func (p Product) TradingStatus() TradingStatus {
+	return TradingStatus{
+		Status:          p.Status,
+		StatusMessage:   p.StatusMessage,
+		PostOnly:        p.PostOnly,
+		LimitOnly:       p.LimitOnly,
+		CancelOnly:      p.CancelOnly,
+		TradingDisabled: p.TradingDisabled,
+	}
+}
+
+// CanPlaceOrders reports whether the exchange currently accepts new orders for the product.
+func (s TradingStatus) CanPlaceOrders() bool {
+	return !s.TradingDisabled && !s.CancelOnly && (s.Status == "" || s.Status == "online")
+}
+
+func (t *Client) GetProducts() ([]Product, error) {
+	var products []Product
+	_, err := t.executeRequest("GET", "/products", nil, &products, defaultMaxRetriesOn429)
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func (t *Client) GetProduct(productId string) (Product, error) {
+	var product Product
+	_, err := t.executeRequest("GET", fmt.Sprintf("/products/%s", productId), nil, &product, defaultMaxRetriesOn429)
+	if err != nil {
+		return Product{}, err
+	}
+
+	return product, nil
+}
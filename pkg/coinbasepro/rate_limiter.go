@@ -0,0 +1,68 @@
+package coinbasepro
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultRequestsPerSecond = 5
+const defaultRequestBurst = 10
+
+// RateLimiter is a token bucket that spaces requests out so a client stays within its exchange budget.
+type RateLimiter struct {
+	mutex             sync.Mutex
+	requestsPerSecond float64
+	burst             float64
+	tokens            float64
+	last              time.Time
+}
+
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if requestsPerSecond <= 0 {
+		requestsPerSecond = defaultRequestsPerSecond
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		requestsPerSecond: requestsPerSecond,
+		burst:             float64(burst),
+		tokens:            float64(burst),
+		last:              time.Now(),
+	}
+}
+
+func newDefaultRateLimiter() *RateLimiter {
+	return NewRateLimiter(defaultRequestsPerSecond, defaultRequestBurst)
+}
+
+func (t *RateLimiter) reserve() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	t.tokens += now.Sub(t.last).Seconds() * t.requestsPerSecond
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+	t.last = now
+
+	t.tokens--
+	if t.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-t.tokens / t.requestsPerSecond * float64(time.Second))
+}
+
+// Wait blocks until a request may be sent and returns how long it waited.
+func (t *RateLimiter) Wait() time.Duration {
+	wait := t.reserve()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+
+	return wait
+}
@@ -0,0 +1,241 @@
+package coinbasepro
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultBalanceTolerance = 1e-9
+
+type ReconcileOptions struct {
+	// ClientOidPrefix marks orders owned by this bot, see NewClientOid.
+	ClientOidPrefix string
+	// CancelOrphans cancels owned orders that are open on the exchange but missing from the snapshot.
+	CancelOrphans    bool
+	BalanceTolerance float64
+	// OrderManager, when set, starts tracking every owned order that is still open.
+	OrderManager *OrderManager
+}
+
+type OrderDiscrepancy struct {
+	Snapshot ManagedOrder
+	Exchange Order
+}
+
+type BalanceChange struct {
+	Currency string
+	Expected Balance
+	Actual   Balance
+}
+
+type ReconcileReport struct {
+	// OrphanedOrders are open on the exchange but unknown to the snapshot.
+	OrphanedOrders []Order
+	// CancelledOrphans are the orphaned orders that were cancelled because CancelOrphans was set.
+	CancelledOrphans []Order
+	// MissingOrders were open in the snapshot but are no longer open and did not fill any further.
+	MissingOrders []OrderDiscrepancy
+	// UnexpectedlyFilled orders received fills the snapshot does not know about.
+	UnexpectedlyFilled []OrderDiscrepancy
+	RecentFills        []Fill
+	BalanceChanges     []BalanceChange
+	Balances           map[string]Balance
+	Errors             []error
+}
+
+func (r ReconcileReport) Clean() bool {
+	return len(r.OrphanedOrders) == 0 && len(r.MissingOrders) == 0 && len(r.UnexpectedlyFilled) == 0 && len(r.BalanceChanges) == 0 && len(r.Errors) == 0
+}
+
+func (r ReconcileReport) String() string {
+	return fmt.Sprintf("orphaned=%d cancelled_orphans=%d missing=%d unexpectedly_filled=%d recent_fills=%d balance_changes=%d errors=%d",
+		len(r.OrphanedOrders), len(r.CancelledOrphans), len(r.MissingOrders), len(r.UnexpectedlyFilled), len(r.RecentFills), len(r.BalanceChanges), len(r.Errors))
+}
+
+func ownsClientOid(prefix, clientOid string) bool {
+	return prefix != "" && strings.HasPrefix(clientOid, prefix)
+}
+
+// Reconcile compares a persisted snapshot with the orders, fills and balances on the exchange.
+func Reconcile(client TradingAPI, accounts AccountsAPI, snapshot Snapshot, options ReconcileOptions) (ReconcileReport, error) {
+	if options.BalanceTolerance <= 0 {
+		options.BalanceTolerance = defaultBalanceTolerance
+	}
+
+	report := ReconcileReport{}
+
+	openOrders, err := client.ListOrders(OrderFilter{})
+	if err != nil {
+		return report, err
+	}
+
+	expected := make(map[string]ManagedOrder)
+	for _, order := range snapshot.Orders {
+		if order.State.IsTerminal() {
+			continue
+		}
+
+		expected[order.ClientOid] = order
+		if order.OrderId != "" {
+			expected[order.OrderId] = order
+		}
+	}
+
+	products := make(map[string]bool)
+	matched := make(map[string]bool)
+
+	for _, order := range openOrders {
+		products[order.ProductId] = true
+
+		known, found := expected[order.Id]
+		if !found && order.ClientOid != "" {
+			known, found = expected[order.ClientOid]
+		}
+
+		if found {
+			matched[known.ClientOid] = true
+			if filledSize, _ := parseDecimal(order.FilledSize); filledSize > known.FilledSize+options.BalanceTolerance {
+				report.UnexpectedlyFilled = append(report.UnexpectedlyFilled, OrderDiscrepancy{Snapshot: known, Exchange: order})
+			}
+
+			if options.OrderManager != nil {
+				if order.ClientOid == "" {
+					order.ClientOid = known.ClientOid
+				}
+				options.OrderManager.Track(order)
+			}
+
+			continue
+		}
+
+		report.OrphanedOrders = append(report.OrphanedOrders, order)
+
+		if !ownsClientOid(options.ClientOidPrefix, order.ClientOid) {
+			continue
+		}
+
+		if options.CancelOrphans {
+			if err := client.CancelOrder(order.Id); err != nil {
+				report.Errors = append(report.Errors, fmt.Errorf("cancel orphaned order %s: %w", order.Id, err))
+			} else {
+				report.CancelledOrphans = append(report.CancelledOrphans, order)
+			}
+
+			continue
+		}
+
+		if options.OrderManager != nil {
+			options.OrderManager.Track(order)
+		}
+	}
+
+	for clientOid, known := range expected {
+		if clientOid != known.ClientOid || matched[clientOid] {
+			continue
+		}
+
+		products[known.ProductId] = true
+
+		var current Order
+		if known.OrderId != "" {
+			current, err = client.GetOrder(known.OrderId)
+		} else {
+			current, err = client.GetOrderByClientOid(known.ClientOid)
+		}
+
+		var apiError ApiError
+		if err != nil && !(errors.As(err, &apiError) && apiError.StatusCode == http.StatusNotFound) {
+			report.Errors = append(report.Errors, fmt.Errorf("look up order %s: %w", clientOid, err))
+			continue
+		}
+
+		discrepancy := OrderDiscrepancy{Snapshot: known, Exchange: current}
+		if filledSize, _ := parseDecimal(current.FilledSize); filledSize > known.FilledSize+options.BalanceTolerance {
+			report.UnexpectedlyFilled = append(report.UnexpectedlyFilled, discrepancy)
+		} else {
+			report.MissingOrders = append(report.MissingOrders, discrepancy)
+		}
+	}
+
+	sort.Slice(report.MissingOrders, func(i, j int) bool {
+		return report.MissingOrders[i].Snapshot.CreatedAt.Before(report.MissingOrders[j].Snapshot.CreatedAt)
+	})
+
+	recentFills, err := fillsSince(client, products, snapshot.TakenAt)
+	if err != nil {
+		report.Errors = append(report.Errors, err)
+	}
+	report.RecentFills = recentFills
+
+	balances, err := accounts.GetAccounts()
+	if err != nil {
+		return report, err
+	}
+
+	report.Balances, err = balancesFromAccounts(balances)
+	if err != nil {
+		return report, err
+	}
+
+	report.BalanceChanges = compareBalances(snapshot.Balances, report.Balances, options.BalanceTolerance)
+
+	return report, nil
+}
+
+func fillsSince(client TradingAPI, products map[string]bool, since time.Time) ([]Fill, error) {
+	productIds := make([]string, 0, len(products))
+	for productId := range products {
+		productIds = append(productIds, productId)
+	}
+	sort.Strings(productIds)
+
+	recent := make([]Fill, 0)
+	for _, productId := range productIds {
+		filter := FillFilter{ProductId: productId, Since: since}
+		fills, err := client.GetFills(filter)
+		if err != nil {
+			return recent, fmt.Errorf("list fills for %s: %w", productId, err)
+		}
+
+		// not every TradingAPI honours Since
+		for _, fill := range fills {
+			if filter.Includes(fill) {
+				recent = append(recent, fill)
+			}
+		}
+	}
+
+	return recent, nil
+}
+
+func compareBalances(expected, actual map[string]Balance, tolerance float64) []BalanceChange {
+	currencies := make(map[string]bool)
+	for currency := range expected {
+		currencies[currency] = true
+	}
+
+	for currency := range actual {
+		currencies[currency] = true
+	}
+
+	sortedCurrencies := make([]string, 0, len(currencies))
+	for currency := range currencies {
+		sortedCurrencies = append(sortedCurrencies, currency)
+	}
+	sort.Strings(sortedCurrencies)
+
+	changes := make([]BalanceChange, 0)
+	for _, currency := range sortedCurrencies {
+		before, after := expected[currency], actual[currency]
+		if math.Abs(before.Balance-after.Balance) > tolerance || math.Abs(before.Hold-after.Hold) > tolerance {
+			changes = append(changes, BalanceChange{Currency: currency, Expected: before, Actual: after})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,156 @@
+package coinbasepro
+
+import (
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Run("should save and load snapshots", func(t *testing.T) {
+		manager, _ := newTestOrderManager(t)
+		_, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		snapshot, err := TakeSnapshot(manager, []Account{
+			{Currency: "USD", Balance: "100.5", Available: "0.5", Hold: "100"},
+			{Currency: "BTC", Balance: "1", Available: "1", Hold: "0"},
+		})
+		assert.Assert(t, is.Nil(err))
+
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+		assert.Assert(t, is.Nil(SaveSnapshot(path, snapshot)))
+
+		loaded, err := LoadSnapshot(path)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, loaded.TakenAt.Equal(snapshot.TakenAt))
+		assert.Equal(t, len(loaded.Orders), 1)
+		assert.Equal(t, loaded.Orders[0].ClientOid, snapshot.Orders[0].ClientOid)
+		assert.Equal(t, loaded.Orders[0].State, OrderStatePending)
+		assert.DeepEqual(t, loaded.Balances, map[string]Balance{
+			"USD": {Balance: 100.5, Available: 0.5, Hold: 100},
+			"BTC": {Balance: 1, Available: 1},
+		})
+	})
+
+	t.Run("a missing snapshot is empty", func(t *testing.T) {
+		snapshot, err := LoadSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(snapshot.Orders), 0)
+		assert.Equal(t, len(snapshot.Balances), 0)
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	t.Run("should report what changed while the bot was offline", func(t *testing.T) {
+		manager, exchange := newTestOrderManager(t)
+		client := exchange.client(t)
+
+		open, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+		filled, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+		cancelled, err := manager.Place(Order{ProductId: "ETH-USD", Side: "sell", Price: "10", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		snapshot, err := TakeSnapshot(manager, []Account{{Currency: "USD", Balance: "1000", Available: "800", Hold: "200"}})
+		assert.Assert(t, is.Nil(err))
+
+		exchange.addFill(Fill{TradeId: 1, ProductId: "BTC-USD", OrderId: filled.OrderId, CreatedAt: snapshot.TakenAt.Add(-time.Hour).Format(time.RFC3339Nano)})
+		exchange.addFill(Fill{TradeId: 2, ProductId: "BTC-USD", OrderId: filled.OrderId, CreatedAt: snapshot.TakenAt.Add(time.Second).Format(time.RFC3339Nano)})
+		exchange.setOrderStatus(filled.OrderId, "done", "1")
+		exchange.setOrderStatus(cancelled.OrderId, "done", "0")
+		exchange.setAccounts(Account{Currency: "USD", Balance: "900", Available: "800", Hold: "100"})
+
+		owned, err := client.PlaceOrder(Order{ClientOid: NewClientOid("b07"), ProductId: "BTC-USD", Side: "buy", Price: "90", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+		foreign, err := client.PlaceOrder(Order{ClientOid: NewClientOid("ccc"), ProductId: "BTC-USD", Side: "buy", Price: "80", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		restarted, err := NewOrderManager(client, "b07")
+		assert.Assert(t, is.Nil(err))
+
+		report, err := Reconcile(client, client, snapshot, ReconcileOptions{ClientOidPrefix: "b07", CancelOrphans: true, OrderManager: restarted})
+		assert.Assert(t, is.Nil(err), "unexpected error reconciling", err)
+		assert.Assert(t, !report.Clean())
+
+		assert.Equal(t, len(report.OrphanedOrders), 2)
+		assert.Equal(t, len(report.CancelledOrphans), 1)
+		assert.Equal(t, report.CancelledOrphans[0].Id, owned.Id)
+		assert.DeepEqual(t, exchange.cancelled, []string{owned.Id})
+
+		assert.Equal(t, len(report.MissingOrders), 1)
+		assert.Equal(t, report.MissingOrders[0].Snapshot.ClientOid, cancelled.ClientOid)
+
+		assert.Equal(t, len(report.UnexpectedlyFilled), 1)
+		assert.Equal(t, report.UnexpectedlyFilled[0].Snapshot.ClientOid, filled.ClientOid)
+		assert.Equal(t, report.UnexpectedlyFilled[0].Exchange.Status, "done")
+
+		assert.Equal(t, len(report.RecentFills), 1)
+		assert.Equal(t, report.RecentFills[0].TradeId, int64(2))
+
+		assert.DeepEqual(t, report.BalanceChanges, []BalanceChange{{
+			Currency: "USD",
+			Expected: Balance{Balance: 1000, Available: 800, Hold: 200},
+			Actual:   Balance{Balance: 900, Available: 800, Hold: 100},
+		}})
+		assert.Equal(t, len(report.Errors), 0)
+
+		_, found := restarted.Order(open.ClientOid)
+		assert.Assert(t, found)
+		_, found = restarted.Order(foreign.Id)
+		assert.Assert(t, !found)
+	})
+
+	t.Run("should be clean when nothing changed", func(t *testing.T) {
+		manager, exchange := newTestOrderManager(t)
+		_, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		accounts := []Account{{Currency: "USD", Balance: "10", Available: "10", Hold: "0"}}
+		exchange.setAccounts(accounts...)
+
+		snapshot, err := TakeSnapshot(manager, accounts)
+		assert.Assert(t, is.Nil(err))
+
+		client := exchange.client(t)
+		report, err := Reconcile(client, client, snapshot, ReconcileOptions{ClientOidPrefix: "b07"})
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, report.Clean(), report.String())
+	})
+	t.Run("should follow pagination past the first page of orders and fills", func(t *testing.T) {
+		manager, exchange := newTestOrderManager(t)
+		for i := 0; i < paginationLimit+50; i++ {
+			_, err := manager.Place(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+			assert.Assert(t, is.Nil(err))
+		}
+
+		snapshot, err := TakeSnapshot(manager, nil)
+		assert.Assert(t, is.Nil(err))
+
+		for i := 1; i <= 2*paginationLimit+50; i++ {
+			exchange.addFill(Fill{TradeId: int64(i), ProductId: "BTC-USD", CreatedAt: snapshot.TakenAt.Add(-time.Hour).Format(time.RFC3339Nano)})
+		}
+		for i := 1; i <= paginationLimit+30; i++ {
+			exchange.addFill(Fill{TradeId: int64(1000 + i), ProductId: "BTC-USD", CreatedAt: snapshot.TakenAt.Add(time.Second).Format(time.RFC3339Nano)})
+		}
+
+		client := exchange.client(t)
+		orders, err := client.ListOrders(OrderFilter{})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(orders), paginationLimit+50)
+
+		report, err := Reconcile(client, client, snapshot, ReconcileOptions{ClientOidPrefix: "b07"})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(report.OrphanedOrders), 0)
+		assert.Equal(t, len(report.MissingOrders), 0)
+		assert.Equal(t, len(report.RecentFills), paginationLimit+30)
+		assert.Equal(t, report.RecentFills[0].TradeId, int64(1000+paginationLimit+30))
+		assert.Equal(t, report.RecentFills[paginationLimit+29].TradeId, int64(1001))
+
+		// the second page reaches fills from before the snapshot, older pages are never asked for
+		assert.Equal(t, exchange.fillPages, 2)
+	})
+}
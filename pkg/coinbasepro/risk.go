@@ -0,0 +1,505 @@
+package coinbasepro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RiskViolation string
+
+const RiskViolationProductNotAllowed RiskViolation = "product_not_allowed"
+const RiskViolationOrderNotional RiskViolation = "max_order_notional"
+const RiskViolationPosition RiskViolation = "max_position"
+const RiskViolationOpenOrders RiskViolation = "max_open_orders"
+const RiskViolationDailyLoss RiskViolation = "max_daily_loss"
+const RiskViolationPriceBand RiskViolation = "price_band"
+const RiskViolationNoReferencePrice RiskViolation = "no_reference_price"
+
+// RiskLimits are the pre-trade limits checked by a RiskInterceptor, a zero value disables a limit.
+// Notional and loss limits are in quote currency, positions in base currency and PriceBand is the
+// largest allowed distance from the reference price as a fraction, 0.05 for 5%.
+type RiskLimits struct {
+	AllowedProducts  []string           `json:"allowed_products,omitempty"`
+	MaxOrderNotional float64            `json:"max_order_notional,omitempty"`
+	MaxPosition      map[string]float64 `json:"max_position,omitempty"`
+	MaxOpenOrders    int                `json:"max_open_orders,omitempty"`
+	MaxDailyLoss     float64            `json:"max_daily_loss,omitempty"`
+	PriceBand        float64            `json:"price_band,omitempty"`
+}
+
+func LoadRiskLimits(path string) (RiskLimits, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return RiskLimits{}, err
+	}
+
+	var limits RiskLimits
+	if err := json.Unmarshal(contents, &limits); err != nil {
+		return RiskLimits{}, fmt.Errorf("invalid risk limits %s: %w", path, err)
+	}
+
+	return limits, nil
+}
+
+// RiskRejection is returned by the client when an order breaks a risk limit, it is never sent.
+type RiskRejection struct {
+	Violation RiskViolation
+	ProductId string
+	Limit     float64
+	Value     float64
+}
+
+func (r RiskRejection) Error() string {
+	if r.Violation == RiskViolationProductNotAllowed {
+		return fmt.Sprintf("risk check %s failed: %s is not allowed", r.Violation, r.ProductId)
+	}
+
+	if r.Violation == RiskViolationNoReferencePrice {
+		return fmt.Sprintf("risk check %s failed: no price for %s to check the order against", r.Violation, r.ProductId)
+	}
+
+	return fmt.Sprintf("risk check %s failed for %s: %s exceeds limit %s", r.Violation, r.ProductId, formatRiskValue(r.Value), formatRiskValue(r.Limit))
+}
+
+func formatRiskValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+type riskOrder struct {
+	productId string
+	side      string
+	remaining float64
+	trades    map[int64]bool
+}
+
+type riskPosition struct {
+	size        float64
+	averageCost float64
+}
+
+type riskReference struct {
+	last    float64
+	bestBid float64
+	bestAsk float64
+}
+
+// price is the mid price when both sides of the book are known and the last trade otherwise.
+func (r riskReference) price() float64 {
+	if r.bestBid > 0 && r.bestAsk > 0 {
+		return (r.bestBid + r.bestAsk) / 2
+	}
+
+	return r.last
+}
+
+// RiskInterceptor rejects orders that break its RiskLimits before they are signed. Positions,
+// open orders and daily profit and loss are followed from the client's own order responses and
+// from user channel messages passed to HandleMessage, reference prices from ticker and match
+// messages. Limits can be replaced at any time with SetLimits or WatchLimits.
+type RiskInterceptor struct {
+	Logger *log.Logger
+
+	mutex      sync.Mutex
+	exempt     func(order Order) bool
+	limits     RiskLimits
+	allowed    map[string]bool
+	now        func() time.Time
+	orders     map[string]*riskOrder
+	positions  map[string]*riskPosition
+	references map[string]*riskReference
+	day        time.Time
+	dailyPnl   float64
+}
+
+func NewRiskInterceptor(limits RiskLimits, logger *log.Logger) *RiskInterceptor {
+	if logger == nil {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	r := &RiskInterceptor{
+		Logger:     logger,
+		now:        time.Now,
+		orders:     make(map[string]*riskOrder),
+		positions:  make(map[string]*riskPosition),
+		references: make(map[string]*riskReference),
+	}
+	r.SetLimits(limits)
+
+	return r
+}
+
+// SetExemption lets the orders exempt reports through without any check, pass KillSwitch.Exempt
+// so flattening a position is not blocked by the limits it is meant to protect.
+func (r *RiskInterceptor) SetExemption(exempt func(order Order) bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.exempt = exempt
+}
+
+func (r *RiskInterceptor) SetLimits(limits RiskLimits) {
+	allowed := make(map[string]bool, len(limits.AllowedProducts))
+	for _, productId := range limits.AllowedProducts {
+		allowed[productId] = true
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.limits = limits
+	r.allowed = allowed
+}
+
+func (r *RiskInterceptor) Limits() RiskLimits {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.limits
+}
+
+// SetClock replaces the clock that decides when the daily loss resets, at midnight UTC.
+func (r *RiskInterceptor) SetClock(now func() time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.now = now
+}
+
+// WatchLimits reloads the limits from path whenever its modification time changes. A file that
+// fails to load is logged and the previous limits stay in force. It returns a function that stops
+// watching.
+func (r *RiskInterceptor) WatchLimits(path string, interval time.Duration) func() {
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		var modified time.Time
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if info, err := os.Stat(path); err != nil {
+				r.Logger.Printf("risk_limits=%s error=%q", path, err.Error())
+			} else if !info.ModTime().Equal(modified) {
+				modified = info.ModTime()
+				if limits, err := LoadRiskLimits(path); err != nil {
+					r.Logger.Printf("risk_limits=%s error=%q", path, err.Error())
+				} else {
+					r.SetLimits(limits)
+					r.Logger.Printf("risk_limits=%s reloaded", path)
+				}
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// SetPosition seeds the position in a product, for example from balances at startup.
+func (r *RiskInterceptor) SetPosition(productId string, size, averageCost float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.positions[productId] = &riskPosition{size: size, averageCost: averageCost}
+}
+
+func (r *RiskInterceptor) Position(productId string) float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if position, found := r.positions[productId]; found {
+		return position.size
+	}
+
+	return 0
+}
+
+// TrackOrder counts an order placed before the interceptor was added as open.
+func (r *RiskInterceptor) TrackOrder(order Order) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.trackOrder(order)
+}
+
+func (r *RiskInterceptor) trackOrder(order Order) {
+	// rejected orders, like post only orders that would have crossed, never get a done message
+	if order.Id == "" || order.Status == "done" || order.Status == "rejected" {
+		return
+	}
+
+	size, _ := parseDecimal(order.Size)
+	filled, _ := parseDecimal(order.FilledSize)
+	r.orders[order.Id] = &riskOrder{productId: order.ProductId, side: order.Side, remaining: size - filled, trades: make(map[int64]bool)}
+}
+
+func (r *RiskInterceptor) OpenOrders() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.orders)
+}
+
+// SetReferencePrice sets the price used for the price band and market order notional.
+func (r *RiskInterceptor) SetReferencePrice(productId string, price float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.references[productId] = &riskReference{last: price}
+}
+
+func (r *RiskInterceptor) reference(productId string) *riskReference {
+	reference, found := r.references[productId]
+	if !found {
+		reference = &riskReference{}
+		r.references[productId] = reference
+	}
+
+	return reference
+}
+
+// DailyPnl is the profit or loss realised since midnight UTC.
+func (r *RiskInterceptor) DailyPnl() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.rollDay()
+	return r.dailyPnl
+}
+
+func (r *RiskInterceptor) rollDay() {
+	if day := r.now().UTC().Truncate(24 * time.Hour); !day.Equal(r.day) {
+		r.day = day
+		r.dailyPnl = 0
+	}
+}
+
+// HandleMessage follows fills and completed orders from the user channel and reference prices
+// from the ticker and matches channels.
+func (r *RiskInterceptor) HandleMessage(message Message) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch message.Type {
+	case MessageTypeTicker:
+		reference := r.reference(message.ProductId)
+		reference.last, _ = parseDecimal(message.Price)
+		reference.bestBid, _ = parseDecimal(message.BestBid)
+		reference.bestAsk, _ = parseDecimal(message.BestAsk)
+	case MessageTypeMatch, MessageTypeLastMatch:
+		price, err := parseDecimal(message.Price)
+		if err != nil {
+			return
+		}
+		r.reference(message.ProductId).last = price
+
+		size, _ := parseDecimal(message.Size)
+		for _, orderId := range []string{message.MakerOrderId, message.TakerOrderId} {
+			if order, found := r.orders[orderId]; found && !order.trades[message.TradeId] {
+				order.trades[message.TradeId] = true
+				order.remaining -= size
+				r.applyFill(order.productId, order.side, price, size)
+			}
+		}
+	case MessageTypeDone:
+		delete(r.orders, message.OrderId)
+	}
+}
+
+// applyFill moves the position and realises profit or loss on the part that reduces it.
+func (r *RiskInterceptor) applyFill(productId, side string, price, size float64) {
+	position, found := r.positions[productId]
+	if !found {
+		position = &riskPosition{}
+		r.positions[productId] = position
+	}
+
+	signed := size
+	if side == OrderSideSell {
+		signed = -size
+	}
+
+	if position.size != 0 && (position.size > 0) != (signed > 0) {
+		closed := signed
+		if math.Abs(closed) > math.Abs(position.size) {
+			closed = -position.size
+		}
+
+		r.rollDay()
+		r.dailyPnl += -closed * (price - position.averageCost)
+		position.size += closed
+		signed -= closed
+
+		if math.Abs(position.size) < incrementEpsilon {
+			position.size, position.averageCost = 0, 0
+		}
+	}
+
+	if signed != 0 {
+		position.averageCost = (position.averageCost*position.size + price*signed) / (position.size + signed)
+		position.size += signed
+	}
+}
+
+func (r *RiskInterceptor) BeforeSign(call *Call) error {
+	if call.Method != "POST" || call.Path != "/orders" {
+		return nil
+	}
+
+	var order Order
+	switch body := call.Body.(type) {
+	case Order:
+		order = body
+	case *Order:
+		order = *body
+	default:
+		return nil
+	}
+
+	if err := r.Check(order); err != nil {
+		r.Logger.Printf("risk=rejected violation=%s product=%s side=%s size=%s price=%s funds=%s error=%q",
+			err.Violation, order.ProductId, order.Side, order.Size, order.Price, order.Funds, err.Error())
+		return *err
+	}
+
+	return nil
+}
+
+func (r *RiskInterceptor) AfterSign(call *Call) error {
+	return nil
+}
+
+func (r *RiskInterceptor) AfterResponse(call *Call) {
+	if call.Err != nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch response := call.Response.(type) {
+	case *Order:
+		if call.Method == "POST" && call.Path == "/orders" {
+			r.trackOrder(*response)
+		}
+	case *string:
+		if call.Method == "DELETE" && strings.HasPrefix(call.Path, "/orders/") {
+			delete(r.orders, *response)
+		}
+	}
+}
+
+// Check returns the first limit order breaks, or nil when it may be placed.
+func (r *RiskInterceptor) Check(order Order) *RiskRejection {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.exempt != nil && r.exempt(order) {
+		return nil
+	}
+
+	limits := r.limits
+	reject := func(violation RiskViolation, limit, value float64) *RiskRejection {
+		return &RiskRejection{Violation: violation, ProductId: order.ProductId, Limit: limit, Value: value}
+	}
+
+	if len(r.allowed) > 0 && !r.allowed[order.ProductId] {
+		return reject(RiskViolationProductNotAllowed, 0, 0)
+	}
+
+	r.rollDay()
+	if limits.MaxDailyLoss > 0 && -r.dailyPnl >= limits.MaxDailyLoss {
+		return reject(RiskViolationDailyLoss, limits.MaxDailyLoss, -r.dailyPnl)
+	}
+
+	if limits.MaxOpenOrders > 0 && len(r.orders) >= limits.MaxOpenOrders {
+		return reject(RiskViolationOpenOrders, float64(limits.MaxOpenOrders), float64(len(r.orders)+1))
+	}
+
+	referencePrice := 0.0
+	if reference, found := r.references[order.ProductId]; found {
+		referencePrice = reference.price()
+	}
+
+	price, _ := parseDecimal(order.Price)
+	if order.orderType() == OrderTypeLimit && limits.PriceBand > 0 && referencePrice > 0 {
+		if distance := math.Abs(price-referencePrice) / referencePrice; distance > limits.PriceBand {
+			return reject(RiskViolationPriceBand, limits.PriceBand, distance)
+		}
+	}
+
+	if order.orderType() != OrderTypeLimit {
+		price = referencePrice
+	}
+
+	size, _ := parseDecimal(order.Size)
+	funds, _ := parseDecimal(order.Funds)
+
+	// without a price the notional of a market order by size and the size of one by funds are
+	// unknown, refuse them rather than let them pass the limits unchecked
+	maxPosition, hasMaxPosition := limits.MaxPosition[order.ProductId]
+	hasMaxPosition = hasMaxPosition && maxPosition > 0
+	if price <= 0 && ((size > 0 && limits.MaxOrderNotional > 0) || (size == 0 && hasMaxPosition)) {
+		return reject(RiskViolationNoReferencePrice, 0, 0)
+	}
+	notional := size * price
+	if size == 0 {
+		notional = funds
+		if price > 0 {
+			size = funds / price
+		}
+	}
+
+	if limits.MaxOrderNotional > 0 && notional > limits.MaxOrderNotional {
+		return reject(RiskViolationOrderNotional, limits.MaxOrderNotional, notional)
+	}
+
+	if hasMaxPosition {
+		// resting orders on the same side count as if they had filled
+		projected := 0.0
+		if position, found := r.positions[order.ProductId]; found {
+			projected = position.size
+		}
+
+		for _, open := range append(r.openOrders(order.ProductId, order.Side), &riskOrder{side: order.Side, remaining: size}) {
+			if open.side == OrderSideBuy {
+				projected += open.remaining
+			} else {
+				projected -= open.remaining
+			}
+		}
+
+		if math.Abs(projected) > maxPosition+incrementEpsilon {
+			return reject(RiskViolationPosition, maxPosition, math.Abs(projected))
+		}
+	}
+
+	return nil
+}
+
+func (r *RiskInterceptor) openOrders(productId, side string) []*riskOrder {
+	orders := make([]*riskOrder, 0)
+	for _, order := range r.orders {
+		if order.productId == productId && order.side == side && order.remaining > 0 {
+			orders = append(orders, order)
+		}
+	}
+
+	return orders
+}
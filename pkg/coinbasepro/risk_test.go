@@ -0,0 +1,191 @@
+package coinbasepro
+
+import (
+	"bytes"
+	"errors"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRiskClient(t *testing.T, limits RiskLimits) (*Client, *RiskInterceptor, *fakeExchange, *bytes.Buffer) {
+	exchange := newFakeExchange(t)
+	client := exchange.client(t)
+
+	output := &bytes.Buffer{}
+	risk := NewRiskInterceptor(limits, log.New(output, "", 0))
+	client.Use(risk)
+
+	return client, risk, exchange, output
+}
+
+func assertRejected(t *testing.T, err error, violation RiskViolation) {
+	t.Helper()
+
+	var rejection RiskRejection
+	assert.Assert(t, errors.As(err, &rejection), "expected a risk rejection, got %v", err)
+	assert.Equal(t, rejection.Violation, violation)
+}
+
+func TestRiskInterceptor(t *testing.T) {
+	t.Run("should reject orders before they are sent", func(t *testing.T) {
+		client, risk, exchange, output := newTestRiskClient(t, RiskLimits{
+			AllowedProducts:  []string{"BTC-USD"},
+			MaxOrderNotional: 1000,
+			PriceBand:        0.05,
+		})
+		risk.HandleMessage(Message{Type: MessageTypeTicker, ProductId: "BTC-USD", Price: "101", BestBid: "99", BestAsk: "101"})
+
+		_, err := client.PlaceOrder(Order{ProductId: "ETH-USD", Side: OrderSideBuy, Price: "10", Size: "1"})
+		assertRejected(t, err, RiskViolationProductNotAllowed)
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "110", Size: "1"})
+		assertRejected(t, err, RiskViolationPriceBand)
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "11"})
+		assertRejected(t, err, RiskViolationOrderNotional)
+		assert.Error(t, err, "risk check max_order_notional failed for BTC-USD: 1100 exceeds limit 1000")
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideSell, Type: OrderTypeMarket, Size: "11"})
+		assertRejected(t, err, RiskViolationOrderNotional)
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "5"})
+		assert.Assert(t, is.Nil(err))
+
+		assert.Equal(t, len(exchange.orders), 1)
+		assert.Equal(t, strings.Count(output.String(), "risk=rejected"), 4)
+		assert.Assert(t, strings.Contains(output.String(), "violation=price_band product=BTC-USD"), output.String())
+	})
+
+	t.Run("should reject market orders it cannot check before there is a price", func(t *testing.T) {
+		client, risk, exchange, _ := newTestRiskClient(t, RiskLimits{
+			MaxOrderNotional: 1000,
+			MaxPosition:      map[string]float64{"BTC-USD": 2},
+		})
+
+		_, err := client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Type: OrderTypeMarket, Size: "100"})
+		assertRejected(t, err, RiskViolationNoReferencePrice)
+		assert.Error(t, err, "risk check no_reference_price failed: no price for BTC-USD to check the order against")
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Type: OrderTypeMarket, Funds: "900"})
+		assertRejected(t, err, RiskViolationNoReferencePrice)
+
+		_, err = client.PlaceOrder(Order{ProductId: "ETH-USD", Side: OrderSideBuy, Type: OrderTypeMarket, Funds: "900"})
+		assert.Assert(t, is.Nil(err))
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		risk.HandleMessage(Message{Type: MessageTypeTicker, ProductId: "BTC-USD", Price: "100", BestBid: "99", BestAsk: "101"})
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Type: OrderTypeMarket, Funds: "90"})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(exchange.orders), 3)
+	})
+
+	t.Run("should limit open orders and positions", func(t *testing.T) {
+		client, risk, _, _ := newTestRiskClient(t, RiskLimits{MaxOpenOrders: 2, MaxPosition: map[string]float64{"BTC-USD": 1}})
+
+		first, err := client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "0.6"})
+		assert.Assert(t, is.Nil(err))
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "0.6"})
+		assertRejected(t, err, RiskViolationPosition)
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideSell, Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideSell, Price: "100", Size: "0.1"})
+		assertRejected(t, err, RiskViolationOpenOrders)
+
+		assert.Assert(t, is.Nil(client.CancelOrder(first.Id)))
+		assert.Equal(t, risk.OpenOrders(), 1)
+
+		risk.HandleMessage(Message{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 1, MakerOrderId: "other", TakerOrderId: "another", Price: "100", Size: "1"})
+		assert.Equal(t, risk.Position("BTC-USD"), 0.0)
+	})
+
+	t.Run("should not count rejected orders as open", func(t *testing.T) {
+		client, risk, exchange, _ := newTestRiskClient(t, RiskLimits{MaxOpenOrders: 2})
+		exchange.rejectPostOnly = true
+
+		for i := 0; i < 3; i++ {
+			order, err := client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "1", PostOnly: true})
+			assert.Assert(t, is.Nil(err))
+			assert.Equal(t, order.Status, "rejected")
+		}
+		assert.Equal(t, risk.OpenOrders(), 0)
+
+		_, err := client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, risk.OpenOrders(), 1)
+	})
+
+	t.Run("should stop trading after the daily loss limit", func(t *testing.T) {
+		now := time.Date(2021, 3, 1, 23, 0, 0, 0, time.UTC)
+		client, risk, _, _ := newTestRiskClient(t, RiskLimits{MaxDailyLoss: 50})
+		risk.SetClock(func() time.Time { return now })
+		risk.SetPosition("BTC-USD", 1, 150)
+
+		sell, err := client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideSell, Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		risk.HandleMessage(Message{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 7, MakerOrderId: sell.Id, Price: "100", Size: "1"})
+		risk.HandleMessage(Message{Type: MessageTypeMatch, ProductId: "BTC-USD", TradeId: 7, MakerOrderId: sell.Id, Price: "100", Size: "1"})
+		risk.HandleMessage(Message{Type: MessageTypeDone, ProductId: "BTC-USD", OrderId: sell.Id, Reason: "filled"})
+		assert.Equal(t, risk.DailyPnl(), -50.0)
+		assert.Equal(t, risk.Position("BTC-USD"), 0.0)
+		assert.Equal(t, risk.OpenOrders(), 0)
+
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "0.1"})
+		assertRejected(t, err, RiskViolationDailyLoss)
+
+		now = now.Add(2 * time.Hour)
+		_, err = client.PlaceOrder(Order{ProductId: "BTC-USD", Side: OrderSideBuy, Price: "100", Size: "0.1"})
+		assert.Assert(t, is.Nil(err))
+	})
+
+	t.Run("should mark managed orders rejected", func(t *testing.T) {
+		client, _, _, _ := newTestRiskClient(t, RiskLimits{AllowedProducts: []string{"BTC-USD"}})
+		manager, err := NewOrderManager(client, "")
+		assert.Assert(t, is.Nil(err))
+
+		order, err := manager.Place(Order{ProductId: "ETH-USD", Side: OrderSideBuy, Price: "10", Size: "1"})
+		assertRejected(t, err, RiskViolationProductNotAllowed)
+		assert.Equal(t, order.State, OrderStateRejected)
+		assert.Equal(t, order.RejectReason, "risk check product_not_allowed failed: ETH-USD is not allowed")
+	})
+
+	t.Run("should hot reload limits", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "risk.json")
+		assert.Assert(t, is.Nil(ioutil.WriteFile(path, []byte(`{"max_order_notional": 100}`), 0644)))
+
+		limits, err := LoadRiskLimits(path)
+		assert.Assert(t, is.Nil(err))
+
+		risk := NewRiskInterceptor(limits, log.New(ioutil.Discard, "", 0))
+		stop := risk.WatchLimits(path, 5*time.Millisecond)
+		defer stop()
+
+		assert.Assert(t, is.Nil(ioutil.WriteFile(path, []byte(`{"max_order_notional": 500, "max_open_orders": 3}`), 0644)))
+		later := time.Now().Add(time.Second)
+		assert.Assert(t, is.Nil(os.Chtimes(path, later, later)))
+
+		deadline := time.Now().Add(2 * time.Second)
+		for risk.Limits().MaxOrderNotional != 500 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.DeepEqual(t, risk.Limits(), RiskLimits{MaxOrderNotional: 500, MaxOpenOrders: 3})
+
+		assert.Assert(t, is.Nil(ioutil.WriteFile(path, []byte(`{"max_order_notional": `), 0644)))
+		earlier := later.Add(time.Second)
+		assert.Assert(t, is.Nil(os.Chtimes(path, earlier, earlier)))
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, risk.Limits().MaxOrderNotional, 500.0)
+	})
+}
@@ -0,0 +1,105 @@
+package coinbasepro
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Snapshot is the bot's own view of its orders and balances, persisted so it can be compared with
+// the exchange after a restart.
+type Snapshot struct {
+	TakenAt  time.Time          `json:"taken_at"`
+	Orders   []ManagedOrder     `json:"orders"`
+	Balances map[string]Balance `json:"balances"`
+}
+
+type Balance struct {
+	Balance   float64 `json:"balance"`
+	Available float64 `json:"available"`
+	Hold      float64 `json:"hold"`
+}
+
+func TakeSnapshot(manager *OrderManager, accounts []Account) (Snapshot, error) {
+	snapshot := Snapshot{
+		TakenAt:  time.Now().UTC(),
+		Orders:   make([]ManagedOrder, 0),
+		Balances: make(map[string]Balance),
+	}
+
+	if manager != nil {
+		snapshot.Orders = manager.Orders()
+	}
+
+	balances, err := balancesFromAccounts(accounts)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snapshot.Balances = balances
+
+	return snapshot, nil
+}
+
+func balancesFromAccounts(accounts []Account) (map[string]Balance, error) {
+	balances := make(map[string]Balance, len(accounts))
+	for _, account := range accounts {
+		balance, err := parseDecimal(account.Balance)
+		if err != nil {
+			return nil, err
+		}
+
+		available, err := parseDecimal(account.Available)
+		if err != nil {
+			return nil, err
+		}
+
+		hold, err := parseDecimal(account.Hold)
+		if err != nil {
+			return nil, err
+		}
+
+		current := balances[account.Currency]
+		balances[account.Currency] = Balance{
+			Balance:   current.Balance + balance,
+			Available: current.Available + available,
+			Hold:      current.Hold + hold,
+		}
+	}
+
+	return balances, nil
+}
+
+func SaveSnapshot(path string, snapshot Snapshot) error {
+	contents, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporaryPath := path + ".tmp"
+	if err := ioutil.WriteFile(temporaryPath, contents, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryPath, path)
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot. A missing file is not an error, it returns
+// an empty snapshot as on the very first start.
+func LoadSnapshot(path string) (Snapshot, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Snapshot{Orders: make([]ManagedOrder, 0), Balances: make(map[string]Balance)}, nil
+	}
+
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(contents, &snapshot); err != nil {
+		return Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,299 @@
+package coinbasepro
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	RecordKindOrderRequest   = "order_request"
+	RecordKindOrderResponse  = "order_response"
+	RecordKindOrderError     = "order_error"
+	RecordKindOrderCancelled = "order_cancelled"
+	RecordKindFill           = "fill"
+	RecordKindBalances       = "balances"
+	RecordKindKillSwitch     = "kill_switch"
+)
+
+const StoreClosedErrorMessage = "store is closed"
+const CorruptJournalErrorMessage = "corrupt journal record"
+
+// Record is a single entry in a Store. Sequence and Time are assigned by the store on Append.
+type Record struct {
+	Sequence   int64           `json:"seq"`
+	Time       time.Time       `json:"time"`
+	Kind       string          `json:"kind"`
+	Method     string          `json:"method,omitempty"`
+	Path       string          `json:"path,omitempty"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+func NewRecord(kind string, data interface{}) (Record, error) {
+	record := Record{Kind: kind}
+	if data == nil {
+		return record, nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Record{}, err
+	}
+	record.Data = encoded
+
+	return record, nil
+}
+
+// Decode unmarshals the record data into value.
+func (r Record) Decode(value interface{}) error {
+	return json.Unmarshal(r.Data, value)
+}
+
+// Store is a durable, append only log of everything the client sent and received.
+type Store interface {
+	Append(record Record) (Record, error)
+	// Replay calls fn for every record in the order they were appended, stopping at the first error.
+	Replay(fn func(record Record) error) error
+	Close() error
+}
+
+// JournalStore is a Store backed by a file of newline delimited JSON records. Every append is
+// synced to disk before it returns, and a record torn by a crash is truncated on open.
+type JournalStore struct {
+	path string
+
+	mutex        sync.Mutex
+	file         *os.File
+	lastSequence int64
+}
+
+func OpenJournalStore(path string) (*JournalStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &JournalStore{path: path, file: file}
+	validLength, err := store.scan(file, nil)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if err := file.Truncate(validLength); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if _, err := file.Seek(validLength, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// scan reads complete records from reader and returns the length of the valid prefix. Only a final
+// line without a newline, as left by a crash during an append, is ignored. A complete line that
+// does not parse is an error so records after it are never dropped, blank lines are skipped.
+func (s *JournalStore) scan(reader io.Reader, fn func(record Record) error) (int64, error) {
+	buffered := bufio.NewReader(reader)
+
+	var validLength int64
+	for {
+		line, err := buffered.ReadBytes('\n')
+		if err == io.EOF {
+			return validLength, nil
+		}
+
+		if err != nil {
+			return validLength, err
+		}
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			validLength += int64(len(line))
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(bytes.TrimSpace(line), &record); err != nil {
+			return validLength, fmt.Errorf("%s at byte %d: %w", CorruptJournalErrorMessage, validLength, err)
+		}
+
+		validLength += int64(len(line))
+		if record.Sequence > s.lastSequence {
+			s.lastSequence = record.Sequence
+		}
+
+		if fn != nil {
+			if err := fn(record); err != nil {
+				return validLength, err
+			}
+		}
+	}
+}
+
+func (s *JournalStore) Path() string {
+	return s.path
+}
+
+func (s *JournalStore) LastSequence() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.lastSequence
+}
+
+func (s *JournalStore) Append(record Record) (Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return Record{}, errors.New(StoreClosedErrorMessage)
+	}
+
+	record.Sequence = s.lastSequence + 1
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return Record{}, err
+	}
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return Record{}, err
+	}
+
+	if err := s.file.Sync(); err != nil {
+		return Record{}, err
+	}
+
+	s.lastSequence = record.Sequence
+
+	return record, nil
+}
+
+func (s *JournalStore) Replay(fn func(record Record) error) error {
+	s.mutex.Lock()
+	closed := s.file == nil
+	s.mutex.Unlock()
+
+	if closed {
+		return errors.New(StoreClosedErrorMessage)
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := &JournalStore{}
+	_, err = reader.scan(file, fn)
+
+	return err
+}
+
+func (s *JournalStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+
+	return err
+}
+
+// Recovery is the latest state found in a Store, used to resume after a crash.
+type Recovery struct {
+	LastSequence int64
+	// Orders holds the most recent exchange view of every order, in the order they were first seen.
+	Orders     []Order
+	Fills      []Fill
+	Balances   map[string]Balance
+	BalancesAt time.Time
+}
+
+func (r Recovery) OpenOrders() []Order {
+	open := make([]Order, 0)
+	for _, order := range r.Orders {
+		if order.Status != "done" && order.Status != "rejected" {
+			open = append(open, order)
+		}
+	}
+
+	return open
+}
+
+// Recover replays a Store and folds its records into the latest known orders, fills and balances.
+func Recover(store Store) (Recovery, error) {
+	recovery := Recovery{Orders: make([]Order, 0), Fills: make([]Fill, 0), Balances: make(map[string]Balance)}
+	orderIndex := make(map[string]int)
+	seenTrades := make(map[string]bool)
+
+	err := store.Replay(func(record Record) error {
+		recovery.LastSequence = record.Sequence
+
+		switch record.Kind {
+		case RecordKindOrderResponse:
+			var order Order
+			if err := record.Decode(&order); err != nil {
+				return err
+			}
+
+			if index, found := orderIndex[order.Id]; found {
+				recovery.Orders[index] = order
+			} else {
+				orderIndex[order.Id] = len(recovery.Orders)
+				recovery.Orders = append(recovery.Orders, order)
+			}
+		case RecordKindOrderCancelled:
+			var orderId string
+			if err := record.Decode(&orderId); err != nil {
+				return err
+			}
+
+			if index, found := orderIndex[orderId]; found {
+				recovery.Orders[index].Status = "done"
+				recovery.Orders[index].DoneReason = doneReasonCanceled
+			}
+		case RecordKindFill:
+			var fill Fill
+			if err := record.Decode(&fill); err != nil {
+				return err
+			}
+
+			key := fmt.Sprintf("%s/%s/%d", fill.ProductId, fill.OrderId, fill.TradeId)
+			if !seenTrades[key] {
+				seenTrades[key] = true
+				recovery.Fills = append(recovery.Fills, fill)
+			}
+		case RecordKindBalances:
+			balances := make(map[string]Balance)
+			if err := record.Decode(&balances); err != nil {
+				return err
+			}
+
+			recovery.Balances = balances
+			recovery.BalancesAt = record.Time
+		}
+
+		return nil
+	})
+
+	return recovery, err
+}
@@ -0,0 +1,97 @@
+package coinbasepro
+
+import (
+	"strings"
+)
+
+// StoreInterceptor records order requests, exchange responses, fills and balances into a Store as
+// they pass through the client. Failing to write never fails the api call, errors are handed to
+// OnError instead.
+type StoreInterceptor struct {
+	store   Store
+	OnError func(err error)
+}
+
+func NewStoreInterceptor(store Store) *StoreInterceptor {
+	return &StoreInterceptor{store: store}
+}
+
+func isOrderPath(requestPath string) bool {
+	return requestPath == "/orders" || strings.HasPrefix(requestPath, "/orders/") || strings.HasPrefix(requestPath, "/orders?")
+}
+
+func isOrderMutation(call *Call) bool {
+	return (call.Method == "POST" || call.Method == "DELETE") && isOrderPath(call.Path)
+}
+
+func (s *StoreInterceptor) BeforeSign(call *Call) error {
+	return nil
+}
+
+func (s *StoreInterceptor) AfterSign(call *Call) error {
+	if isOrderMutation(call) {
+		s.append(call, RecordKindOrderRequest, call.Body)
+	}
+
+	return nil
+}
+
+func (s *StoreInterceptor) AfterResponse(call *Call) {
+	if call.Err != nil {
+		if isOrderMutation(call) {
+			s.append(call, RecordKindOrderError, nil)
+		}
+
+		return
+	}
+
+	switch response := call.Response.(type) {
+	case *Order:
+		s.append(call, RecordKindOrderResponse, response)
+	case *[]Order:
+		for _, order := range *response {
+			s.append(call, RecordKindOrderResponse, order)
+		}
+	case *string:
+		if call.Method == "DELETE" && isOrderPath(call.Path) {
+			s.append(call, RecordKindOrderCancelled, response)
+		}
+	case *[]Fill:
+		for _, fill := range *response {
+			s.append(call, RecordKindFill, fill)
+		}
+	case *[]Account:
+		balances, err := balancesFromAccounts(*response)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+
+		s.append(call, RecordKindBalances, balances)
+	}
+}
+
+func (s *StoreInterceptor) append(call *Call, kind string, data interface{}) {
+	record, err := NewRecord(kind, data)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	record.Method = call.Method
+	record.Path = call.Path
+	record.StatusCode = call.StatusCode
+	if call.Err != nil {
+		record.Error = call.Err.Error()
+	}
+
+	if _, err := s.store.Append(record); err != nil {
+		s.fail(err)
+	}
+}
+
+func (s *StoreInterceptor) fail(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
@@ -0,0 +1,176 @@
+package coinbasepro
+
+import (
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func replayKinds(t *testing.T, store Store) []string {
+	kinds := make([]string, 0)
+	err := store.Replay(func(record Record) error {
+		kinds = append(kinds, record.Kind)
+		return nil
+	})
+	assert.Assert(t, is.Nil(err), "unexpected error replaying store", err)
+
+	return kinds
+}
+
+func TestJournalStore(t *testing.T) {
+	t.Run("should append, replay and continue after reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+		store, err := OpenJournalStore(path)
+		assert.Assert(t, is.Nil(err))
+
+		record, err := NewRecord(RecordKindFill, Fill{TradeId: 1})
+		assert.Assert(t, is.Nil(err))
+
+		first, err := store.Append(record)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, first.Sequence, int64(1))
+		assert.Assert(t, !first.Time.IsZero())
+
+		_, err = store.Append(Record{Kind: RecordKindBalances})
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(store.Close()))
+
+		_, err = store.Append(Record{Kind: RecordKindFill})
+		assert.Error(t, err, StoreClosedErrorMessage)
+
+		store, err = OpenJournalStore(path)
+		assert.Assert(t, is.Nil(err))
+		defer store.Close()
+		assert.Equal(t, store.LastSequence(), int64(2))
+
+		third, err := store.Append(Record{Kind: RecordKindOrderRequest})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, third.Sequence, int64(3))
+
+		assert.DeepEqual(t, replayKinds(t, store), []string{RecordKindFill, RecordKindBalances, RecordKindOrderRequest})
+
+		var fill Fill
+		err = store.Replay(func(record Record) error {
+			if record.Kind == RecordKindFill {
+				return record.Decode(&fill)
+			}
+			return nil
+		})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, fill.TradeId, int64(1))
+	})
+
+	t.Run("should truncate a record torn by a crash", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+		store, err := OpenJournalStore(path)
+		assert.Assert(t, is.Nil(err))
+		_, err = store.Append(Record{Kind: RecordKindFill})
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(store.Close()))
+
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+		assert.Assert(t, is.Nil(err))
+		_, err = file.WriteString(`{"seq":2,"kind":"fi`)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(file.Close()))
+
+		store, err = OpenJournalStore(path)
+		assert.Assert(t, is.Nil(err))
+		defer store.Close()
+
+		record, err := store.Append(Record{Kind: RecordKindBalances})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, record.Sequence, int64(2))
+		assert.DeepEqual(t, replayKinds(t, store), []string{RecordKindFill, RecordKindBalances})
+
+		contents, err := ioutil.ReadFile(path)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, !strings.Contains(string(contents), `"kind":"fi"`), string(contents))
+	})
+
+	t.Run("should refuse to drop records after a corrupt one", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "journal.jsonl")
+		contents := "{\"seq\":1,\"kind\":\"fill\"}\n\n{\"seq\":2,\"ki\n{\"seq\":3,\"kind\":\"balances\"}\n"
+		assert.Assert(t, is.Nil(ioutil.WriteFile(path, []byte(contents), 0600)))
+
+		_, err := OpenJournalStore(path)
+		assert.ErrorContains(t, err, CorruptJournalErrorMessage+" at byte 25")
+
+		unchanged, err := ioutil.ReadFile(path)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, string(unchanged), contents)
+
+		assert.Assert(t, is.Nil(ioutil.WriteFile(path, []byte("{\"seq\":1,\"kind\":\"fill\"}\n\n"), 0600)))
+		store, err := OpenJournalStore(path)
+		assert.Assert(t, is.Nil(err))
+		defer store.Close()
+		assert.DeepEqual(t, replayKinds(t, store), []string{RecordKindFill})
+	})
+}
+
+func TestStoreInterceptor(t *testing.T) {
+	exchange := newFakeExchange(t)
+	client := exchange.client(t)
+
+	store, err := OpenJournalStore(filepath.Join(t.TempDir(), "journal.jsonl"))
+	assert.Assert(t, is.Nil(err))
+	defer store.Close()
+
+	storeErrors := make([]error, 0)
+	interceptor := NewStoreInterceptor(store)
+	interceptor.OnError = func(err error) {
+		storeErrors = append(storeErrors, err)
+	}
+	client.Use(interceptor)
+
+	open, err := client.PlaceOrder(Order{ClientOid: NewClientOid("b07"), ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+	assert.Assert(t, is.Nil(err))
+	cancelled, err := client.PlaceOrder(Order{ProductId: "BTC-USD", Side: "buy", Price: "90", Size: "1"})
+	assert.Assert(t, is.Nil(err))
+	assert.Assert(t, is.Nil(client.CancelOrder(cancelled.Id)))
+	assert.Assert(t, client.CancelOrder("unknown") != nil)
+
+	exchange.addFill(Fill{TradeId: 7, ProductId: "BTC-USD", OrderId: open.Id, Price: "100", Size: "0.5"})
+	exchange.setOrderStatus(open.Id, "open", "0.5")
+	_, err = client.GetFills(FillFilter{OrderId: open.Id})
+	assert.Assert(t, is.Nil(err))
+	_, err = client.GetFills(FillFilter{OrderId: open.Id})
+	assert.Assert(t, is.Nil(err))
+	_, err = client.GetOrder(open.Id)
+	assert.Assert(t, is.Nil(err))
+
+	exchange.setAccounts(Account{Currency: "USD", Balance: "1000", Available: "900", Hold: "100"})
+	_, err = client.GetAccounts()
+	assert.Assert(t, is.Nil(err))
+
+	_, err = client.GetProducts()
+	assert.Assert(t, err != nil)
+
+	assert.Equal(t, len(storeErrors), 0)
+	assert.DeepEqual(t, replayKinds(t, store), []string{
+		RecordKindOrderRequest, RecordKindOrderResponse,
+		RecordKindOrderRequest, RecordKindOrderResponse,
+		RecordKindOrderRequest, RecordKindOrderCancelled,
+		RecordKindOrderRequest, RecordKindOrderError,
+		RecordKindFill, RecordKindFill,
+		RecordKindOrderResponse,
+		RecordKindBalances,
+	})
+
+	recovery, err := Recover(store)
+	assert.Assert(t, is.Nil(err), "unexpected error recovering", err)
+	assert.Equal(t, recovery.LastSequence, int64(12))
+	assert.Equal(t, len(recovery.Orders), 2)
+	assert.Equal(t, recovery.Orders[0].FilledSize, "0.5")
+	assert.Equal(t, recovery.Orders[1].DoneReason, doneReasonCanceled)
+	assert.Equal(t, len(recovery.OpenOrders()), 1)
+	assert.Equal(t, recovery.OpenOrders()[0].Id, open.Id)
+	assert.Equal(t, len(recovery.Fills), 1)
+	assert.DeepEqual(t, recovery.Balances, map[string]Balance{"USD": {Balance: 1000, Available: 900, Hold: 100}})
+}
@@ -0,0 +1,86 @@
+package coinbasepro
+
+import (
+	"sync"
+	"time"
+)
+
+type EndpointTiming struct {
+	Calls        int
+	Errors       int
+	Retries      int
+	TotalLatency time.Duration
+	MinLatency   time.Duration
+	MaxLatency   time.Duration
+}
+
+func (e EndpointTiming) AverageLatency() time.Duration {
+	if e.Calls == 0 {
+		return 0
+	}
+
+	return e.TotalLatency / time.Duration(e.Calls)
+}
+
+// TimingInterceptor aggregates call latency per endpoint.
+type TimingInterceptor struct {
+	mutex     sync.Mutex
+	endpoints map[string]EndpointTiming
+}
+
+func NewTimingInterceptor() *TimingInterceptor {
+	return &TimingInterceptor{endpoints: make(map[string]EndpointTiming)}
+}
+
+func (t *TimingInterceptor) BeforeSign(call *Call) error {
+	return nil
+}
+
+func (t *TimingInterceptor) AfterSign(call *Call) error {
+	return nil
+}
+
+func (t *TimingInterceptor) AfterResponse(call *Call) {
+	endpoint := call.Endpoint()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	timing := t.endpoints[endpoint]
+	timing.Calls++
+	timing.Retries += call.Retries
+	timing.TotalLatency += call.Latency
+
+	if call.Err != nil {
+		timing.Errors++
+	}
+
+	if timing.MinLatency == 0 || call.Latency < timing.MinLatency {
+		timing.MinLatency = call.Latency
+	}
+
+	if call.Latency > timing.MaxLatency {
+		timing.MaxLatency = call.Latency
+	}
+
+	t.endpoints[endpoint] = timing
+}
+
+func (t *TimingInterceptor) Timings() map[string]EndpointTiming {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	timings := make(map[string]EndpointTiming, len(t.endpoints))
+	for endpoint, timing := range t.endpoints {
+		timings[endpoint] = timing
+	}
+
+	return timings
+}
+
+func (t *TimingInterceptor) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.endpoints = make(map[string]EndpointTiming)
+}
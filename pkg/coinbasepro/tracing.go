@@ -0,0 +1,169 @@
+package coinbasepro
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const AttributeHttpMethod = "http.method"
+const AttributeHttpStatusCode = "http.status_code"
+const AttributeEndpoint = "coinbasepro.endpoint"
+const AttributeProductId = "coinbasepro.product_id"
+const AttributeClientOid = "coinbasepro.client_oid"
+const AttributeOrderId = "coinbasepro.order_id"
+const AttributeRetries = "coinbasepro.retries"
+const AttributeError = "error"
+
+type SpanContext struct {
+	TraceId string
+	SpanId  string
+}
+
+func (c SpanContext) IsValid() bool {
+	return c.TraceId != "" && c.SpanId != ""
+}
+
+type Span interface {
+	SpanContext() SpanContext
+	SetAttribute(key string, value interface{})
+	AddLink(linked SpanContext)
+	End()
+}
+
+// Tracer starts spans. A zero parent starts a new trace. It mirrors the shape of an OpenTelemetry
+// tracer so an adapter to a real exporter only has to forward these calls.
+type Tracer interface {
+	Start(name string, parent SpanContext) Span
+}
+
+type NoopTracer struct{}
+
+func (NoopTracer) Start(name string, parent SpanContext) Span {
+	return noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SpanContext() SpanContext {
+	return SpanContext{}
+}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan) AddLink(linked SpanContext) {}
+
+func (noopSpan) End() {}
+
+type RecordedSpan struct {
+	Name         string
+	TraceId      string
+	SpanId       string
+	ParentSpanId string
+	Attributes   map[string]interface{}
+	Links        []SpanContext
+	StartedAt    time.Time
+	EndedAt      time.Time
+}
+
+// InMemoryTracer keeps every ended span in memory, it is meant for tests.
+type InMemoryTracer struct {
+	mutex sync.Mutex
+	spans []RecordedSpan
+}
+
+func NewInMemoryTracer() *InMemoryTracer {
+	return &InMemoryTracer{}
+}
+
+func (t *InMemoryTracer) Start(name string, parent SpanContext) Span {
+	traceId := parent.TraceId
+	if traceId == "" {
+		traceId = randomHexId(16)
+	}
+
+	return &inMemorySpan{
+		tracer: t,
+		recorded: RecordedSpan{
+			Name:         name,
+			TraceId:      traceId,
+			SpanId:       randomHexId(8),
+			ParentSpanId: parent.SpanId,
+			Attributes:   make(map[string]interface{}),
+			StartedAt:    time.Now(),
+		},
+	}
+}
+
+func (t *InMemoryTracer) Spans() []RecordedSpan {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	spans := make([]RecordedSpan, len(t.spans))
+	copy(spans, t.spans)
+
+	return spans
+}
+
+func (t *InMemoryTracer) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.spans = nil
+}
+
+func (t *InMemoryTracer) export(span RecordedSpan) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.spans = append(t.spans, span)
+}
+
+type inMemorySpan struct {
+	mutex    sync.Mutex
+	tracer   *InMemoryTracer
+	recorded RecordedSpan
+	ended    bool
+}
+
+func (s *inMemorySpan) SpanContext() SpanContext {
+	return SpanContext{TraceId: s.recorded.TraceId, SpanId: s.recorded.SpanId}
+}
+
+func (s *inMemorySpan) SetAttribute(key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.recorded.Attributes[key] = value
+}
+
+func (s *inMemorySpan) AddLink(linked SpanContext) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.recorded.Links = append(s.recorded.Links, linked)
+}
+
+func (s *inMemorySpan) End() {
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.recorded.EndedAt = time.Now()
+	recorded := s.recorded
+	s.mutex.Unlock()
+
+	s.tracer.export(recorded)
+}
+
+func randomHexId(bytes int) string {
+	id := make([]byte, bytes)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(id)
+}
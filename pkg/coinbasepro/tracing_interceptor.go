@@ -0,0 +1,172 @@
+package coinbasepro
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const maxTrackedOrderSpans = 10000
+
+type tracingSpanKey struct{}
+
+// TracingInterceptor records a span for every api call. The span of a successful order placement is
+// remembered by order id and client_oid so that later cancels and fill lookups for the same order
+// are linked back to it.
+type TracingInterceptor struct {
+	tracer Tracer
+
+	mutex      sync.Mutex
+	orderSpans map[string]SpanContext
+	orderKeys  []string
+}
+
+func NewTracingInterceptor(tracer Tracer) *TracingInterceptor {
+	if tracer == nil {
+		tracer = NoopTracer{}
+	}
+
+	return &TracingInterceptor{
+		tracer:     tracer,
+		orderSpans: make(map[string]SpanContext),
+	}
+}
+
+func (t *TracingInterceptor) BeforeSign(call *Call) error {
+	span := t.tracer.Start("coinbasepro "+call.Endpoint(), SpanContext{})
+	span.SetAttribute(AttributeHttpMethod, call.Method)
+	span.SetAttribute(AttributeEndpoint, call.Endpoint())
+
+	orderIds, clientOid, productId := callOrderReferences(call)
+	if productId != "" {
+		span.SetAttribute(AttributeProductId, productId)
+	}
+
+	if clientOid != "" {
+		span.SetAttribute(AttributeClientOid, clientOid)
+		t.link(span, clientOid)
+	}
+
+	for _, orderId := range orderIds {
+		span.SetAttribute(AttributeOrderId, orderId)
+		t.link(span, orderId)
+	}
+
+	call.SetValue(tracingSpanKey{}, span)
+
+	return nil
+}
+
+func (t *TracingInterceptor) AfterSign(call *Call) error {
+	return nil
+}
+
+func (t *TracingInterceptor) AfterResponse(call *Call) {
+	span, ok := call.Value(tracingSpanKey{}).(Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttribute(AttributeHttpStatusCode, call.StatusCode)
+	span.SetAttribute(AttributeRetries, call.Retries)
+
+	if call.Err != nil {
+		span.SetAttribute(AttributeError, call.Err.Error())
+	}
+
+	switch response := call.Response.(type) {
+	case *Order:
+		if call.Method == "POST" && response.Id != "" {
+			span.SetAttribute(AttributeOrderId, response.Id)
+			t.remember(response.Id, span.SpanContext())
+			if response.ClientOid != "" {
+				t.remember(response.ClientOid, span.SpanContext())
+			}
+		}
+	case *[]Fill:
+		linked := make(map[string]bool)
+		for _, fill := range *response {
+			if !linked[fill.OrderId] {
+				linked[fill.OrderId] = true
+				t.link(span, fill.OrderId)
+			}
+		}
+	}
+
+	span.End()
+}
+
+// OrderSpanContext returns the span that placed the order with the given order id or client_oid.
+func (t *TracingInterceptor) OrderSpanContext(orderIdOrClientOid string) (SpanContext, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	spanContext, found := t.orderSpans[orderIdOrClientOid]
+	return spanContext, found
+}
+
+func (t *TracingInterceptor) link(span Span, orderIdOrClientOid string) {
+	if spanContext, found := t.OrderSpanContext(orderIdOrClientOid); found {
+		span.AddLink(spanContext)
+	}
+}
+
+func (t *TracingInterceptor) remember(orderIdOrClientOid string, spanContext SpanContext) {
+	if !spanContext.IsValid() {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, found := t.orderSpans[orderIdOrClientOid]; !found {
+		t.orderKeys = append(t.orderKeys, orderIdOrClientOid)
+	}
+	t.orderSpans[orderIdOrClientOid] = spanContext
+
+	for len(t.orderKeys) > maxTrackedOrderSpans {
+		delete(t.orderSpans, t.orderKeys[0])
+		t.orderKeys = t.orderKeys[1:]
+	}
+}
+
+func callOrderReferences(call *Call) (orderIds []string, clientOid, productId string) {
+	switch body := call.Body.(type) {
+	case Order:
+		clientOid, productId = body.ClientOid, body.ProductId
+	case *Order:
+		clientOid, productId = body.ClientOid, body.ProductId
+	}
+
+	requestPath, rawQuery := call.Path, ""
+	if index := strings.Index(requestPath, "?"); index >= 0 {
+		requestPath, rawQuery = requestPath[:index], requestPath[index+1:]
+	}
+
+	segments := strings.Split(strings.Trim(requestPath, "/"), "/")
+	if len(segments) >= 2 {
+		switch segments[0] {
+		case "orders":
+			if strings.HasPrefix(segments[1], "client:") {
+				clientOid = strings.TrimPrefix(segments[1], "client:")
+			} else {
+				orderIds = append(orderIds, segments[1])
+			}
+		case "products":
+			productId = segments[1]
+		}
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err == nil {
+		if orderId := query.Get("order_id"); orderId != "" {
+			orderIds = append(orderIds, orderId)
+		}
+
+		if queryProductId := query.Get("product_id"); queryProductId != "" && productId == "" {
+			productId = queryProductId
+		}
+	}
+
+	return orderIds, clientOid, productId
+}
@@ -0,0 +1,92 @@
+package coinbasepro
+
+import (
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"net/http"
+	"testing"
+)
+
+func TestTracingInterceptor(t *testing.T) {
+	t.Run("should default to a no-op tracer", func(t *testing.T) {
+		exchange := newFakeExchange(t)
+		client := exchange.client(t)
+		client.Use(NewTracingInterceptor(nil))
+
+		_, err := client.PlaceOrder(Order{ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+	})
+
+	t.Run("should record a span per call with order attributes", func(t *testing.T) {
+		exchange := newFakeExchange(t)
+		client := exchange.client(t)
+		tracer := NewInMemoryTracer()
+		client.Use(NewTracingInterceptor(tracer))
+
+		order, err := client.PlaceOrder(Order{ClientOid: "9b0d2b4a-6c1e-4b4f-9f3a-2e8c7d6b5a41", ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		spans := tracer.Spans()
+		assert.Equal(t, len(spans), 1)
+
+		span := spans[0]
+		assert.Equal(t, span.Name, "coinbasepro POST /orders")
+		assert.Equal(t, span.Attributes[AttributeEndpoint], "POST /orders")
+		assert.Equal(t, span.Attributes[AttributeProductId], "BTC-USD")
+		assert.Equal(t, span.Attributes[AttributeClientOid], "9b0d2b4a-6c1e-4b4f-9f3a-2e8c7d6b5a41")
+		assert.Equal(t, span.Attributes[AttributeOrderId], order.Id)
+		assert.Equal(t, span.Attributes[AttributeHttpStatusCode], http.StatusOK)
+		assert.Equal(t, span.Attributes[AttributeRetries], 0)
+		assert.Assert(t, span.TraceId != "" && span.SpanId != "")
+	})
+
+	t.Run("should link cancels and fills to the placing span", func(t *testing.T) {
+		exchange := newFakeExchange(t)
+		client := exchange.client(t)
+		tracer := NewInMemoryTracer()
+		tracing := NewTracingInterceptor(tracer)
+		client.Use(tracing)
+
+		first, err := client.PlaceOrder(Order{ClientOid: "1c7e2a10-2b3c-4d5e-8f90-a1b2c3d4e5f6", ProductId: "BTC-USD", Side: "buy", Price: "100", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+		second, err := client.PlaceOrder(Order{ProductId: "BTC-USD", Side: "sell", Price: "110", Size: "1"})
+		assert.Assert(t, is.Nil(err))
+
+		placements := tracer.Spans()
+		firstSpan := SpanContext{TraceId: placements[0].TraceId, SpanId: placements[0].SpanId}
+		secondSpan := SpanContext{TraceId: placements[1].TraceId, SpanId: placements[1].SpanId}
+
+		linked, found := tracing.OrderSpanContext("1c7e2a10-2b3c-4d5e-8f90-a1b2c3d4e5f6")
+		assert.Assert(t, found)
+		assert.DeepEqual(t, linked, firstSpan)
+
+		exchange.addFill(Fill{TradeId: 1, ProductId: "BTC-USD", OrderId: first.Id, Price: "100", Size: "0.5"})
+		exchange.addFill(Fill{TradeId: 2, ProductId: "BTC-USD", OrderId: second.Id, Price: "110", Size: "1"})
+
+		_, err = client.GetFills(FillFilter{ProductId: "BTC-USD"})
+		assert.Assert(t, is.Nil(err))
+
+		err = client.CancelOrderByClientOid("1c7e2a10-2b3c-4d5e-8f90-a1b2c3d4e5f6")
+		assert.Assert(t, is.Nil(err))
+
+		exchange.setOrderStatus(second.Id, "done", "1")
+		err = client.CancelOrder(second.Id)
+		assert.Error(t, err, "404 - order not found")
+
+		spans := tracer.Spans()
+		assert.Equal(t, len(spans), 5)
+
+		fillsSpan := spans[2]
+		assert.Equal(t, fillsSpan.Name, "coinbasepro GET /fills")
+		assert.DeepEqual(t, fillsSpan.Links, []SpanContext{secondSpan, firstSpan})
+
+		cancelSpan := spans[3]
+		assert.Equal(t, cancelSpan.Name, "coinbasepro DELETE /orders/:client_oid")
+		assert.DeepEqual(t, cancelSpan.Links, []SpanContext{firstSpan})
+
+		failedCancelSpan := spans[4]
+		assert.DeepEqual(t, failedCancelSpan.Links, []SpanContext{secondSpan})
+		assert.Equal(t, failedCancelSpan.Attributes[AttributeHttpStatusCode], http.StatusNotFound)
+		assert.Equal(t, failedCancelSpan.Attributes[AttributeError], "404 - order not found")
+	})
+}
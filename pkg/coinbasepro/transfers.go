@@ -0,0 +1,88 @@
+package coinbasepro
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const TransferTypeDeposit = "deposit"
+const TransferTypeWithdraw = "withdraw"
+const TransferTypeInternalDeposit = "internal_deposit"
+const TransferTypeInternalWithdraw = "internal_withdraw"
+
+type Transfer struct {
+	Id          string            `json:"id"`
+	Type        string            `json:"type"`
+	CreatedAt   string            `json:"created_at"`
+	CompletedAt string            `json:"completed_at"`
+	CanceledAt  string            `json:"canceled_at"`
+	ProcessedAt string            `json:"processed_at"`
+	Amount      string            `json:"amount"`
+	Details     map[string]string `json:"details"`
+}
+
+type TransferFilter struct {
+	Type      string
+	ProfileId string
+}
+
+func (f TransferFilter) query() url.Values {
+	query := url.Values{}
+	if f.Type != "" {
+		query.Set("type", f.Type)
+	}
+
+	if f.ProfileId != "" {
+		query.Set("profile_id", f.ProfileId)
+	}
+
+	return query
+}
+
+type PaymentMethod struct {
+	Id            string `json:"id"`
+	Type          string `json:"type"`
+	Name          string `json:"name"`
+	Currency      string `json:"currency"`
+	PrimaryBuy    bool   `json:"primary_buy"`
+	PrimarySell   bool   `json:"primary_sell"`
+	AllowBuy      bool   `json:"allow_buy"`
+	AllowSell     bool   `json:"allow_sell"`
+	AllowDeposit  bool   `json:"allow_deposit"`
+	AllowWithdraw bool   `json:"allow_withdraw"`
+}
+
+func (t *Client) GetTransfers(filter TransferFilter) ([]Transfer, error) {
+	requestPath := "/transfers"
+	if query := filter.query().Encode(); query != "" {
+		requestPath += "?" + query
+	}
+
+	var transfers []Transfer
+	_, err := t.executeRequest("GET", requestPath, nil, &transfers, defaultMaxRetriesOn429)
+	if err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+func (t *Client) GetTransfer(transferId string) (Transfer, error) {
+	var transfer Transfer
+	_, err := t.executeRequest("GET", fmt.Sprintf("/transfers/%s", transferId), nil, &transfer, defaultMaxRetriesOn429)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	return transfer, nil
+}
+
+func (t *Client) GetPaymentMethods() ([]PaymentMethod, error) {
+	var paymentMethods []PaymentMethod
+	_, err := t.executeRequest("GET", "/payment-methods", nil, &paymentMethods, defaultMaxRetriesOn429)
+	if err != nil {
+		return nil, err
+	}
+
+	return paymentMethods, nil
+}
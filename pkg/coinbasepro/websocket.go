@@ -0,0 +1,258 @@
+package coinbasepro
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const websocketGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+const websocketDialTimeout = 10 * time.Second
+const maxWebsocketFrameSize = 64 << 20
+
+const websocketOpContinuation = 0x0
+const websocketOpText = 0x1
+const websocketOpBinary = 0x2
+const websocketOpClose = 0x8
+const websocketOpPing = 0x9
+const websocketOpPong = 0xA
+
+const WebsocketClosedErrorMessage = "websocket connection closed"
+
+// websocketConn is a minimal RFC 6455 client connection, enough for the exchange feed which only
+// ever exchanges text frames.
+type websocketConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMutex sync.Mutex
+	masked     bool
+}
+
+func dialWebsocket(rawUrl string) (*websocketConn, error) {
+	target, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	host := target.Host
+	if target.Port() == "" {
+		switch target.Scheme {
+		case "wss":
+			host = net.JoinHostPort(target.Hostname(), "443")
+		case "ws":
+			host = net.JoinHostPort(target.Hostname(), "80")
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: websocketDialTimeout}
+	var conn net.Conn
+	switch target.Scheme {
+	case "wss":
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: target.Hostname()})
+	case "ws":
+		conn, err = dialer.Dial("tcp", host)
+	default:
+		return nil, fmt.Errorf("unsupported websocket scheme %s", target.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	requestUri := target.RequestURI()
+	handshake := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", requestUri, target.Host, key)
+
+	conn.SetDeadline(time.Now().Add(websocketDialTimeout))
+	if _, err := io.WriteString(conn, handshake); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, &http.Request{Method: "GET"})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed with status %d", res.StatusCode)
+	}
+
+	if res.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		conn.Close()
+		return nil, errors.New("websocket handshake returned an invalid Sec-WebSocket-Accept")
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return &websocketConn{conn: conn, reader: reader, masked: true}, nil
+}
+
+func websocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGuid))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	header := make([]byte, 2, 14)
+	header[0] = 0x80 | opcode
+
+	length := len(payload)
+	switch {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header[1] = 127
+		header = append(header, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	if c.masked {
+		header[1] |= 0x80
+		mask := make([]byte, 4)
+		if _, err := rand.Read(mask); err != nil {
+			return err
+		}
+		header = append(header, mask...)
+
+		maskedPayload := make([]byte, length)
+		for i := range payload {
+			maskedPayload[i] = payload[i] ^ mask[i%4]
+		}
+		payload = maskedPayload
+	}
+
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+func (c *websocketConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(c.reader, header); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err = io.ReadFull(c.reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err = io.ReadFull(c.reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+
+	if length > maxWebsocketFrameSize {
+		return false, 0, nil, fmt.Errorf("websocket frame of %d bytes exceeds limit", length)
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err = io.ReadFull(c.reader, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// ReadMessage returns the next complete text or binary message, answering pings along the way.
+func (c *websocketConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case websocketOpPing:
+			if err := c.writeFrame(websocketOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case websocketOpPong:
+			continue
+		case websocketOpClose:
+			c.writeFrame(websocketOpClose, payload)
+			return nil, errors.New(WebsocketClosedErrorMessage)
+		case websocketOpText, websocketOpBinary, websocketOpContinuation:
+			message = append(message, payload...)
+		}
+
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+func (c *websocketConn) WriteMessage(payload []byte) error {
+	return c.writeFrame(websocketOpText, payload)
+}
+
+func (c *websocketConn) Ping() error {
+	return c.writeFrame(websocketOpPing, nil)
+}
+
+func (c *websocketConn) Close() error {
+	c.writeFrame(websocketOpClose, []byte{0x03, 0xE8})
+	return c.conn.Close()
+}
+
+func (c *websocketConn) SetReadDeadline(deadline time.Time) error {
+	return c.conn.SetReadDeadline(deadline)
+}
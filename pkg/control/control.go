@@ -0,0 +1,312 @@
+// Package control serves a small HTTP/JSON API to inspect and control a running strategy engine:
+// strategy state and PnL, open orders, balances and risk limits, pausing, resuming and stopping
+// strategies and engaging or resetting the kill switch that cancels every open order. Every
+// endpoint but the OpenAPI description needs the bearer token.
+package control
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/strategy"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const MissingTokenErrorMessage = "the control api needs a token"
+const MissingEngineErrorMessage = "the control api needs a strategy engine"
+const UnauthorizedErrorMessage = "missing or invalid bearer token"
+const NotFoundErrorMessage = "not found"
+const NotConfiguredErrorMessage = "not configured on this server"
+
+const OpenApiPath = "/openapi.json"
+
+// Config wires the server to what it controls. Accounts, Risk and KillSwitch are optional, their
+// endpoints answer 501 without them.
+type Config struct {
+	Token      string
+	Engine     *strategy.Engine
+	Accounts   coinbasepro.AccountsAPI
+	Risk       *coinbasepro.RiskInterceptor
+	KillSwitch *coinbasepro.KillSwitch
+	Logger     *log.Logger
+}
+
+// Server is an http.Handler, mount it on any mux or serve it on its own. Every control action is
+// logged with the address it came from.
+type Server struct {
+	config Config
+	logger *log.Logger
+}
+
+func NewServer(config Config) (*Server, error) {
+	if config.Token == "" {
+		return nil, errors.New(MissingTokenErrorMessage)
+	}
+
+	if config.Engine == nil {
+		return nil, errors.New(MissingEngineErrorMessage)
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = log.New(ioutil.Discard, "", 0)
+	}
+
+	return &Server{config: config, logger: logger}, nil
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJson(writer http.ResponseWriter, status int, body interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(body)
+}
+
+func writeError(writer http.ResponseWriter, status int, message string) {
+	writeJson(writer, status, errorResponse{Error: message})
+}
+
+func (s *Server) authorized(request *http.Request) bool {
+	header := request.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+
+	token := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Token)) == 1
+}
+
+// route is one endpoint. A path segment of {name} matches any strategy name.
+type route struct {
+	method  string
+	path    string
+	handler func(s *Server, writer http.ResponseWriter, request *http.Request, name string)
+}
+
+var routes = []route{
+	{http.MethodGet, "/strategies", (*Server).listStrategies},
+	{http.MethodGet, "/strategies/{name}", (*Server).getStrategy},
+	{http.MethodGet, "/strategies/{name}/orders", (*Server).strategyOrders},
+	{http.MethodPost, "/strategies/{name}/pause", (*Server).pause},
+	{http.MethodPost, "/strategies/{name}/resume", (*Server).resume},
+	{http.MethodPost, "/strategies/{name}/stop", (*Server).stop},
+	{http.MethodGet, "/orders", (*Server).listOrders},
+	{http.MethodPost, "/orders/cancel-all", (*Server).cancelAll},
+	{http.MethodDelete, "/orders/cancel-all", (*Server).resetKillSwitch},
+	{http.MethodGet, "/balances", (*Server).balances},
+	{http.MethodGet, "/risk", (*Server).risk},
+}
+
+// match returns the strategy name a path holds in place of {name}.
+func match(pattern, path string) (string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return "", false
+	}
+
+	name := ""
+	for i, segment := range patternSegments {
+		switch {
+		case segment == "{name}" && pathSegments[i] != "":
+			name = pathSegments[i]
+		case segment != pathSegments[i]:
+			return "", false
+		}
+	}
+
+	return name, true
+}
+
+func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path == OpenApiPath && request.Method == http.MethodGet {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write([]byte(openApi))
+		return
+	}
+
+	if !s.authorized(request) {
+		writer.Header().Set("WWW-Authenticate", `Bearer realm="control"`)
+		writeError(writer, http.StatusUnauthorized, UnauthorizedErrorMessage)
+		return
+	}
+
+	allowed := make([]string, 0)
+	for _, route := range routes {
+		name, found := match(route.path, request.URL.Path)
+		if !found {
+			continue
+		}
+
+		if route.method != request.Method {
+			allowed = append(allowed, route.method)
+			continue
+		}
+
+		route.handler(s, writer, request, name)
+		return
+	}
+
+	if len(allowed) > 0 {
+		writer.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(writer, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	writeError(writer, http.StatusNotFound, NotFoundErrorMessage)
+}
+
+// strategyError answers with the status that fits an engine error.
+func strategyError(writer http.ResponseWriter, err error) {
+	switch err.Error() {
+	case strategy.UnknownStrategyErrorMessage:
+		writeError(writer, http.StatusNotFound, err.Error())
+	case strategy.StrategyNotRunningErrorMessage, strategy.StrategyNotPausedErrorMessage:
+		writeError(writer, http.StatusConflict, err.Error())
+	default:
+		writeError(writer, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (s *Server) listStrategies(writer http.ResponseWriter, request *http.Request, name string) {
+	writeJson(writer, http.StatusOK, s.config.Engine.Statuses())
+}
+
+func (s *Server) getStrategy(writer http.ResponseWriter, request *http.Request, name string) {
+	status, err := s.config.Engine.Status(name)
+	if err != nil {
+		strategyError(writer, err)
+		return
+	}
+
+	writeJson(writer, http.StatusOK, status)
+}
+
+func (s *Server) strategyOrders(writer http.ResponseWriter, request *http.Request, name string) {
+	orders, err := s.config.Engine.OpenOrders(name)
+	if err != nil {
+		strategyError(writer, err)
+		return
+	}
+
+	writeJson(writer, http.StatusOK, orders)
+}
+
+// act runs a control action on one strategy and answers with the strategy's status after it.
+func (s *Server) act(writer http.ResponseWriter, request *http.Request, name, action string, fn func(name string) error) {
+	if err := fn(name); err != nil {
+		s.logger.Printf("control action=%s strategy=%s remote=%s error=%q", action, name, request.RemoteAddr, err.Error())
+		strategyError(writer, err)
+		return
+	}
+
+	s.logger.Printf("control action=%s strategy=%s remote=%s", action, name, request.RemoteAddr)
+	s.getStrategy(writer, request, name)
+}
+
+func (s *Server) pause(writer http.ResponseWriter, request *http.Request, name string) {
+	s.act(writer, request, name, "pause", s.config.Engine.Pause)
+}
+
+func (s *Server) resume(writer http.ResponseWriter, request *http.Request, name string) {
+	s.act(writer, request, name, "resume", s.config.Engine.Resume)
+}
+
+func (s *Server) stop(writer http.ResponseWriter, request *http.Request, name string) {
+	s.act(writer, request, name, "stop", s.config.Engine.StopStrategy)
+}
+
+func (s *Server) listOrders(writer http.ResponseWriter, request *http.Request, name string) {
+	writeJson(writer, http.StatusOK, s.config.Engine.OrderManager().OpenOrders())
+}
+
+// KillSwitchStatus is whether the kill switch keeps trading halted.
+type KillSwitchStatus struct {
+	Halted bool `json:"halted"`
+}
+
+// killSwitchReason is the reason query parameter, or where the request came from without one.
+func killSwitchReason(request *http.Request) string {
+	if reason := request.URL.Query().Get("reason"); reason != "" {
+		return reason
+	}
+
+	return "control " + request.RemoteAddr
+}
+
+// cancelAll engages the kill switch, which halts trading and cancels every open order on its
+// targets, and answers with its report. Strategies keep running, pause or stop them as well.
+func (s *Server) cancelAll(writer http.ResponseWriter, request *http.Request, name string) {
+	if s.config.KillSwitch == nil {
+		writeError(writer, http.StatusNotImplemented, NotConfiguredErrorMessage)
+		return
+	}
+
+	report := s.config.KillSwitch.Engage(killSwitchReason(request))
+	cancelled := 0
+	for _, ids := range report.Cancelled {
+		cancelled += len(ids)
+	}
+	s.logger.Printf("control action=cancel-all remote=%s cancelled=%d unconfirmed=%d", request.RemoteAddr, cancelled, len(report.Unconfirmed))
+
+	status := http.StatusOK
+	if !report.Confirmed() {
+		status = http.StatusBadGateway
+	}
+	writeJson(writer, status, report)
+}
+
+// resetKillSwitch lets orders through again after cancelAll.
+func (s *Server) resetKillSwitch(writer http.ResponseWriter, request *http.Request, name string) {
+	if s.config.KillSwitch == nil {
+		writeError(writer, http.StatusNotImplemented, NotConfiguredErrorMessage)
+		return
+	}
+
+	s.config.KillSwitch.Reset(killSwitchReason(request))
+	s.logger.Printf("control action=reset remote=%s", request.RemoteAddr)
+
+	writeJson(writer, http.StatusOK, KillSwitchStatus{Halted: s.config.KillSwitch.Halted()})
+}
+
+func (s *Server) balances(writer http.ResponseWriter, request *http.Request, name string) {
+	if s.config.Accounts == nil {
+		writeError(writer, http.StatusNotImplemented, NotConfiguredErrorMessage)
+		return
+	}
+
+	accounts, err := s.config.Accounts.GetAccounts()
+	if err != nil {
+		writeError(writer, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	writeJson(writer, http.StatusOK, accounts)
+}
+
+// RiskStatus is the risk limits in force and how close trading is to them.
+type RiskStatus struct {
+	Limits     coinbasepro.RiskLimits `json:"limits"`
+	OpenOrders int                    `json:"open_orders"`
+	DailyPnl   float64                `json:"daily_pnl"`
+}
+
+func (s *Server) risk(writer http.ResponseWriter, request *http.Request, name string) {
+	if s.config.Risk == nil {
+		writeError(writer, http.StatusNotImplemented, NotConfiguredErrorMessage)
+		return
+	}
+
+	writeJson(writer, http.StatusOK, RiskStatus{
+		Limits:     s.config.Risk.Limits(),
+		OpenOrders: s.config.Risk.OpenOrders(),
+		DailyPnl:   s.config.Risk.DailyPnl(),
+	})
+}
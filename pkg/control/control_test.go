@@ -0,0 +1,278 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/strategy"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testToken = "s3cret"
+
+type contextStrategy struct {
+	strategy.BaseStrategy
+	ctx *strategy.Context
+}
+
+func (s *contextStrategy) OnStart(ctx *strategy.Context) error {
+	s.ctx = ctx
+	return nil
+}
+
+type fixture struct {
+	server     *httptest.Server
+	engine     *strategy.Engine
+	paper      *coinbasepro.PaperClient
+	makers     *contextStrategy
+	logs       *bytes.Buffer
+	limits     coinbasepro.RiskLimits
+	killSwitch *coinbasepro.KillSwitch
+}
+
+func newFixture(t *testing.T) *fixture {
+	paper := coinbasepro.NewPaperClient(map[string]float64{"USD": 10000, "BTC": 2}, coinbasepro.FeeTier{})
+	t.Cleanup(func() { paper.Close() })
+	assert.Assert(t, is.Nil(paper.HandleMessage(coinbasepro.Message{
+		Type: coinbasepro.MessageTypeSnapshot, ProductId: "BTC-USD", Bids: [][]string{{"99", "1"}}, Asks: [][]string{{"101", "1"}},
+	})))
+
+	engine, err := strategy.NewEngine(paper, "c0de")
+	assert.Assert(t, is.Nil(err))
+	engine.Logger = log.New(ioutil.Discard, "", 0)
+
+	makers := &contextStrategy{}
+	assert.Assert(t, is.Nil(engine.Add("makers", makers, strategy.Config{ProductIds: []string{"BTC-USD"}})))
+	assert.Assert(t, is.Nil(engine.Add("idle", &contextStrategy{}, strategy.Config{ProductIds: []string{"BTC-USD"}})))
+	engine.Start()
+	t.Cleanup(engine.Stop)
+
+	killSwitch := coinbasepro.NewKillSwitch(nil, log.New(ioutil.Discard, "", 0))
+	killSwitch.RetryDelay = 0
+	assert.Assert(t, is.Nil(killSwitch.Add("paper", paper)))
+
+	limits := coinbasepro.RiskLimits{MaxOpenOrders: 5, MaxDailyLoss: 100}
+	logs := &bytes.Buffer{}
+	handler, err := NewServer(Config{
+		Token:      testToken,
+		Engine:     engine,
+		Accounts:   paper,
+		Risk:       coinbasepro.NewRiskInterceptor(limits, log.New(ioutil.Discard, "", 0)),
+		KillSwitch: killSwitch,
+		Logger:     log.New(logs, "", 0),
+	})
+	assert.Assert(t, is.Nil(err))
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &fixture{server: server, engine: engine, paper: paper, makers: makers, logs: logs, limits: limits, killSwitch: killSwitch}
+}
+
+func (f *fixture) call(t *testing.T, method, path, token string, body interface{}) *http.Response {
+	request, err := http.NewRequest(method, f.server.URL+path, nil)
+	assert.Assert(t, is.Nil(err))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	assert.Assert(t, is.Nil(err))
+	defer response.Body.Close()
+
+	if body != nil {
+		assert.Assert(t, is.Nil(json.NewDecoder(response.Body).Decode(body)))
+	}
+
+	return response
+}
+
+func TestServer(t *testing.T) {
+	t.Run("should need a token", func(t *testing.T) {
+		_, err := NewServer(Config{})
+		assert.Error(t, err, MissingTokenErrorMessage)
+
+		_, err = NewServer(Config{Token: testToken})
+		assert.Error(t, err, MissingEngineErrorMessage)
+	})
+
+	t.Run("should reject requests without a valid bearer token", func(t *testing.T) {
+		f := newFixture(t)
+
+		for _, token := range []string{"", "wrong", testToken + "x"} {
+			var body errorResponse
+			response := f.call(t, http.MethodPost, "/strategies/makers/stop", token, &body)
+			assert.Equal(t, response.StatusCode, http.StatusUnauthorized)
+			assert.Equal(t, response.Header.Get("WWW-Authenticate"), `Bearer realm="control"`)
+			assert.Equal(t, body.Error, UnauthorizedErrorMessage)
+		}
+
+		status, _ := f.engine.Status("makers")
+		assert.Equal(t, status.State, strategy.StateRunning)
+	})
+
+	t.Run("should list strategies, orders, balances and risk", func(t *testing.T) {
+		f := newFixture(t)
+		_, err := f.makers.ctx.Buy("BTC-USD", 0.5, 90)
+		assert.Assert(t, is.Nil(err))
+
+		var statuses []strategy.Status
+		response := f.call(t, http.MethodGet, "/strategies", testToken, &statuses)
+		assert.Equal(t, response.StatusCode, http.StatusOK)
+		assert.Equal(t, response.Header.Get("Content-Type"), "application/json")
+		assert.Equal(t, len(statuses), 2)
+		assert.Equal(t, statuses[1].Name, "makers")
+		assert.Equal(t, statuses[1].OpenOrders, 1)
+
+		var status strategy.Status
+		f.call(t, http.MethodGet, "/strategies/makers", testToken, &status)
+		assert.Equal(t, status.State, strategy.StateRunning)
+
+		var orders []coinbasepro.ManagedOrder
+		f.call(t, http.MethodGet, "/strategies/makers/orders", testToken, &orders)
+		assert.Equal(t, len(orders), 1)
+		assert.Equal(t, orders[0].Price, "90")
+
+		f.call(t, http.MethodGet, "/strategies/idle/orders", testToken, &orders)
+		assert.Equal(t, len(orders), 0)
+
+		f.call(t, http.MethodGet, "/orders", testToken, &orders)
+		assert.Equal(t, len(orders), 1)
+
+		var accounts []coinbasepro.Account
+		response = f.call(t, http.MethodGet, "/balances", testToken, &accounts)
+		assert.Equal(t, response.StatusCode, http.StatusOK)
+		assert.Equal(t, len(accounts), 2)
+
+		var risk RiskStatus
+		response = f.call(t, http.MethodGet, "/risk", testToken, &risk)
+		assert.Equal(t, response.StatusCode, http.StatusOK)
+		assert.DeepEqual(t, risk.Limits, f.limits)
+
+		var body errorResponse
+		response = f.call(t, http.MethodGet, "/strategies/missing", testToken, &body)
+		assert.Equal(t, response.StatusCode, http.StatusNotFound)
+		assert.Equal(t, body.Error, strategy.UnknownStrategyErrorMessage)
+
+		response = f.call(t, http.MethodGet, "/nothing/here", testToken, &body)
+		assert.Equal(t, response.StatusCode, http.StatusNotFound)
+
+		response = f.call(t, http.MethodDelete, "/strategies/makers", testToken, &body)
+		assert.Equal(t, response.StatusCode, http.StatusMethodNotAllowed)
+		assert.Equal(t, response.Header.Get("Allow"), http.MethodGet)
+	})
+
+	t.Run("should answer 501 for what is not configured", func(t *testing.T) {
+		f := newFixture(t)
+		handler, err := NewServer(Config{Token: testToken, Engine: f.engine})
+		assert.Assert(t, is.Nil(err))
+
+		for _, route := range [][2]string{{http.MethodGet, "/balances"}, {http.MethodGet, "/risk"}, {http.MethodPost, "/orders/cancel-all"}, {http.MethodDelete, "/orders/cancel-all"}} {
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(route[0], route[1], nil)
+			request.Header.Set("Authorization", "Bearer "+testToken)
+			handler.ServeHTTP(recorder, request)
+			assert.Equal(t, recorder.Code, http.StatusNotImplemented)
+			assert.Assert(t, is.Contains(recorder.Body.String(), NotConfiguredErrorMessage))
+		}
+	})
+
+	t.Run("should pause, resume and stop a strategy", func(t *testing.T) {
+		f := newFixture(t)
+
+		var status strategy.Status
+		response := f.call(t, http.MethodPost, "/strategies/makers/pause", testToken, &status)
+		assert.Equal(t, response.StatusCode, http.StatusOK)
+		assert.Equal(t, status.State, strategy.StatePaused)
+
+		_, err := f.makers.ctx.Buy("BTC-USD", 0.5, 90)
+		assert.Error(t, err, strategy.StrategyNotRunningErrorMessage)
+
+		var body errorResponse
+		response = f.call(t, http.MethodPost, "/strategies/makers/pause", testToken, &body)
+		assert.Equal(t, response.StatusCode, http.StatusConflict)
+		assert.Equal(t, body.Error, strategy.StrategyNotRunningErrorMessage)
+
+		response = f.call(t, http.MethodPost, "/strategies/idle/resume", testToken, &body)
+		assert.Equal(t, response.StatusCode, http.StatusConflict)
+		assert.Equal(t, body.Error, strategy.StrategyNotPausedErrorMessage)
+
+		f.call(t, http.MethodPost, "/strategies/makers/resume", testToken, &status)
+		assert.Equal(t, status.State, strategy.StateRunning)
+
+		f.call(t, http.MethodPost, "/strategies/makers/stop", testToken, &status)
+		assert.Equal(t, status.State, strategy.StateStopped)
+
+		response = f.call(t, http.MethodPost, "/strategies/missing/stop", testToken, &body)
+		assert.Equal(t, response.StatusCode, http.StatusNotFound)
+
+		response = f.call(t, http.MethodGet, "/strategies/makers/stop", testToken, &body)
+		assert.Equal(t, response.StatusCode, http.StatusMethodNotAllowed)
+
+		assert.Assert(t, is.Contains(f.logs.String(), "control action=pause strategy=makers remote=127.0.0.1"))
+		assert.Assert(t, is.Contains(f.logs.String(), "control action=stop strategy=makers remote=127.0.0.1"))
+	})
+
+	t.Run("should engage and reset the kill switch", func(t *testing.T) {
+		f := newFixture(t)
+		orderIds := make([]string, 0)
+		for _, price := range []float64{90, 91} {
+			order, err := f.makers.ctx.Buy("BTC-USD", 0.5, price)
+			assert.Assert(t, is.Nil(err))
+			orderIds = append(orderIds, order.OrderId)
+		}
+
+		var report coinbasepro.KillSwitchReport
+		response := f.call(t, http.MethodPost, "/orders/cancel-all?reason=drill", testToken, &report)
+		assert.Equal(t, response.StatusCode, http.StatusOK)
+		assert.Equal(t, report.Reason, "drill")
+		assert.Assert(t, report.Confirmed())
+		assert.DeepEqual(t, report.Cancelled, map[string][]string{"paper": {orderIds[1], orderIds[0]}})
+		assert.Assert(t, f.killSwitch.Halted())
+
+		open, err := f.paper.ListOrders(coinbasepro.OrderFilter{Statuses: []string{"open", "pending", "active"}})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(open), 0)
+		assert.Assert(t, is.Contains(f.logs.String(), "control action=cancel-all remote=127.0.0.1"))
+		assert.Assert(t, is.Contains(f.logs.String(), "cancelled=2 unconfirmed=0"))
+
+		var status KillSwitchStatus
+		response = f.call(t, http.MethodDelete, "/orders/cancel-all", testToken, &status)
+		assert.Equal(t, response.StatusCode, http.StatusOK)
+		assert.Assert(t, !status.Halted)
+		assert.Assert(t, !f.killSwitch.Halted())
+		assert.Assert(t, is.Contains(f.logs.String(), "control action=reset remote=127.0.0.1"))
+
+		response = f.call(t, http.MethodGet, "/orders/cancel-all", testToken, nil)
+		assert.Equal(t, response.StatusCode, http.StatusMethodNotAllowed)
+		assert.Equal(t, response.Header.Get("Allow"), "POST, DELETE")
+	})
+
+	t.Run("should describe every route without a token", func(t *testing.T) {
+		f := newFixture(t)
+
+		var document struct {
+			OpenApi string                            `json:"openapi"`
+			Paths   map[string]map[string]interface{} `json:"paths"`
+		}
+		response := f.call(t, http.MethodGet, OpenApiPath, "", &document)
+		assert.Equal(t, response.StatusCode, http.StatusOK)
+		assert.Equal(t, document.OpenApi, "3.0.3")
+
+		paths := make(map[string]bool)
+		for _, route := range routes {
+			paths[route.path] = true
+			path := document.Paths[route.path]
+			assert.Assert(t, path != nil, route.path)
+			_, found := path[map[string]string{http.MethodGet: "get", http.MethodPost: "post", http.MethodDelete: "delete"}[route.method]]
+			assert.Assert(t, found, route.method+" "+route.path)
+		}
+		assert.Equal(t, len(document.Paths), len(paths))
+	})
+}
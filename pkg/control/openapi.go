@@ -0,0 +1,241 @@
+package control
+
+// openApi describes the control API, served unauthenticated at OpenApiPath.
+const openApi = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "coinbasepro-trader control API",
+    "version": "1.0.0",
+    "description": "Inspect and control the strategies of a running trader."
+  },
+  "security": [{"bearer": []}],
+  "paths": {
+    "/strategies": {
+      "get": {
+        "summary": "List strategies with their state, positions and PnL",
+        "responses": {
+          "200": {"description": "Strategies", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Status"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      }
+    },
+    "/strategies/{name}": {
+      "parameters": [{"$ref": "#/components/parameters/Name"}],
+      "get": {
+        "summary": "Get one strategy",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Status"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/strategies/{name}/orders": {
+      "parameters": [{"$ref": "#/components/parameters/Name"}],
+      "get": {
+        "summary": "List the open orders of one strategy",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Orders"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/strategies/{name}/pause": {
+      "parameters": [{"$ref": "#/components/parameters/Name"}],
+      "post": {
+        "summary": "Pause a running strategy, it gets no market data and can not place orders until resumed, its fills and order updates are delivered on resume",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Status"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/strategies/{name}/resume": {
+      "parameters": [{"$ref": "#/components/parameters/Name"}],
+      "post": {
+        "summary": "Resume a paused strategy",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Status"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/strategies/{name}/stop": {
+      "parameters": [{"$ref": "#/components/parameters/Name"}],
+      "post": {
+        "summary": "Stop a running or paused strategy for good",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Status"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/orders": {
+      "get": {
+        "summary": "List every open order placed by the strategies",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Orders"},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      }
+    },
+    "/orders/cancel-all": {
+      "parameters": [{"$ref": "#/components/parameters/Reason"}],
+      "post": {
+        "summary": "Engage the kill switch, halting trading and cancelling every open order",
+        "responses": {
+          "200": {"$ref": "#/components/responses/KillSwitchReport"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "501": {"$ref": "#/components/responses/Error"},
+          "502": {"$ref": "#/components/responses/KillSwitchReport"}
+        }
+      },
+      "delete": {
+        "summary": "Reset the kill switch so orders are accepted again",
+        "responses": {
+          "200": {"description": "Kill switch", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/KillSwitchStatus"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "501": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/balances": {
+      "get": {
+        "summary": "List account balances",
+        "responses": {
+          "200": {"description": "Accounts", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Account"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "501": {"$ref": "#/components/responses/Error"},
+          "502": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/risk": {
+      "get": {
+        "summary": "Get the risk limits and current usage",
+        "responses": {
+          "200": {"description": "Risk", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Risk"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "501": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearer": {"type": "http", "scheme": "bearer"}
+    },
+    "parameters": {
+      "Name": {"name": "name", "in": "path", "required": true, "schema": {"type": "string"}},
+      "Reason": {"name": "reason", "in": "query", "required": false, "schema": {"type": "string"}}
+    },
+    "responses": {
+      "Unauthorized": {"description": "Missing or invalid bearer token", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Error": {"description": "Error", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}},
+      "Status": {"description": "Strategy", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Status"}}}},
+      "Orders": {"description": "Open orders", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Order"}}}}},
+      "KillSwitchReport": {"description": "What the kill switch cancelled, 502 when a target could not be confirmed free of open orders", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/KillSwitchReport"}}}}
+    },
+    "schemas": {
+      "Error": {
+        "type": "object",
+        "properties": {"error": {"type": "string"}}
+      },
+      "Position": {
+        "type": "object",
+        "properties": {
+          "product_id": {"type": "string"},
+          "size": {"type": "number"},
+          "average_cost": {"type": "number"},
+          "realized_pnl": {"type": "number"}
+        }
+      },
+      "Status": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string"},
+          "state": {"type": "string", "enum": ["created", "running", "paused", "stopped", "failed"]},
+          "product_ids": {"type": "array", "items": {"type": "string"}},
+          "positions": {"type": "object", "additionalProperties": {"$ref": "#/components/schemas/Position"}},
+          "realized_pnl": {"type": "number"},
+          "unrealized_pnl": {"type": "number"},
+          "open_orders": {"type": "integer"},
+          "error": {"type": "string"}
+        }
+      },
+      "Order": {
+        "type": "object",
+        "properties": {
+          "client_oid": {"type": "string"},
+          "order_id": {"type": "string"},
+          "product_id": {"type": "string"},
+          "side": {"type": "string", "enum": ["buy", "sell"]},
+          "type": {"type": "string", "enum": ["limit", "market"]},
+          "price": {"type": "string"},
+          "size": {"type": "string"},
+          "funds": {"type": "string"},
+          "filled_size": {"type": "number"},
+          "executed_value": {"type": "number"},
+          "state": {"type": "string", "enum": ["pending", "open", "partially_filled", "done", "cancelled", "rejected"]},
+          "done_reason": {"type": "string"},
+          "reject_reason": {"type": "string"},
+          "created_at": {"type": "string", "format": "date-time"},
+          "updated_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "KillSwitchReport": {
+        "type": "object",
+        "properties": {
+          "reason": {"type": "string"},
+          "started_at": {"type": "string", "format": "date-time"},
+          "finished_at": {"type": "string", "format": "date-time"},
+          "cancelled": {"type": "object", "additionalProperties": {"type": "array", "items": {"type": "string"}}},
+          "unconfirmed": {"type": "array", "items": {"type": "string"}},
+          "flattened": {"type": "array", "items": {"type": "object"}}
+        }
+      },
+      "KillSwitchStatus": {
+        "type": "object",
+        "properties": {"halted": {"type": "boolean"}}
+      },
+      "Account": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "currency": {"type": "string"},
+          "balance": {"type": "string"},
+          "available": {"type": "string"},
+          "hold": {"type": "string"},
+          "profile_id": {"type": "string"},
+          "trading_enabled": {"type": "boolean"}
+        }
+      },
+      "Risk": {
+        "type": "object",
+        "properties": {
+          "limits": {
+            "type": "object",
+            "properties": {
+              "allowed_products": {"type": "array", "items": {"type": "string"}},
+              "max_order_notional": {"type": "number"},
+              "max_position": {"type": "object", "additionalProperties": {"type": "number"}},
+              "max_open_orders": {"type": "integer"},
+              "max_daily_loss": {"type": "number"},
+              "price_band": {"type": "number"}
+            }
+          },
+          "open_orders": {"type": "integer"},
+          "daily_pnl": {"type": "number"}
+        }
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,444 @@
+// Package execution works orders on the client side through an OrderManager, from algorithms that
+// split parent orders over time to iceberg, pegged and contingent orders.
+package execution
+
+import (
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type State string
+
+const StateRunning State = "running"
+const StatePaused State = "paused"
+const StateCompleted State = "completed"
+const StateCancelled State = "cancelled"
+const StateExpired State = "expired"
+const StateFailed State = "failed"
+
+const InvalidParamsErrorMessage = "parent order needs a product, a side, a positive size, slices and an end after start"
+const AlgoFinishedErrorMessage = "execution has already finished"
+
+// Params describe the parent order. LimitPrice is the worst price any child may trade at,
+// MaxParticipation caps the executed size at a fraction of the market volume seen since Start and
+// SizeIncrement and MinSize are the product's base increment and minimum order size.
+type Params struct {
+	ProductId        string
+	Side             string
+	Size             float64
+	Start            time.Time
+	End              time.Time
+	Slices           int
+	LimitPrice       float64
+	MaxParticipation float64
+	SizeIncrement    float64
+	MinSize          float64
+}
+
+func (p Params) validate() error {
+	if p.ProductId == "" || (p.Side != coinbasepro.OrderSideBuy && p.Side != coinbasepro.OrderSideSell) || p.Size <= 0 || p.Slices <= 0 || !p.End.After(p.Start) {
+		return errors.New(InvalidParamsErrorMessage)
+	}
+
+	return nil
+}
+
+// Progress reports execution against the benchmark, the market VWAP over the window for VWAP and
+// the average market price at the slice times for TWAP. Slippage is in basis points, positive
+// when the execution was worse than the benchmark.
+type Progress struct {
+	State        State     `json:"state"`
+	Filled       float64   `json:"filled"`
+	Remaining    float64   `json:"remaining"`
+	AveragePrice float64   `json:"average_price"`
+	Benchmark    float64   `json:"benchmark"`
+	SlippageBps  float64   `json:"slippage_bps"`
+	ChildOrders  int       `json:"child_orders"`
+	Guarded      int       `json:"guarded"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Algo works a parent order along a schedule. It is driven by Step, either from Run or, in tests
+// and backtests, with simulated time. Market data arrives through HandleMessage and fills through
+// the OrderManager. It is safe for concurrent use.
+type Algo struct {
+	orders   *coinbasepro.OrderManager
+	params   Params
+	schedule []slice
+	vwap     bool
+
+	// OnProgress is called after every change in execution.
+	OnProgress func(progress Progress)
+
+	mutex        sync.Mutex
+	state        State
+	next         int
+	children     *childOrders
+	outstanding  string
+	guarded      int
+	bestBid      float64
+	bestAsk      float64
+	marketVolume float64
+	marketValue  float64
+	samples      []float64
+	updatedAt    time.Time
+}
+
+// NewTWAP slices the parent order evenly, seed drives the timing jitter of up to jitter of a slice
+// interval so runs are reproducible.
+func NewTWAP(orders *coinbasepro.OrderManager, params Params, jitter float64, seed int64) (*Algo, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	schedule := twapSchedule(params.Start, params.End, params.Slices, math.Min(math.Max(jitter, 0), 1), rand.New(rand.NewSource(seed)))
+	return newAlgo(orders, params, schedule, false), nil
+}
+
+// NewVWAP sizes slices to follow the intraday volume profile.
+func NewVWAP(orders *coinbasepro.OrderManager, params Params, profile VolumeProfile) (*Algo, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	return newAlgo(orders, params, vwapSchedule(params.Start, params.End, params.Slices, profile), true), nil
+}
+
+func newAlgo(orders *coinbasepro.OrderManager, params Params, schedule []slice, vwap bool) *Algo {
+	if params.SizeIncrement <= 0 {
+		params.SizeIncrement = 1e-8
+	}
+
+	algo := &Algo{
+		orders:   orders,
+		params:   params,
+		schedule: schedule,
+		vwap:     vwap,
+		state:    StateRunning,
+		children: newChildOrders(),
+	}
+	orders.Subscribe(algo.handleOrderUpdate)
+
+	return algo
+}
+
+func (a *Algo) Params() Params {
+	return a.params
+}
+
+// HandleMessage follows the best bid and ask from ticker and level2 messages and market volume
+// from matches.
+func (a *Algo) HandleMessage(message coinbasepro.Message) {
+	if message.ProductId != a.params.ProductId {
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	switch message.Type {
+	case coinbasepro.MessageTypeTicker:
+		a.bestBid = parseFloat(message.BestBid)
+		a.bestAsk = parseFloat(message.BestAsk)
+	case coinbasepro.MessageTypeSnapshot:
+		if len(message.Bids) > 0 {
+			a.bestBid = parseFloat(message.Bids[0][0])
+		}
+		if len(message.Asks) > 0 {
+			a.bestAsk = parseFloat(message.Asks[0][0])
+		}
+	case coinbasepro.MessageTypeMatch, coinbasepro.MessageTypeLastMatch:
+		at, err := time.Parse(time.RFC3339Nano, message.Time)
+		if err == nil && (at.Before(a.params.Start) || at.After(a.params.End)) {
+			return
+		}
+
+		size := parseFloat(message.Size)
+		a.marketVolume += size
+		a.marketValue += size * parseFloat(message.Price)
+	}
+}
+
+// SetQuote sets the best bid and ask, for callers that keep their own order book.
+func (a *Algo) SetQuote(bestBid, bestAsk float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.bestBid, a.bestAsk = bestBid, bestAsk
+}
+
+func (a *Algo) handleOrderUpdate(update coinbasepro.OrderUpdate) {
+	a.mutex.Lock()
+	if !a.children.owns(update.Order.ClientOid) {
+		a.mutex.Unlock()
+		return
+	}
+
+	changed := a.children.apply(update.Order)
+
+	if update.Order.State.IsTerminal() && a.outstanding == update.Order.ClientOid {
+		a.outstanding = ""
+	}
+
+	if a.state == StateRunning && a.children.filled >= a.params.Size-a.params.SizeIncrement/2 {
+		a.state = StateCompleted
+		changed = true
+	}
+
+	progress := a.progress()
+	a.mutex.Unlock()
+
+	if changed {
+		a.notify(progress)
+	}
+}
+
+func (a *Algo) notify(progress Progress) {
+	if a.OnProgress != nil {
+		a.OnProgress(progress)
+	}
+}
+
+// Step places the child order due at now, if any. A child is only placed once the previous one
+// has finished, so at most one is ever working.
+func (a *Algo) Step(now time.Time) error {
+	a.mutex.Lock()
+	a.updatedAt = now
+
+	if a.state != StateRunning || a.outstanding != "" || now.Before(a.params.Start) {
+		a.mutex.Unlock()
+		return nil
+	}
+
+	target := 0.0
+	for a.next < len(a.schedule) && !now.Before(a.schedule[a.next].At) {
+		a.next++
+		if reference := a.referencePrice(); reference > 0 {
+			a.samples = append(a.samples, reference)
+		}
+	}
+	if a.next > 0 {
+		target = a.schedule[a.next-1].Target * a.params.Size
+	}
+
+	if !now.Before(a.params.End) {
+		// the window is over, whatever is left stays unfilled
+		a.finishExpired()
+		progress := a.progress()
+		a.mutex.Unlock()
+		a.notify(progress)
+		return nil
+	}
+
+	size := target - a.children.filled
+	if a.params.MaxParticipation > 0 {
+		size = math.Min(size, a.params.MaxParticipation*a.marketVolume-a.children.filled)
+	}
+	size = floorToIncrement(size, a.params.SizeIncrement)
+
+	if size <= 0 || size < a.params.MinSize {
+		a.mutex.Unlock()
+		return nil
+	}
+
+	if a.touch() <= 0 && a.params.LimitPrice <= 0 {
+		// without a quote or a limit price the child would be an unguarded market order
+		a.mutex.Unlock()
+		return nil
+	}
+
+	order, guarded := a.childOrder(size)
+	if guarded {
+		a.guarded++
+		progress := a.progress()
+		a.mutex.Unlock()
+		a.notify(progress)
+		return nil
+	}
+
+	order.ClientOid = coinbasepro.NewClientOid(a.orders.ClientOidPrefix())
+	a.children.add(order.ClientOid)
+	a.outstanding = order.ClientOid
+	a.mutex.Unlock()
+
+	placed, err := a.orders.Place(order)
+	if err != nil || placed.State.IsTerminal() {
+		a.mutex.Lock()
+		if a.outstanding == order.ClientOid {
+			a.outstanding = ""
+		}
+		a.mutex.Unlock()
+	}
+
+	return err
+}
+
+func (a *Algo) finishExpired() {
+	if a.children.filled >= a.params.Size-a.params.SizeIncrement/2 {
+		a.state = StateCompleted
+	} else {
+		a.state = StateExpired
+	}
+}
+
+func (a *Algo) referencePrice() float64 {
+	if a.bestBid > 0 && a.bestAsk > 0 {
+		return (a.bestBid + a.bestAsk) / 2
+	}
+
+	return math.Max(a.bestBid, a.bestAsk)
+}
+
+// touch is the best price on the side a child order takes from.
+func (a *Algo) touch() float64 {
+	if a.params.Side == coinbasepro.OrderSideSell {
+		return a.bestBid
+	}
+
+	return a.bestAsk
+}
+
+// childOrder builds an immediate or cancel order at the touch, or at the limit price before there
+// is a quote, or reports that the touch is beyond the limit price.
+func (a *Algo) childOrder(size float64) (coinbasepro.Order, bool) {
+	order := coinbasepro.Order{
+		ProductId: a.params.ProductId,
+		Side:      a.params.Side,
+		Size:      formatIncrement(size, a.params.SizeIncrement),
+	}
+
+	touch := a.touch()
+	if touch <= 0 {
+		touch = a.params.LimitPrice
+	}
+
+	if a.params.LimitPrice > 0 {
+		if (a.params.Side == coinbasepro.OrderSideBuy && touch > a.params.LimitPrice) || (a.params.Side == coinbasepro.OrderSideSell && touch < a.params.LimitPrice) {
+			return order, true
+		}
+	}
+
+	order.Type = coinbasepro.OrderTypeLimit
+	order.Price = strconv.FormatFloat(touch, 'f', -1, 64)
+	order.TimeInForce = "IOC"
+
+	return order, false
+}
+
+// Run steps the algo every interval until it finishes.
+func (a *Algo) Run(interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.Step(time.Now()); err != nil {
+			return err
+		}
+
+		if a.Progress().State.finished() {
+			return nil
+		}
+
+		<-ticker.C
+	}
+}
+
+func (s State) finished() bool {
+	return s == StateCompleted || s == StateCancelled || s == StateExpired || s == StateFailed
+}
+
+func (a *Algo) Pause() error {
+	return a.transition(StateRunning, StatePaused)
+}
+
+func (a *Algo) Resume() error {
+	return a.transition(StatePaused, StateRunning)
+}
+
+func (a *Algo) transition(from, to State) error {
+	a.mutex.Lock()
+	if a.state.finished() {
+		a.mutex.Unlock()
+		return errors.New(AlgoFinishedErrorMessage)
+	}
+
+	if a.state == from {
+		a.state = to
+	}
+	progress := a.progress()
+	a.mutex.Unlock()
+
+	a.notify(progress)
+	return nil
+}
+
+// Cancel stops placing child orders and cancels the working one.
+func (a *Algo) Cancel() error {
+	a.mutex.Lock()
+	if a.state.finished() {
+		a.mutex.Unlock()
+		return errors.New(AlgoFinishedErrorMessage)
+	}
+
+	a.state = StateCancelled
+	outstanding := a.outstanding
+	progress := a.progress()
+	a.mutex.Unlock()
+
+	a.notify(progress)
+
+	if outstanding != "" {
+		return a.orders.Cancel(outstanding)
+	}
+
+	return nil
+}
+
+func (a *Algo) Progress() Progress {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.progress()
+}
+
+func (a *Algo) progress() Progress {
+	progress := Progress{
+		State:       a.state,
+		Filled:      a.children.filled,
+		Remaining:   math.Max(a.params.Size-a.children.filled, 0),
+		ChildOrders: a.children.count,
+		Guarded:     a.guarded,
+		UpdatedAt:   a.updatedAt,
+	}
+
+	progress.AveragePrice = a.children.averagePrice()
+
+	if a.vwap && a.marketVolume > 0 {
+		progress.Benchmark = a.marketValue / a.marketVolume
+	} else if !a.vwap && len(a.samples) > 0 {
+		sum := 0.0
+		for _, sample := range a.samples {
+			sum += sample
+		}
+		progress.Benchmark = sum / float64(len(a.samples))
+	}
+
+	if progress.Benchmark > 0 && progress.AveragePrice > 0 {
+		progress.SlippageBps = (progress.AveragePrice - progress.Benchmark) / progress.Benchmark * 1e4
+		if a.params.Side == coinbasepro.OrderSideSell {
+			progress.SlippageBps = -progress.SlippageBps
+		}
+	}
+
+	return progress
+}
+
+func parseFloat(value string) float64 {
+	parsed, _ := strconv.ParseFloat(value, 64)
+	return parsed
+}
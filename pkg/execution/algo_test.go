@@ -0,0 +1,227 @@
+package execution
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var windowStart = time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func bookSnapshot(bid, ask string) coinbasepro.Message {
+	return coinbasepro.Message{
+		Type:      coinbasepro.MessageTypeSnapshot,
+		ProductId: "BTC-USD",
+		Bids:      [][]string{{bid, "10"}},
+		Asks:      [][]string{{ask, "10"}},
+	}
+}
+
+func newTestOrders(t *testing.T, bid, ask string) (*coinbasepro.OrderManager, *coinbasepro.PaperClient) {
+	paper := coinbasepro.NewPaperClient(map[string]float64{"USD": 100000, "BTC": 10}, coinbasepro.FeeTier{})
+	t.Cleanup(func() { paper.Close() })
+	assert.Assert(t, is.Nil(paper.HandleMessage(bookSnapshot(bid, ask))))
+
+	orders, err := coinbasepro.NewOrderManager(paper, "a1")
+	assert.Assert(t, is.Nil(err))
+
+	return orders, paper
+}
+
+func testParams(side string, size float64) Params {
+	return Params{ProductId: "BTC-USD", Side: side, Size: size, Start: windowStart, End: windowStart.Add(4 * time.Minute), Slices: 4}
+}
+
+func stepEvery(t *testing.T, algo *Algo, interval time.Duration, until time.Duration) {
+	for at := time.Duration(0); at <= until; at += interval {
+		assert.Assert(t, is.Nil(algo.Step(windowStart.Add(at))))
+	}
+}
+
+func assertClose(t *testing.T, actual, expected float64) {
+	t.Helper()
+	assert.Assert(t, math.Abs(actual-expected) < 1e-9, "expected %v, got %v", expected, actual)
+}
+
+func TestTWAP(t *testing.T) {
+	t.Run("should slice evenly and report against the benchmark", func(t *testing.T) {
+		orders, _ := newTestOrders(t, "99", "101")
+		algo, err := NewTWAP(orders, testParams(coinbasepro.OrderSideBuy, 2), 0, 1)
+		assert.Assert(t, is.Nil(err))
+		algo.HandleMessage(bookSnapshot("99", "101"))
+
+		filled := make([]float64, 0)
+		algo.OnProgress = func(progress Progress) {
+			filled = append(filled, progress.Filled)
+		}
+
+		stepEvery(t, algo, 30*time.Second, 5*time.Minute)
+
+		progress := algo.Progress()
+		assert.Equal(t, progress.State, StateCompleted)
+		assert.Equal(t, progress.ChildOrders, 4)
+		assert.DeepEqual(t, filled, []float64{0.5, 1, 1.5, 2})
+		assertClose(t, progress.AveragePrice, 101)
+		assertClose(t, progress.Benchmark, 100)
+		assertClose(t, progress.SlippageBps, 100)
+	})
+
+	t.Run("should not cross the limit price", func(t *testing.T) {
+		orders, paper := newTestOrders(t, "99", "101")
+		params := testParams(coinbasepro.OrderSideBuy, 1)
+		params.LimitPrice = 100
+		algo, err := NewTWAP(orders, params, 0, 1)
+		assert.Assert(t, is.Nil(err))
+		algo.HandleMessage(bookSnapshot("99", "101"))
+
+		stepEvery(t, algo, time.Minute, 2*time.Minute)
+		assert.Equal(t, algo.Progress().ChildOrders, 0)
+		assert.Equal(t, algo.Progress().Guarded, 3)
+
+		assert.Assert(t, is.Nil(paper.HandleMessage(bookSnapshot("98", "100"))))
+		algo.HandleMessage(bookSnapshot("98", "100"))
+		assert.Assert(t, is.Nil(algo.Step(windowStart.Add(2*time.Minute))))
+
+		progress := algo.Progress()
+		assert.Equal(t, progress.ChildOrders, 1)
+		assertClose(t, progress.Filled, 0.75)
+		assertClose(t, progress.AveragePrice, 100)
+	})
+
+	t.Run("should expire at the end while the touch is beyond the limit price", func(t *testing.T) {
+		orders, _ := newTestOrders(t, "99", "101")
+		params := testParams(coinbasepro.OrderSideBuy, 1)
+		params.LimitPrice = 100
+		algo, err := NewTWAP(orders, params, 0, 1)
+		assert.Assert(t, is.Nil(err))
+		algo.HandleMessage(bookSnapshot("99", "101"))
+
+		stepEvery(t, algo, time.Minute, 6*time.Minute)
+
+		progress := algo.Progress()
+		assert.Equal(t, progress.State, StateExpired)
+		assert.Equal(t, progress.ChildOrders, 0)
+		assert.Equal(t, progress.Guarded, 4)
+	})
+
+	t.Run("should wait for a quote before placing without a limit price", func(t *testing.T) {
+		orders, _ := newTestOrders(t, "99", "101")
+		algo, err := NewTWAP(orders, testParams(coinbasepro.OrderSideBuy, 2), 0, 1)
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, is.Nil(algo.Step(windowStart)))
+		assert.Equal(t, algo.Progress().ChildOrders, 0)
+		assert.Equal(t, algo.Progress().State, StateRunning)
+
+		algo.SetQuote(99, 101)
+		assert.Assert(t, is.Nil(algo.Step(windowStart.Add(time.Second))))
+		assert.Equal(t, algo.Progress().ChildOrders, 1)
+		assertClose(t, algo.Progress().AveragePrice, 101)
+	})
+
+	t.Run("should cap participation in market volume", func(t *testing.T) {
+		orders, _ := newTestOrders(t, "99", "101")
+		params := testParams(coinbasepro.OrderSideSell, 2)
+		params.MaxParticipation = 0.1
+		algo, err := NewTWAP(orders, params, 0, 1)
+		assert.Assert(t, is.Nil(err))
+		algo.HandleMessage(bookSnapshot("99", "101"))
+
+		assert.Assert(t, is.Nil(algo.Step(windowStart)))
+		assert.Equal(t, algo.Progress().ChildOrders, 0)
+
+		algo.HandleMessage(coinbasepro.Message{Type: coinbasepro.MessageTypeMatch, ProductId: "BTC-USD", Price: "100", Size: "3", Time: "2021-03-01T12:00:10Z"})
+		algo.HandleMessage(coinbasepro.Message{Type: coinbasepro.MessageTypeMatch, ProductId: "BTC-USD", Price: "100", Size: "50", Time: "2021-03-01T11:00:10Z"})
+		stepEvery(t, algo, time.Minute, 5*time.Minute)
+
+		progress := algo.Progress()
+		assertClose(t, progress.Filled, 0.3)
+		assert.Equal(t, progress.State, StateExpired)
+		assertClose(t, progress.AveragePrice, 99)
+		assertClose(t, progress.SlippageBps, 100)
+	})
+
+	t.Run("should pause, resume and cancel", func(t *testing.T) {
+		orders, _ := newTestOrders(t, "99", "101")
+		algo, err := NewTWAP(orders, testParams(coinbasepro.OrderSideBuy, 2), 0, 1)
+		assert.Assert(t, is.Nil(err))
+		algo.SetQuote(99, 101)
+
+		assert.Assert(t, is.Nil(algo.Pause()))
+		stepEvery(t, algo, time.Minute, time.Minute)
+		assert.Equal(t, algo.Progress().State, StatePaused)
+		assert.Equal(t, algo.Progress().ChildOrders, 0)
+
+		assert.Assert(t, is.Nil(algo.Resume()))
+		assert.Assert(t, is.Nil(algo.Step(windowStart.Add(time.Minute))))
+		assertClose(t, algo.Progress().Filled, 1)
+
+		assert.Assert(t, is.Nil(algo.Cancel()))
+		stepEvery(t, algo, time.Minute, 5*time.Minute)
+		assert.Equal(t, algo.Progress().State, StateCancelled)
+		assertClose(t, algo.Progress().Filled, 1)
+		assert.Error(t, algo.Cancel(), AlgoFinishedErrorMessage)
+	})
+
+	t.Run("should jitter slice times reproducibly", func(t *testing.T) {
+		first := twapSchedule(windowStart, windowStart.Add(time.Hour), 6, 0.5, rand.New(rand.NewSource(7)))
+		second := twapSchedule(windowStart, windowStart.Add(time.Hour), 6, 0.5, rand.New(rand.NewSource(7)))
+		assert.DeepEqual(t, first, second)
+
+		assert.Equal(t, first[0].At, windowStart)
+		for i, slice := range first {
+			expected := windowStart.Add(time.Duration(i) * 10 * time.Minute)
+			assert.Assert(t, slice.At.Sub(expected) <= 150*time.Second && expected.Sub(slice.At) <= 150*time.Second)
+		}
+
+		_, err := NewTWAP(nil, Params{ProductId: "BTC-USD", Side: "buy", Size: 1, Start: windowStart, End: windowStart, Slices: 1}, 0, 1)
+		assert.Error(t, err, InvalidParamsErrorMessage)
+	})
+}
+
+func TestVWAP(t *testing.T) {
+	candles := make([]coinbasepro.Candle, 0)
+	for day := 0; day < 3; day++ {
+		for hour := 0; hour < 24; hour++ {
+			volume := 1.0
+			if hour == 12 {
+				volume = 3
+			}
+			candles = append(candles, coinbasepro.Candle{Time: windowStart.Truncate(24 * time.Hour).Add(time.Duration(day*24+hour) * time.Hour), Volume: volume})
+		}
+	}
+
+	profile, err := NewVolumeProfile(candles, time.Hour)
+	assert.Assert(t, is.Nil(err))
+	assertClose(t, profile.Shares[12], 3.0/26)
+
+	schedule := vwapSchedule(windowStart, windowStart.Add(2*time.Hour), 2, profile)
+	assertClose(t, schedule[0].Target, 0.75)
+	assertClose(t, schedule[1].Target, 1)
+
+	orders, _ := newTestOrders(t, "99", "101")
+	params := testParams(coinbasepro.OrderSideBuy, 4)
+	params.End = windowStart.Add(2 * time.Hour)
+	params.Slices = 2
+	algo, err := NewVWAP(orders, params, profile)
+	assert.Assert(t, is.Nil(err))
+	algo.HandleMessage(bookSnapshot("99", "101"))
+	algo.HandleMessage(coinbasepro.Message{Type: coinbasepro.MessageTypeMatch, ProductId: "BTC-USD", Price: "100", Size: "1", Time: "2021-03-01T12:30:00Z"})
+	algo.HandleMessage(coinbasepro.Message{Type: coinbasepro.MessageTypeMatch, ProductId: "BTC-USD", Price: "103", Size: "2", Time: "2021-03-01T13:30:00Z"})
+
+	assert.Assert(t, is.Nil(algo.Step(windowStart)))
+	assertClose(t, algo.Progress().Filled, 3)
+	assert.Assert(t, is.Nil(algo.Step(windowStart.Add(time.Hour))))
+
+	progress := algo.Progress()
+	assert.Equal(t, progress.State, StateCompleted)
+	assertClose(t, progress.Benchmark, 102)
+	assertClose(t, progress.SlippageBps, (101.0-102)/102*1e4)
+
+	_, err = NewVolumeProfile(nil, time.Hour)
+	assert.Error(t, err, EmptyVolumeProfileErrorMessage)
+}
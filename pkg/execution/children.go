@@ -0,0 +1,75 @@
+package execution
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"math"
+	"strconv"
+)
+
+type childFill struct {
+	size  float64
+	value float64
+}
+
+// childOrders adds up the fills of the child orders placed for one parent order. Fills are taken
+// from the difference in filled size between updates, so repeated and out of order REST and
+// websocket updates are counted once.
+type childOrders struct {
+	fills  map[string]childFill
+	filled float64
+	value  float64
+	count  int
+}
+
+func newChildOrders() *childOrders {
+	return &childOrders{fills: make(map[string]childFill)}
+}
+
+func (c *childOrders) add(clientOid string) {
+	c.fills[clientOid] = childFill{}
+	c.count++
+}
+
+func (c *childOrders) owns(clientOid string) bool {
+	_, found := c.fills[clientOid]
+	return found
+}
+
+// apply records the fills in an update of a child and reports whether any were new.
+func (c *childOrders) apply(order coinbasepro.ManagedOrder) bool {
+	previous, found := c.fills[order.ClientOid]
+	if !found || order.FilledSize-previous.size <= 1e-12 {
+		return false
+	}
+
+	c.filled += order.FilledSize - previous.size
+	c.value += order.ExecutedValue - previous.value
+	c.fills[order.ClientOid] = childFill{size: order.FilledSize, value: order.ExecutedValue}
+
+	return true
+}
+
+func (c *childOrders) averagePrice() float64 {
+	if c.filled <= 0 {
+		return 0
+	}
+
+	return c.value / c.filled
+}
+
+func formatIncrement(value, increment float64) string {
+	places := 0
+	if increment > 0 && increment < 1 {
+		places = int(math.Ceil(-math.Log10(increment) - 1e-9))
+	}
+
+	return strconv.FormatFloat(value, 'f', places, 64)
+}
+
+func floorToIncrement(value, increment float64) float64 {
+	return math.Floor(value/increment+1e-9) * increment
+}
+
+func ceilToIncrement(value, increment float64) float64 {
+	return math.Ceil(value/increment-1e-9) * increment
+}
@@ -0,0 +1,791 @@
+package execution
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const ContingentKindOCO = "oco"
+const ContingentKindBracket = "bracket"
+const ContingentKindTrailingStop = "trailing_stop"
+
+const LegEntry = "entry"
+const LegTakeProfit = "take_profit"
+const LegStopLoss = "stop_loss"
+
+const LegTypeLimit = "limit"
+const LegTypeStop = "stop"
+
+const InvalidContingentErrorMessage = "contingent order needs a product, a side, a positive size and prices on the right side of each other"
+const UnknownContingentErrorMessage = "contingent order not found"
+
+const fillEpsilon = 1e-12
+
+// ContingentParams describe a contingent order. For OCO and trailing stop orders Side is the side
+// of the exit, sell to protect a long position. For brackets it is the side of the entry and the
+// exits take the other side. An EntryPrice of zero enters a bracket with a market order. A stop
+// loss trails the best price seen by TrailAmount or by the fraction TrailPercent when either is set.
+type ContingentParams struct {
+	ProductId      string
+	Side           string
+	Size           float64
+	EntryPrice     float64
+	TakeProfit     float64
+	StopLoss       float64
+	TrailAmount    float64
+	TrailPercent   float64
+	PriceIncrement float64
+	SizeIncrement  float64
+}
+
+// Leg is one order of a contingent order. Limit legs rest on the exchange. Stop legs are held
+// locally and sent as market orders once the price trades through StopPrice, so they never lock up
+// funds on the exchange and can follow the price when they trail.
+type Leg struct {
+	Name         string                 `json:"name"`
+	Type         string                 `json:"type"`
+	Price        float64                `json:"price,omitempty"`
+	StopPrice    float64                `json:"stop_price,omitempty"`
+	TrailAmount  float64                `json:"trail_amount,omitempty"`
+	TrailPercent float64                `json:"trail_percent,omitempty"`
+	Extreme      float64                `json:"extreme,omitempty"`
+	Triggered    bool                   `json:"triggered,omitempty"`
+	ClientOid    string                 `json:"client_oid,omitempty"`
+	OrderId      string                 `json:"order_id,omitempty"`
+	State        coinbasepro.OrderState `json:"state,omitempty"`
+	Filled       float64                `json:"filled"`
+	Value        float64                `json:"value"`
+}
+
+func (l *Leg) live() bool {
+	return l.ClientOid != "" && !l.State.IsTerminal()
+}
+
+// Contingent is a group of orders that are placed and cancelled depending on each other.
+type Contingent struct {
+	Id             string    `json:"id"`
+	Kind           string    `json:"kind"`
+	ProductId      string    `json:"product_id"`
+	Side           string    `json:"side"`
+	Size           float64   `json:"size"`
+	State          State     `json:"state"`
+	Error          string    `json:"error,omitempty"`
+	Entry          *Leg      `json:"entry,omitempty"`
+	Exits          []*Leg    `json:"exits"`
+	PriceIncrement float64   `json:"price_increment"`
+	SizeIncrement  float64   `json:"size_increment"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ExitSide is the side of the exit legs.
+func (c *Contingent) ExitSide() string {
+	if c.Entry != nil {
+		return oppositeSide(c.Side)
+	}
+
+	return c.Side
+}
+
+// ExitSize is the size the exits have to close, the filled entry size for brackets.
+func (c *Contingent) ExitSize() float64 {
+	if c.Entry != nil {
+		return c.Entry.Filled
+	}
+
+	return c.Size
+}
+
+// ExitFilled is the size filled by all exits together.
+func (c *Contingent) ExitFilled() float64 {
+	filled := 0.0
+	for _, leg := range c.Exits {
+		filled += leg.Filled
+	}
+
+	return filled
+}
+
+// armed reports whether the exits are working, brackets arm them once the entry is done.
+func (c *Contingent) armed() bool {
+	return c.State == StateRunning && (c.Entry == nil || (c.Entry.State.IsTerminal() && c.Entry.Filled > fillEpsilon))
+}
+
+func (c *Contingent) leg(clientOid string) *Leg {
+	if c.Entry != nil && c.Entry.ClientOid == clientOid {
+		return c.Entry
+	}
+
+	for _, leg := range c.Exits {
+		if leg.ClientOid == clientOid {
+			return leg
+		}
+	}
+
+	return nil
+}
+
+func (c *Contingent) stopLoss() *Leg {
+	for _, leg := range c.Exits {
+		if leg.Type == LegTypeStop {
+			return leg
+		}
+	}
+
+	return nil
+}
+
+func (c *Contingent) clone() Contingent {
+	clone := *c
+	if c.Entry != nil {
+		entry := *c.Entry
+		clone.Entry = &entry
+	}
+
+	clone.Exits = make([]*Leg, len(c.Exits))
+	for i, leg := range c.Exits {
+		exit := *leg
+		clone.Exits[i] = &exit
+	}
+
+	return clone
+}
+
+func oppositeSide(side string) string {
+	if side == coinbasepro.OrderSideBuy {
+		return coinbasepro.OrderSideSell
+	}
+
+	return coinbasepro.OrderSideBuy
+}
+
+// ContingentOrders manages OCO, bracket and trailing stop orders on top of an OrderManager. Every
+// change is written to a state file, so after a restart NewContingentOrders picks the orders up
+// where they were and Sync catches up with what happened on the exchange in the meantime. Prices
+// arrive through HandleMessage or SetPrice. It is safe for concurrent use.
+type ContingentOrders struct {
+	orders *coinbasepro.OrderManager
+	path   string
+
+	// OnUpdate is called after every change to a contingent order.
+	OnUpdate func(contingent Contingent)
+
+	mutex       sync.Mutex
+	contingents map[string]*Contingent
+	owners      map[string]string
+	prices      map[string]float64
+}
+
+// NewContingentOrders loads the contingent orders saved at path, an empty path keeps them in
+// memory only, and adopts their live orders into orders.
+func NewContingentOrders(orders *coinbasepro.OrderManager, path string) (*ContingentOrders, error) {
+	manager := &ContingentOrders{
+		orders:      orders,
+		path:        path,
+		contingents: make(map[string]*Contingent),
+		owners:      make(map[string]string),
+		prices:      make(map[string]float64),
+	}
+
+	if err := manager.load(); err != nil {
+		return nil, err
+	}
+
+	orders.Subscribe(manager.handleOrderUpdate)
+
+	return manager, nil
+}
+
+func (m *ContingentOrders) load() error {
+	if m.path == "" {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var contingents []*Contingent
+	if err := json.Unmarshal(contents, &contingents); err != nil {
+		return err
+	}
+
+	for _, contingent := range contingents {
+		m.contingents[contingent.Id] = contingent
+
+		for _, leg := range append([]*Leg{contingent.Entry}, contingent.Exits...) {
+			if leg == nil || leg.ClientOid == "" {
+				continue
+			}
+
+			m.owners[leg.ClientOid] = contingent.Id
+			if contingent.State == StateRunning && !leg.State.IsTerminal() {
+				m.orders.Track(m.legOrder(contingent, leg))
+			}
+		}
+	}
+
+	return nil
+}
+
+// save writes every contingent order to the state file, it must be called with the mutex held.
+func (m *ContingentOrders) save() error {
+	if m.path == "" {
+		return nil
+	}
+
+	contingents := make([]*Contingent, 0, len(m.contingents))
+	for _, contingent := range m.contingents {
+		contingents = append(contingents, contingent)
+	}
+	sort.Slice(contingents, func(i, j int) bool {
+		return contingents[i].CreatedAt.Before(contingents[j].CreatedAt)
+	})
+
+	contents, err := json.MarshalIndent(contingents, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporaryPath := m.path + ".tmp"
+	if err := ioutil.WriteFile(temporaryPath, contents, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryPath, m.path)
+}
+
+func (p ContingentParams) validate(kind string) error {
+	invalid := p.ProductId == "" || (p.Side != coinbasepro.OrderSideBuy && p.Side != coinbasepro.OrderSideSell) ||
+		p.Size <= 0 || p.TrailAmount < 0 || p.TrailPercent < 0 || p.TrailPercent >= 1 || p.EntryPrice < 0
+
+	exitSide := p.Side
+	if kind == ContingentKindBracket {
+		exitSide = oppositeSide(p.Side)
+	}
+
+	switch kind {
+	case ContingentKindOCO, ContingentKindBracket:
+		invalid = invalid || p.TakeProfit <= 0 || p.StopLoss <= 0 ||
+			(exitSide == coinbasepro.OrderSideSell && p.TakeProfit <= p.StopLoss) ||
+			(exitSide == coinbasepro.OrderSideBuy && p.TakeProfit >= p.StopLoss)
+
+		if kind == ContingentKindBracket && p.EntryPrice > 0 {
+			invalid = invalid || (exitSide == coinbasepro.OrderSideSell && (p.EntryPrice >= p.TakeProfit || p.EntryPrice <= p.StopLoss)) ||
+				(exitSide == coinbasepro.OrderSideBuy && (p.EntryPrice <= p.TakeProfit || p.EntryPrice >= p.StopLoss))
+		}
+	case ContingentKindTrailingStop:
+		invalid = invalid || (p.TrailAmount == 0) == (p.TrailPercent == 0)
+	}
+
+	if invalid {
+		return errors.New(InvalidContingentErrorMessage)
+	}
+
+	return nil
+}
+
+// PlaceOCO places a take profit limit order and holds a stop loss against it, whichever fills
+// first cancels the other. A partial fill of the take profit shrinks the stop loss.
+func (m *ContingentOrders) PlaceOCO(params ContingentParams) (Contingent, error) {
+	return m.place(ContingentKindOCO, params)
+}
+
+// PlaceBracket places an entry order and, once it is done, an OCO of take profit and stop loss
+// for the size it filled.
+func (m *ContingentOrders) PlaceBracket(params ContingentParams) (Contingent, error) {
+	return m.place(ContingentKindBracket, params)
+}
+
+// PlaceTrailingStop holds a stop that follows the best price by TrailAmount or TrailPercent and
+// sends a market order when the price comes back through it.
+func (m *ContingentOrders) PlaceTrailingStop(params ContingentParams) (Contingent, error) {
+	return m.place(ContingentKindTrailingStop, params)
+}
+
+func (m *ContingentOrders) place(kind string, params ContingentParams) (Contingent, error) {
+	if err := params.validate(kind); err != nil {
+		return Contingent{}, err
+	}
+
+	if params.PriceIncrement <= 0 {
+		params.PriceIncrement = 0.01
+	}
+
+	if params.SizeIncrement <= 0 {
+		params.SizeIncrement = 1e-8
+	}
+
+	now := time.Now().UTC()
+	contingent := &Contingent{
+		Id:             coinbasepro.NewClientOid(m.orders.ClientOidPrefix()),
+		Kind:           kind,
+		ProductId:      params.ProductId,
+		Side:           params.Side,
+		Size:           params.Size,
+		State:          StateRunning,
+		Exits:          make([]*Leg, 0, 2),
+		PriceIncrement: params.PriceIncrement,
+		SizeIncrement:  params.SizeIncrement,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if kind == ContingentKindBracket {
+		contingent.Entry = &Leg{Name: LegEntry, Type: coinbasepro.OrderTypeMarket, Price: params.EntryPrice}
+		if params.EntryPrice > 0 {
+			contingent.Entry.Type = LegTypeLimit
+		}
+	}
+
+	if kind != ContingentKindTrailingStop {
+		contingent.Exits = append(contingent.Exits, &Leg{Name: LegTakeProfit, Type: LegTypeLimit, Price: params.TakeProfit})
+	}
+
+	contingent.Exits = append(contingent.Exits, &Leg{
+		Name:         LegStopLoss,
+		Type:         LegTypeStop,
+		StopPrice:    params.StopLoss,
+		TrailAmount:  params.TrailAmount,
+		TrailPercent: params.TrailPercent,
+	})
+
+	m.mutex.Lock()
+	m.contingents[contingent.Id] = contingent
+	if price, found := m.prices[contingent.ProductId]; found && contingent.Entry == nil {
+		m.trail(contingent, contingent.stopLoss(), price)
+	}
+
+	actions := m.advance(contingent)
+	if err := m.save(); err != nil {
+		delete(m.contingents, contingent.Id)
+		m.mutex.Unlock()
+		return Contingent{}, err
+	}
+	snapshot := contingent.clone()
+	m.mutex.Unlock()
+
+	m.notify(snapshot)
+
+	err := m.run(actions)
+	snapshot, _ = m.Contingent(contingent.Id)
+
+	return snapshot, err
+}
+
+// legOrder is the exchange order for a limit or entry leg, or the market order a triggered stop
+// loss sends.
+func (m *ContingentOrders) legOrder(contingent *Contingent, leg *Leg) coinbasepro.Order {
+	order := coinbasepro.Order{
+		Id:        leg.OrderId,
+		ClientOid: leg.ClientOid,
+		Type:      coinbasepro.OrderTypeLimit,
+		Side:      contingent.ExitSide(),
+		ProductId: contingent.ProductId,
+	}
+
+	size := contingent.ExitSize()
+	switch {
+	case leg == contingent.Entry:
+		order.Side = contingent.Side
+		size = contingent.Size
+		if leg.Type == coinbasepro.OrderTypeMarket {
+			order.Type = coinbasepro.OrderTypeMarket
+		}
+	case leg.Type == LegTypeStop:
+		order.Type = coinbasepro.OrderTypeMarket
+		size = contingent.ExitSize() - (contingent.ExitFilled() - leg.Filled)
+	}
+
+	if order.Type == coinbasepro.OrderTypeLimit {
+		order.Price = formatIncrement(leg.Price, contingent.PriceIncrement)
+	}
+	order.Size = formatIncrement(floorToIncrement(size, contingent.SizeIncrement), contingent.SizeIncrement)
+
+	return order
+}
+
+// submit assigns the leg a client_oid and returns the action placing it. It must be called with
+// the mutex held, so a leg is never placed twice.
+func (m *ContingentOrders) submit(contingent *Contingent, leg *Leg) func() error {
+	leg.ClientOid = coinbasepro.NewClientOid(m.orders.ClientOidPrefix())
+	leg.State = coinbasepro.OrderStatePending
+	m.owners[leg.ClientOid] = contingent.Id
+	order := m.legOrder(contingent, leg)
+
+	return func() error {
+		_, err := m.orders.Place(order)
+		return err
+	}
+}
+
+func (m *ContingentOrders) cancel(clientOid string) func() error {
+	return func() error {
+		return m.orders.Cancel(clientOid)
+	}
+}
+
+// advance moves a contingent order on after a change and returns the orders to place and cancel.
+// It must be called with the mutex held, the actions must be run without it.
+func (m *ContingentOrders) advance(contingent *Contingent) []func() error {
+	if contingent.State != StateRunning {
+		return nil
+	}
+
+	contingent.UpdatedAt = time.Now().UTC()
+
+	if entry := contingent.Entry; entry != nil {
+		if entry.ClientOid == "" {
+			return []func() error{m.submit(contingent, entry)}
+		}
+
+		if !entry.State.IsTerminal() {
+			return nil
+		}
+
+		if entry.Filled <= fillEpsilon {
+			if entry.State == coinbasepro.OrderStateRejected {
+				return m.finish(contingent, StateFailed, "entry order was rejected")
+			}
+
+			return m.finish(contingent, StateCancelled, "")
+		}
+	}
+
+	if contingent.ExitFilled() >= contingent.ExitSize()-contingent.SizeIncrement/2 {
+		return m.finish(contingent, StateCompleted, "")
+	}
+
+	stop := contingent.stopLoss()
+	actions := make([]func() error, 0)
+	for _, leg := range contingent.Exits {
+		switch {
+		case leg.Type == LegTypeLimit && leg.ClientOid == "" && !stop.Triggered:
+			actions = append(actions, m.submit(contingent, leg))
+		case leg.Type == LegTypeLimit && leg.ClientOid != "" && leg.State.IsTerminal() && !stop.Triggered:
+			// the exchange or someone else took the take profit away, the stop loss goes with it
+			if leg.State == coinbasepro.OrderStateRejected {
+				return m.finish(contingent, StateFailed, leg.Name+" order was rejected")
+			}
+
+			return m.finish(contingent, StateCancelled, leg.Name+" order was cancelled")
+		case leg.Type == LegTypeStop && leg.ClientOid != "" && leg.State.IsTerminal():
+			return m.finish(contingent, StateFailed, fmt.Sprintf("%s order finished in state %s before it filled", leg.Name, leg.State))
+		}
+	}
+
+	return actions
+}
+
+// finish ends a contingent order and returns the actions cancelling whatever is still live.
+func (m *ContingentOrders) finish(contingent *Contingent, state State, reason string) []func() error {
+	contingent.State = state
+	contingent.Error = reason
+
+	actions := make([]func() error, 0)
+	for _, leg := range append([]*Leg{contingent.Entry}, contingent.Exits...) {
+		if leg != nil && leg.live() {
+			actions = append(actions, m.cancel(leg.ClientOid))
+		}
+	}
+
+	return actions
+}
+
+func (m *ContingentOrders) run(actions []func() error) error {
+	var firstErr error
+	for _, action := range actions {
+		if err := action(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func (m *ContingentOrders) handleOrderUpdate(update coinbasepro.OrderUpdate) {
+	m.mutex.Lock()
+	id, found := m.owners[update.Order.ClientOid]
+	if !found {
+		m.mutex.Unlock()
+		return
+	}
+
+	contingent := m.contingents[id]
+	leg := contingent.leg(update.Order.ClientOid)
+	if leg == nil || (leg.State == update.Order.State && leg.OrderId == update.Order.OrderId && update.Order.FilledSize-leg.Filled <= fillEpsilon) {
+		m.mutex.Unlock()
+		return
+	}
+
+	leg.OrderId = update.Order.OrderId
+	leg.State = update.Order.State
+	if update.Order.FilledSize > leg.Filled {
+		leg.Filled = update.Order.FilledSize
+		leg.Value = update.Order.ExecutedValue
+	}
+
+	actions := m.advance(contingent)
+	m.save()
+	snapshot := contingent.clone()
+	m.mutex.Unlock()
+
+	m.notify(snapshot)
+	m.run(actions)
+}
+
+// HandleMessage takes the last traded price from ticker and match messages.
+func (m *ContingentOrders) HandleMessage(message coinbasepro.Message) error {
+	switch message.Type {
+	case coinbasepro.MessageTypeTicker, coinbasepro.MessageTypeMatch, coinbasepro.MessageTypeLastMatch:
+		if message.Price == "" {
+			return nil
+		}
+
+		return m.SetPrice(message.ProductId, parseFloat(message.Price))
+	}
+
+	return nil
+}
+
+// SetPrice moves trailing stops with the price and triggers the stops it trades through.
+func (m *ContingentOrders) SetPrice(productId string, price float64) error {
+	m.mutex.Lock()
+	m.prices[productId] = price
+
+	changed := make([]Contingent, 0)
+	triggered := make([]string, 0)
+	for _, contingent := range m.contingents {
+		if contingent.ProductId != productId || !contingent.armed() {
+			continue
+		}
+
+		stop := contingent.stopLoss()
+		if stop.Triggered {
+			continue
+		}
+
+		moved := m.trail(contingent, stop, price)
+		if stop.StopPrice > 0 && ((contingent.ExitSide() == coinbasepro.OrderSideSell && price <= stop.StopPrice) ||
+			(contingent.ExitSide() == coinbasepro.OrderSideBuy && price >= stop.StopPrice)) {
+			stop.Triggered = true
+			triggered = append(triggered, contingent.Id)
+		}
+
+		if moved || stop.Triggered {
+			contingent.UpdatedAt = time.Now().UTC()
+			changed = append(changed, contingent.clone())
+		}
+	}
+
+	var err error
+	if len(changed) > 0 {
+		err = m.save()
+	}
+	m.mutex.Unlock()
+
+	for _, contingent := range changed {
+		m.notify(contingent)
+	}
+
+	for _, id := range triggered {
+		if triggerErr := m.trigger(id); triggerErr != nil && err == nil {
+			err = triggerErr
+		}
+	}
+
+	return err
+}
+
+// trail moves a trailing stop behind the best price seen and reports whether it moved. It must be
+// called with the mutex held.
+func (m *ContingentOrders) trail(contingent *Contingent, stop *Leg, price float64) bool {
+	if stop.TrailAmount == 0 && stop.TrailPercent == 0 {
+		return false
+	}
+
+	sell := contingent.ExitSide() == coinbasepro.OrderSideSell
+	if stop.Extreme != 0 && ((sell && price <= stop.Extreme) || (!sell && price >= stop.Extreme)) {
+		return false
+	}
+	stop.Extreme = price
+
+	if sell {
+		candidate := floorToIncrement(price-stop.TrailAmount-price*stop.TrailPercent, contingent.PriceIncrement)
+		if candidate > stop.StopPrice {
+			stop.StopPrice = candidate
+		}
+	} else {
+		candidate := ceilToIncrement(price+stop.TrailAmount+price*stop.TrailPercent, contingent.PriceIncrement)
+		if stop.StopPrice == 0 || candidate < stop.StopPrice {
+			stop.StopPrice = candidate
+		}
+	}
+
+	return true
+}
+
+// trigger cancels the take profit and sends the stop loss for whatever the take profit did not
+// fill. The final filled size of the take profit is fetched from the exchange first, so a fill
+// racing the cancel can never lead to closing more than the position.
+func (m *ContingentOrders) trigger(id string) error {
+	m.mutex.Lock()
+	contingent := m.contingents[id]
+	live := make([]string, 0)
+	for _, leg := range contingent.Exits {
+		if leg.Type == LegTypeLimit && leg.live() {
+			live = append(live, leg.ClientOid)
+		}
+	}
+	m.mutex.Unlock()
+
+	for _, clientOid := range live {
+		cancelErr := m.orders.Cancel(clientOid)
+		final, err := m.orders.Refresh(clientOid)
+		if !final.State.IsTerminal() {
+			if cancelErr != nil {
+				return cancelErr
+			}
+			return err
+		}
+	}
+
+	m.mutex.Lock()
+	stop := contingent.stopLoss()
+	actions := m.advance(contingent)
+	if contingent.State == StateRunning && stop.ClientOid == "" {
+		actions = append(actions, m.submit(contingent, stop))
+	}
+	m.save()
+	snapshot := contingent.clone()
+	m.mutex.Unlock()
+
+	m.notify(snapshot)
+
+	return m.run(actions)
+}
+
+// Sync refreshes every live leg over REST, then arms, places and triggers whatever should have
+// happened while the process was not running. Call it after a restart and whenever the feed was
+// interrupted.
+func (m *ContingentOrders) Sync() error {
+	m.mutex.Lock()
+	live := make([]string, 0)
+	for _, contingent := range m.contingents {
+		for _, leg := range append([]*Leg{contingent.Entry}, contingent.Exits...) {
+			if contingent.State == StateRunning && leg != nil && leg.live() {
+				live = append(live, leg.ClientOid)
+			}
+		}
+	}
+	m.mutex.Unlock()
+
+	var firstErr error
+	for _, clientOid := range live {
+		if _, err := m.orders.Refresh(clientOid); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	m.mutex.Lock()
+	actions := make([]func() error, 0)
+	triggered := make([]string, 0)
+	for _, contingent := range m.contingents {
+		actions = append(actions, m.advance(contingent)...)
+
+		if stop := contingent.stopLoss(); contingent.armed() && stop.Triggered && stop.ClientOid == "" {
+			triggered = append(triggered, contingent.Id)
+		}
+	}
+	if err := m.save(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	m.mutex.Unlock()
+
+	if err := m.run(actions); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	for _, id := range triggered {
+		if err := m.trigger(id); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Cancel cancels every live leg of a contingent order and stops it from placing any more.
+func (m *ContingentOrders) Cancel(id string) error {
+	m.mutex.Lock()
+	contingent, found := m.contingents[id]
+	if !found {
+		m.mutex.Unlock()
+		return fmt.Errorf("%s: %s", UnknownContingentErrorMessage, id)
+	}
+
+	if contingent.State.finished() {
+		m.mutex.Unlock()
+		return errors.New(AlgoFinishedErrorMessage)
+	}
+
+	actions := m.finish(contingent, StateCancelled, "")
+	contingent.UpdatedAt = time.Now().UTC()
+	err := m.save()
+	snapshot := contingent.clone()
+	m.mutex.Unlock()
+
+	m.notify(snapshot)
+
+	if runErr := m.run(actions); runErr != nil {
+		return runErr
+	}
+
+	return err
+}
+
+func (m *ContingentOrders) Contingent(id string) (Contingent, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	contingent, found := m.contingents[id]
+	if !found {
+		return Contingent{}, false
+	}
+
+	return contingent.clone(), true
+}
+
+// Contingents returns every contingent order, oldest first.
+func (m *ContingentOrders) Contingents() []Contingent {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	contingents := make([]Contingent, 0, len(m.contingents))
+	for _, contingent := range m.contingents {
+		contingents = append(contingents, contingent.clone())
+	}
+	sort.Slice(contingents, func(i, j int) bool {
+		return contingents[i].CreatedAt.Before(contingents[j].CreatedAt)
+	})
+
+	return contingents
+}
+
+func (m *ContingentOrders) notify(contingent Contingent) {
+	if m.OnUpdate != nil {
+		m.OnUpdate(contingent)
+	}
+}
@@ -0,0 +1,222 @@
+package execution
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"path/filepath"
+	"testing"
+)
+
+func newTestContingentOrders(t *testing.T, path string) (*ContingentOrders, *coinbasepro.OrderManager, *coinbasepro.PaperClient) {
+	orders, paper := newTestOrders(t, "99", "101")
+	contingents, err := NewContingentOrders(orders, path)
+	assert.Assert(t, is.Nil(err))
+
+	return contingents, orders, paper
+}
+
+func TestOCO(t *testing.T) {
+	t.Run("should complete when the take profit fills without sending the stop", func(t *testing.T) {
+		contingents, orders, paper := newTestContingentOrders(t, "")
+		oco, err := contingents.PlaceOCO(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideSell, Size: 1, TakeProfit: 110, StopLoss: 95})
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, oco.State, StateRunning)
+
+		open := orders.OpenOrders()
+		assert.Equal(t, len(open), 1)
+		assert.Equal(t, open[0].Side, coinbasepro.OrderSideSell)
+		assert.Equal(t, open[0].Price, "110.00")
+
+		assert.Assert(t, is.Nil(paper.HandleMessage(trade("111", "5"))))
+		assert.Assert(t, is.Nil(orders.Poll()))
+		assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 90)))
+
+		oco, _ = contingents.Contingent(oco.Id)
+		assert.Equal(t, oco.State, StateCompleted)
+		assertClose(t, oco.ExitFilled(), 1)
+		assert.Equal(t, oco.Exits[1].ClientOid, "")
+		assert.Equal(t, len(orders.Orders()), 1)
+	})
+
+	t.Run("should cancel the take profit and stop out what it did not fill", func(t *testing.T) {
+		contingents, orders, paper := newTestContingentOrders(t, "")
+		oco, err := contingents.PlaceOCO(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideSell, Size: 1, TakeProfit: 110, StopLoss: 96})
+		assert.Assert(t, is.Nil(err))
+
+		// the partial fill is not seen before the stop triggers, the cancel has to find it
+		assert.Assert(t, is.Nil(paper.HandleMessage(trade("111", "0.4"))))
+		assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 97)))
+		oco, _ = contingents.Contingent(oco.Id)
+		assert.Assert(t, !oco.Exits[1].Triggered)
+
+		assert.Assert(t, is.Nil(contingents.HandleMessage(coinbasepro.Message{Type: coinbasepro.MessageTypeTicker, ProductId: "BTC-USD", Price: "96"})))
+
+		oco, _ = contingents.Contingent(oco.Id)
+		assert.Equal(t, oco.State, StateCompleted)
+		assert.Equal(t, oco.Exits[0].State, coinbasepro.OrderStateCancelled)
+		assertClose(t, oco.Exits[0].Filled, 0.4)
+		assertClose(t, oco.Exits[1].Filled, 0.6)
+		assert.Equal(t, len(orders.OpenOrders()), 0)
+
+		stop, found := orders.Order(oco.Exits[1].ClientOid)
+		assert.Assert(t, found)
+		assert.Equal(t, stop.Type, coinbasepro.OrderTypeMarket)
+		assert.Equal(t, stop.Size, "0.60000000")
+		assertClose(t, paper.Balance("BTC").Balance, 9)
+	})
+
+	t.Run("should cancel every leg", func(t *testing.T) {
+		contingents, orders, _ := newTestContingentOrders(t, "")
+		oco, err := contingents.PlaceOCO(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Size: 1, TakeProfit: 90, StopLoss: 105})
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, is.Nil(contingents.Cancel(oco.Id)))
+		assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 110)))
+
+		oco, _ = contingents.Contingent(oco.Id)
+		assert.Equal(t, oco.State, StateCancelled)
+		assert.Equal(t, oco.Exits[1].ClientOid, "")
+		assert.Equal(t, len(orders.OpenOrders()), 0)
+		assert.Error(t, contingents.Cancel(oco.Id), AlgoFinishedErrorMessage)
+		assert.Error(t, contingents.Cancel("unknown"), UnknownContingentErrorMessage+": unknown")
+	})
+
+	t.Run("should validate prices", func(t *testing.T) {
+		contingents, _, _ := newTestContingentOrders(t, "")
+		_, err := contingents.PlaceOCO(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideSell, Size: 1, TakeProfit: 95, StopLoss: 110})
+		assert.Error(t, err, InvalidContingentErrorMessage)
+
+		_, err = contingents.PlaceBracket(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Size: 1, EntryPrice: 120, TakeProfit: 110, StopLoss: 95})
+		assert.Error(t, err, InvalidContingentErrorMessage)
+
+		_, err = contingents.PlaceTrailingStop(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideSell, Size: 1})
+		assert.Error(t, err, InvalidContingentErrorMessage)
+		assert.Equal(t, len(contingents.Contingents()), 0)
+	})
+}
+
+func TestBracket(t *testing.T) {
+	t.Run("should protect what the entry filled", func(t *testing.T) {
+		contingents, orders, paper := newTestContingentOrders(t, "")
+		bracket, err := contingents.PlaceBracket(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Size: 1, EntryPrice: 100, TakeProfit: 110, StopLoss: 95})
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, is.Nil(paper.HandleMessage(trade("99.5", "0.5"))))
+		assert.Assert(t, is.Nil(orders.Poll()))
+		assert.Equal(t, len(orders.OpenOrders()), 1)
+
+		assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 90)))
+		bracket, _ = contingents.Contingent(bracket.Id)
+		assert.Assert(t, !bracket.Exits[1].Triggered)
+
+		assert.Assert(t, is.Nil(orders.Cancel(bracket.Entry.ClientOid)))
+
+		bracket, _ = contingents.Contingent(bracket.Id)
+		assert.Equal(t, bracket.State, StateRunning)
+		assertClose(t, bracket.ExitSize(), 0.5)
+
+		open := orders.OpenOrders()
+		assert.Equal(t, len(open), 1)
+		assert.Equal(t, open[0].Side, coinbasepro.OrderSideSell)
+		assert.Equal(t, open[0].Size, "0.50000000")
+
+		assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 94)))
+		bracket, _ = contingents.Contingent(bracket.Id)
+		assert.Equal(t, bracket.State, StateCompleted)
+		assertClose(t, bracket.Exits[1].Filled, 0.5)
+		assertClose(t, paper.Balance("BTC").Balance, 10)
+	})
+
+	t.Run("should cancel when the entry never fills", func(t *testing.T) {
+		contingents, orders, _ := newTestContingentOrders(t, "")
+		bracket, err := contingents.PlaceBracket(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideSell, Size: 1, EntryPrice: 102, TakeProfit: 90, StopLoss: 105})
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(orders.Cancel(bracket.Entry.ClientOid)))
+
+		bracket, _ = contingents.Contingent(bracket.Id)
+		assert.Equal(t, bracket.State, StateCancelled)
+		assert.Equal(t, len(orders.Orders()), 1)
+	})
+}
+
+func TestTrailingStop(t *testing.T) {
+	t.Run("should follow the price up and sell when it comes back", func(t *testing.T) {
+		contingents, orders, _ := newTestContingentOrders(t, "")
+		assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 100)))
+
+		stops := make([]float64, 0)
+		contingents.OnUpdate = func(contingent Contingent) {
+			stops = append(stops, contingent.Exits[0].StopPrice)
+		}
+
+		trailing, err := contingents.PlaceTrailingStop(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideSell, Size: 1, TrailAmount: 2})
+		assert.Assert(t, is.Nil(err))
+		assertClose(t, trailing.Exits[0].StopPrice, 98)
+
+		for _, price := range []float64{105, 104, 106.5, 104.6} {
+			assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", price)))
+		}
+		assert.Equal(t, len(orders.Orders()), 0)
+
+		assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 104.5)))
+		trailing, _ = contingents.Contingent(trailing.Id)
+		assert.Equal(t, trailing.State, StateCompleted)
+		assertClose(t, trailing.Exits[0].Extreme, 106.5)
+		assert.DeepEqual(t, stops[:3], []float64{98, 103, 104.5})
+		assert.Equal(t, len(orders.Orders()), 1)
+	})
+
+	t.Run("should trail a buy stop by percentage", func(t *testing.T) {
+		contingents, _, _ := newTestContingentOrders(t, "")
+		trailing, err := contingents.PlaceTrailingStop(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Size: 1, TrailPercent: 0.1})
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 100)))
+		assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 90)))
+		trailing, _ = contingents.Contingent(trailing.Id)
+		assertClose(t, trailing.Exits[0].StopPrice, 99)
+
+		assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 99)))
+		trailing, _ = contingents.Contingent(trailing.Id)
+		assert.Equal(t, trailing.State, StateCompleted)
+	})
+}
+
+func TestContingentPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contingent.json")
+	contingents, orders, paper := newTestContingentOrders(t, path)
+
+	oco, err := contingents.PlaceOCO(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideSell, Size: 1, TakeProfit: 110, StopLoss: 95})
+	assert.Assert(t, is.Nil(err))
+	trailing, err := contingents.PlaceTrailingStop(ContingentParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideSell, Size: 2, TrailAmount: 5})
+	assert.Assert(t, is.Nil(err))
+	assert.Assert(t, is.Nil(contingents.SetPrice("BTC-USD", 120)))
+	assert.Assert(t, is.Nil(paper.HandleMessage(trade("111", "0.25"))))
+	assert.Assert(t, is.Nil(orders.Poll()))
+
+	// the process restarts, and the take profit fills while it is down
+	assert.Assert(t, is.Nil(paper.HandleMessage(trade("112", "5"))))
+
+	restarted, err := coinbasepro.NewOrderManager(paper, "a1")
+	assert.Assert(t, is.Nil(err))
+	recovered, err := NewContingentOrders(restarted, path)
+	assert.Assert(t, is.Nil(err))
+
+	assert.Equal(t, len(recovered.Contingents()), 2)
+	assert.Equal(t, len(restarted.OpenOrders()), 1)
+
+	assert.Assert(t, is.Nil(recovered.Sync()))
+	oco, _ = recovered.Contingent(oco.Id)
+	assert.Equal(t, oco.State, StateCompleted)
+	assertClose(t, oco.Exits[0].Filled, 1)
+
+	trailing, _ = recovered.Contingent(trailing.Id)
+	assert.Equal(t, trailing.State, StateRunning)
+	assertClose(t, trailing.Exits[0].StopPrice, 115)
+
+	assert.Assert(t, is.Nil(recovered.SetPrice("BTC-USD", 115)))
+	trailing, _ = recovered.Contingent(trailing.Id)
+	assert.Equal(t, trailing.State, StateCompleted)
+	assertClose(t, trailing.Exits[0].Filled, 2)
+}
@@ -0,0 +1,199 @@
+package execution
+
+import (
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"math"
+	"sync"
+)
+
+const InvalidIcebergErrorMessage = "iceberg needs a product, a side, a price and a display size no larger than its size"
+
+// IcebergParams describe a limit order of which only DisplaySize is ever shown on the book.
+type IcebergParams struct {
+	ProductId      string
+	Side           string
+	Size           float64
+	DisplaySize    float64
+	Price          float64
+	PostOnly       bool
+	PriceIncrement float64
+	SizeIncrement  float64
+}
+
+// Iceberg shows one slice of a larger limit order at a time and places the next slice once the
+// shown one has filled. It is safe for concurrent use.
+type Iceberg struct {
+	orders *coinbasepro.OrderManager
+	params IcebergParams
+
+	// OnProgress is called after every fill and state change.
+	OnProgress func(progress Progress)
+
+	mutex    sync.Mutex
+	state    State
+	err      error
+	current  string
+	children *childOrders
+}
+
+func NewIceberg(orders *coinbasepro.OrderManager, params IcebergParams) (*Iceberg, error) {
+	if params.ProductId == "" || (params.Side != coinbasepro.OrderSideBuy && params.Side != coinbasepro.OrderSideSell) ||
+		params.Price <= 0 || params.DisplaySize <= 0 || params.DisplaySize > params.Size {
+		return nil, errors.New(InvalidIcebergErrorMessage)
+	}
+
+	if params.SizeIncrement <= 0 {
+		params.SizeIncrement = 1e-8
+	}
+
+	if params.PriceIncrement <= 0 {
+		params.PriceIncrement = 0.01
+	}
+
+	iceberg := &Iceberg{orders: orders, params: params, state: StateRunning, children: newChildOrders()}
+	orders.Subscribe(iceberg.handleOrderUpdate)
+
+	return iceberg, nil
+}
+
+// Start shows the first slice.
+func (i *Iceberg) Start() error {
+	return i.placeSlice()
+}
+
+func (i *Iceberg) placeSlice() error {
+	i.mutex.Lock()
+	remaining := floorToIncrement(i.params.Size-i.children.filled, i.params.SizeIncrement)
+	if i.state != StateRunning || i.current != "" || remaining <= 0 {
+		i.mutex.Unlock()
+		return nil
+	}
+
+	order := coinbasepro.Order{
+		ClientOid: coinbasepro.NewClientOid(i.orders.ClientOidPrefix()),
+		Type:      coinbasepro.OrderTypeLimit,
+		Side:      i.params.Side,
+		ProductId: i.params.ProductId,
+		Price:     formatIncrement(i.params.Price, i.params.PriceIncrement),
+		Size:      formatIncrement(math.Min(i.params.DisplaySize, remaining), i.params.SizeIncrement),
+		PostOnly:  i.params.PostOnly,
+	}
+	i.children.add(order.ClientOid)
+	i.current = order.ClientOid
+	i.mutex.Unlock()
+
+	placed, err := i.orders.Place(order)
+	if err != nil || placed.State == coinbasepro.OrderStateRejected {
+		if err == nil {
+			err = errors.New(placed.RejectReason)
+		}
+
+		i.fail(order.ClientOid, err)
+		return err
+	}
+
+	return nil
+}
+
+func (i *Iceberg) fail(clientOid string, err error) {
+	i.mutex.Lock()
+	if i.current == clientOid {
+		i.current = ""
+	}
+	i.state = StateFailed
+	i.err = err
+	progress := i.progress()
+	i.mutex.Unlock()
+
+	i.notify(progress)
+}
+
+// handleOrderUpdate counts fills and shows the next slice once the current one has finished. A
+// slice that was cancelled by Cancel is not replaced, one cancelled by the exchange is.
+func (i *Iceberg) handleOrderUpdate(update coinbasepro.OrderUpdate) {
+	i.mutex.Lock()
+	if !i.children.owns(update.Order.ClientOid) {
+		i.mutex.Unlock()
+		return
+	}
+
+	changed := i.children.apply(update.Order)
+
+	replenish := false
+	if update.Order.State.IsTerminal() && i.current == update.Order.ClientOid {
+		i.current = ""
+		replenish = i.state == StateRunning && update.Order.State != coinbasepro.OrderStateRejected
+	}
+
+	if i.state == StateRunning && i.children.filled >= i.params.Size-i.params.SizeIncrement/2 {
+		i.state = StateCompleted
+		replenish = false
+		changed = true
+	}
+
+	progress := i.progress()
+	i.mutex.Unlock()
+
+	if changed {
+		i.notify(progress)
+	}
+
+	if replenish {
+		i.placeSlice()
+	}
+}
+
+// Cancel cancels the shown slice and stops replenishing. Fills of the slice that race the cancel
+// are still counted.
+func (i *Iceberg) Cancel() error {
+	i.mutex.Lock()
+	if i.state.finished() {
+		i.mutex.Unlock()
+		return errors.New(AlgoFinishedErrorMessage)
+	}
+
+	i.state = StateCancelled
+	current := i.current
+	progress := i.progress()
+	i.mutex.Unlock()
+
+	i.notify(progress)
+
+	if current != "" {
+		return i.orders.Cancel(current)
+	}
+
+	return nil
+}
+
+// Err is the error that failed the iceberg, if any.
+func (i *Iceberg) Err() error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	return i.err
+}
+
+func (i *Iceberg) Progress() Progress {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	return i.progress()
+}
+
+func (i *Iceberg) progress() Progress {
+	return Progress{
+		State:        i.state,
+		Filled:       i.children.filled,
+		Remaining:    math.Max(i.params.Size-i.children.filled, 0),
+		AveragePrice: i.children.averagePrice(),
+		ChildOrders:  i.children.count,
+	}
+}
+
+func (i *Iceberg) notify(progress Progress) {
+	if i.OnProgress != nil {
+		i.OnProgress(progress)
+	}
+}
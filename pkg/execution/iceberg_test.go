@@ -0,0 +1,75 @@
+package execution
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"testing"
+)
+
+func trade(price, size string) coinbasepro.Message {
+	return coinbasepro.Message{Type: coinbasepro.MessageTypeMatch, ProductId: "BTC-USD", Price: price, Size: size, MakerOrderId: "maker", TakerOrderId: "taker"}
+}
+
+func TestIceberg(t *testing.T) {
+	t.Run("should show one slice at a time until filled", func(t *testing.T) {
+		orders, paper := newTestOrders(t, "99", "101")
+		iceberg, err := NewIceberg(orders, IcebergParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Size: 2.5, DisplaySize: 1, Price: 100, PostOnly: true})
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(iceberg.Start()))
+
+		for i := 0; i < 3; i++ {
+			open := orders.OpenOrders()
+			assert.Equal(t, len(open), 1)
+			assert.Assert(t, open[0].Size == "1.00000000" || (i == 2 && open[0].Size == "0.50000000"), open[0].Size)
+			placed, err := paper.GetOrder(open[0].OrderId)
+			assert.Assert(t, is.Nil(err))
+			assert.Assert(t, placed.PostOnly)
+
+			assert.Assert(t, is.Nil(paper.HandleMessage(trade("99.5", "5"))))
+			assert.Assert(t, is.Nil(orders.Poll()))
+		}
+
+		progress := iceberg.Progress()
+		assert.Equal(t, progress.State, StateCompleted)
+		assertClose(t, progress.Filled, 2.5)
+		assertClose(t, progress.AveragePrice, 100)
+		assert.Equal(t, progress.ChildOrders, 3)
+		assert.Equal(t, len(orders.OpenOrders()), 0)
+	})
+
+	t.Run("should count partial fills and stop replenishing once cancelled", func(t *testing.T) {
+		orders, paper := newTestOrders(t, "99", "101")
+		iceberg, err := NewIceberg(orders, IcebergParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideSell, Size: 3, DisplaySize: 1, Price: 100.5})
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(iceberg.Start()))
+
+		assert.Assert(t, is.Nil(paper.HandleMessage(trade("101", "0.4"))))
+		assert.Assert(t, is.Nil(orders.Poll()))
+		assert.Assert(t, is.Nil(iceberg.Cancel()))
+
+		progress := iceberg.Progress()
+		assert.Equal(t, progress.State, StateCancelled)
+		assertClose(t, progress.Filled, 0.4)
+		assert.Equal(t, progress.ChildOrders, 1)
+		assert.Equal(t, len(orders.OpenOrders()), 0)
+		assert.Error(t, iceberg.Cancel(), AlgoFinishedErrorMessage)
+	})
+
+	t.Run("should fail when a post only slice is rejected", func(t *testing.T) {
+		orders, _ := newTestOrders(t, "99", "101")
+		iceberg, err := NewIceberg(orders, IcebergParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Size: 2, DisplaySize: 1, Price: 102, PostOnly: true})
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, iceberg.Start() != nil)
+		assert.Equal(t, iceberg.Progress().State, StateFailed)
+		assert.Assert(t, iceberg.Err() != nil)
+		assert.Equal(t, len(orders.OpenOrders()), 0)
+	})
+
+	t.Run("should validate params", func(t *testing.T) {
+		orders, _ := newTestOrders(t, "99", "101")
+		_, err := NewIceberg(orders, IcebergParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Size: 1, DisplaySize: 2, Price: 100})
+		assert.Error(t, err, InvalidIcebergErrorMessage)
+	})
+}
@@ -0,0 +1,337 @@
+package execution
+
+import (
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"math"
+	"sync"
+)
+
+const InvalidPeggedErrorMessage = "pegged order needs a product, a side, a positive size and a non negative offset"
+
+// PeggedParams describe a post only limit order that rests Offset behind the best price on its
+// own side of the book, the best bid for buys and the best ask for sells. LimitPrice is the worst
+// price it may be pegged to.
+type PeggedParams struct {
+	ProductId      string
+	Side           string
+	Size           float64
+	Offset         float64
+	LimitPrice     float64
+	PriceIncrement float64
+	SizeIncrement  float64
+}
+
+// Pegged keeps a post only order at the pegged price by cancelling and replacing it whenever the
+// book moves. A replacement is only placed once the previous order is confirmed done and its final
+// filled size is known, so a fill that races the cancel can never lead to overfilling. It is safe
+// for concurrent use.
+type Pegged struct {
+	orders *coinbasepro.OrderManager
+	params PeggedParams
+	book   *coinbasepro.OrderBook
+
+	// OnProgress is called after every fill and state change.
+	OnProgress func(progress Progress)
+
+	mutex        sync.Mutex
+	state        State
+	err          error
+	current      string
+	currentPrice float64
+	repricing    bool
+	reprices     int
+	bestBid      float64
+	bestAsk      float64
+	children     *childOrders
+}
+
+func NewPegged(orders *coinbasepro.OrderManager, params PeggedParams) (*Pegged, error) {
+	if params.ProductId == "" || (params.Side != coinbasepro.OrderSideBuy && params.Side != coinbasepro.OrderSideSell) ||
+		params.Size <= 0 || params.Offset < 0 {
+		return nil, errors.New(InvalidPeggedErrorMessage)
+	}
+
+	if params.SizeIncrement <= 0 {
+		params.SizeIncrement = 1e-8
+	}
+
+	if params.PriceIncrement <= 0 {
+		params.PriceIncrement = 0.01
+	}
+
+	pegged := &Pegged{
+		orders:   orders,
+		params:   params,
+		book:     coinbasepro.NewOrderBook(params.ProductId),
+		state:    StateRunning,
+		children: newChildOrders(),
+	}
+	orders.Subscribe(pegged.handleOrderUpdate)
+
+	return pegged, nil
+}
+
+// HandleMessage follows the book from level2 and ticker messages and re-pegs the order when the
+// pegged price changes.
+func (p *Pegged) HandleMessage(message coinbasepro.Message) error {
+	if message.ProductId != p.params.ProductId {
+		return nil
+	}
+
+	switch message.Type {
+	case coinbasepro.MessageTypeSnapshot, coinbasepro.MessageTypeL2Update:
+		if err := p.book.HandleMessage(message); err != nil {
+			return err
+		}
+
+		bid, _ := p.book.BestBid()
+		ask, _ := p.book.BestAsk()
+		return p.SetQuote(bid.Price, ask.Price)
+	case coinbasepro.MessageTypeTicker:
+		return p.SetQuote(parseFloat(message.BestBid), parseFloat(message.BestAsk))
+	}
+
+	return nil
+}
+
+// SetQuote sets the best bid and ask and re-pegs the order.
+func (p *Pegged) SetQuote(bestBid, bestAsk float64) error {
+	p.mutex.Lock()
+	p.bestBid, p.bestAsk = bestBid, bestAsk
+	p.mutex.Unlock()
+
+	return p.reprice()
+}
+
+// peggedPrice is the price the order should rest at, never crossing the spread.
+func (p *Pegged) peggedPrice() (float64, bool) {
+	increment := p.params.PriceIncrement
+	if p.params.Side == coinbasepro.OrderSideBuy {
+		if p.bestBid <= 0 {
+			return 0, false
+		}
+
+		price := p.bestBid - p.params.Offset
+		if p.params.LimitPrice > 0 {
+			price = math.Min(price, p.params.LimitPrice)
+		}
+		if p.bestAsk > 0 {
+			price = math.Min(price, p.bestAsk-increment)
+		}
+
+		price = floorToIncrement(price, increment)
+		return price, price > 0
+	}
+
+	if p.bestAsk <= 0 {
+		return 0, false
+	}
+
+	price := p.bestAsk + p.params.Offset
+	if p.params.LimitPrice > 0 {
+		price = math.Max(price, p.params.LimitPrice)
+	}
+	if p.bestBid > 0 {
+		price = math.Max(price, p.bestBid+increment)
+	}
+
+	return ceilToIncrement(price, increment), true
+}
+
+// reprice cancels the order if it is away from the pegged price and places a replacement for
+// whatever has not filled. Only one caller reprices at a time, the others return at once and the
+// one repricing checks the latest quote again before it returns, unless it is the price it just tried.
+func (p *Pegged) reprice() error {
+	attempted := math.NaN()
+	for {
+		p.mutex.Lock()
+		price, ok := p.peggedPrice()
+		target := p.currentPrice
+		if p.current == "" {
+			target = attempted
+		}
+
+		if p.state != StateRunning || p.repricing || !ok || math.Abs(price-target) < p.params.PriceIncrement/2 {
+			p.mutex.Unlock()
+			return nil
+		}
+
+		p.repricing = true
+		previous := p.current
+		p.mutex.Unlock()
+
+		// replace clears repricing on every path, together with the state it leaves behind
+		attempted = price
+		if err := p.replace(previous, price); err != nil {
+			return err
+		}
+
+		// a cancel that has not landed yet is tried again on the next quote
+		p.mutex.Lock()
+		working := previous != "" && p.current == previous
+		p.mutex.Unlock()
+		if working {
+			return nil
+		}
+	}
+}
+
+func (p *Pegged) replace(previous string, price float64) error {
+	if previous != "" {
+		cancelErr := p.orders.Cancel(previous)
+
+		// the final filled size decides the size of the replacement, so it must come from the
+		// exchange rather than from websocket messages that may still be in flight
+		final, err := p.orders.Refresh(previous)
+		if !final.State.IsTerminal() {
+			p.mutex.Lock()
+			p.repricing = false
+			p.mutex.Unlock()
+
+			if cancelErr != nil {
+				return cancelErr
+			}
+			return err
+		}
+
+		p.mutex.Lock()
+		p.children.apply(final)
+		if p.current == previous {
+			p.current = ""
+		}
+		p.mutex.Unlock()
+	}
+
+	p.mutex.Lock()
+	remaining := floorToIncrement(p.params.Size-p.children.filled, p.params.SizeIncrement)
+	if p.state != StateRunning || remaining <= 0 {
+		p.repricing = false
+		p.mutex.Unlock()
+		return nil
+	}
+
+	order := coinbasepro.Order{
+		ClientOid: coinbasepro.NewClientOid(p.orders.ClientOidPrefix()),
+		Type:      coinbasepro.OrderTypeLimit,
+		Side:      p.params.Side,
+		ProductId: p.params.ProductId,
+		Price:     formatIncrement(price, p.params.PriceIncrement),
+		Size:      formatIncrement(remaining, p.params.SizeIncrement),
+		PostOnly:  true,
+	}
+	p.children.add(order.ClientOid)
+	p.current = order.ClientOid
+	p.currentPrice = price
+	if previous != "" {
+		p.reprices++
+	}
+	p.mutex.Unlock()
+
+	placed, err := p.orders.Place(order)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// a post only order that would have crossed is rejected, the next quote tries again
+	if err != nil || placed.State.IsTerminal() {
+		if p.current == order.ClientOid {
+			p.current = ""
+		}
+	}
+	p.repricing = false
+
+	return err
+}
+
+func (p *Pegged) handleOrderUpdate(update coinbasepro.OrderUpdate) {
+	p.mutex.Lock()
+	if !p.children.owns(update.Order.ClientOid) {
+		p.mutex.Unlock()
+		return
+	}
+
+	changed := p.children.apply(update.Order)
+
+	// while repricing the replacement decides what happens to the current order
+	if update.Order.State.IsTerminal() && p.current == update.Order.ClientOid && !p.repricing {
+		p.current = ""
+	}
+
+	if p.state == StateRunning && p.children.filled >= p.params.Size-p.params.SizeIncrement/2 {
+		p.state = StateCompleted
+		changed = true
+	}
+
+	progress := p.progress()
+	p.mutex.Unlock()
+
+	if changed {
+		p.notify(progress)
+	}
+}
+
+// Cancel cancels the resting order and stops re-pegging.
+func (p *Pegged) Cancel() error {
+	p.mutex.Lock()
+	if p.state.finished() {
+		p.mutex.Unlock()
+		return errors.New(AlgoFinishedErrorMessage)
+	}
+
+	p.state = StateCancelled
+	current := p.current
+	progress := p.progress()
+	p.mutex.Unlock()
+
+	p.notify(progress)
+
+	if current != "" {
+		return p.orders.Cancel(current)
+	}
+
+	return nil
+}
+
+// Price is the price of the resting order, zero when there is none.
+func (p *Pegged) Price() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.current == "" {
+		return 0
+	}
+
+	return p.currentPrice
+}
+
+// Reprices is the number of times the order was cancelled and replaced.
+func (p *Pegged) Reprices() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.reprices
+}
+
+func (p *Pegged) Progress() Progress {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.progress()
+}
+
+func (p *Pegged) progress() Progress {
+	return Progress{
+		State:        p.state,
+		Filled:       p.children.filled,
+		Remaining:    math.Max(p.params.Size-p.children.filled, 0),
+		AveragePrice: p.children.averagePrice(),
+		ChildOrders:  p.children.count,
+	}
+}
+
+func (p *Pegged) notify(progress Progress) {
+	if p.OnProgress != nil {
+		p.OnProgress(progress)
+	}
+}
@@ -0,0 +1,91 @@
+package execution
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"testing"
+)
+
+func TestPegged(t *testing.T) {
+	t.Run("should follow the best bid by cancel and replace", func(t *testing.T) {
+		orders, paper := newTestOrders(t, "99", "101")
+		pegged, err := NewPegged(orders, PeggedParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Size: 1, Offset: 0.5})
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, is.Nil(pegged.HandleMessage(bookSnapshot("99", "101"))))
+		assertClose(t, pegged.Price(), 98.5)
+
+		assert.Assert(t, is.Nil(pegged.HandleMessage(bookSnapshot("99", "100.5"))))
+		assert.Equal(t, pegged.Reprices(), 0)
+
+		assert.Assert(t, is.Nil(pegged.HandleMessage(bookSnapshot("99.5", "100.5"))))
+		assertClose(t, pegged.Price(), 99)
+		assert.Equal(t, pegged.Reprices(), 1)
+
+		open := orders.OpenOrders()
+		assert.Equal(t, len(open), 1)
+		assert.Equal(t, open[0].Price, "99.00")
+		placed, err := paper.GetOrder(open[0].OrderId)
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, placed.PostOnly)
+	})
+
+	t.Run("should only replace what has not filled", func(t *testing.T) {
+		orders, paper := newTestOrders(t, "99", "101")
+		pegged, err := NewPegged(orders, PeggedParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideSell, Size: 2, LimitPrice: 100.5})
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, is.Nil(pegged.SetQuote(99, 101)))
+		assertClose(t, pegged.Price(), 101)
+
+		// the fill is never seen on the feed, the replacement learns of it from the exchange
+		assert.Assert(t, is.Nil(paper.HandleMessage(trade("101.5", "0.75"))))
+		assert.Assert(t, is.Nil(pegged.SetQuote(99, 100)))
+		assertClose(t, pegged.Price(), 100.5)
+
+		open := orders.OpenOrders()
+		assert.Equal(t, len(open), 1)
+		assert.Equal(t, open[0].Size, "1.25000000")
+
+		assert.Assert(t, is.Nil(paper.HandleMessage(trade("101", "5"))))
+		assert.Assert(t, is.Nil(orders.Poll()))
+
+		progress := pegged.Progress()
+		assert.Equal(t, progress.State, StateCompleted)
+		assertClose(t, progress.Filled, 2)
+		assertClose(t, progress.AveragePrice, (101*0.75+100.5*1.25)/2)
+		assert.Equal(t, progress.ChildOrders, 2)
+	})
+
+	t.Run("should retry a rejected post only order on the next quote", func(t *testing.T) {
+		orders, paper := newTestOrders(t, "99", "101")
+		pegged, err := NewPegged(orders, PeggedParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Size: 1})
+		assert.Assert(t, is.Nil(err))
+
+		// the local quote is stale, the paper book has already moved down through it
+		assert.Assert(t, is.Nil(paper.HandleMessage(bookSnapshot("97", "98"))))
+		assert.Assert(t, is.Nil(pegged.SetQuote(99, 101)))
+		assertClose(t, pegged.Price(), 0)
+		assert.Equal(t, len(orders.OpenOrders()), 0)
+
+		assert.Assert(t, is.Nil(pegged.SetQuote(97, 98)))
+		assertClose(t, pegged.Price(), 97)
+		assert.Equal(t, len(orders.OpenOrders()), 1)
+		assert.Equal(t, pegged.Progress().ChildOrders, 2)
+	})
+
+	t.Run("should cancel the resting order", func(t *testing.T) {
+		orders, _ := newTestOrders(t, "99", "101")
+		pegged, err := NewPegged(orders, PeggedParams{ProductId: "BTC-USD", Side: coinbasepro.OrderSideBuy, Size: 1})
+		assert.Assert(t, is.Nil(err))
+		assert.Assert(t, is.Nil(pegged.SetQuote(99, 101)))
+
+		assert.Assert(t, is.Nil(pegged.Cancel()))
+		assert.Equal(t, pegged.Progress().State, StateCancelled)
+		assert.Equal(t, len(orders.OpenOrders()), 0)
+
+		assert.Assert(t, is.Nil(pegged.SetQuote(100, 101)))
+		assert.Equal(t, len(orders.OpenOrders()), 0)
+	})
+}
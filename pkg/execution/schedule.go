@@ -0,0 +1,106 @@
+package execution
+
+import (
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"math/rand"
+	"time"
+)
+
+const EmptyVolumeProfileErrorMessage = "volume profile needs candles with volume"
+
+// slice is a point in time by which Target of the parent size should have been executed.
+type slice struct {
+	At     time.Time
+	Target float64
+}
+
+// twapSchedule spreads size evenly over slices between start and end. Each slice time is moved by
+// up to jitter of a slice interval in either direction so child orders are harder to spot.
+func twapSchedule(start, end time.Time, slices int, jitter float64, random *rand.Rand) []slice {
+	interval := end.Sub(start) / time.Duration(slices)
+	schedule := make([]slice, slices)
+	for i := range schedule {
+		at := start.Add(interval * time.Duration(i))
+		if i > 0 && jitter > 0 {
+			at = at.Add(time.Duration((random.Float64()*2 - 1) * jitter / 2 * float64(interval)))
+		}
+
+		schedule[i] = slice{At: at, Target: float64(i+1) / float64(slices)}
+	}
+
+	return schedule
+}
+
+// VolumeProfile is the share of daily volume traded in each bucket of the UTC day.
+type VolumeProfile struct {
+	Bucket time.Duration
+	Shares []float64
+}
+
+// NewVolumeProfile averages the intraday volume distribution of historical candles, typically a
+// few weeks of 5 minute to hourly candles, into buckets of the given length.
+func NewVolumeProfile(candles []coinbasepro.Candle, bucket time.Duration) (VolumeProfile, error) {
+	day := 24 * time.Hour
+	profile := VolumeProfile{Bucket: bucket, Shares: make([]float64, int(day/bucket))}
+
+	total := 0.0
+	for _, candle := range candles {
+		offset := candle.Time.UTC().Sub(candle.Time.UTC().Truncate(day))
+		profile.Shares[int(offset/bucket)%len(profile.Shares)] += candle.Volume
+		total += candle.Volume
+	}
+
+	if total <= 0 {
+		return VolumeProfile{}, errors.New(EmptyVolumeProfileErrorMessage)
+	}
+
+	for i := range profile.Shares {
+		profile.Shares[i] /= total
+	}
+
+	return profile, nil
+}
+
+// share is the expected share of daily volume traded between from and to.
+func (p VolumeProfile) share(from, to time.Time) float64 {
+	total := 0.0
+	for at := from; at.Before(to); {
+		bucketStart := at.UTC().Truncate(p.Bucket)
+		bucketEnd := bucketStart.Add(p.Bucket)
+		if bucketEnd.After(to) {
+			bucketEnd = to
+		}
+
+		offset := bucketStart.Sub(bucketStart.Truncate(24 * time.Hour))
+		total += p.Shares[int(offset/p.Bucket)%len(p.Shares)] * float64(bucketEnd.Sub(at)) / float64(p.Bucket)
+		at = bucketEnd
+	}
+
+	return total
+}
+
+// vwapSchedule sizes slices by the share of volume the profile expects in each of them. A window
+// the profile expects no volume in falls back to an even split.
+func vwapSchedule(start, end time.Time, slices int, profile VolumeProfile) []slice {
+	schedule := twapSchedule(start, end, slices, 0, nil)
+
+	total := profile.share(start, end)
+	if total <= 0 {
+		return schedule
+	}
+
+	cumulative := 0.0
+	for i := range schedule {
+		sliceEnd := end
+		if i+1 < len(schedule) {
+			sliceEnd = schedule[i+1].At
+		}
+
+		cumulative += profile.share(schedule[i].At, sliceEnd)
+		schedule[i].Target = cumulative / total
+	}
+	schedule[len(schedule)-1].Target = 1
+
+	return schedule
+}
@@ -0,0 +1,171 @@
+// Package indicators implements technical indicators over coinbasepro candles. Every indicator is
+// streaming, updated with one candle at a time, and Batch runs one over a slice of candles.
+package indicators
+
+import (
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"math"
+)
+
+const InvalidPeriodErrorMessage = "indicator periods must be positive"
+
+// Indicator is updated with candles in ascending time order. Value is NaN until the indicator has
+// seen enough candles to be Ready.
+type Indicator interface {
+	Update(candle coinbasepro.Candle)
+	Value() float64
+	Ready() bool
+}
+
+// Batch returns the value of indicator after each candle, NaN while it is warming up.
+func Batch(indicator Indicator, candles []coinbasepro.Candle) []float64 {
+	return BatchFunc(indicator, candles, indicator.Value)
+}
+
+// BatchFunc is Batch for indicators with more than one output, value reads the output to collect.
+func BatchFunc(indicator Indicator, candles []coinbasepro.Candle, value func() float64) []float64 {
+	values := make([]float64, len(candles))
+	for i, candle := range candles {
+		indicator.Update(candle)
+		if indicator.Ready() {
+			values[i] = value()
+		} else {
+			values[i] = math.NaN()
+		}
+	}
+
+	return values
+}
+
+func validatePeriods(periods ...int) error {
+	for _, period := range periods {
+		if period <= 0 {
+			return errors.New(InvalidPeriodErrorMessage)
+		}
+	}
+
+	return nil
+}
+
+func typicalPrice(candle coinbasepro.Candle) float64 {
+	return (candle.High + candle.Low + candle.Close) / 3
+}
+
+// window holds the last period values added to it.
+type window struct {
+	values []float64
+	next   int
+	full   bool
+}
+
+func newWindow(period int) *window {
+	return &window{values: make([]float64, period)}
+}
+
+// add stores value and returns the value it replaced, zero until the window is full.
+func (w *window) add(value float64) float64 {
+	replaced := w.values[w.next]
+	w.values[w.next] = value
+	w.next = (w.next + 1) % len(w.values)
+	if w.next == 0 {
+		w.full = true
+	}
+
+	return replaced
+}
+
+func (w *window) each(fn func(value float64)) {
+	length := len(w.values)
+	if !w.full {
+		length = w.next
+	}
+
+	for i := 0; i < length; i++ {
+		fn(w.values[i])
+	}
+}
+
+// SMA is the simple moving average of the last Period closes.
+type SMA struct {
+	window *window
+	sum    float64
+}
+
+func NewSMA(period int) (*SMA, error) {
+	if err := validatePeriods(period); err != nil {
+		return nil, err
+	}
+
+	return &SMA{window: newWindow(period)}, nil
+}
+
+func (s *SMA) Update(candle coinbasepro.Candle) {
+	s.Add(candle.Close)
+}
+
+// Add updates the average with any value, for smoothing the output of another indicator.
+func (s *SMA) Add(value float64) {
+	s.sum += value - s.window.add(value)
+}
+
+func (s *SMA) Ready() bool {
+	return s.window.full
+}
+
+func (s *SMA) Value() float64 {
+	if !s.Ready() {
+		return math.NaN()
+	}
+
+	return s.sum / float64(len(s.window.values))
+}
+
+// EMA is the exponential moving average of closes with a smoothing factor of 2 / (period + 1),
+// seeded with the simple average of the first period closes.
+type EMA struct {
+	period int
+	alpha  float64
+	value  float64
+	count  int
+}
+
+func NewEMA(period int) (*EMA, error) {
+	if err := validatePeriods(period); err != nil {
+		return nil, err
+	}
+
+	return &EMA{period: period, alpha: 2 / float64(period+1)}, nil
+}
+
+// newWilderAverage is the EMA variant Wilder used for RSI and ATR, smoothing by 1 / period.
+func newWilderAverage(period int) *EMA {
+	return &EMA{period: period, alpha: 1 / float64(period)}
+}
+
+func (e *EMA) Update(candle coinbasepro.Candle) {
+	e.Add(candle.Close)
+}
+
+// Add updates the average with any value, for smoothing the output of another indicator.
+func (e *EMA) Add(value float64) {
+	e.count++
+	if e.count <= e.period {
+		e.value += (value - e.value) / float64(e.count)
+		return
+	}
+
+	e.value += e.alpha * (value - e.value)
+}
+
+func (e *EMA) Ready() bool {
+	return e.count >= e.period
+}
+
+func (e *EMA) Value() float64 {
+	if !e.Ready() {
+		return math.NaN()
+	}
+
+	return e.value
+}
@@ -0,0 +1,224 @@
+package indicators
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"math"
+	"testing"
+	"time"
+)
+
+// The 10 day SMA and EMA reference values are StockCharts' published worked example.
+var referenceCloses = []float64{
+	22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29,
+	22.15, 22.39, 22.38, 22.61, 23.36, 24.05, 23.75, 23.83, 23.95, 23.63,
+	23.82, 23.87, 23.65, 23.19, 23.10, 23.33, 22.68, 23.10, 22.40, 22.17,
+}
+
+// The 14 period RSI reference values are Wilder's worked example as published by StockCharts.
+var rsiCloses = []float64{
+	44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08, 45.89,
+	46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64, 46.21, 46.25,
+	45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57, 43.42, 42.66, 43.13,
+}
+
+var referenceEpoch = time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// referenceCandles builds daily candles from closes, for the indicators that only read closes.
+func referenceCandles(closes []float64) []coinbasepro.Candle {
+	candles := make([]coinbasepro.Candle, len(closes))
+	for i, close := range closes {
+		candles[i] = coinbasepro.Candle{Time: referenceEpoch.Add(time.Duration(i) * 24 * time.Hour), Open: close, High: close, Low: close, Close: close}
+	}
+
+	return candles
+}
+
+// rangeCandles builds daily candles from high, low, close and volume rows.
+func rangeCandles(rows [][4]float64) []coinbasepro.Candle {
+	candles := make([]coinbasepro.Candle, len(rows))
+	for i, row := range rows {
+		candles[i] = coinbasepro.Candle{Time: referenceEpoch.Add(time.Duration(i) * 24 * time.Hour), High: row[0], Low: row[1], Open: row[2], Close: row[2], Volume: row[3]}
+	}
+
+	return candles
+}
+
+// ramp is closes rising by slope every candle from zero.
+func ramp(length int, slope float64) []float64 {
+	closes := make([]float64, length)
+	for i := range closes {
+		closes[i] = float64(i) * slope
+	}
+
+	return closes
+}
+
+func assertValues(t *testing.T, actual []float64, from int, expected []float64, tolerance float64) {
+	t.Helper()
+
+	for i := 0; i < from; i++ {
+		assert.Assert(t, math.IsNaN(actual[i]), "value %d should be warming up, got %f", i, actual[i])
+	}
+
+	for i, value := range expected {
+		assert.Assert(t, math.Abs(actual[from+i]-value) <= tolerance, "value %d: expected %f, got %f", from+i, value, actual[from+i])
+	}
+}
+
+func TestMovingAverages(t *testing.T) {
+	candles := referenceCandles(referenceCloses)
+
+	sma, err := NewSMA(10)
+	assert.Assert(t, is.Nil(err))
+	assertValues(t, Batch(sma, candles), 9, []float64{
+		22.22, 22.21, 22.23, 22.26, 22.30, 22.42, 22.61, 22.77, 22.91, 23.08, 23.21,
+		23.38, 23.52, 23.65, 23.71, 23.68, 23.61, 23.51, 23.43, 23.28, 23.13,
+	}, 0.01)
+
+	ema, err := NewEMA(10)
+	assert.Assert(t, is.Nil(err))
+	assertValues(t, Batch(ema, candles), 9, []float64{
+		22.22, 22.21, 22.24, 22.27, 22.33, 22.52, 22.80, 22.97, 23.13, 23.28, 23.34,
+		23.43, 23.51, 23.54, 23.47, 23.40, 23.39, 23.26, 23.23, 23.08, 22.92,
+	}, 0.01)
+
+	_, err = NewSMA(0)
+	assert.Error(t, err, InvalidPeriodErrorMessage)
+}
+
+func TestMomentum(t *testing.T) {
+	t.Run("rsi", func(t *testing.T) {
+		rsi, err := NewRSI(14)
+		assert.Assert(t, is.Nil(err))
+
+		// the published values were computed from rounded averages, so they differ slightly
+		assertValues(t, Batch(rsi, referenceCandles(rsiCloses)), 14, []float64{
+			70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+			54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77,
+		}, 0.1)
+	})
+
+	t.Run("rsi of a flat series", func(t *testing.T) {
+		rsi, err := NewRSI(14)
+		assert.Assert(t, is.Nil(err))
+		assertValues(t, Batch(rsi, referenceCandles([]float64{10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10})), 14, []float64{50}, 0)
+
+		rsi, _ = NewRSI(3)
+		assertValues(t, Batch(rsi, referenceCandles([]float64{10, 11, 12, 13})), 3, []float64{100}, 0)
+	})
+
+	t.Run("macd", func(t *testing.T) {
+		// An EMA seeded with the average of its first n closes of a ramp with slope s lags the ramp
+		// by exactly s(n-1)/2 from the seed on, so MACD(12, 26, 9) of a ramp is 7s for both lines,
+		// with Wilder's smoothing it would be 14s. Once the ramp stops at T each EMA closes the gap
+		// geometrically, T - s(n-1)/2 (1 - 2/(n+1))^k after k flat candles.
+		closes := ramp(40, 0.5)
+		for k := 0; k < 5; k++ {
+			closes = append(closes, closes[39])
+		}
+		candles := referenceCandles(closes)
+
+		macd, err := NewMACD(12, 26, 9)
+		assert.Assert(t, is.Nil(err))
+		values := BatchFunc(macd, candles[:40], macd.Value)
+		assertValues(t, values, 33, []float64{3.5, 3.5, 3.5, 3.5, 3.5, 3.5, 3.5}, 1e-9)
+		assert.Assert(t, math.Abs(macd.Signal()-3.5) < 1e-9, macd.Signal())
+		assert.Assert(t, math.Abs(macd.Histogram()) < 1e-9, macd.Histogram())
+
+		for k := 1; k <= 5; k++ {
+			macd.Update(candles[39+k])
+			expected := 0.5 * (12.5*math.Pow(1-2.0/27, float64(k)) - 5.5*math.Pow(1-2.0/13, float64(k)))
+			assert.Assert(t, math.Abs(macd.Value()-expected) < 1e-9, "after %d flat candles: expected %f, got %f", k, expected, macd.Value())
+		}
+	})
+
+	t.Run("stochastic", func(t *testing.T) {
+		// worked by hand: %K = 100 (close - lowest low) / (highest high - lowest low) over 3 candles,
+		// the comments give the highest high and lowest low
+		candles := rangeCandles([][4]float64{
+			{10, 8, 9, 0},
+			{11, 9, 10.5, 0},
+			{12, 10, 10, 0}, // 12 and 8: %K = 100 * 2 / 4 = 50
+			{11, 7, 8, 0},   // 12 and 7: %K = 100 * 1 / 5 = 20, %D = 35
+			{9, 6, 8.5, 0},  // 12 and 6: %K = 100 * 2.5 / 6 = 41.67, %D = 30.83
+			{9, 9, 9, 0},    // 11 and 6: %K = 100 * 3 / 5 = 60, %D = 50.83
+			{9, 9, 9, 0},    // 9 and 6: %K = 100 * 3 / 3 = 100, %D = 80
+			{9, 9, 9, 0},    // flat range: %K = 50, %D = 75
+		})
+
+		stochastic, err := NewStochastic(3, 2)
+		assert.Assert(t, is.Nil(err))
+		k := BatchFunc(stochastic, candles, stochastic.Value)
+		assertValues(t, k, 3, []float64{20, 41.666667, 60, 100, 50}, 1e-6)
+
+		stochastic, _ = NewStochastic(3, 2)
+		assertValues(t, BatchFunc(stochastic, candles, stochastic.D), 3, []float64{35, 30.833333, 50.833333, 80, 75}, 1e-6)
+	})
+}
+
+func TestVolatility(t *testing.T) {
+	t.Run("bollinger bands", func(t *testing.T) {
+		// closes 1 to 20 average 10.5 with a population variance of (20^2 - 1) / 12 = 33.25, a sample
+		// standard deviation would give 5.916080 instead of 5.766281
+		bands, err := NewBollingerBands(20, 2)
+		assert.Assert(t, is.Nil(err))
+		closes := ramp(21, 1)[1:]
+		assertValues(t, BatchFunc(bands, referenceCandles(closes), bands.Value), 19, []float64{10.5}, 1e-9)
+		assert.Assert(t, math.Abs(bands.StandardDeviation()-math.Sqrt(33.25)) < 1e-9, bands.StandardDeviation())
+		assert.Assert(t, math.Abs(bands.Upper()-22.032563) < 1e-6, bands.Upper())
+		assert.Assert(t, math.Abs(bands.Lower()+1.032563) < 1e-6, bands.Lower())
+	})
+
+	t.Run("atr", func(t *testing.T) {
+		// worked by hand: the first true range is high - low, later ones reach back to the previous
+		// close, the first ATR is their average and later ones smooth by 1 / period
+		candles := rangeCandles([][4]float64{
+			{10, 8, 9, 0},         // true range 2
+			{11, 9.5, 10, 0},      // 11 - 9 = 2
+			{10.5, 9, 9.5, 0},     // 10.5 - 9 = 1.5
+			{12, 11, 11.5, 0},     // 12 - 9.5 = 2.5, ATR (2 + 2 + 1.5 + 2.5) / 4 = 2
+			{11.6, 11.2, 11.4, 0}, // 11.6 - 11.2 = 0.4, ATR (2 * 3 + 0.4) / 4 = 1.6
+			{14, 12, 13, 0},       // 14 - 11.4 = 2.6, ATR (1.6 * 3 + 2.6) / 4 = 1.85
+		})
+
+		atr, err := NewATR(4)
+		assert.Assert(t, is.Nil(err))
+		assertValues(t, Batch(atr, candles), 3, []float64{2, 1.6, 1.85}, 1e-9)
+	})
+}
+
+func TestVolume(t *testing.T) {
+	// typical prices (high + low + close) / 3 of 10, 12, 15 and 20, the fourth an hour after the third
+	// and a fifth like it on the next day
+	candles := rangeCandles([][4]float64{
+		{12, 9, 9, 100},
+		{13, 11, 12, 300},
+		{16, 14, 15, 100},
+		{21, 19, 20, 200},
+	})
+	candles[3].Time = candles[2].Time.Add(time.Hour)
+	candles = append(candles, rangeCandles([][4]float64{{21, 19, 20, 200}})...)
+	candles[4].Time = candles[2].Time.Add(24 * time.Hour)
+
+	t.Run("vwap", func(t *testing.T) {
+		vwap := NewVWAP(0)
+		assert.Assert(t, math.IsNaN(vwap.Value()))
+		// (1000 + 3600) / 400, (4600 + 1500) / 500, (6100 + 4000) / 700, (10100 + 4000) / 900
+		assertValues(t, Batch(vwap, candles), 0, []float64{10, 11.5, 12.2, 14.428571, 15.666667}, 1e-6)
+
+		daily := NewVWAP(24 * time.Hour)
+		// the fourth candle shares the third one's day, the fifth starts a new one
+		assertValues(t, Batch(daily, candles), 0, []float64{10, 12, 15, 18.333333, 20}, 1e-6)
+	})
+
+	t.Run("obv", func(t *testing.T) {
+		// closes 9, 12, 15, 20, 20: up 300, up 100, up 200, unchanged
+		obv := NewOBV()
+		assertValues(t, Batch(obv, candles), 0, []float64{0, 300, 400, 600, 600}, 0)
+
+		obv = NewOBV()
+		assertValues(t, Batch(obv, rangeCandles([][4]float64{{0, 0, 10, 100}, {0, 0, 11, 200}, {0, 0, 11, 300}, {0, 0, 10.5, 400}, {0, 0, 12, 500}})), 0, []float64{0, 200, 200, -200, 300}, 0)
+	})
+}
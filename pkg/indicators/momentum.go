@@ -0,0 +1,156 @@
+package indicators
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"math"
+)
+
+// RSI is Wilder's relative strength index of closes.
+type RSI struct {
+	gains     *EMA
+	losses    *EMA
+	lastClose float64
+	started   bool
+}
+
+func NewRSI(period int) (*RSI, error) {
+	if err := validatePeriods(period); err != nil {
+		return nil, err
+	}
+
+	return &RSI{gains: newWilderAverage(period), losses: newWilderAverage(period)}, nil
+}
+
+func (r *RSI) Update(candle coinbasepro.Candle) {
+	if r.started {
+		change := candle.Close - r.lastClose
+		r.gains.Add(math.Max(change, 0))
+		r.losses.Add(math.Max(-change, 0))
+	}
+
+	r.lastClose = candle.Close
+	r.started = true
+}
+
+func (r *RSI) Ready() bool {
+	return r.gains.Ready()
+}
+
+func (r *RSI) Value() float64 {
+	if !r.Ready() {
+		return math.NaN()
+	}
+
+	// without losses the index is pinned at 100, unless nothing moved at all
+	if r.losses.Value() == 0 {
+		if r.gains.Value() == 0 {
+			return 50
+		}
+		return 100
+	}
+
+	return 100 - 100/(1+r.gains.Value()/r.losses.Value())
+}
+
+// MACD is the difference between a fast and a slow EMA of closes, with a signal line that is an EMA
+// of the difference. Value is the MACD line.
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+}
+
+func NewMACD(fastPeriod, slowPeriod, signalPeriod int) (*MACD, error) {
+	if err := validatePeriods(fastPeriod, slowPeriod, signalPeriod); err != nil {
+		return nil, err
+	}
+
+	fast, _ := NewEMA(fastPeriod)
+	slow, _ := NewEMA(slowPeriod)
+	signal, _ := NewEMA(signalPeriod)
+
+	return &MACD{fast: fast, slow: slow, signal: signal}, nil
+}
+
+func (m *MACD) Update(candle coinbasepro.Candle) {
+	m.fast.Update(candle)
+	m.slow.Update(candle)
+	if m.fast.Ready() && m.slow.Ready() {
+		m.signal.Add(m.fast.Value() - m.slow.Value())
+	}
+}
+
+// Ready reports whether the signal line has warmed up as well as the MACD line.
+func (m *MACD) Ready() bool {
+	return m.signal.Ready()
+}
+
+func (m *MACD) Value() float64 {
+	if !m.Ready() {
+		return math.NaN()
+	}
+
+	return m.fast.Value() - m.slow.Value()
+}
+
+func (m *MACD) Signal() float64 {
+	return m.signal.Value()
+}
+
+func (m *MACD) Histogram() float64 {
+	return m.Value() - m.Signal()
+}
+
+// Stochastic is the stochastic oscillator, %K places the close within the high-low range of the
+// last kPeriod candles and %D is its simple average over dPeriod. Value is %K.
+type Stochastic struct {
+	highs *window
+	lows  *window
+	k     float64
+	d     *SMA
+}
+
+func NewStochastic(kPeriod, dPeriod int) (*Stochastic, error) {
+	if err := validatePeriods(kPeriod, dPeriod); err != nil {
+		return nil, err
+	}
+
+	d, _ := NewSMA(dPeriod)
+	return &Stochastic{highs: newWindow(kPeriod), lows: newWindow(kPeriod), d: d}, nil
+}
+
+func (s *Stochastic) Update(candle coinbasepro.Candle) {
+	s.highs.add(candle.High)
+	s.lows.add(candle.Low)
+	if !s.highs.full {
+		return
+	}
+
+	highest, lowest := math.Inf(-1), math.Inf(1)
+	s.highs.each(func(value float64) { highest = math.Max(highest, value) })
+	s.lows.each(func(value float64) { lowest = math.Min(lowest, value) })
+
+	// a flat range has no position within it, treat it as the middle
+	s.k = 50
+	if highest > lowest {
+		s.k = 100 * (candle.Close - lowest) / (highest - lowest)
+	}
+	s.d.Add(s.k)
+}
+
+// Ready reports whether %D has warmed up as well as %K.
+func (s *Stochastic) Ready() bool {
+	return s.d.Ready()
+}
+
+func (s *Stochastic) Value() float64 {
+	if !s.Ready() {
+		return math.NaN()
+	}
+
+	return s.k
+}
+
+func (s *Stochastic) D() float64 {
+	return s.d.Value()
+}
@@ -0,0 +1,90 @@
+package indicators
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"math"
+)
+
+// BollingerBands are an SMA of closes with bands Width population standard deviations above and
+// below it. Value is the middle band.
+type BollingerBands struct {
+	middle *SMA
+	width  float64
+}
+
+func NewBollingerBands(period int, width float64) (*BollingerBands, error) {
+	if err := validatePeriods(period); err != nil {
+		return nil, err
+	}
+
+	middle, _ := NewSMA(period)
+	return &BollingerBands{middle: middle, width: width}, nil
+}
+
+func (b *BollingerBands) Update(candle coinbasepro.Candle) {
+	b.middle.Update(candle)
+}
+
+func (b *BollingerBands) Ready() bool {
+	return b.middle.Ready()
+}
+
+func (b *BollingerBands) Value() float64 {
+	return b.middle.Value()
+}
+
+func (b *BollingerBands) StandardDeviation() float64 {
+	if !b.Ready() {
+		return math.NaN()
+	}
+
+	mean := b.middle.Value()
+	variance := 0.0
+	b.middle.window.each(func(value float64) {
+		variance += (value - mean) * (value - mean)
+	})
+
+	return math.Sqrt(variance / float64(len(b.middle.window.values)))
+}
+
+func (b *BollingerBands) Upper() float64 {
+	return b.Value() + b.width*b.StandardDeviation()
+}
+
+func (b *BollingerBands) Lower() float64 {
+	return b.Value() - b.width*b.StandardDeviation()
+}
+
+// ATR is Wilder's average true range.
+type ATR struct {
+	average   *EMA
+	lastClose float64
+	started   bool
+}
+
+func NewATR(period int) (*ATR, error) {
+	if err := validatePeriods(period); err != nil {
+		return nil, err
+	}
+
+	return &ATR{average: newWilderAverage(period)}, nil
+}
+
+func (a *ATR) Update(candle coinbasepro.Candle) {
+	trueRange := candle.High - candle.Low
+	if a.started {
+		trueRange = math.Max(trueRange, math.Max(math.Abs(candle.High-a.lastClose), math.Abs(candle.Low-a.lastClose)))
+	}
+
+	a.average.Add(trueRange)
+	a.lastClose = candle.Close
+	a.started = true
+}
+
+func (a *ATR) Ready() bool {
+	return a.average.Ready()
+}
+
+func (a *ATR) Value() float64 {
+	return a.average.Value()
+}
@@ -0,0 +1,81 @@
+package indicators
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"math"
+	"time"
+)
+
+// VWAP is the volume weighted average of typical prices. With a session length it starts over at
+// each session boundary, a day for the usual intraday VWAP, otherwise it accumulates forever.
+type VWAP struct {
+	session      time.Duration
+	sessionStart time.Time
+	value        float64
+	volume       float64
+}
+
+func NewVWAP(session time.Duration) *VWAP {
+	return &VWAP{session: session}
+}
+
+func (v *VWAP) Update(candle coinbasepro.Candle) {
+	if v.session > 0 {
+		if start := candle.Time.UTC().Truncate(v.session); !start.Equal(v.sessionStart) {
+			v.sessionStart = start
+			v.value, v.volume = 0, 0
+		}
+	}
+
+	v.value += typicalPrice(candle) * candle.Volume
+	v.volume += candle.Volume
+}
+
+func (v *VWAP) Ready() bool {
+	return v.volume > 0
+}
+
+func (v *VWAP) Value() float64 {
+	if !v.Ready() {
+		return math.NaN()
+	}
+
+	return v.value / v.volume
+}
+
+// OBV is on-balance volume, the running total of volume added on up closes and subtracted on down
+// closes.
+type OBV struct {
+	value     float64
+	lastClose float64
+	started   bool
+}
+
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+func (o *OBV) Update(candle coinbasepro.Candle) {
+	if o.started {
+		if candle.Close > o.lastClose {
+			o.value += candle.Volume
+		} else if candle.Close < o.lastClose {
+			o.value -= candle.Volume
+		}
+	}
+
+	o.lastClose = candle.Close
+	o.started = true
+}
+
+func (o *OBV) Ready() bool {
+	return o.started
+}
+
+func (o *OBV) Value() float64 {
+	if !o.Ready() {
+		return math.NaN()
+	}
+
+	return o.value
+}
@@ -0,0 +1,153 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"strconv"
+)
+
+const StrategyNotRunningErrorMessage = "strategy is not running"
+const ProductNotAllowedErrorMessage = "strategy is not configured to trade this product"
+const OrderNotOwnedErrorMessage = "order was not placed by this strategy"
+
+// Context is the view of the engine given to one strategy. Orders can only be placed for the
+// strategy's products and only its own orders can be seen or cancelled.
+type Context struct {
+	engine *Engine
+	runner *runner
+}
+
+func (c *Context) Name() string {
+	return c.runner.name
+}
+
+func (c *Context) ProductIds() []string {
+	return append([]string(nil), c.runner.config.ProductIds...)
+}
+
+func (c *Context) Logf(format string, args ...interface{}) {
+	c.engine.Logger.Printf("strategy=%s %s", c.runner.name, fmt.Sprintf(format, args...))
+}
+
+// Book returns the level2 book of one of the strategy's products, it is empty unless the engine
+// is fed level2 messages.
+func (c *Context) Book(productId string) (*coinbasepro.OrderBook, error) {
+	if !c.runner.products[productId] {
+		return nil, errors.New(ProductNotAllowedErrorMessage)
+	}
+
+	c.engine.mutex.RLock()
+	defer c.engine.mutex.RUnlock()
+
+	return c.engine.books[productId], nil
+}
+
+func (c *Context) Position(productId string) Position {
+	c.runner.mutex.Lock()
+	defer c.runner.mutex.Unlock()
+
+	if position, found := c.runner.positions[productId]; found {
+		return *position
+	}
+
+	return Position{ProductId: productId}
+}
+
+// PlaceOrder places an order for one of the strategy's products. The client_oid is always assigned
+// by the engine so updates can be routed back to the strategy.
+func (c *Context) PlaceOrder(order coinbasepro.Order) (coinbasepro.ManagedOrder, error) {
+	if !c.runner.running() {
+		return coinbasepro.ManagedOrder{}, errors.New(StrategyNotRunningErrorMessage)
+	}
+
+	if !c.runner.products[order.ProductId] {
+		return coinbasepro.ManagedOrder{}, errors.New(ProductNotAllowedErrorMessage)
+	}
+
+	order.ClientOid = coinbasepro.NewClientOid(c.engine.orders.ClientOidPrefix())
+
+	c.runner.mutex.Lock()
+	c.runner.orders[order.ClientOid] = true
+	c.runner.mutex.Unlock()
+
+	c.engine.mutex.Lock()
+	c.engine.owners[order.ClientOid] = c.runner
+	c.engine.mutex.Unlock()
+
+	return c.engine.orders.Place(order)
+}
+
+// Buy places a limit order to buy size at price.
+func (c *Context) Buy(productId string, size, price float64) (coinbasepro.ManagedOrder, error) {
+	return c.PlaceOrder(limitOrder(productId, coinbasepro.OrderSideBuy, size, price))
+}
+
+// Sell places a limit order to sell size at price.
+func (c *Context) Sell(productId string, size, price float64) (coinbasepro.ManagedOrder, error) {
+	return c.PlaceOrder(limitOrder(productId, coinbasepro.OrderSideSell, size, price))
+}
+
+func limitOrder(productId, side string, size, price float64) coinbasepro.Order {
+	return coinbasepro.Order{
+		Type:      coinbasepro.OrderTypeLimit,
+		Side:      side,
+		ProductId: productId,
+		Size:      strconv.FormatFloat(size, 'f', -1, 64),
+		Price:     strconv.FormatFloat(price, 'f', -1, 64),
+	}
+}
+
+func (c *Context) owns(orderIdOrClientOid string) (coinbasepro.ManagedOrder, bool) {
+	order, found := c.engine.orders.Order(orderIdOrClientOid)
+	if !found {
+		return coinbasepro.ManagedOrder{}, false
+	}
+
+	c.runner.mutex.Lock()
+	defer c.runner.mutex.Unlock()
+
+	return order, c.runner.orders[order.ClientOid]
+}
+
+// Cancel cancels one of the strategy's orders by order id or client_oid.
+func (c *Context) Cancel(orderIdOrClientOid string) error {
+	if _, owned := c.owns(orderIdOrClientOid); !owned {
+		return errors.New(OrderNotOwnedErrorMessage)
+	}
+
+	return c.engine.orders.Cancel(orderIdOrClientOid)
+}
+
+// CancelAll cancels every open order of the strategy and returns the first error.
+func (c *Context) CancelAll() error {
+	var firstErr error
+	for _, order := range c.OpenOrders() {
+		if err := c.engine.orders.Cancel(order.ClientOid); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Order returns one of the strategy's orders by order id or client_oid.
+func (c *Context) Order(orderIdOrClientOid string) (coinbasepro.ManagedOrder, bool) {
+	return c.owns(orderIdOrClientOid)
+}
+
+func (c *Context) OpenOrders() []coinbasepro.ManagedOrder {
+	open := c.engine.orders.OpenOrders()
+
+	c.runner.mutex.Lock()
+	defer c.runner.mutex.Unlock()
+
+	owned := make([]coinbasepro.ManagedOrder, 0, len(open))
+	for _, order := range open {
+		if c.runner.orders[order.ClientOid] {
+			owned = append(owned, order)
+		}
+	}
+
+	return owned
+}
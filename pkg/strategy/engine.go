@@ -0,0 +1,595 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"log"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type State string
+
+const StateCreated State = "created"
+const StateRunning State = "running"
+const StatePaused State = "paused"
+const StateStopped State = "stopped"
+const StateFailed State = "failed"
+
+const DuplicateStrategyErrorMessage = "a strategy with this name is already registered"
+const UnknownStrategyErrorMessage = "no strategy is registered with this name"
+const MissingProductsErrorMessage = "a strategy needs at least one product"
+const EngineStartedErrorMessage = "strategies must be added before the engine starts"
+const StrategyNotPausedErrorMessage = "strategy is not paused"
+
+// Config selects the events a strategy receives. With a zero BarSpec the strategy gets no
+// candles unless they are passed to Engine.HandleBar.
+type Config struct {
+	ProductIds []string
+	BarSpec    coinbasepro.BarSpec
+}
+
+// Status is a point in time view of a strategy for monitoring.
+type Status struct {
+	Name          string              `json:"name"`
+	State         State               `json:"state"`
+	ProductIds    []string            `json:"product_ids"`
+	Positions     map[string]Position `json:"positions"`
+	RealizedPnl   float64             `json:"realized_pnl"`
+	UnrealizedPnl float64             `json:"unrealized_pnl"`
+	OpenOrders    int                 `json:"open_orders"`
+	Error         string              `json:"error,omitempty"`
+}
+
+type fillProgress struct {
+	size  float64
+	value float64
+}
+
+// runner owns one strategy and serialises its callbacks. An event raised while a callback runs,
+// from the same goroutine or another one, is queued and delivered by the goroutine already
+// dispatching, which keeps delivery synchronous and deterministic without reentrant callbacks.
+type runner struct {
+	name     string
+	strategy Strategy
+	config   Config
+	products map[string]bool
+	context  *Context
+
+	mutex       sync.Mutex
+	state       State
+	err         error
+	queue       []func()
+	held        []func()
+	dispatching bool
+	orders      map[string]bool
+	fills       map[string]fillProgress
+	positions   map[string]*Position
+}
+
+func (r *runner) running() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.state == StateRunning
+}
+
+func (r *runner) dispatch(fn func()) {
+	r.mutex.Lock()
+	r.queue = append(r.queue, fn)
+	if r.dispatching {
+		r.mutex.Unlock()
+		return
+	}
+
+	r.dispatching = true
+	for len(r.queue) > 0 {
+		next := r.queue[0]
+		r.queue = r.queue[1:]
+		r.mutex.Unlock()
+		next()
+		r.mutex.Lock()
+	}
+	r.dispatching = false
+	r.mutex.Unlock()
+}
+
+// Engine routes market data and order updates to strategies and places their orders through a
+// TradingAPI, usually a Client or a PaperClient. It is safe for concurrent use.
+type Engine struct {
+	orders *coinbasepro.OrderManager
+	Logger *log.Logger
+
+	mutex       sync.RWMutex
+	started     bool
+	runners     map[string]*runner
+	owners      map[string]*runner
+	books       map[string]*coinbasepro.OrderBook
+	aggregators map[coinbasepro.BarSpec]*coinbasepro.CandleAggregator
+}
+
+func NewEngine(trading coinbasepro.TradingAPI, clientOidPrefix string) (*Engine, error) {
+	orders, err := coinbasepro.NewOrderManager(trading, clientOidPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := &Engine{
+		orders:      orders,
+		Logger:      log.New(os.Stderr, "", log.LstdFlags),
+		runners:     make(map[string]*runner),
+		owners:      make(map[string]*runner),
+		books:       make(map[string]*coinbasepro.OrderBook),
+		aggregators: make(map[coinbasepro.BarSpec]*coinbasepro.CandleAggregator),
+	}
+	orders.Subscribe(engine.handleOrderUpdate)
+
+	return engine, nil
+}
+
+// OrderManager is shared by every strategy, it is exposed to feed it user channel messages from a
+// separate source or to poll it.
+func (e *Engine) OrderManager() *coinbasepro.OrderManager {
+	return e.orders
+}
+
+func (e *Engine) Add(name string, strategy Strategy, config Config) error {
+	if len(config.ProductIds) == 0 {
+		return errors.New(MissingProductsErrorMessage)
+	}
+
+	var aggregator *coinbasepro.CandleAggregator
+	if config.BarSpec != (coinbasepro.BarSpec{}) {
+		var err error
+		if aggregator, err = coinbasepro.NewCandleAggregator(config.BarSpec, 0); err != nil {
+			return err
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.started {
+		return errors.New(EngineStartedErrorMessage)
+	}
+
+	if _, found := e.runners[name]; found {
+		return errors.New(DuplicateStrategyErrorMessage)
+	}
+
+	r := &runner{
+		name:      name,
+		strategy:  strategy,
+		config:    config,
+		products:  make(map[string]bool),
+		state:     StateCreated,
+		orders:    make(map[string]bool),
+		fills:     make(map[string]fillProgress),
+		positions: make(map[string]*Position),
+	}
+	r.context = &Context{engine: e, runner: r}
+
+	for _, productId := range config.ProductIds {
+		r.products[productId] = true
+		if _, found := e.books[productId]; !found {
+			e.books[productId] = coinbasepro.NewOrderBook(productId)
+		}
+	}
+
+	if aggregator != nil {
+		if _, found := e.aggregators[config.BarSpec]; !found {
+			e.aggregators[config.BarSpec] = aggregator
+			spec := config.BarSpec
+			aggregator.Subscribe(func(update coinbasepro.BarUpdate) {
+				if update.Closed && !update.Revised {
+					e.deliverBar(spec, update.Bar)
+				}
+			})
+		}
+	}
+
+	e.runners[name] = r
+	return nil
+}
+
+func (e *Engine) sortedRunners() []*runner {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	runners := make([]*runner, 0, len(e.runners))
+	for _, r := range e.runners {
+		runners = append(runners, r)
+	}
+
+	sort.Slice(runners, func(i, j int) bool {
+		return runners[i].name < runners[j].name
+	})
+
+	return runners
+}
+
+// Start calls OnStart on every strategy in name order. A strategy whose OnStart fails or panics is
+// marked failed and receives no events, the others run regardless.
+func (e *Engine) Start() {
+	e.mutex.Lock()
+	e.started = true
+	e.mutex.Unlock()
+
+	for _, r := range e.sortedRunners() {
+		r.mutex.Lock()
+		if r.state != StateCreated {
+			r.mutex.Unlock()
+			continue
+		}
+		r.state = StateRunning
+		r.mutex.Unlock()
+
+		r.dispatch(func() {
+			var err error
+			e.call(r, "OnStart", func() { err = r.strategy.OnStart(r.context) })
+			if err != nil {
+				e.fail(r, fmt.Errorf("OnStart: %w", err))
+			}
+		})
+	}
+}
+
+// Stop calls OnStop on every running strategy. Open orders are left alone, cancelling them is up
+// to the strategies.
+func (e *Engine) Stop() {
+	for _, r := range e.sortedRunners() {
+		e.stop(r)
+	}
+}
+
+func (e *Engine) stop(r *runner) {
+	r.dispatch(func() {
+		r.mutex.Lock()
+		active := r.state == StateRunning || r.state == StatePaused
+		r.mutex.Unlock()
+
+		if !active {
+			return
+		}
+
+		e.call(r, "OnStop", func() { r.strategy.OnStop(r.context) })
+
+		r.mutex.Lock()
+		if r.state == StateRunning || r.state == StatePaused {
+			r.state = StateStopped
+		}
+		r.held = nil
+		r.mutex.Unlock()
+	})
+}
+
+// StopStrategy calls OnStop on one running or paused strategy, which then receives no more events.
+func (e *Engine) StopStrategy(name string) error {
+	r, err := e.runner(name)
+	if err != nil {
+		return err
+	}
+
+	e.stop(r)
+	return nil
+}
+
+// Pause stops a running strategy receiving market data and placing orders until Resume. Its open
+// orders stay on the book and fills still update its positions, OnFill and OnOrderUpdate are held
+// back and called in order on Resume.
+func (e *Engine) Pause(name string) error {
+	return e.transition(name, StateRunning, StatePaused, StrategyNotRunningErrorMessage)
+}
+
+func (e *Engine) Resume(name string) error {
+	if err := e.transition(name, StatePaused, StateRunning, StrategyNotPausedErrorMessage); err != nil {
+		return err
+	}
+
+	r, err := e.runner(name)
+	if err != nil {
+		return err
+	}
+
+	// the held events were queued by transition, this delivers them unless another goroutine is
+	// already dispatching
+	r.dispatch(func() {})
+	return nil
+}
+
+func (e *Engine) transition(name string, from, to State, message string) error {
+	r, err := e.runner(name)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.state != from {
+		return errors.New(message)
+	}
+
+	r.state = to
+	if to == StateRunning {
+		r.queue = append(r.queue, r.held...)
+		r.held = nil
+	}
+	e.Logger.Printf("strategy=%s state=%s", r.name, to)
+
+	return nil
+}
+
+// call runs one callback, turning a panic into a failure of that strategy alone.
+func (e *Engine) call(r *runner, callback string, fn func()) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			e.Logger.Printf("strategy=%s callback=%s panic=%q\n%s", r.name, callback, fmt.Sprint(recovered), debug.Stack())
+			e.fail(r, fmt.Errorf("%s panicked: %v", callback, recovered))
+		}
+	}()
+
+	fn()
+}
+
+func (e *Engine) fail(r *runner, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.state = StateFailed
+	r.err = err
+	r.queue = nil
+	r.held = nil
+	e.Logger.Printf("strategy=%s state=failed error=%q", r.name, err.Error())
+}
+
+// deliver queues a callback for every running strategy trading productId.
+func (e *Engine) deliver(productId, callback string, fn func(r *runner)) {
+	for _, r := range e.sortedRunners() {
+		if !r.products[productId] {
+			continue
+		}
+
+		r := r
+		r.dispatch(func() {
+			if r.running() {
+				e.call(r, callback, func() { fn(r) })
+			}
+		})
+	}
+}
+
+func (e *Engine) deliverBar(spec coinbasepro.BarSpec, bar coinbasepro.Bar) {
+	e.deliver(bar.ProductId, "OnCandle", func(r *runner) {
+		if r.config.BarSpec == spec {
+			r.strategy.OnCandle(r.context, bar)
+		}
+	})
+}
+
+// HandleBar delivers a closed bar built elsewhere, for example from stored candles, to every
+// strategy trading its product.
+func (e *Engine) HandleBar(bar coinbasepro.Bar) {
+	e.deliver(bar.ProductId, "OnCandle", func(r *runner) {
+		r.strategy.OnCandle(r.context, bar)
+	})
+}
+
+// HandleMessage routes a feed message: tickers and book updates go to the strategies trading the
+// product, matches build their candles and user channel messages update their orders.
+func (e *Engine) HandleMessage(message coinbasepro.Message) error {
+	switch message.Type {
+	case coinbasepro.MessageTypeTicker:
+		ticker := tickerFromMessage(message)
+		e.deliver(message.ProductId, "OnTicker", func(r *runner) {
+			r.strategy.OnTicker(r.context, ticker)
+		})
+	case coinbasepro.MessageTypeSnapshot, coinbasepro.MessageTypeL2Update:
+		e.mutex.RLock()
+		book, found := e.books[message.ProductId]
+		e.mutex.RUnlock()
+
+		if !found {
+			return nil
+		}
+
+		if err := book.HandleMessage(message); err != nil {
+			return err
+		}
+
+		e.deliver(message.ProductId, "OnBookUpdate", func(r *runner) {
+			r.strategy.OnBookUpdate(r.context, book)
+		})
+	case coinbasepro.MessageTypeMatch, coinbasepro.MessageTypeLastMatch:
+		e.mutex.RLock()
+		aggregators := make([]*coinbasepro.CandleAggregator, 0, len(e.aggregators))
+		for _, aggregator := range e.aggregators {
+			aggregators = append(aggregators, aggregator)
+		}
+		e.mutex.RUnlock()
+
+		for _, aggregator := range aggregators {
+			if err := aggregator.HandleMessage(message); err != nil {
+				return err
+			}
+		}
+	}
+
+	e.orders.HandleMessage(message)
+	return nil
+}
+
+// Consume handles every message from source until source fails.
+func (e *Engine) Consume(source coinbasepro.MessageSource) error {
+	for {
+		message, err := source.Read()
+		if err != nil {
+			return err
+		}
+
+		if err := e.HandleMessage(message); err != nil {
+			return err
+		}
+	}
+}
+
+// Flush closes time bars whose interval ended before now, call it periodically in quiet markets.
+func (e *Engine) Flush(now time.Time) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	for _, aggregator := range e.aggregators {
+		aggregator.Flush(now)
+	}
+}
+
+func (e *Engine) handleOrderUpdate(update coinbasepro.OrderUpdate) {
+	e.mutex.RLock()
+	r, found := e.owners[update.Order.ClientOid]
+	e.mutex.RUnlock()
+
+	if !found {
+		return
+	}
+
+	var fill *Fill
+	r.mutex.Lock()
+	previous := r.fills[update.Order.ClientOid]
+	if size := update.Order.FilledSize - previous.size; size > 1e-12 {
+		fill = &Fill{
+			ClientOid: update.Order.ClientOid,
+			OrderId:   update.Order.OrderId,
+			ProductId: update.Order.ProductId,
+			Side:      update.Order.Side,
+			Price:     (update.Order.ExecutedValue - previous.value) / size,
+			Size:      size,
+			Time:      update.Order.UpdatedAt,
+		}
+
+		position, found := r.positions[fill.ProductId]
+		if !found {
+			position = &Position{ProductId: fill.ProductId}
+			r.positions[fill.ProductId] = position
+		}
+		position.apply(fill.Side, fill.Price, fill.Size)
+		r.fills[update.Order.ClientOid] = fillProgress{size: update.Order.FilledSize, value: update.Order.ExecutedValue}
+	}
+	r.mutex.Unlock()
+
+	var deliver func()
+	deliver = func() {
+		r.mutex.Lock()
+		state := r.state
+		if state == StatePaused {
+			r.held = append(r.held, deliver)
+		}
+		r.mutex.Unlock()
+
+		if state != StateRunning {
+			return
+		}
+
+		if fill != nil {
+			e.call(r, "OnFill", func() { r.strategy.OnFill(r.context, *fill) })
+		}
+
+		if r.running() {
+			e.call(r, "OnOrderUpdate", func() { r.strategy.OnOrderUpdate(r.context, update) })
+		}
+	}
+
+	r.dispatch(deliver)
+}
+
+func (e *Engine) runner(name string) (*runner, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	r, found := e.runners[name]
+	if !found {
+		return nil, errors.New(UnknownStrategyErrorMessage)
+	}
+
+	return r, nil
+}
+
+// Status returns the status of one strategy.
+func (e *Engine) Status(name string) (Status, error) {
+	r, err := e.runner(name)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return e.status(r), nil
+}
+
+// Statuses returns the status of every strategy in name order.
+func (e *Engine) Statuses() []Status {
+	runners := e.sortedRunners()
+	statuses := make([]Status, 0, len(runners))
+	for _, r := range runners {
+		statuses = append(statuses, e.status(r))
+	}
+
+	return statuses
+}
+
+// OpenOrders returns the open orders placed by one strategy.
+func (e *Engine) OpenOrders(name string) ([]coinbasepro.ManagedOrder, error) {
+	r, err := e.runner(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.context.OpenOrders(), nil
+}
+
+// status values open positions at the mid of the engine's book, positions in products without a
+// book add nothing to UnrealizedPnl.
+func (e *Engine) status(r *runner) Status {
+	openOrders := len(r.context.OpenOrders())
+
+	e.mutex.RLock()
+	marks := make(map[string]float64, len(r.config.ProductIds))
+	for _, productId := range r.config.ProductIds {
+		if mid, found := e.books[productId].Mid(); found {
+			marks[productId] = mid
+		}
+	}
+	e.mutex.RUnlock()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	status := Status{
+		Name:       r.name,
+		State:      r.state,
+		ProductIds: append([]string(nil), r.config.ProductIds...),
+		Positions:  make(map[string]Position, len(r.positions)),
+		OpenOrders: openOrders,
+	}
+
+	for productId, position := range r.positions {
+		status.Positions[productId] = *position
+		status.RealizedPnl += position.RealizedPnl
+		if mark, found := marks[productId]; found {
+			status.UnrealizedPnl += position.UnrealizedPnl(mark)
+		}
+	}
+
+	if r.err != nil {
+		status.Error = r.err.Error()
+	}
+
+	return status
+}
+
+func parseFloat(value string) float64 {
+	parsed, _ := strconv.ParseFloat(value, 64)
+	return parsed
+}
@@ -0,0 +1,269 @@
+package strategy
+
+import (
+	"errors"
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"io/ioutil"
+	"log"
+	"math"
+	"testing"
+	"time"
+)
+
+type recordingStrategy struct {
+	BaseStrategy
+	events   []string
+	fills    []Fill
+	bars     []coinbasepro.Bar
+	onTick   func(ctx *Context, ticker Ticker)
+	startErr error
+}
+
+func (s *recordingStrategy) OnStart(ctx *Context) error {
+	s.events = append(s.events, "start")
+	return s.startErr
+}
+
+func (s *recordingStrategy) OnTicker(ctx *Context, ticker Ticker) {
+	s.events = append(s.events, "ticker")
+	if s.onTick != nil {
+		s.onTick(ctx, ticker)
+	}
+}
+
+func (s *recordingStrategy) OnCandle(ctx *Context, bar coinbasepro.Bar) {
+	s.events = append(s.events, "candle")
+	s.bars = append(s.bars, bar)
+}
+
+func (s *recordingStrategy) OnBookUpdate(ctx *Context, book *coinbasepro.OrderBook) {
+	s.events = append(s.events, "book")
+}
+
+func (s *recordingStrategy) OnFill(ctx *Context, fill Fill) {
+	s.events = append(s.events, "fill")
+	s.fills = append(s.fills, fill)
+}
+
+func (s *recordingStrategy) OnOrderUpdate(ctx *Context, update coinbasepro.OrderUpdate) {
+	s.events = append(s.events, "order:"+string(update.Order.State))
+}
+
+func (s *recordingStrategy) OnStop(ctx *Context) {
+	s.events = append(s.events, "stop")
+}
+
+func newTestEngine(t *testing.T) (*Engine, *coinbasepro.PaperClient) {
+	paper := coinbasepro.NewPaperClient(map[string]float64{"USD": 10000, "BTC": 2, "ETH": 10}, coinbasepro.FeeTier{})
+	t.Cleanup(func() { paper.Close() })
+
+	for _, productId := range []string{"BTC-USD", "ETH-USD"} {
+		err := paper.HandleMessage(coinbasepro.Message{
+			Type:      coinbasepro.MessageTypeSnapshot,
+			ProductId: productId,
+			Bids:      [][]string{{"99", "1"}},
+			Asks:      [][]string{{"101", "1"}},
+		})
+		assert.Assert(t, is.Nil(err))
+	}
+
+	engine, err := NewEngine(paper, "5eed")
+	assert.Assert(t, is.Nil(err))
+	engine.Logger = log.New(ioutil.Discard, "", 0)
+
+	return engine, paper
+}
+
+func tickerMessage(productId, price string) coinbasepro.Message {
+	return coinbasepro.Message{Type: coinbasepro.MessageTypeTicker, ProductId: productId, Price: price, Time: "2021-03-01T12:00:00Z"}
+}
+
+func TestEngine(t *testing.T) {
+	t.Run("should route events and track fills and positions", func(t *testing.T) {
+		engine, _ := newTestEngine(t)
+
+		strategy := &recordingStrategy{}
+		strategy.onTick = func(ctx *Context, ticker Ticker) {
+			if len(strategy.fills) > 0 {
+				return
+			}
+
+			_, err := ctx.Buy("BTC-USD", 0.5, 101)
+			assert.Check(t, is.Nil(err))
+			assert.Check(t, is.Len(strategy.fills, 0), "updates must wait for the callback to return")
+		}
+
+		assert.Assert(t, is.Nil(engine.Add("buyer", strategy, Config{ProductIds: []string{"BTC-USD"}})))
+		engine.Start()
+
+		assert.Assert(t, is.Nil(engine.HandleMessage(tickerMessage("ETH-USD", "10"))))
+		assert.Assert(t, is.Nil(engine.HandleMessage(tickerMessage("BTC-USD", "100"))))
+		assert.Assert(t, is.Nil(engine.HandleMessage(coinbasepro.Message{
+			Type: coinbasepro.MessageTypeL2Update, ProductId: "BTC-USD", Changes: [][]string{{"buy", "99.5", "1"}},
+		})))
+		engine.Stop()
+
+		assert.DeepEqual(t, strategy.events, []string{"start", "ticker", "order:pending", "fill", "order:done", "book", "stop"})
+		assert.Equal(t, strategy.fills[0].Size, 0.5)
+		assert.Equal(t, strategy.fills[0].Price, 101.0)
+
+		status, err := engine.Status("buyer")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, status.State, StateStopped)
+		assert.DeepEqual(t, status.Positions["BTC-USD"], Position{ProductId: "BTC-USD", Size: 0.5, AverageCost: 101})
+	})
+
+	t.Run("should isolate a panicking strategy", func(t *testing.T) {
+		engine, _ := newTestEngine(t)
+
+		panicking := &recordingStrategy{onTick: func(ctx *Context, ticker Ticker) {
+			panic("boom")
+		}}
+		healthy := &recordingStrategy{}
+		failing := &recordingStrategy{startErr: errors.New("no config")}
+
+		assert.Assert(t, is.Nil(engine.Add("a-panicking", panicking, Config{ProductIds: []string{"BTC-USD"}})))
+		assert.Assert(t, is.Nil(engine.Add("b-healthy", healthy, Config{ProductIds: []string{"BTC-USD"}})))
+		assert.Assert(t, is.Nil(engine.Add("c-failing", failing, Config{ProductIds: []string{"BTC-USD"}})))
+		engine.Start()
+
+		for i := 0; i < 2; i++ {
+			assert.Assert(t, is.Nil(engine.HandleMessage(tickerMessage("BTC-USD", "100"))))
+		}
+
+		assert.DeepEqual(t, panicking.events, []string{"start", "ticker"})
+		assert.DeepEqual(t, healthy.events, []string{"start", "ticker", "ticker"})
+		assert.DeepEqual(t, failing.events, []string{"start"})
+
+		statuses := engine.Statuses()
+		assert.Equal(t, statuses[0].State, StateFailed)
+		assert.Equal(t, statuses[0].Error, "OnTicker panicked: boom")
+		assert.Equal(t, statuses[1].State, StateRunning)
+		assert.Equal(t, statuses[2].Error, "OnStart: no config")
+	})
+
+	t.Run("should constrain strategies to their products and orders", func(t *testing.T) {
+		engine, _ := newTestEngine(t)
+
+		first, second := &recordingStrategy{}, &recordingStrategy{}
+		assert.Assert(t, is.Nil(engine.Add("first", first, Config{ProductIds: []string{"BTC-USD"}})))
+		assert.Assert(t, is.Nil(engine.Add("second", second, Config{ProductIds: []string{"ETH-USD"}})))
+		assert.Error(t, engine.Add("first", first, Config{ProductIds: []string{"BTC-USD"}}), DuplicateStrategyErrorMessage)
+		engine.Start()
+
+		firstContext := engine.runners["first"].context
+		secondContext := engine.runners["second"].context
+
+		_, err := firstContext.Buy("ETH-USD", 1, 10)
+		assert.Error(t, err, ProductNotAllowedErrorMessage)
+
+		order, err := firstContext.Buy("BTC-USD", 0.1, 90)
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, order.State, coinbasepro.OrderStateOpen)
+		assert.Equal(t, order.ClientOid[:4], "5eed")
+
+		assert.Error(t, secondContext.Cancel(order.ClientOid), OrderNotOwnedErrorMessage)
+		assert.Equal(t, len(secondContext.OpenOrders()), 0)
+		assert.Equal(t, len(firstContext.OpenOrders()), 1)
+
+		assert.Assert(t, is.Nil(firstContext.CancelAll()))
+		assert.Equal(t, len(firstContext.OpenOrders()), 0)
+
+		engine.Stop()
+		_, err = firstContext.Buy("BTC-USD", 0.1, 90)
+		assert.Error(t, err, StrategyNotRunningErrorMessage)
+	})
+
+	t.Run("should pause, resume and stop one strategy", func(t *testing.T) {
+		engine, _ := newTestEngine(t)
+
+		paused, other := &recordingStrategy{}, &recordingStrategy{}
+		assert.Assert(t, is.Nil(engine.Add("paused", paused, Config{ProductIds: []string{"BTC-USD"}})))
+		assert.Assert(t, is.Nil(engine.Add("other", other, Config{ProductIds: []string{"BTC-USD"}})))
+		assert.Error(t, engine.Pause("paused"), StrategyNotRunningErrorMessage)
+		engine.Start()
+
+		pausedContext := engine.runners["paused"].context
+		_, err := pausedContext.Sell("BTC-USD", 0.5, 99)
+		assert.Assert(t, is.Nil(err))
+		_, err = pausedContext.Buy("BTC-USD", 0.1, 90)
+		assert.Assert(t, is.Nil(err))
+
+		assert.Assert(t, is.Nil(engine.Pause("paused")))
+		assert.Error(t, engine.Pause("paused"), StrategyNotRunningErrorMessage)
+		assert.Error(t, engine.Resume("other"), StrategyNotPausedErrorMessage)
+		assert.Error(t, engine.Pause("missing"), UnknownStrategyErrorMessage)
+
+		assert.Assert(t, is.Nil(engine.HandleMessage(tickerMessage("BTC-USD", "100"))))
+		_, err = pausedContext.Buy("BTC-USD", 0.1, 90)
+		assert.Error(t, err, StrategyNotRunningErrorMessage)
+
+		assert.Assert(t, is.Nil(engine.HandleMessage(coinbasepro.Message{
+			Type: coinbasepro.MessageTypeSnapshot, ProductId: "BTC-USD", Bids: [][]string{{"99", "1"}}, Asks: [][]string{{"101", "1"}},
+		})))
+
+		status, err := engine.Status("paused")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, status.State, StatePaused)
+		assert.Equal(t, status.OpenOrders, 1)
+		assert.Equal(t, status.Positions["BTC-USD"].Size, -0.5)
+		assert.Equal(t, status.UnrealizedPnl, -0.5)
+
+		orders, err := engine.OpenOrders("paused")
+		assert.Assert(t, is.Nil(err))
+		assert.Equal(t, len(orders), 1)
+		assert.Equal(t, orders[0].Price, "90")
+
+		assert.Assert(t, is.Nil(engine.OrderManager().Cancel(orders[0].ClientOid)))
+		assert.DeepEqual(t, paused.events, []string{"start", "order:pending", "fill", "order:done", "order:pending", "order:open"})
+
+		assert.Assert(t, is.Nil(engine.Resume("paused")))
+		assert.Assert(t, is.Nil(engine.HandleMessage(tickerMessage("BTC-USD", "100"))))
+		assert.Assert(t, is.Nil(engine.StopStrategy("paused")))
+
+		assert.DeepEqual(t, paused.events, []string{"start", "order:pending", "fill", "order:done", "order:pending", "order:open", "order:cancelled", "ticker", "stop"})
+		assert.DeepEqual(t, other.events, []string{"start", "ticker", "book", "ticker"})
+
+		status, _ = engine.Status("paused")
+		assert.Equal(t, status.State, StateStopped)
+		status, _ = engine.Status("other")
+		assert.Equal(t, status.State, StateRunning)
+	})
+
+	t.Run("should build candles from matches", func(t *testing.T) {
+		engine, _ := newTestEngine(t)
+
+		strategy := &recordingStrategy{}
+		spec := coinbasepro.BarSpec{Type: coinbasepro.BarTypeTime, Interval: time.Minute}
+		assert.Assert(t, is.Nil(engine.Add("bars", strategy, Config{ProductIds: []string{"BTC-USD"}, BarSpec: spec})))
+		engine.Start()
+
+		for i, at := range []string{"2021-03-01T12:00:10Z", "2021-03-01T12:00:50Z", "2021-03-01T12:01:05Z"} {
+			err := engine.HandleMessage(coinbasepro.Message{
+				Type: coinbasepro.MessageTypeMatch, ProductId: "BTC-USD", TradeId: int64(i + 1), Price: "100", Size: "1", Time: at,
+			})
+			assert.Assert(t, is.Nil(err))
+		}
+
+		assert.Equal(t, len(strategy.bars), 1)
+		assert.Equal(t, strategy.bars[0].Volume, 2.0)
+
+		engine.Flush(time.Date(2021, 3, 1, 12, 2, 0, 0, time.UTC))
+		assert.Equal(t, len(strategy.bars), 2)
+	})
+}
+
+func TestPosition(t *testing.T) {
+	position := Position{ProductId: "BTC-USD"}
+	position.apply(coinbasepro.OrderSideBuy, 100, 1)
+	position.apply(coinbasepro.OrderSideBuy, 110, 1)
+	assert.Equal(t, position.AverageCost, 105.0)
+
+	position.apply(coinbasepro.OrderSideSell, 120, 3)
+	assert.Equal(t, position.RealizedPnl, 30.0)
+	assert.Equal(t, position.Size, -1.0)
+	assert.Equal(t, position.AverageCost, 120.0)
+	assert.Assert(t, math.Abs(position.UnrealizedPnl(100)-20) < 1e-9)
+}
@@ -0,0 +1,115 @@
+// Package strategy runs trading strategies against live or simulated market data. Strategies only
+// see the events for their own products and can only touch the orders they placed themselves.
+package strategy
+
+import (
+	"github.com/anishpateluk/coinbasepro-trader/pkg/coinbasepro"
+	"time"
+)
+
+// Strategy receives market and order events from an Engine. Callbacks for one strategy are never
+// called concurrently, so a strategy can keep its state in plain fields. Orders placed from a
+// callback produce their updates after the callback returns.
+type Strategy interface {
+	OnStart(ctx *Context) error
+	OnCandle(ctx *Context, bar coinbasepro.Bar)
+	OnTicker(ctx *Context, ticker Ticker)
+	OnBookUpdate(ctx *Context, book *coinbasepro.OrderBook)
+	OnFill(ctx *Context, fill Fill)
+	OnOrderUpdate(ctx *Context, update coinbasepro.OrderUpdate)
+	OnStop(ctx *Context)
+}
+
+// BaseStrategy implements every callback as a no-op, embed it to only implement the ones needed.
+type BaseStrategy struct{}
+
+func (BaseStrategy) OnStart(ctx *Context) error                                 { return nil }
+func (BaseStrategy) OnCandle(ctx *Context, bar coinbasepro.Bar)                 {}
+func (BaseStrategy) OnTicker(ctx *Context, ticker Ticker)                       {}
+func (BaseStrategy) OnBookUpdate(ctx *Context, book *coinbasepro.OrderBook)     {}
+func (BaseStrategy) OnFill(ctx *Context, fill Fill)                             {}
+func (BaseStrategy) OnOrderUpdate(ctx *Context, update coinbasepro.OrderUpdate) {}
+func (BaseStrategy) OnStop(ctx *Context)                                        {}
+
+type Ticker struct {
+	ProductId string
+	Price     float64
+	BestBid   float64
+	BestAsk   float64
+	LastSize  float64
+	Volume24h float64
+	TradeId   int64
+	Time      time.Time
+}
+
+func tickerFromMessage(message coinbasepro.Message) Ticker {
+	ticker := Ticker{ProductId: message.ProductId, TradeId: message.TradeId}
+	ticker.Price = parseFloat(message.Price)
+	ticker.BestBid = parseFloat(message.BestBid)
+	ticker.BestAsk = parseFloat(message.BestAsk)
+	ticker.LastSize = parseFloat(message.LastSize)
+	ticker.Volume24h = parseFloat(message.Volume24h)
+	ticker.Time, _ = time.Parse(time.RFC3339Nano, message.Time)
+
+	return ticker
+}
+
+// Fill is the part of an order executed since the previous update of that order.
+type Fill struct {
+	ClientOid string
+	OrderId   string
+	ProductId string
+	Side      string
+	Price     float64
+	Size      float64
+	Time      time.Time
+}
+
+// Position is the net base currency held by a strategy in one product, valued at average cost.
+type Position struct {
+	ProductId   string  `json:"product_id"`
+	Size        float64 `json:"size"`
+	AverageCost float64 `json:"average_cost"`
+	RealizedPnl float64 `json:"realized_pnl"`
+}
+
+// apply adds a fill, realising profit or loss on the part that reduces the position.
+func (p *Position) apply(side string, price, size float64) {
+	signed := size
+	if side == coinbasepro.OrderSideSell {
+		signed = -size
+	}
+
+	if p.Size != 0 && (p.Size > 0) != (signed > 0) {
+		closed := signed
+		if abs(closed) > abs(p.Size) {
+			closed = -p.Size
+		}
+
+		p.RealizedPnl += -closed * (price - p.AverageCost)
+		p.Size += closed
+		signed -= closed
+
+		if abs(p.Size) < 1e-12 {
+			p.Size, p.AverageCost = 0, 0
+		}
+	}
+
+	if signed != 0 {
+		p.AverageCost = (p.AverageCost*p.Size + price*signed) / (p.Size + signed)
+		p.Size += signed
+	}
+}
+
+// UnrealizedPnl values the position at mark.
+func (p Position) UnrealizedPnl(mark float64) float64 {
+	return p.Size * (mark - p.AverageCost)
+}
+
+func abs(value float64) float64 {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}